@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/migrations"
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// loadEnv loads the migration environment
+func loadEnv() string {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "dev"
+	}
+
+	// Map "production" to "prod" for filename
+	filename := env
+	if env == "production" {
+		filename = "prod"
+	}
+
+	envPath := "configs/.env." + filename
+
+	if err := godotenv.Load(envPath); err != nil {
+		log.Printf("⚠️ No %s found, using system env vars", envPath)
+	} else {
+		log.Printf("🔧 Loaded environment from %s", envPath)
+	}
+	return env
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	currency := flag.String("currency", models.DefaultCurrency, "currency of amounts stored before the money migration")
+	flag.Parse()
+
+	env := loadEnv()
+	log.Printf("🔥 Initializing Firebase (%s)...", env)
+	if err := firebase.InitFirebase(); err != nil {
+		log.Fatalf("❌ Firebase init failed: %v", err)
+	}
+	defer firebase.FirestoreClient.Close()
+
+	ctx := context.Background()
+
+	log.Printf("🚚 Converting stored amounts to %s minor units (dry run: %t)...", *currency, *dryRun)
+	if _, err := migrations.MigrateMoney(ctx, firebase.FirestoreClient, *currency, *dryRun); err != nil {
+		log.Fatalf("❌ Money migration failed: %v", err)
+	}
+
+	log.Println("🎉 Migration complete!")
+}
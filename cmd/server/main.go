@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 
 	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/handlers"
 	"github.com/cecvl/art-print-backend/internal/middleware"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	courier "github.com/cecvl/art-print-backend/internal/services/delivery/providers"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/payment"
+	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
 )
 
 // loadEnv loads the environment variables based on APP_ENV
@@ -35,29 +45,42 @@ func loadEnv() string {
 }
 
 // setupRoutes initializes all routes
-func setupRoutes() http.Handler {
+func setupRoutes(store *repositories.Store, settings *config.SettingsConfigService, paymentProviders *providers.Registry, geocoder geo.Geocoder, rates shipping.RateProvider) http.Handler {
 	mux := http.NewServeMux()
 
+	// Buyer, artist and admin handlers
+	authHandler := handlers.NewAuthHandler(store)
+	artworkHandler := handlers.NewArtworkHandler(store)
+	artistHandler := handlers.NewArtistHandler(store)
+	profileHandler := handlers.NewProfileHandler(store)
+	addressHandler := handlers.NewAddressHandler(store, geocoder)
+	cartHandler := handlers.NewCartHandler(store, geocoder)
+	orderHandler := handlers.NewOrderHandler(store, settings, geocoder, rates)
+	adminHandler := handlers.NewAdminHandler(store, settings, paymentProviders, geocoder, rates)
+
 	// Print shop console handlers
 	printOptionsHandler := handlers.NewPrintOptionsHandler()
-	pricingHandler := handlers.NewPricingHandler()
-	printShopConsoleHandler := handlers.NewPrintShopConsoleHandler()
-	printShopConfigHandler := handlers.NewPrintShopConfigHandler()
-	printShopServiceConfigHandler := handlers.NewPrintShopServiceConfigHandler()
-	publicPrintShopHandler := handlers.NewPublicPrintShopHandler()
-	matchingHandler := handlers.NewMatchingHandler()
-	paymentHandler := handlers.NewPaymentHandler()
+	pricingHandler := handlers.NewPricingHandler(store)
+	printShopConsoleHandler := handlers.NewPrintShopConsoleHandler(store, settings, geocoder, rates)
+	printShopConfigHandler := handlers.NewPrintShopConfigHandler(store)
+	printShopServiceConfigHandler := handlers.NewPrintShopServiceConfigHandler(store)
+	printShopFrameHandler := handlers.NewPrintShopFrameHandler(store)
+	printShopIssueHandler := handlers.NewPrintShopIssueHandler(store)
+	publicPrintShopHandler := handlers.NewPublicPrintShopHandler(store, settings, geocoder)
+	matchingHandler := handlers.NewMatchingHandler(store, settings, geocoder, rates)
+	paymentHandler := handlers.NewPaymentHandler(store, paymentProviders)
+	deliveryHandler := handlers.NewDeliveryHandler(store, courier.NewCourierFromEnv())
 
 	// Health check route (no logging middleware for efficiency)
 	mux.Handle("/health", http.HandlerFunc(handlers.HealthHandler))
 
 	// Public routes
-	mux.Handle("/signup", middleware.LogMiddleware(http.HandlerFunc(handlers.SignUpHandler)))
+	mux.Handle("/signup", middleware.LogMiddleware(http.HandlerFunc(authHandler.SignUpHandler)))
 	mux.Handle("/sessionLogin", middleware.LogMiddleware(http.HandlerFunc(handlers.SessionLoginHandler)))
 	mux.Handle("/sessionLogout", middleware.LogMiddleware(http.HandlerFunc(handlers.SessionLogoutHandler)))
-	mux.Handle("/artworks", middleware.LogMiddleware(http.HandlerFunc(handlers.GetArtworksHandler)))
-	mux.Handle("/artworks/status", middleware.LogMiddleware(http.HandlerFunc(handlers.GetArtworkStatusHandler)))
-	mux.Handle("/artists", middleware.LogMiddleware(http.HandlerFunc(handlers.GetArtistsHandler)))
+	mux.Handle("/artworks", middleware.LogMiddleware(http.HandlerFunc(artworkHandler.GetArtworksHandler)))
+	mux.Handle("/artworks/status", middleware.LogMiddleware(http.HandlerFunc(artworkHandler.GetArtworkStatusHandler)))
+	mux.Handle("/artists", middleware.LogMiddleware(http.HandlerFunc(artistHandler.GetArtistsHandler)))
 
 	// Print options route
 	mux.Handle("/print-options", middleware.LogMiddleware(http.HandlerFunc(printOptionsHandler.GetPrintOptions)))
@@ -68,34 +91,49 @@ func setupRoutes() http.Handler {
 	mux.Handle("/printshops/match", middleware.LogMiddleware(http.HandlerFunc(publicPrintShopHandler.MatchShopsForOrder)))
 	mux.Handle("/printshops/calculate", middleware.LogMiddleware(http.HandlerFunc(publicPrintShopHandler.CalculatePriceForService)))
 
-	// Authenticated routes
-	protected := middleware.AuthMiddleware
-	mux.Handle("/artworks/upload", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.UploadArtHandler))))
-	mux.Handle("/getprofile", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.GetProfileHandler))))
-	mux.Handle("/updateprofile", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.UpdateProfileHandler))))
-	mux.Handle("/cart/add", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.AddToCartHandler))))
-	mux.Handle("/cart/remove", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.RemoveFromCartHandler))))
-	mux.Handle("/cart", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.GetCartHandler))))
-	mux.Handle("/checkout", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.CheckoutHandler))))
-	mux.Handle("/orders", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.GetOrdersHandler))))
+	// Authenticated routes. POSTs carrying an Idempotency-Key are safe to retry:
+	// a repeated key replays the first response instead of creating state again.
+	idempotent := middleware.Idempotency(store.Idempotency)
+	protected := func(h http.HandlerFunc) http.HandlerFunc {
+		return middleware.AuthMiddleware(idempotent(h))
+	}
+	mux.Handle("/artworks/upload", middleware.LogMiddleware(protected(http.HandlerFunc(artworkHandler.UploadArtHandler))))
+	mux.Handle("/getprofile", middleware.LogMiddleware(protected(http.HandlerFunc(profileHandler.GetProfileHandler))))
+	mux.Handle("/updateprofile", middleware.LogMiddleware(protected(http.HandlerFunc(profileHandler.UpdateProfileHandler))))
+	mux.Handle("/addresses", middleware.LogMiddleware(protected(http.HandlerFunc(addressHandler.ListAddresses))))
+	mux.Handle("/addresses/add", middleware.LogMiddleware(protected(http.HandlerFunc(addressHandler.AddAddress))))
+	mux.Handle("/addresses/update", middleware.LogMiddleware(protected(http.HandlerFunc(addressHandler.UpdateAddress))))
+	mux.Handle("/addresses/delete", middleware.LogMiddleware(protected(http.HandlerFunc(addressHandler.DeleteAddress))))
+	mux.Handle("/cart/add", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.AddToCartHandler))))
+	mux.Handle("/cart/remove", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.RemoveFromCartHandler))))
+	mux.Handle("/cart", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.GetCartHandler))))
+	mux.Handle("/cart/lines/quantity", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.UpdateLineQuantity))))
+	mux.Handle("/cart/lines/options", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.UpdateLineOptions))))
+	mux.Handle("/cart/lines/duplicate", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.DuplicateLine))))
+	mux.Handle("/cart/clear", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.ClearCart))))
+	mux.Handle("/cart/validate", middleware.LogMiddleware(protected(http.HandlerFunc(cartHandler.ValidateCart))))
+	mux.Handle("/checkout", middleware.LogMiddleware(protected(http.HandlerFunc(orderHandler.CheckoutHandler))))
+	mux.Handle("/orders", middleware.LogMiddleware(protected(http.HandlerFunc(orderHandler.GetOrdersHandler))))
 	//calculate price
 	mux.Handle("/calculate-price", middleware.LogMiddleware(protected(http.HandlerFunc(pricingHandler.CalculatePrice))))
 
 	// Print Shop Console routes (requires printShop role)
 	// Chain: AuthMiddleware -> PrintShopAuthMiddleware -> Handler
 	printShopChain := func(h http.HandlerFunc) http.HandlerFunc {
-		return middleware.AuthMiddleware(middleware.PrintShopAuthMiddleware(h))
+		return middleware.AuthMiddleware(middleware.PrintShopAuthMiddleware(idempotent(h)))
 	}
 
 	// Admin routes chain: Auth -> AdminOnly
 	adminChain := func(h http.HandlerFunc) http.HandlerFunc {
-		return middleware.AuthMiddleware(middleware.AdminOnly(h))
+		return middleware.AuthMiddleware(middleware.AdminOnly(idempotent(h)))
 	}
 
 	// Shop profile management
 	mux.Handle("/printshop/profile", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.GetShopProfile)))
 	mux.Handle("/printshop/profile/create", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.CreateShopProfile)))
 	mux.Handle("/printshop/profile/update", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.UpdateShopProfile)))
+	mux.Handle("/printshop/calendar", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.GetCalendar)))
+	mux.Handle("/printshop/calendar/update", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.UpdateCalendar)))
 
 	// Service management
 	mux.Handle("/printshop/services", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.GetServices)))
@@ -110,12 +148,19 @@ func setupRoutes() http.Handler {
 	mux.Handle("/printshop/frames/delete/", middleware.LogMiddleware(printShopChain(printShopConfigHandler.DeleteFrame)))
 
 	// Printshop frame uploads (images for frame types) - upload/list/remove
-	mux.Handle("/printshop/frames/upload", middleware.LogMiddleware(printShopChain(handlers.UploadFrameHandler)))
-	mux.Handle("/printshop/frames/list", middleware.LogMiddleware(printShopChain(handlers.GetFramesHandler)))
-	mux.Handle("/printshop/frames/remove", middleware.LogMiddleware(printShopChain(handlers.RemoveFrameHandler)))
+	mux.Handle("/printshop/frames/upload", middleware.LogMiddleware(printShopChain(printShopFrameHandler.UploadFrameHandler)))
+	mux.Handle("/printshop/frames/list", middleware.LogMiddleware(printShopChain(printShopFrameHandler.GetFramesHandler)))
+	mux.Handle("/printshop/frames/remove", middleware.LogMiddleware(printShopChain(printShopFrameHandler.RemoveFrameHandler)))
+
+	// Printshop order inbox (sub-orders assigned to the shop)
+	mux.Handle("/printshop/orders", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.GetShopOrders)))
+	mux.Handle("/printshop/orders/accept", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.AcceptOrder)))
+	mux.Handle("/printshop/orders/decline", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.DeclineOrder)))
+	mux.Handle("/printshop/orders/update-status", middleware.LogMiddleware(printShopChain(printShopConsoleHandler.UpdateOrderStage)))
+	mux.Handle("/printshop/orders/pack", middleware.LogMiddleware(printShopChain(deliveryHandler.PackOrder)))
 
 	// Printshop can report fulfillment issues
-	mux.Handle("/printshop/orders/report-issue", middleware.LogMiddleware(printShopChain(handlers.PrintShopReportIssueHandler)))
+	mux.Handle("/printshop/orders/report-issue", middleware.LogMiddleware(printShopChain(printShopIssueHandler.PrintShopReportIssueHandler)))
 
 	// Configuration management - Sizes
 	mux.Handle("/printshop/sizes", middleware.LogMiddleware(printShopChain(printShopConfigHandler.GetSizes)))
@@ -129,6 +174,12 @@ func setupRoutes() http.Handler {
 	mux.Handle("/printshop/materials/update/", middleware.LogMiddleware(printShopChain(printShopConfigHandler.UpdateMaterial)))
 	mux.Handle("/printshop/materials/delete/", middleware.LogMiddleware(printShopChain(printShopConfigHandler.DeleteMaterial)))
 
+	// Configuration management - Mediums
+	mux.Handle("/printshop/mediums", middleware.LogMiddleware(printShopChain(printShopConfigHandler.GetMediums)))
+	mux.Handle("/printshop/mediums/create", middleware.LogMiddleware(printShopChain(printShopConfigHandler.CreateMedium)))
+	mux.Handle("/printshop/mediums/update/", middleware.LogMiddleware(printShopChain(printShopConfigHandler.UpdateMedium)))
+	mux.Handle("/printshop/mediums/delete/", middleware.LogMiddleware(printShopChain(printShopConfigHandler.DeleteMedium)))
+
 	// Service pricing configuration
 	mux.Handle("/printshop/services/pricing/", middleware.LogMiddleware(printShopChain(printShopServiceConfigHandler.GetServicePricing)))
 	mux.Handle("/printshop/services/pricing/update/", middleware.LogMiddleware(printShopChain(printShopServiceConfigHandler.UpdateServicePricing)))
@@ -139,68 +190,92 @@ func setupRoutes() http.Handler {
 	mux.Handle("/orders/assign", middleware.LogMiddleware(protected(http.HandlerFunc(matchingHandler.AssignShopToOrder))))
 
 	// Admin artwork review endpoints
-	mux.Handle("/admin/artworks", middleware.LogMiddleware(adminChain(handlers.GetAdminArtworksHandler)))
-	mux.Handle("/admin/artworks/get", middleware.LogMiddleware(adminChain(handlers.GetAdminArtworkHandler)))
-	mux.Handle("/admin/artworks/resolve", middleware.LogMiddleware(adminChain(handlers.ResolveArtworkHandler)))
-	mux.Handle("/admin/artworks/assign", middleware.LogMiddleware(adminChain(handlers.AssignArtworkHandler)))
+	mux.Handle("/admin/artworks", middleware.LogMiddleware(adminChain(adminHandler.GetAdminArtworksHandler)))
+	mux.Handle("/admin/artworks/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminArtworkHandler)))
+	mux.Handle("/admin/artworks/resolve", middleware.LogMiddleware(adminChain(adminHandler.ResolveArtworkHandler)))
+	mux.Handle("/admin/artworks/assign", middleware.LogMiddleware(adminChain(adminHandler.AssignArtworkHandler)))
 
 	// Admin frames review endpoints
-	mux.Handle("/admin/frames", middleware.LogMiddleware(adminChain(handlers.GetAdminFramesHandler)))
-	mux.Handle("/admin/frames/resolve", middleware.LogMiddleware(adminChain(handlers.ResolveFrameHandler)))
+	mux.Handle("/admin/frames", middleware.LogMiddleware(adminChain(adminHandler.GetAdminFramesHandler)))
+	mux.Handle("/admin/frames/resolve", middleware.LogMiddleware(adminChain(adminHandler.ResolveFrameHandler)))
 
 	// Admin users management
-	mux.Handle("/admin/users", middleware.LogMiddleware(adminChain(handlers.GetAdminUsersHandler)))
-	mux.Handle("/admin/users/get", middleware.LogMiddleware(adminChain(handlers.GetAdminUserHandler)))
-	mux.Handle("/admin/users/update-roles", middleware.LogMiddleware(adminChain(handlers.UpdateUserRolesHandler)))
-	mux.Handle("/admin/users/deactivate", middleware.LogMiddleware(adminChain(handlers.DeactivateUserHandler)))
-	mux.Handle("/admin/users/reactivate", middleware.LogMiddleware(adminChain(handlers.ReactivateUserHandler)))
+	mux.Handle("/admin/users", middleware.LogMiddleware(adminChain(adminHandler.GetAdminUsersHandler)))
+	mux.Handle("/admin/users/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminUserHandler)))
+	mux.Handle("/admin/users/update-roles", middleware.LogMiddleware(adminChain(adminHandler.UpdateUserRolesHandler)))
+	mux.Handle("/admin/users/deactivate", middleware.LogMiddleware(adminChain(adminHandler.DeactivateUserHandler)))
+	mux.Handle("/admin/users/reactivate", middleware.LogMiddleware(adminChain(adminHandler.ReactivateUserHandler)))
+	mux.Handle("/admin/users/tax-exemption", middleware.LogMiddleware(adminChain(adminHandler.UpdateTaxExemptionHandler)))
 
 	// Admin orders management
-	mux.Handle("/admin/orders", middleware.LogMiddleware(adminChain(handlers.GetAdminOrdersHandler)))
-	mux.Handle("/admin/orders/get", middleware.LogMiddleware(adminChain(handlers.GetAdminOrderHandler)))
-	mux.Handle("/admin/orders/update-status", middleware.LogMiddleware(adminChain(handlers.UpdateOrderStatusHandler)))
-	mux.Handle("/admin/orders/reassign", middleware.LogMiddleware(adminChain(handlers.ReassignOrderHandler)))
-	mux.Handle("/admin/orders/cancel", middleware.LogMiddleware(adminChain(handlers.CancelOrderHandler)))
-	mux.Handle("/admin/orders/refund", middleware.LogMiddleware(adminChain(handlers.RefundOrderHandler)))
+	mux.Handle("/admin/orders", middleware.LogMiddleware(adminChain(adminHandler.GetAdminOrdersHandler)))
+	mux.Handle("/admin/orders/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminOrderHandler)))
+	mux.Handle("/admin/orders/update-status", middleware.LogMiddleware(adminChain(adminHandler.UpdateOrderStatusHandler)))
+	mux.Handle("/admin/orders/reassign", middleware.LogMiddleware(adminChain(adminHandler.ReassignOrderHandler)))
+	mux.Handle("/admin/orders/cancel", middleware.LogMiddleware(adminChain(adminHandler.CancelOrderHandler)))
+	mux.Handle("/admin/orders/refund", middleware.LogMiddleware(adminChain(adminHandler.RefundOrderHandler)))
 
 	// Admin payments management
-	mux.Handle("/admin/payments", middleware.LogMiddleware(adminChain(handlers.GetAdminPaymentsHandler)))
-	mux.Handle("/admin/payments/get", middleware.LogMiddleware(adminChain(handlers.GetAdminPaymentHandler)))
-	mux.Handle("/admin/payments/verify", middleware.LogMiddleware(adminChain(handlers.VerifyPaymentAdminHandler)))
-	mux.Handle("/admin/payments/refund", middleware.LogMiddleware(adminChain(handlers.RefundPaymentAdminHandler)))
+	mux.Handle("/admin/payments", middleware.LogMiddleware(adminChain(adminHandler.GetAdminPaymentsHandler)))
+	mux.Handle("/admin/payments/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminPaymentHandler)))
+	mux.Handle("/admin/payments/verify", middleware.LogMiddleware(adminChain(adminHandler.VerifyPaymentAdminHandler)))
+	mux.Handle("/admin/payments/refund", middleware.LogMiddleware(adminChain(adminHandler.RefundPaymentAdminHandler)))
+	mux.Handle("/admin/payments/events", middleware.LogMiddleware(adminChain(adminHandler.GetPaymentEventsHandler)))
+	mux.Handle("/admin/payments/events/replay", middleware.LogMiddleware(adminChain(adminHandler.ReplayPaymentEventHandler)))
 
 	// Admin printshops / catalog
-	mux.Handle("/admin/printshops", middleware.LogMiddleware(adminChain(handlers.GetAdminPrintShopsHandler)))
-	mux.Handle("/admin/printshops/get", middleware.LogMiddleware(adminChain(handlers.GetAdminPrintShopHandler)))
-	mux.Handle("/admin/printshops/update-service-price", middleware.LogMiddleware(adminChain(handlers.UpdateServicePriceHandler)))
+	mux.Handle("/admin/printshops", middleware.LogMiddleware(adminChain(adminHandler.GetAdminPrintShopsHandler)))
+	mux.Handle("/admin/printshops/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminPrintShopHandler)))
+	mux.Handle("/admin/printshops/update-service-price", middleware.LogMiddleware(adminChain(adminHandler.UpdateServicePriceHandler)))
 
 	// Admin service management: create, enable/disable
-	mux.Handle("/admin/printshops/service-add", middleware.LogMiddleware(adminChain(handlers.AdminCreateServiceHandler)))
-	mux.Handle("/admin/printshops/service-status", middleware.LogMiddleware(adminChain(handlers.UpdateServiceStatusHandler)))
+	mux.Handle("/admin/printshops/service-add", middleware.LogMiddleware(adminChain(adminHandler.AdminCreateServiceHandler)))
+	mux.Handle("/admin/printshops/service-status", middleware.LogMiddleware(adminChain(adminHandler.UpdateServiceStatusHandler)))
+
+	// Admin fulfillment and matching settings
+	mux.Handle("/admin/settings/fulfillment", middleware.LogMiddleware(adminChain(adminHandler.GetFulfillmentSettingsHandler)))
+	mux.Handle("/admin/settings/fulfillment/update", middleware.LogMiddleware(adminChain(adminHandler.UpdateFulfillmentSettingsHandler)))
+	mux.Handle("/admin/settings/scoring", middleware.LogMiddleware(adminChain(adminHandler.GetScoringConfigHandler)))
+	mux.Handle("/admin/settings/scoring/update", middleware.LogMiddleware(adminChain(adminHandler.UpdateScoringConfigHandler)))
+	mux.Handle("/admin/settings/tax", middleware.LogMiddleware(adminChain(adminHandler.GetTaxSettingsHandler)))
+	mux.Handle("/admin/settings/tax/update", middleware.LogMiddleware(adminChain(adminHandler.UpdateTaxSettingsHandler)))
+
+	// Admin promotions and coupon codes
+	mux.Handle("/admin/promotions", middleware.LogMiddleware(adminChain(adminHandler.GetAdminPromotionsHandler)))
+	mux.Handle("/admin/promotions/create", middleware.LogMiddleware(adminChain(adminHandler.CreatePromotionHandler)))
+	mux.Handle("/admin/promotions/update", middleware.LogMiddleware(adminChain(adminHandler.UpdatePromotionHandler)))
+	mux.Handle("/admin/promotions/redemptions", middleware.LogMiddleware(adminChain(adminHandler.GetPromotionRedemptionsHandler)))
 
 	// Admin reports
-	mux.Handle("/admin/reports/sales-monthly", middleware.LogMiddleware(adminChain(handlers.SalesMonthlyHandler)))
+	mux.Handle("/admin/reports/sales-monthly", middleware.LogMiddleware(adminChain(adminHandler.SalesMonthlyHandler)))
+	mux.Handle("/admin/reports/tax-liability", middleware.LogMiddleware(adminChain(adminHandler.TaxLiabilityHandler)))
 
 	// Developer-only admin seed/simulate endpoints (guarded by APP_ENV)
-	mux.Handle("/admin/dev/simulate-orders", middleware.LogMiddleware(adminChain(handlers.SimulateOrdersHandler)))
-	mux.Handle("/admin/dev/add-services", middleware.LogMiddleware(adminChain(handlers.AddServicesHandler)))
+	mux.Handle("/admin/dev/simulate-orders", middleware.LogMiddleware(adminChain(adminHandler.SimulateOrdersHandler)))
+	mux.Handle("/admin/dev/add-services", middleware.LogMiddleware(adminChain(adminHandler.AddServicesHandler)))
 
 	// Admin signups
-	mux.Handle("/admin/signups", middleware.LogMiddleware(adminChain(handlers.GetAdminSignupsHandler)))
+	mux.Handle("/admin/signups", middleware.LogMiddleware(adminChain(adminHandler.GetAdminSignupsHandler)))
 
 	// Admin artists (detailed)
-	mux.Handle("/admin/artists", middleware.LogMiddleware(adminChain(handlers.GetAdminArtistsHandler)))
-	mux.Handle("/admin/artists/get", middleware.LogMiddleware(adminChain(handlers.GetAdminArtistHandler)))
+	mux.Handle("/admin/artists", middleware.LogMiddleware(adminChain(adminHandler.GetAdminArtistsHandler)))
+	mux.Handle("/admin/artists/get", middleware.LogMiddleware(adminChain(adminHandler.GetAdminArtistHandler)))
 
 	// Payment endpoints
 	mux.Handle("/payments/create", middleware.LogMiddleware(protected(http.HandlerFunc(paymentHandler.CreatePaymentHandler))))
 	mux.Handle("/payments/verify", middleware.LogMiddleware(protected(http.HandlerFunc(paymentHandler.VerifyPaymentHandler))))
 	mux.Handle("/payments", middleware.LogMiddleware(protected(http.HandlerFunc(paymentHandler.GetPaymentsHandler))))
 	mux.Handle("/payments/webhook/", middleware.LogMiddleware(http.HandlerFunc(paymentHandler.PaymentWebhookHandler)))
-	mux.Handle("/payments/refund", middleware.LogMiddleware(protected(http.HandlerFunc(paymentHandler.RefundPaymentHandler))))
+
+	// Delivery tracking
+	mux.Handle("/orders/tracking", middleware.LogMiddleware(protected(http.HandlerFunc(deliveryHandler.GetTrackingHandler))))
+	mux.Handle("/delivery/webhook/", middleware.LogMiddleware(http.HandlerFunc(deliveryHandler.CourierWebhookHandler)))
+
+	// Invoices
+	mux.Handle("/orders/invoice", middleware.LogMiddleware(protected(http.HandlerFunc(orderHandler.GetInvoiceHandler))))
 
 	// allow buyer/artist to select printshop for an order
-	mux.Handle("/orders/select-printshop", middleware.LogMiddleware(protected(http.HandlerFunc(handlers.SelectPrintShopHandler))))
+	mux.Handle("/orders/select-printshop", middleware.LogMiddleware(protected(http.HandlerFunc(orderHandler.SelectPrintShopHandler))))
 
 	return middleware.CORS(mux)
 }
@@ -218,7 +293,21 @@ func main() {
 		port = "8080"
 	}
 
-	handler := setupRoutes()
+	store := repositories.NewFirestoreStore(firebase.FirestoreClient)
+	// Fulfillment settings are shared so an admin switch reaches every matcher at once
+	settings := config.NewSettingsConfigService(store.Settings, config.DefaultSettingsTTL)
+	// One registry so every handler sees the same provider state and enabled methods
+	paymentProviders := providers.NewRegistryFromEnv()
+	// Geocoding and shipping rates are configured once and shared by matching, checkout and reassignment
+	geocoder := geo.NewGeocoderFromEnv()
+	rates := shipping.NewRateProviderFromEnv()
+	handler := setupRoutes(store, settings, paymentProviders, geocoder, rates)
+
+	// Offers shops leave unanswered past the acceptance SLA are reassigned in the background
+	go orders.NewAssignments(settings, store, geocoder, rates).RunExpiryLoop(context.Background(), time.Minute)
+	// Refunds the providers have not settled yet are followed up in the background
+	go payment.NewPaymentService(store, paymentProviders).RunRefundSettlementLoop(context.Background(), 5*time.Minute)
+
 	log.Printf("🚀 Server running in %s mode on :%s", env, port)
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
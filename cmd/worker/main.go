@@ -8,6 +8,7 @@ import (
 
 	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/processing"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 func main() {
@@ -17,9 +18,12 @@ func main() {
 	}
 	defer firebase.FirestoreClient.Close()
 
+	store := repositories.NewFirestoreStore(firebase.FirestoreClient)
+	worker := processing.NewWorker(store.Queue, store.Artworks, store.Frames)
+
 	// run worker loop; return on fatal
 	go func() {
-		if err := processing.StartWorker(ctx); err != nil {
+		if err := worker.Start(ctx); err != nil {
 			log.Fatalf("worker failed: %v", err)
 		}
 	}()
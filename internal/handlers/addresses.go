@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/google/uuid"
+)
+
+// maxSavedAddresses caps how many shipping addresses a buyer can keep
+const maxSavedAddresses = 10
+
+// AddressHandler manages the shipping addresses saved on the buyer's profile
+type AddressHandler struct {
+	users    repositories.UserRepository
+	geocoder geo.Geocoder
+}
+
+// NewAddressHandler creates a new address handler
+func NewAddressHandler(store *repositories.Store, geocoder geo.Geocoder) *AddressHandler {
+	return &AddressHandler{users: store.Users, geocoder: geocoder}
+}
+
+// ListAddresses returns the buyer's saved addresses
+func (h *AddressHandler) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	writeAddresses(w, user.Addresses)
+}
+
+// AddAddress saves a new address. The first address, or one sent with isDefault, becomes the default.
+func (h *AddressHandler) AddAddress(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	address, ok := h.decodeAddress(w, r)
+	if !ok {
+		return
+	}
+	if len(user.Addresses) >= maxSavedAddresses {
+		http.Error(w, "address book is full", http.StatusBadRequest)
+		return
+	}
+
+	address.ID = uuid.NewString()
+	user.Addresses = append(user.Addresses, address)
+	h.save(w, r, user, address.ID, address.IsDefault)
+}
+
+// UpdateAddress replaces a saved address, identified by its id
+func (h *AddressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	address, ok := h.decodeAddress(w, r)
+	if !ok {
+		return
+	}
+	existing := user.Address(address.ID)
+	if existing == nil {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	*existing = address
+	h.save(w, r, user, address.ID, address.IsDefault)
+}
+
+// DeleteAddress removes a saved address; if it was the default, the first remaining one takes over
+func (h *AddressHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.loadUser(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ID == "" {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	i := slices.IndexFunc(user.Addresses, func(a models.Address) bool { return a.ID == body.ID })
+	if i < 0 {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	user.Addresses = slices.Delete(user.Addresses, i, i+1)
+	h.save(w, r, user, "", false)
+}
+
+// loadUser fetches the authenticated user's profile
+func (h *AddressHandler) loadUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	ctx := r.Context()
+	uid, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	user, err := h.users.GetUserByID(ctx, uid)
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// decodeAddress reads and validates an address, placing it on the map when possible
+func (h *AddressHandler) decodeAddress(w http.ResponseWriter, r *http.Request) (models.Address, bool) {
+	var address models.Address
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		http.Error(w, "invalid address", http.StatusBadRequest)
+		return address, false
+	}
+	if err := address.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return address, false
+	}
+	geo.Resolve(r.Context(), h.geocoder, &address.Location)
+	return address, true
+}
+
+// save writes the address book back, keeping exactly one default. makeDefault promotes defaultID.
+func (h *AddressHandler) save(w http.ResponseWriter, r *http.Request, user *models.User, defaultID string, makeDefault bool) {
+	current := user.DefaultAddress()
+	if !makeDefault && current != nil {
+		defaultID = current.ID
+	}
+	for i := range user.Addresses {
+		user.Addresses[i].IsDefault = user.Addresses[i].ID == defaultID
+	}
+	if len(user.Addresses) > 0 && user.DefaultAddress().ID != defaultID {
+		user.Addresses[0].IsDefault = true
+	}
+
+	updates := map[string]interface{}{"addresses": user.Addresses, "updatedAt": time.Now()}
+	if err := h.users.UpdateUser(r.Context(), user.UID, updates); err != nil {
+		log.Printf("❌ Failed to save addresses for user %s: %v", user.UID, err)
+		http.Error(w, "failed to save address", http.StatusInternalServerError)
+		return
+	}
+	writeAddresses(w, user.Addresses)
+}
+
+func writeAddresses(w http.ResponseWriter, addresses []models.Address) {
+	if addresses == nil {
+		addresses = []models.Address{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(addresses)
+}
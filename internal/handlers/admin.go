@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/payment"
+	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+)
+
+// AdminHandler serves the /admin console endpoints
+type AdminHandler struct {
+	orders   repositories.OrderRepository
+	artworks repositories.ArtworkRepository
+	users    repositories.UserRepository
+	frames   repositories.FrameRepository
+	queue    repositories.ProcessingQueueRepository
+	shops    repositories.PrintShopRepository
+	payments repositories.PaymentRepository
+	logs     repositories.ActivityLogRepository
+
+	paymentEvents repositories.PaymentEventRepository
+
+	promotions repositories.PromotionRepository
+
+	lifecycle      *orders.Lifecycle
+	assignments    *orders.Assignments
+	paymentService *payment.PaymentService
+	settings       *config.SettingsConfigService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(store *repositories.Store, settings *config.SettingsConfigService, registry *providers.Registry, geocoder geo.Geocoder, rates shipping.RateProvider) *AdminHandler {
+	return &AdminHandler{
+		orders:         store.Orders,
+		artworks:       store.Artworks,
+		users:          store.Users,
+		frames:         store.Frames,
+		queue:          store.Queue,
+		shops:          store.PrintShops,
+		payments:       store.Payments,
+		logs:           store.ActivityLog,
+		paymentEvents:  store.PaymentEvents,
+		promotions:     store.Promotions,
+		lifecycle:      orders.NewLifecycle(store),
+		assignments:    orders.NewAssignments(settings, store, geocoder, rates),
+		paymentService: payment.NewPaymentService(store, registry),
+		settings:       settings,
+	}
+}
+
+// userIDFrom returns the authenticated user ID set by AuthMiddleware, or "" if absent
+func userIDFrom(ctx context.Context) string {
+	if v, ok := ctx.Value("userId").(string); ok {
+		return v
+	}
+	return ""
+}
@@ -6,11 +6,11 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
-// writeAdminAction records admin actions to `admin_actions` collection.
-func writeAdminAction(ctx context.Context, r *http.Request, action, resourceType, resourceID string, details interface{}) {
+// writeAdminAction records admin actions to the `admin_actions` log.
+func writeAdminAction(ctx context.Context, logs repositories.ActivityLogRepository, r *http.Request, action, resourceType, resourceID string, details interface{}) {
 	performedBy := "unknown"
 	if v := r.Context().Value("userId"); v != nil {
 		if s, ok := v.(string); ok {
@@ -25,7 +25,7 @@ func writeAdminAction(ctx context.Context, r *http.Request, action, resourceType
 		"createdAt":    time.Now(),
 		"details":      details,
 	}
-	if _, _, err := firebase.FirestoreClient.Collection("admin_actions").Add(ctx, payload); err != nil {
+	if err := logs.Record(ctx, repositories.LogAdminActions, payload); err != nil {
 		log.Printf("⚠️ failed to write admin action: %v", err)
 	}
 }
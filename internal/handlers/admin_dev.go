@@ -10,12 +10,8 @@ import (
 
 	"github.com/google/uuid"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
-	"github.com/cecvl/art-print-backend/internal/services/payment"
-	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
 )
 
 // guardDev ensures dev-only endpoints are disabled in production
@@ -33,7 +29,7 @@ func guardDev() bool {
 
 // AddServicesHandler creates service docs under a shop
 // Body: { "shopId": "...", "services": [ { name, description, technology, basePrice } ] }
-func AddServicesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) AddServicesHandler(w http.ResponseWriter, r *http.Request) {
 	if !guardDev() {
 		http.Error(w, "dev endpoints disabled", http.StatusForbidden)
 		return
@@ -52,7 +48,6 @@ func AddServicesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
 	created := []string{}
 	for _, s := range body.Services {
 		s.ID = uuid.NewString()
@@ -60,12 +55,12 @@ func AddServicesHandler(w http.ResponseWriter, r *http.Request) {
 		s.CreatedAt = time.Now()
 		s.UpdatedAt = time.Now()
 		s.IsActive = true
-		if err := repo.CreateService(ctx, &s); err != nil {
+		if err := h.shops.CreateService(ctx, &s); err != nil {
 			log.Printf("⚠️ failed to create service: %v", err)
 			continue
 		}
 		// write service change entry
-		_, _, _ = firebase.FirestoreClient.Collection("services_changes").Add(ctx, map[string]interface{}{"serviceId": s.ID, "shopId": s.ShopID, "action": "created", "details": s, "createdAt": time.Now(), "createdBy": ctx.Value("userId")})
+		_ = h.logs.Record(ctx, repositories.LogServiceChanges, map[string]interface{}{"serviceId": s.ID, "shopId": s.ShopID, "action": "created", "details": s, "createdAt": time.Now(), "createdBy": ctx.Value("userId")})
 		created = append(created, s.ID)
 	}
 
@@ -74,7 +69,7 @@ func AddServicesHandler(w http.ResponseWriter, r *http.Request) {
 
 // SimulateOrdersHandler creates synthetic orders over past N months
 // Body: { "months": 3, "perMonth": 50, "shopId": "optional" }
-func SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	if !guardDev() {
 		http.Error(w, "dev endpoints disabled", http.StatusForbidden)
 		return
@@ -97,13 +92,10 @@ func SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// gather sample artworks
-	artsDocs, _ := firebase.FirestoreClient.Collection("artworks").Limit(200).Documents(ctx).GetAll()
+	arts, _ := h.artworks.ListArtworks(ctx, repositories.ArtworkFilter{Limit: 200})
 	artworkIDs := []string{}
-	for _, d := range artsDocs {
-		var a models.Artwork
-		if d.DataTo(&a) == nil {
-			artworkIDs = append(artworkIDs, d.Ref.ID)
-		}
+	for _, a := range arts {
+		artworkIDs = append(artworkIDs, a.ID)
 	}
 	if len(artworkIDs) == 0 {
 		http.Error(w, "no artworks found to simulate orders", http.StatusBadRequest)
@@ -113,16 +105,15 @@ func SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	// pick a printshop if not provided
 	shopId := body.ShopID
 	if shopId == "" {
-		shopsDocs, _ := firebase.FirestoreClient.Collection("printshops").Where("isActive", "==", true).Limit(10).Documents(ctx).GetAll()
-		if len(shopsDocs) > 0 {
-			shopId = shopsDocs[rand.Intn(len(shopsDocs))].Ref.ID
+		shops, _ := h.shops.GetActiveShops(ctx)
+		if len(shops) > 10 {
+			shops = shops[:10]
+		}
+		if len(shops) > 0 {
+			shopId = shops[rand.Intn(len(shops))].ID
 		}
 	}
 
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	provider := providers.NewSimulatedProvider()
-	paymentService := payment.NewPaymentService(paymentRepo, provider)
-
 	createdOrders := []string{}
 	rand.Seed(time.Now().UnixNano())
 	for m := 0; m < body.Months; m++ {
@@ -130,7 +121,7 @@ func SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
 			// choose artwork
 			aid := artworkIDs[rand.Intn(len(artworkIDs))]
 			qty := 1 + rand.Intn(3)
-			price := 20 + rand.Float64()*180
+			price := models.FromMajor(20+rand.Float64()*180, models.DefaultCurrency)
 
 			createdAt := time.Now().AddDate(0, -m, -rand.Intn(27))
 
@@ -141,27 +132,26 @@ func SimulateOrdersHandler(w http.ResponseWriter, r *http.Request) {
 				Items: []models.CartItem{
 					{ArtworkID: aid, Quantity: qty, Price: price},
 				},
-				TotalAmount:   float64(qty) * price,
+				TotalAmount:   price.Mul(qty),
 				PaymentMethod: "simulated",
 				PaymentStatus: string(models.PaymentStatusCompleted),
-				Status:        "completed",
+				Status:        models.OrderStatusCompleted,
 				CreatedAt:     createdAt,
 				UpdatedAt:     createdAt,
 			}
 
 			// persist order
-			_, err := firebase.FirestoreClient.Collection("orders").Doc(order.OrderID).Set(ctx, order)
-			if err != nil {
+			if err := h.orders.CreateOrder(ctx, &order); err != nil {
 				log.Printf("⚠️ failed to write simulated order: %v", err)
 				continue
 			}
 
 			// create payment record
-			payReq := models.PaymentRequest{OrderID: order.OrderID, Amount: order.TotalAmount, PaymentMethod: "simulated", PaymentType: "full", Metadata: map[string]string{"simulated": "true"}}
-			pmt, err := paymentService.CreatePayment(ctx, payReq, order.TotalAmount)
+			payReq := models.PaymentRequest{OrderID: order.OrderID, Amount: &order.TotalAmount, PaymentMethod: "simulated", PaymentType: "full", Metadata: map[string]string{"simulated": "true"}}
+			pmt, err := h.paymentService.CreatePayment(ctx, payReq, order.TotalAmount)
 			if err == nil && pmt != nil {
 				// link payment id to order
-				_, _ = firebase.FirestoreClient.Collection("orders").Doc(order.OrderID).Update(ctx, []firestore.Update{{Path: "paymentId", Value: pmt.ID}, {Path: "paymentStatus", Value: string(models.PaymentStatusCompleted)}})
+				_ = h.orders.UpdateOrder(ctx, order.OrderID, map[string]interface{}{"paymentId": pmt.ID, "paymentStatus": string(models.PaymentStatusCompleted)})
 			}
 
 			createdOrders = append(createdOrders, order.OrderID)
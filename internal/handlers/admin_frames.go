@@ -7,67 +7,53 @@ import (
 	"strconv"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // GetAdminFramesHandler lists frames filtered by processingStatus, shopId, date range, and limit
-func GetAdminFramesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminFramesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	status := r.URL.Query().Get("status")
-	shop := r.URL.Query().Get("shopId")
-	fromStr := r.URL.Query().Get("from")
-	toStr := r.URL.Query().Get("to")
-
-	q := firebase.FirestoreClient.Collection("frames").OrderBy("createdAt", firestore.Desc)
-	if status != "" {
-		q = q.Where("processingStatus", "==", status)
-	}
-	if shop != "" {
-		q = q.Where("shopId", "==", shop)
+	filter := repositories.FrameFilter{
+		ProcessingStatus: r.URL.Query().Get("status"),
+		ShopID:           r.URL.Query().Get("shopId"),
+		Sort:             repositories.NewestFirst,
 	}
-	if fromStr != "" {
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
 		if t, err := time.Parse(time.RFC3339, fromStr); err == nil {
-			q = q.Where("createdAt", ">=", t)
+			filter.CreatedAfter = t
 		}
 	}
-	if toStr != "" {
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
 		if t, err := time.Parse(time.RFC3339, toStr); err == nil {
-			q = q.Where("createdAt", "<=", t)
+			filter.CreatedBefore = t
 		}
 	}
 
-	limit := 100
+	filter.Limit = 100
 	if l := r.URL.Query().Get("limit"); l != "" {
 		if v, err := strconv.Atoi(l); err == nil && v > 0 {
 			if v > 500 {
 				v = 500
 			}
-			limit = v
+			filter.Limit = v
 		}
 	}
 
-	snaps, err := q.Limit(limit).Documents(ctx).GetAll()
+	results, err := h.frames.ListFrames(ctx, filter)
 	if err != nil {
 		log.Printf("❌ Failed to fetch admin frames: %v", err)
 		http.Error(w, "Failed to fetch frames", http.StatusInternalServerError)
 		return
 	}
 
-	var results []map[string]interface{}
-	for _, s := range snaps {
-		d := s.Data()
-		d["id"] = s.Ref.ID
-		results = append(results, d)
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
 
 // ResolveFrameHandler allows admin to approve/reject/reprocess a frame
 // POST body JSON: { "id": "<frameId>", "action": "approve"|"reject"|"reprocess", "note": "optional" }
-func ResolveFrameHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) ResolveFrameHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body struct {
 		ID     string `json:"id"`
@@ -83,33 +69,36 @@ func ResolveFrameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	frameRef := firebase.FirestoreClient.Collection("frames").Doc(body.ID)
+	resolution := models.AdminResolution{ResolvedBy: userIDFrom(ctx), ResolvedAt: time.Now(), ResolutionNote: body.Note}
 	switch body.Action {
 	case "approve":
-		_, err := frameRef.Set(ctx, map[string]interface{}{"processingStatus": "ready", "admin": map[string]interface{}{"resolvedBy": ctx.Value("userId"), "resolvedAt": time.Now(), "resolutionNote": body.Note}}, firestore.MergeAll)
+		err := h.frames.UpdateFrame(ctx, body.ID, map[string]interface{}{"processingStatus": "ready", "admin": resolution})
 		if err != nil {
 			log.Printf("❌ Failed to approve frame %s: %v", body.ID, err)
 			http.Error(w, "update failed", http.StatusInternalServerError)
 			return
 		}
 	case "reject":
-		_, err := frameRef.Set(ctx, map[string]interface{}{"processingStatus": "failed", "processingErrors": []string{"rejected_by_admin"}, "admin": map[string]interface{}{"resolvedBy": ctx.Value("userId"), "resolvedAt": time.Now(), "resolutionNote": body.Note}}, firestore.MergeAll)
+		err := h.frames.UpdateFrame(ctx, body.ID, map[string]interface{}{"processingStatus": "failed", "processingErrors": []string{"rejected_by_admin"}, "admin": resolution})
 		if err != nil {
 			log.Printf("❌ Failed to reject frame %s: %v", body.ID, err)
 			http.Error(w, "update failed", http.StatusInternalServerError)
 			return
 		}
 	case "reprocess":
-		_, err := frameRef.Set(ctx, map[string]interface{}{"processingStatus": "pending", "processingErrors": []string{}, "admin": map[string]interface{}{"resolvedBy": ctx.Value("userId"), "resolvedAt": time.Now(), "resolutionNote": body.Note}}, firestore.MergeAll)
+		err := h.frames.UpdateFrame(ctx, body.ID, map[string]interface{}{"processingStatus": "pending", "processingErrors": []string{}, "admin": resolution})
 		if err != nil {
 			log.Printf("❌ Failed to mark frame %s for reprocess: %v", body.ID, err)
 			http.Error(w, "update failed", http.StatusInternalServerError)
 			return
 		}
 		// enqueue job
-		frameDoc, _ := frameRef.Get(ctx)
-		cloudInfo := map[string]interface{}{"secureUrl": frameDoc.Data()["imageUrl"], "publicId": frameDoc.Data()["cloudinaryPublicId"], "folder": frameDoc.Data()["cloudinaryFolder"]}
-		_, _, _ = firebase.FirestoreClient.Collection("processing_queue").Add(ctx, map[string]interface{}{"frameId": body.ID, "status": "pending", "createdAt": time.Now(), "cloudinary": cloudInfo})
+		if frame, err := h.frames.GetFrameByID(ctx, body.ID); err == nil {
+			_ = h.queue.Enqueue(ctx, &models.ProcessingJob{
+				FrameID:    body.ID,
+				Cloudinary: models.CloudinaryAsset{SecureURL: frame.ImageURL, PublicID: frame.CloudinaryPublicID, Folder: frame.CloudinaryFolder},
+			})
+		}
 	default:
 		http.Error(w, "unknown action", http.StatusBadRequest)
 		return
@@ -7,23 +7,19 @@ import (
 	"strconv"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
-	"github.com/cecvl/art-print-backend/internal/services/payment"
-	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
 )
 
 // GetAdminOrdersHandler lists orders with optional filters
-func GetAdminOrdersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	q := firebase.FirestoreClient.Collection("orders").OrderBy("createdAt", firestore.Desc)
 
 	// filters
 	status := r.URL.Query().Get("status")
 	buyerId := r.URL.Query().Get("buyerId")
 	shopId := r.URL.Query().Get("printShopId")
+	parentId := r.URL.Query().Get("parentOrderId")
 	createdAfter := r.URL.Query().Get("createdAfter")
 	limitStr := r.URL.Query().Get("limit")
 	limit := 50
@@ -33,44 +29,32 @@ func GetAdminOrdersHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if status != "" {
-		q = q.Where("status", "==", status)
-	}
-	if buyerId != "" {
-		q = q.Where("buyerId", "==", buyerId)
-	}
-	if shopId != "" {
-		q = q.Where("printShopId", "==", shopId)
+	filter := repositories.OrderFilter{
+		Status:        models.OrderStatus(status),
+		BuyerID:       buyerId,
+		PrintShopID:   shopId,
+		ParentOrderID: parentId,
+		Sort:          repositories.NewestFirst,
+		Limit:         limit,
 	}
 	if createdAfter != "" {
 		if t, err := time.Parse(time.RFC3339, createdAfter); err == nil {
-			q = q.Where("createdAt", ">=", t)
+			filter.CreatedAfter = t
 		}
 	}
 
-	q = q.OrderBy("createdAt", firestore.Desc).Limit(limit)
-	docs, err := q.Documents(ctx).GetAll()
+	out, err := h.orders.ListOrders(ctx, filter)
 	if err != nil {
 		http.Error(w, "failed to query orders", http.StatusInternalServerError)
 		return
 	}
 
-	out := make([]*models.Order, 0, len(docs))
-	for _, d := range docs {
-		var o models.Order
-		if err := d.DataTo(&o); err != nil {
-			log.Printf("⚠️ failed to parse order: %v", err)
-			continue
-		}
-		out = append(out, &o)
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"orders": out})
 }
 
-// GetAdminOrderHandler returns a single order with payments
-func GetAdminOrderHandler(w http.ResponseWriter, r *http.Request) {
+// GetAdminOrderHandler returns a single order with its sub-orders, payments and status history
+func (h *AdminHandler) GetAdminOrderHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	orderId := r.URL.Query().Get("orderId")
 	if orderId == "" {
@@ -78,23 +62,29 @@ func GetAdminOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc, err := firebase.FirestoreClient.Collection("orders").Doc(orderId).Get(ctx)
+	order, err := h.orders.GetOrderByID(ctx, orderId)
 	if err != nil {
 		http.Error(w, "order not found", http.StatusNotFound)
 		return
 	}
-	var order models.Order
-	if err := doc.DataTo(&order); err != nil {
-		http.Error(w, "invalid order data", http.StatusInternalServerError)
-		return
+
+	// get payments and status history
+	payments, _ := h.payments.GetPaymentsByOrderID(ctx, orderId)
+	events, err := h.orders.ListOrderEvents(ctx, orderId)
+	if err != nil {
+		log.Printf("⚠️ failed to load events for order %s: %v", orderId, err)
 	}
 
-	// get payments
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	payments, _ := paymentRepo.GetPaymentsByOrderID(ctx, orderId)
+	if order.IsParent() {
+		subOrders, err := h.orders.ListOrders(ctx, repositories.OrderFilter{ParentOrderID: orderId})
+		if err != nil {
+			log.Printf("⚠️ failed to load sub-orders of %s: %v", orderId, err)
+		}
+		order.SubOrders = subOrders
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"order": order, "payments": payments})
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"order": order, "payments": payments, "events": events})
 }
 
 type updateStatusReq struct {
@@ -104,7 +94,7 @@ type updateStatusReq struct {
 }
 
 // UpdateOrderStatusHandler updates order status and writes an audit note
-func UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body updateStatusReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -116,22 +106,26 @@ func UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updates := map[string]interface{}{"status": body.Status, "updatedAt": time.Now()}
-	if _, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Set(ctx, updates, firestore.MergeAll); err != nil {
-		http.Error(w, "failed to update order", http.StatusInternalServerError)
+	status := models.OrderStatus(body.Status)
+	if !status.IsValid() {
+		http.Error(w, "unknown status", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.lifecycle.Transition(ctx, body.OrderID, status, userIDFrom(ctx), body.Note); err != nil {
+		writeTransitionError(w, body.OrderID, err)
 		return
 	}
 
 	// append admin note
 	if body.Note != "" {
-		note := map[string]interface{}{"note": body.Note, "createdAt": time.Now(), "createdBy": ctx.Value("userId")}
-		_, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Update(ctx, []firestore.Update{{Path: "adminNotes", Value: firestore.ArrayUnion(note)}})
-		if err != nil {
+		note := models.AdminNote{Note: body.Note, CreatedAt: time.Now(), CreatedBy: userIDFrom(ctx)}
+		if err := h.orders.AppendAdminNote(ctx, body.OrderID, note); err != nil {
 			log.Printf("⚠️ failed to append admin note: %v", err)
 		}
 	}
 
-	writeAdminAction(ctx, r, "update_order_status", "order", body.OrderID, map[string]interface{}{"status": body.Status, "note": body.Note})
+	writeAdminAction(ctx, h.logs, r, "update_order_status", "order", body.OrderID, map[string]interface{}{"status": body.Status, "note": body.Note})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -142,7 +136,7 @@ type reassignReq struct {
 }
 
 // ReassignOrderHandler forces order assignment to a print shop
-func ReassignOrderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) ReassignOrderHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body reassignReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -154,16 +148,24 @@ func ReassignOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updates := map[string]interface{}{"printShopId": body.PrintShopID, "updatedAt": time.Now()}
-	if _, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Set(ctx, updates, firestore.MergeAll); err != nil {
-		http.Error(w, "failed to reassign order", http.StatusInternalServerError)
+	order, err := h.orders.GetOrderByID(ctx, body.OrderID)
+	if err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if order.IsParent() {
+		http.Error(w, "parent orders are not fulfilled by a shop; reassign a sub-order", http.StatusBadRequest)
 		return
 	}
 
-	// record assignment
-	_, _, _ = firebase.FirestoreClient.Collection("assignments").Add(ctx, map[string]interface{}{"orderId": body.OrderID, "printShopId": body.PrintShopID, "status": "assigned", "createdAt": time.Now(), "createdBy": ctx.Value("userId")})
+	// offer the order to the new shop; the assignment is recorded in the assignments log
+	if err := h.assignments.Assign(ctx, order, body.PrintShopID, userIDFrom(ctx), "admin reassignment"); err != nil {
+		log.Printf("❌ failed to reassign order %s: %v", body.OrderID, err)
+		http.Error(w, "failed to reassign order", http.StatusInternalServerError)
+		return
+	}
 
-	writeAdminAction(ctx, r, "reassign_printshop", "order", body.OrderID, map[string]interface{}{"printShopId": body.PrintShopID})
+	writeAdminAction(ctx, h.logs, r, "reassign_printshop", "order", body.OrderID, map[string]interface{}{"printShopId": body.PrintShopID})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -174,7 +176,7 @@ type cancelReq struct {
 }
 
 // CancelOrderHandler marks an order cancelled
-func CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body cancelReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -186,33 +188,32 @@ func CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updates := map[string]interface{}{"status": "cancelled", "updatedAt": time.Now()}
-	if _, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Set(ctx, updates, firestore.MergeAll); err != nil {
-		http.Error(w, "failed to cancel order", http.StatusInternalServerError)
+	if _, err := h.lifecycle.Transition(ctx, body.OrderID, models.OrderStatusCancelled, userIDFrom(ctx), body.Reason); err != nil {
+		writeTransitionError(w, body.OrderID, err)
 		return
 	}
 
 	// write admin note
-	note := map[string]interface{}{"note": "cancelled: " + body.Reason, "createdAt": time.Now(), "createdBy": ctx.Value("userId")}
-	_, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Update(ctx, []firestore.Update{{Path: "adminNotes", Value: firestore.ArrayUnion(note)}})
-	if err != nil {
+	note := models.AdminNote{Note: "cancelled: " + body.Reason, CreatedAt: time.Now(), CreatedBy: userIDFrom(ctx)}
+	if err := h.orders.AppendAdminNote(ctx, body.OrderID, note); err != nil {
 		log.Printf("⚠️ failed to append admin note: %v", err)
 	}
 
-	writeAdminAction(ctx, r, "cancel_order", "order", body.OrderID, map[string]interface{}{"reason": body.Reason})
+	writeAdminAction(ctx, h.logs, r, "cancel_order", "order", body.OrderID, map[string]interface{}{"reason": body.Reason})
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 type refundReq struct {
-	OrderID   string  `json:"orderId,omitempty"`
-	PaymentID string  `json:"paymentId,omitempty"`
-	Amount    float64 `json:"amount,omitempty"`
-	Reason    string  `json:"reason,omitempty"`
+	OrderID   string       `json:"orderId,omitempty"`
+	PaymentID string       `json:"paymentId,omitempty"`
+	Amount    models.Money `json:"amount"` // total to refund; zero refunds everything left
+	Reason    string       `json:"reason,omitempty"`
 }
 
-// RefundOrderHandler processes refunds either by paymentId or for all completed payments on an order
-func RefundOrderHandler(w http.ResponseWriter, r *http.Request) {
+// RefundOrderHandler refunds one payment by paymentId, or an amount spread over the captured
+// payments of an order, newest first
+func (h *AdminHandler) RefundOrderHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body refundReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -220,46 +221,34 @@ func RefundOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	provider := providers.NewSimulatedProvider()
-	paymentService := payment.NewPaymentService(paymentRepo, provider)
-
-	var targets []string
+	var refunds []*models.Refund
+	var err error
 	if body.PaymentID != "" {
-		targets = append(targets, body.PaymentID)
-	} else if body.OrderID != "" {
-		payments, err := paymentRepo.GetPaymentsByOrderID(ctx, body.OrderID)
-		if err != nil {
-			http.Error(w, "failed to find payments", http.StatusInternalServerError)
-			return
-		}
-		for _, p := range payments {
-			if p.Status == models.PaymentStatusCompleted {
-				targets = append(targets, p.ID)
-			}
+		var refund *models.Refund
+		if refund, err = h.paymentService.RefundPayment(ctx, body.PaymentID, body.Amount, body.Reason); err == nil {
+			refunds = append(refunds, refund)
 		}
+	} else if body.OrderID != "" {
+		refunds, err = h.paymentService.RefundOrder(ctx, body.OrderID, body.Amount, body.Reason)
 	} else {
 		http.Error(w, "paymentId or orderId required", http.StatusBadRequest)
 		return
 	}
 
-	if len(targets) == 0 {
-		http.Error(w, "no refundable payments found", http.StatusBadRequest)
-		return
-	}
-
-	for _, pid := range targets {
-		if err := paymentService.RefundPayment(ctx, pid, body.Amount); err != nil {
-			log.Printf("❌ refund failed for %s: %v", pid, err)
-			http.Error(w, "failed to process refund", http.StatusInternalServerError)
-			return
+	if len(refunds) > 0 {
+		refundIDs := make([]string, len(refunds))
+		for i, refund := range refunds {
+			refundIDs[i] = refund.ID
 		}
+		writeAdminAction(ctx, h.logs, r, "refund_payment", "order", body.OrderID, map[string]interface{}{"paymentId": body.PaymentID, "refundIds": refundIDs, "amount": body.Amount, "reason": body.Reason})
+	}
+	if err != nil {
+		writePaymentError(w, "process refund", err)
+		return
 	}
-
-	writeAdminAction(ctx, r, "refund_payment", "order", body.OrderID, map[string]interface{}{"paymentIds": targets, "amount": body.Amount, "reason": body.Reason})
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"refunded": targets})
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"refunds": refunds})
 }
 
 // admin audit helper is in admin_audit.go
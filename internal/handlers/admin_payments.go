@@ -2,20 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
-	"github.com/cecvl/art-print-backend/internal/services/payment"
-	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
 )
 
 // GetAdminPaymentsHandler lists payments with optional filters
-func GetAdminPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	status := r.URL.Query().Get("status")
 	orderId := r.URL.Query().Get("orderId")
@@ -28,39 +25,28 @@ func GetAdminPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var docs []*firestore.DocumentSnapshot
-	var err error
-
+	// only one filter is applied, in order of precedence: orderId, buyerId, status
+	filter := repositories.PaymentFilter{Limit: limit}
 	if orderId != "" {
-		docs, err = firebase.FirestoreClient.Collection("payments").Where("orderId", "==", orderId).OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+		filter.OrderID = orderId
 	} else if buyerId != "" {
-		docs, err = firebase.FirestoreClient.Collection("payments").Where("buyerId", "==", buyerId).OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+		filter.BuyerID = buyerId
 	} else if status != "" {
-		docs, err = firebase.FirestoreClient.Collection("payments").Where("status", "==", status).OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
-	} else {
-		docs, err = firebase.FirestoreClient.Collection("payments").OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+		filter.Status = models.PaymentStatus(status)
 	}
 
+	out, err := h.payments.ListPayments(ctx, filter)
 	if err != nil {
 		log.Printf("❌ failed to query payments: %v", err)
 		http.Error(w, "failed to query payments", http.StatusInternalServerError)
 		return
 	}
 
-	out := make([]*models.Payment, 0, len(docs))
-	for _, d := range docs {
-		var p models.Payment
-		if err := d.DataTo(&p); err != nil {
-			continue
-		}
-		out = append(out, &p)
-	}
-
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"payments": out})
 }
 
 // GetAdminPaymentHandler returns a single payment
-func GetAdminPaymentHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	paymentId := r.URL.Query().Get("paymentId")
 	if paymentId == "" {
@@ -68,25 +54,26 @@ func GetAdminPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	p, err := repo.GetPaymentByID(ctx, paymentId)
+	p, err := h.payments.GetPaymentByID(ctx, paymentId)
 	if err != nil {
 		http.Error(w, "payment not found", http.StatusNotFound)
 		return
 	}
 
-	// include linked order if exists
+	// include linked order and refunds if exist
 	var order *models.Order
 	if p.OrderID != "" {
-		if doc, err := firebase.FirestoreClient.Collection("orders").Doc(p.OrderID).Get(ctx); err == nil {
-			var o models.Order
-			if err := doc.DataTo(&o); err == nil {
-				order = &o
-			}
+		if o, err := h.orders.GetOrderByID(ctx, p.OrderID); err == nil {
+			order = o
 		}
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{"payment": p, "order": order})
+	refunds, err := h.payments.GetRefundsByPaymentID(ctx, paymentId)
+	if err != nil {
+		log.Printf("⚠️ failed to load refunds of payment %s: %v", paymentId, err)
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"payment": p, "order": order, "refunds": refunds})
 }
 
 type verifyReq struct {
@@ -94,7 +81,7 @@ type verifyReq struct {
 }
 
 // VerifyPaymentAdminHandler verifies payment status with provider and updates records
-func VerifyPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) VerifyPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body verifyReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -106,30 +93,26 @@ func VerifyPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	provider := providers.NewSimulatedProvider()
-	paymentService := payment.NewPaymentService(paymentRepo, provider)
-
-	p, err := paymentService.VerifyPayment(ctx, body.PaymentID)
+	p, err := h.paymentService.VerifyPayment(ctx, body.PaymentID)
 	if err != nil {
 		log.Printf("❌ verify payment failed: %v", err)
 		http.Error(w, "failed to verify payment", http.StatusInternalServerError)
 		return
 	}
 
-	writeAdminAction(ctx, r, "verify_payment", "payment", body.PaymentID, nil)
+	writeAdminAction(ctx, h.logs, r, "verify_payment", "payment", body.PaymentID, nil)
 
 	_ = json.NewEncoder(w).Encode(p)
 }
 
 type paymentRefundReq struct {
-	PaymentID string  `json:"paymentId"`
-	Amount    float64 `json:"amount,omitempty"`
-	Reason    string  `json:"reason,omitempty"`
+	PaymentID string       `json:"paymentId"`
+	Amount    models.Money `json:"amount"` // zero refunds all that is left of the payment
+	Reason    string       `json:"reason,omitempty"`
 }
 
 // RefundPaymentAdminHandler triggers provider refund and updates records
-func RefundPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) RefundPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body paymentRefundReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -141,17 +124,69 @@ func RefundPaymentAdminHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	provider := providers.NewSimulatedProvider()
-	paymentService := payment.NewPaymentService(paymentRepo, provider)
+	refund, err := h.paymentService.RefundPayment(ctx, body.PaymentID, body.Amount, body.Reason)
+	if err != nil {
+		writePaymentError(w, "process refund", err)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "refund_payment", "payment", body.PaymentID, map[string]interface{}{"refundId": refund.ID, "amount": refund.Amount, "reason": body.Reason})
 
-	if err := paymentService.RefundPayment(ctx, body.PaymentID, body.Amount); err != nil {
-		log.Printf("❌ refund failed: %v", err)
-		http.Error(w, "failed to process refund", http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"paymentId": body.PaymentID, "refund": refund})
+}
+
+// GetPaymentEventsHandler lists stored payment webhooks, newest first
+func (h *AdminHandler) GetPaymentEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	limit := 50
+	if v, err := strconv.Atoi(q.Get("limit")); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+
+	events, err := h.paymentEvents.ListPaymentEvents(ctx, repositories.PaymentEventFilter{
+		PaymentID:     q.Get("paymentId"),
+		TransactionID: q.Get("transactionId"),
+		Outcome:       models.PaymentEventOutcome(q.Get("outcome")),
+		Limit:         limit,
+	})
+	if err != nil {
+		log.Printf("❌ failed to query payment events: %v", err)
+		http.Error(w, "failed to query payment events", http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
+
+type replayEventReq struct {
+	EventID string `json:"eventId"`
+}
+
+// ReplayPaymentEventHandler processes a stored payment webhook again
+func (h *AdminHandler) ReplayPaymentEventHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body replayEventReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if body.EventID == "" {
+		http.Error(w, "eventId required", http.StatusBadRequest)
+		return
+	}
+
+	event, payment, err := h.paymentService.ReplayPaymentEvent(ctx, body.EventID)
+	if errors.Is(err, repositories.ErrNotFound) && event == nil {
+		http.Error(w, "payment event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writePaymentError(w, "replay payment event", err)
 		return
 	}
 
-	writeAdminAction(ctx, r, "refund_payment", "payment", body.PaymentID, map[string]interface{}{"amount": body.Amount, "reason": body.Reason})
+	writeAdminAction(ctx, h.logs, r, "replay_payment_event", "payment_event", body.EventID, map[string]interface{}{"outcome": event.Outcome})
 
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "refunded", "paymentId": body.PaymentID})
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"event": event, "payment": payment})
 }
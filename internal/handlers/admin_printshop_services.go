@@ -8,24 +8,19 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 type adminCreateServiceReq struct {
-	ShopID string `json:"shopId"
-    `
+	ShopID  string              `json:"shopId"`
 	Service models.PrintService `json:"service"`
 }
 
 // AdminCreateServiceHandler allows admins to create a service for a shop
-func AdminCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) AdminCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	var body struct {
-		ShopID  string              `json:"shopId"`
-		Service models.PrintService `json:"service"`
-	}
+	var body adminCreateServiceReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
@@ -44,17 +39,16 @@ func AdminCreateServiceHandler(w http.ResponseWriter, r *http.Request) {
 	svc.UpdatedAt = time.Now()
 	svc.IsActive = true
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	if err := repo.CreateService(ctx, &svc); err != nil {
+	if err := h.shops.CreateService(ctx, &svc); err != nil {
 		log.Printf("❌ failed to create service: %v", err)
 		http.Error(w, "failed to create service", http.StatusInternalServerError)
 		return
 	}
 
 	// record service change
-	_, _, _ = firebase.FirestoreClient.Collection("services_changes").Add(ctx, map[string]interface{}{"serviceId": svc.ID, "shopId": svc.ShopID, "action": "created", "details": svc, "createdAt": time.Now(), "createdBy": r.Context().Value("userId")})
+	_ = h.logs.Record(ctx, repositories.LogServiceChanges, map[string]interface{}{"serviceId": svc.ID, "shopId": svc.ShopID, "action": "created", "details": svc, "createdAt": time.Now(), "createdBy": r.Context().Value("userId")})
 
-	writeAdminAction(ctx, r, "create_service", "service", svc.ID, svc)
+	writeAdminAction(ctx, h.logs, r, "create_service", "service", svc.ID, svc)
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"serviceId": svc.ID})
@@ -67,7 +61,7 @@ type updateServiceStatusReq struct {
 }
 
 // UpdateServiceStatusHandler toggles a service active state and records change
-func UpdateServiceStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateServiceStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body updateServiceStatusReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -79,9 +73,8 @@ func UpdateServiceStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	updates := map[string]interface{}{"isActive": body.IsActive, "updatedAt": time.Now()}
-	if err := repo.UpdateService(ctx, body.ServiceID, updates); err != nil {
+	updates := map[string]interface{}{"isActive": body.IsActive}
+	if err := h.shops.UpdateService(ctx, body.ServiceID, updates); err != nil {
 		log.Printf("❌ failed to update service status: %v", err)
 		http.Error(w, "failed to update service", http.StatusInternalServerError)
 		return
@@ -92,8 +85,8 @@ func UpdateServiceStatusHandler(w http.ResponseWriter, r *http.Request) {
 		action = "service_enabled"
 	}
 	// write services_changes record
-	_, _, _ = firebase.FirestoreClient.Collection("services_changes").Add(ctx, map[string]interface{}{"serviceId": body.ServiceID, "action": action, "reason": body.Reason, "createdAt": time.Now(), "createdBy": r.Context().Value("userId")})
-	writeAdminAction(ctx, r, action, "service", body.ServiceID, map[string]interface{}{"isActive": body.IsActive, "reason": body.Reason})
+	_ = h.logs.Record(ctx, repositories.LogServiceChanges, map[string]interface{}{"serviceId": body.ServiceID, "action": action, "reason": body.Reason, "createdAt": time.Now(), "createdBy": r.Context().Value("userId")})
+	writeAdminAction(ctx, h.logs, r, action, "service", body.ServiceID, map[string]interface{}{"isActive": body.IsActive, "reason": body.Reason})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -5,16 +5,12 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
-	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // GetAdminPrintShopsHandler lists print shops (filter: isActive)
-func GetAdminPrintShopsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminPrintShopsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	active := r.URL.Query().Get("isActive")
 	limitStr := r.URL.Query().Get("limit")
@@ -25,28 +21,13 @@ func GetAdminPrintShopsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-
 	var shops []*models.PrintShopProfile
 	var err error
 	if active == "true" {
-		shops, err = repo.GetActiveShops(ctx)
+		shops, err = h.shops.GetActiveShops(ctx)
 	} else {
 		// fetch all shops limited
-		docs, dErr := firebase.FirestoreClient.Collection("printshops").OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
-		if dErr != nil {
-			log.Printf("❌ failed to query printshops: %v", dErr)
-			http.Error(w, "failed to query printshops", http.StatusInternalServerError)
-			return
-		}
-		for _, d := range docs {
-			var s models.PrintShopProfile
-			if err := d.DataTo(&s); err == nil {
-				s.ID = d.Ref.ID
-				shops = append(shops, &s)
-			}
-		}
-		err = nil
+		shops, err = h.shops.ListShops(ctx, limit)
 	}
 
 	if err != nil {
@@ -59,7 +40,7 @@ func GetAdminPrintShopsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAdminPrintShopHandler returns a shop profile and services
-func GetAdminPrintShopHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	shopId := r.URL.Query().Get("shopId")
 	if shopId == "" {
@@ -67,14 +48,13 @@ func GetAdminPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	shop, err := repo.GetShopByID(ctx, shopId)
+	shop, err := h.shops.GetShopByID(ctx, shopId)
 	if err != nil {
 		http.Error(w, "shop not found", http.StatusNotFound)
 		return
 	}
 
-	services, _ := repo.GetServicesByShopID(ctx, shopId)
+	services, _ := h.shops.GetServicesByShopID(ctx, shopId)
 
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"shop": shop, "services": services})
 }
@@ -85,7 +65,7 @@ type updateServicePriceReq struct {
 }
 
 // UpdateServicePriceHandler updates a service's base price (and records admin action)
-func UpdateServicePriceHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateServicePriceHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body updateServicePriceReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -97,15 +77,14 @@ func UpdateServicePriceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	updates := map[string]interface{}{"basePrice": body.Price, "updatedAt": time.Now()}
-	if err := repo.UpdateService(ctx, body.ServiceID, updates); err != nil {
+	updates := map[string]interface{}{"basePrice": body.Price}
+	if err := h.shops.UpdateService(ctx, body.ServiceID, updates); err != nil {
 		log.Printf("❌ failed to update service price: %v", err)
 		http.Error(w, "failed to update service", http.StatusInternalServerError)
 		return
 	}
 
-	writeAdminAction(ctx, r, "update_service_price", "service", body.ServiceID, map[string]interface{}{"price": body.Price})
+	writeAdminAction(ctx, h.logs, r, "update_service_price", "service", body.ServiceID, map[string]interface{}{"price": body.Price})
 
 	w.WriteHeader(http.StatusNoContent)
 }
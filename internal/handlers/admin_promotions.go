@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// GetAdminPromotionsHandler lists promotions, newest first; ?active=true hides inactive ones
+func (h *AdminHandler) GetAdminPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	filter := repositories.PromotionFilter{
+		ActiveOnly: r.URL.Query().Get("active") == "true",
+		Sort:       repositories.NewestFirst,
+	}
+	results, err := h.promotions.ListPromotions(r.Context(), filter)
+	if err != nil {
+		log.Printf("❌ Failed to fetch promotions: %v", err)
+		http.Error(w, "Failed to fetch promotions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// CreatePromotionHandler creates a promotion from a JSON body; codes are stored upper-case
+func (h *AdminHandler) CreatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	promotion.ID = ""
+	promotion.Code = models.NormalizeCode(promotion.Code)
+	promotion.RedemptionCount = 0
+	promotion.CreatedBy = userIDFrom(ctx)
+	promotion.CreatedAt = time.Time{}
+	if err := promotion.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.promotions.CreatePromotion(ctx, &promotion); err != nil {
+		if errors.Is(err, repositories.ErrCodeTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("❌ Failed to create promotion %s: %v", promotion.Code, err)
+		http.Error(w, "failed to create promotion", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "create_promotion", "promotion", promotion.ID, map[string]interface{}{"promotion": promotion})
+	log.Printf("✅ Promotion %s created by %s", promotion.Code, promotion.CreatedBy)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// UpdatePromotionHandler changes a promotion identified by id. Omitted fields keep their stored
+// value; the code and redemption count cannot be changed. Send isActive false to retire a code.
+func (h *AdminHandler) UpdatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	var ref struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &ref); err != nil || ref.ID == "" {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.promotions.GetPromotionByID(ctx, ref.ID)
+	if err != nil {
+		http.Error(w, "promotion not found", http.StatusNotFound)
+		return
+	}
+	updated := *current
+	if err := json.Unmarshal(raw, &updated); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	updated.ID, updated.Code, updated.RedemptionCount = current.ID, current.Code, current.RedemptionCount
+	updated.CreatedBy, updated.CreatedAt = current.CreatedBy, current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	if err := updated.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"description":    updated.Description,
+		"type":           updated.Type,
+		"value":          updated.Value,
+		"maxDiscount":    updated.MaxDiscount,
+		"minSubtotal":    updated.MinSubtotal,
+		"artistId":       updated.ArtistID,
+		"shopId":         updated.ShopID,
+		"firstOrderOnly": updated.FirstOrderOnly,
+		"usageLimit":     updated.UsageLimit,
+		"perBuyerLimit":  updated.PerBuyerLimit,
+		"startsAt":       updated.StartsAt,
+		"expiresAt":      updated.ExpiresAt,
+		"isActive":       updated.IsActive,
+		"updatedAt":      updated.UpdatedAt,
+	}
+	if err := h.promotions.UpdatePromotion(ctx, updated.ID, updates); err != nil {
+		log.Printf("❌ Failed to update promotion %s: %v", updated.Code, err)
+		http.Error(w, "failed to update promotion", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "update_promotion", "promotion", updated.ID, map[string]interface{}{
+		"from": current,
+		"to":   updated,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// GetPromotionRedemptionsHandler lists the orders that redeemed a promotion (?id=, optional limit)
+func (h *AdminHandler) GetPromotionRedemptionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			limit = min(v, 500)
+		}
+	}
+
+	results, err := h.promotions.ListRedemptions(r.Context(), id, limit)
+	if err != nil {
+		log.Printf("❌ Failed to fetch redemptions of promotion %s: %v", id, err)
+		http.Error(w, "Failed to fetch redemptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
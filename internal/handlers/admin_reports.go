@@ -1,70 +1,54 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // SalesMonthlyHandler returns sales aggregated by month
 // Query params: from (ISO), to (ISO), shopId, artistId
-func SalesMonthlyHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) SalesMonthlyHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	qFrom := r.URL.Query().Get("from")
-	qTo := r.URL.Query().Get("to")
 	shopId := r.URL.Query().Get("shopId")
 	artistId := r.URL.Query().Get("artistId")
 
-	var from time.Time
-	var to time.Time
-	var err error
-	if qTo == "" {
-		to = time.Now()
-	} else {
-		to, err = time.Parse(time.RFC3339, qTo)
-		if err != nil {
-			http.Error(w, "invalid to", http.StatusBadRequest)
-			return
-		}
-	}
-	if qFrom == "" {
-		from = to.AddDate(0, -12, 0)
-	} else {
-		from, err = time.Parse(time.RFC3339, qFrom)
-		if err != nil {
-			http.Error(w, "invalid from", http.StatusBadRequest)
-			return
-		}
+	from, to, ok := reportRange(w, r)
+	if !ok {
+		return
 	}
 
 	// Query orders in range
-	q := firebase.FirestoreClient.Collection("orders").Where("createdAt", ">=", from).Where("createdAt", "<=", to).OrderBy("createdAt", firestore.Asc)
-	if shopId != "" {
-		q = q.Where("printShopId", "==", shopId)
-	}
-	if artistId != "" {
-		// We'll include orders that contain items for that artist by scanning; Firestore cannot query nested arrays for artistId so we'll filter client-side
+	filter := repositories.OrderFilter{
+		PrintShopID:   shopId,
+		CreatedAfter:  from,
+		CreatedBefore: to,
+		Sort:          repositories.OldestFirst,
 	}
+	// artistId is applied below by scanning items; orders cannot be queried by artwork artist
 
-	docs, err := q.Documents(ctx).GetAll()
+	orders, err := h.orders.ListOrders(ctx, filter)
 	if err != nil {
 		log.Printf("❌ failed to query orders for sales report: %v", err)
 		http.Error(w, "failed to query orders", http.StatusInternalServerError)
 		return
 	}
 
-	// aggregate by month
-	series := map[string]map[string]float64{} // month -> { orders: count, revenue: value }
-	counts := map[string]int{}
+	// aggregate by month; months with sales in several currencies get a row per currency
+	type monthKey struct{ month, currency string }
+	revenue := map[monthKey]models.Money{}
+	counts := map[monthKey]int{}
 
-	for _, d := range docs {
-		var o models.Order
-		if err := d.DataTo(&o); err != nil {
+	for _, o := range orders {
+		// parent orders duplicate the totals of their sub-orders
+		if o.IsParent() {
 			continue
 		}
 		// Optionally filter by artistId: if provided, ensure any CartItem.ArtworkID belongs to artist
@@ -73,16 +57,13 @@ func SalesMonthlyHandler(w http.ResponseWriter, r *http.Request) {
 			matchesArtist := false
 			for _, it := range o.Items {
 				// try to fetch artwork and compare artistId
-				artDoc, err := firebase.FirestoreClient.Collection("artworks").Doc(it.ArtworkID).Get(ctx)
+				art, err := h.artworks.GetArtworkByID(ctx, it.ArtworkID)
 				if err != nil {
 					continue
 				}
-				var art models.Artwork
-				if err := artDoc.DataTo(&art); err == nil {
-					if art.ArtistID == artistId {
-						matchesArtist = true
-						break
-					}
+				if art.ArtistID == artistId {
+					matchesArtist = true
+					break
 				}
 			}
 			if !matchesArtist {
@@ -91,41 +72,166 @@ func SalesMonthlyHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// only count confirmed/completed orders
-		if o.Status != "confirmed" && o.Status != "completed" {
+		if o.Status != models.OrderStatusConfirmed && o.Status != models.OrderStatusCompleted {
 			continue
 		}
-		month := o.CreatedAt.Format("2006-01")
-		if _, ok := series[month]; !ok {
-			series[month] = map[string]float64{"revenue": 0}
-			counts[month] = 0
-		}
-		series[month]["revenue"] += o.TotalAmount
-		counts[month]++
+		key := monthKey{o.CreatedAt.Format("2006-01"), o.TotalAmount.Currency}
+		revenue[key] = revenue[key].Add(o.TotalAmount)
+		counts[key]++
 	}
 
 	// build ordered result
-	months := make([]string, 0, len(series))
-	for m := range series {
-		months = append(months, m)
+	months := make([]monthKey, 0, len(revenue))
+	for k := range revenue {
+		months = append(months, k)
 	}
 	// sort months ascending
-	// use time parse
-	sortKeys := func(a []string) {
-		// simple bubble sort small N
-		for i := 0; i < len(a); i++ {
-			for j := i + 1; j < len(a); j++ {
-				if a[i] > a[j] {
-					a[i], a[j] = a[j], a[i]
-				}
-			}
+	sort.Slice(months, func(i, j int) bool {
+		if months[i].month != months[j].month {
+			return months[i].month < months[j].month
 		}
-	}
-	sortKeys(months)
+		return months[i].currency < months[j].currency
+	})
 
 	out := make([]map[string]interface{}, 0, len(months))
-	for _, m := range months {
-		out = append(out, map[string]interface{}{"month": m, "orders": counts[m], "revenue": series[m]["revenue"]})
+	for _, k := range months {
+		out = append(out, map[string]interface{}{"month": k.month, "orders": counts[k], "revenue": revenue[k]})
 	}
 
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{"series": out})
 }
+
+// reportRange reads the from/to query params (RFC3339); to defaults to now and from to 12 months before it
+func reportRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	var err error
+	to = time.Now()
+	if q := r.URL.Query().Get("to"); q != "" {
+		if to, err = time.Parse(time.RFC3339, q); err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	from = to.AddDate(0, -12, 0)
+	if q := r.URL.Query().Get("from"); q != "" {
+		if from, err = time.Parse(time.RFC3339, q); err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// taxLiabilityRow totals the tax collected in one jurisdiction for one class
+type taxLiabilityRow struct {
+	Jurisdiction string          `json:"jurisdiction"`
+	Name         string          `json:"name,omitempty"`
+	Class        models.TaxClass `json:"class"`
+	Rate         float64         `json:"rate"`
+	Orders       int             `json:"orders"`
+	Taxable      models.Money    `json:"taxable"`
+	Tax          models.Money    `json:"tax"`
+}
+
+// taxedPaymentStatuses are the payment statuses of orders whose tax was collected
+var taxedPaymentStatuses = map[string]bool{"paid": true, "partial": true, "partially_refunded": true}
+
+// TaxLiabilityHandler totals the tax collected on paid orders by jurisdiction and class, for
+// filing returns. Orders paid in part or partly refunded count the share of their tax the
+// buyer's net payments cover. Query params: from, to (ISO); defaults to the last 12 months.
+func (h *AdminHandler) TaxLiabilityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	from, to, ok := reportRange(w, r)
+	if !ok {
+		return
+	}
+
+	orders, err := h.orders.ListOrders(ctx, repositories.OrderFilter{CreatedAfter: from, CreatedBefore: to})
+	if err != nil {
+		log.Printf("❌ failed to query orders for tax report: %v", err)
+		http.Error(w, "failed to query orders", http.StatusInternalServerError)
+		return
+	}
+
+	byID := make(map[string]*models.Order, len(orders))
+	for _, o := range orders {
+		byID[o.OrderID] = o
+	}
+	shares := map[string]float64{} // paying order ID -> share of its total paid net of refunds
+
+	rows := map[string]*taxLiabilityRow{}
+	var keys []string
+	totals := map[string]models.Money{} // currency -> tax
+	for _, o := range orders {
+		// parent orders repeat the tax lines of their sub-orders
+		if o.IsParent() || !taxedPaymentStatuses[o.PaymentStatus] || len(o.TaxLines) == 0 {
+			continue
+		}
+		payerID := o.OrderID
+		if o.IsSubOrder() {
+			payerID = o.ParentOrderID
+		}
+		share, ok := shares[payerID]
+		if !ok {
+			share = h.paidShare(ctx, byID, payerID, o.PaymentStatus)
+			shares[payerID] = share
+		}
+		if share <= 0 {
+			continue
+		}
+		for _, t := range o.TaxLines {
+			if share < 1 {
+				t.Taxable = t.Taxable.MulRate(share, models.RoundHalfEven)
+				t.Amount = t.Amount.MulRate(share, models.RoundHalfEven)
+			}
+			key := fmt.Sprintf("%s|%s|%g|%s", t.Jurisdiction, t.Class, t.Rate, t.Amount.Currency)
+			row, ok := rows[key]
+			if !ok {
+				row = &taxLiabilityRow{Jurisdiction: t.Jurisdiction, Name: t.Name, Class: t.Class, Rate: t.Rate}
+				rows[key] = row
+				keys = append(keys, key)
+			}
+			row.Orders++
+			row.Taxable = row.Taxable.Add(t.Taxable)
+			row.Tax = row.Tax.Add(t.Amount)
+			totals[t.Amount.Currency] = totals[t.Amount.Currency].Add(t.Amount)
+		}
+	}
+
+	sort.Strings(keys)
+	out := make([]*taxLiabilityRow, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, rows[k])
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":     from,
+		"to":       to,
+		"rows":     out,
+		"totalTax": totals,
+	})
+}
+
+// paidShare returns the fraction of an order's total its payments cover net of refunds, from 0
+// to 1. Fully paid orders count in full without loading their payments.
+func (h *AdminHandler) paidShare(ctx context.Context, byID map[string]*models.Order, orderID, paymentStatus string) float64 {
+	if paymentStatus == "paid" {
+		return 1
+	}
+	order, ok := byID[orderID]
+	if !ok {
+		var err error
+		if order, err = h.orders.GetOrderByID(ctx, orderID); err != nil {
+			log.Printf("⚠️ Failed to load order %s for tax report: %v", orderID, err)
+			return 0
+		}
+	}
+	if !order.TotalAmount.IsPositive() {
+		return 0
+	}
+	_, net, err := h.paymentService.CalculatePaymentStatus(ctx, orderID, order.TotalAmount)
+	if err != nil {
+		log.Printf("⚠️ Failed to total payments of order %s for tax report: %v", orderID, err)
+		return 0
+	}
+	return min(1, max(0, float64(net.Amount)/float64(order.TotalAmount.Amount)))
+}
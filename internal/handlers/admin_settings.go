@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+)
+
+// GetFulfillmentSettingsHandler returns the stored fulfillment mode and overrides
+func (h *AdminHandler) GetFulfillmentSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settings.Settings(r.Context())
+	if err != nil {
+		log.Printf("❌ failed to load fulfillment settings: %v", err)
+		http.Error(w, "failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settings)
+}
+
+type updateFulfillmentSettingsReq struct {
+	FulfillmentMode      models.FulfillmentMode            `json:"fulfillmentMode,omitempty"`
+	ShopOverrides        map[string]models.FulfillmentMode `json:"shopOverrides,omitempty"`        // replaces all shop overrides when present
+	CategoryOverrides    map[string]models.FulfillmentMode `json:"categoryOverrides,omitempty"`    // replaces all category overrides when present
+	AcceptanceSLAMinutes *int                              `json:"acceptanceSlaMinutes,omitempty"` // 0 restores the default SLA
+}
+
+// UpdateFulfillmentSettingsHandler switches the fulfillment mode and/or its overrides.
+// Omitted fields keep their stored value; the change takes effect immediately.
+func (h *AdminHandler) UpdateFulfillmentSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body updateFulfillmentSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.settings.Settings(ctx)
+	if err != nil {
+		log.Printf("❌ failed to load fulfillment settings: %v", err)
+		http.Error(w, "failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	updated := *current
+	if body.FulfillmentMode != "" {
+		updated.FulfillmentMode = body.FulfillmentMode
+	}
+	if body.ShopOverrides != nil {
+		updated.ShopOverrides = body.ShopOverrides
+	}
+	if body.CategoryOverrides != nil {
+		updated.CategoryOverrides = body.CategoryOverrides
+	}
+	if body.AcceptanceSLAMinutes != nil {
+		updated.AcceptanceSLAMinutes = *body.AcceptanceSLAMinutes
+	}
+	updated.UpdatedBy = userIDFrom(ctx)
+
+	if err := h.settings.Update(ctx, &updated); err != nil {
+		if errors.Is(err, config.ErrInvalidSettings) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("❌ failed to save fulfillment settings: %v", err)
+		http.Error(w, "failed to save settings", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "update_fulfillment_settings", "settings", "global", map[string]interface{}{
+		"from": current,
+		"to":   updated,
+	})
+	log.Printf("✅ Fulfillment mode set to %s by %s", updated.FulfillmentMode, updated.UpdatedBy)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(updated)
+}
+
+// GetScoringConfigHandler returns the smart matcher weights and tables in effect
+func (h *AdminHandler) GetScoringConfigHandler(w http.ResponseWriter, r *http.Request) {
+	scoring, err := h.settings.Scoring(r.Context())
+	if err != nil {
+		log.Printf("❌ failed to load scoring config: %v", err)
+		http.Error(w, "failed to load scoring config", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(scoring)
+}
+
+// UpdateScoringConfigHandler replaces the smart matcher weights and tables
+func (h *AdminHandler) UpdateScoringConfigHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body models.SmartScoringConfig
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.settings.Scoring(ctx)
+	if err != nil {
+		log.Printf("❌ failed to load scoring config: %v", err)
+		http.Error(w, "failed to load scoring config", http.StatusInternalServerError)
+		return
+	}
+
+	body.UpdatedBy = userIDFrom(ctx)
+	if err := h.settings.UpdateScoring(ctx, &body); err != nil {
+		if errors.Is(err, config.ErrInvalidScoring) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("❌ failed to save scoring config: %v", err)
+		http.Error(w, "failed to save scoring config", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "update_scoring_config", "settings", "scoring", map[string]interface{}{
+		"from": current,
+		"to":   body,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// GetTaxSettingsHandler returns the tax jurisdictions in effect
+func (h *AdminHandler) GetTaxSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.settings.Tax(r.Context())
+	if err != nil {
+		log.Printf("❌ failed to load tax settings: %v", err)
+		http.Error(w, "failed to load tax settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateTaxSettingsHandler replaces the tax jurisdictions. Orders already checked out keep the
+// tax they were charged.
+func (h *AdminHandler) UpdateTaxSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body models.TaxSettings
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.settings.Tax(ctx)
+	if err != nil {
+		log.Printf("❌ failed to load tax settings: %v", err)
+		http.Error(w, "failed to load tax settings", http.StatusInternalServerError)
+		return
+	}
+
+	body.UpdatedBy = userIDFrom(ctx)
+	if err := h.settings.UpdateTax(ctx, &body); err != nil {
+		if errors.Is(err, config.ErrInvalidTax) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("❌ failed to save tax settings: %v", err)
+		http.Error(w, "failed to save tax settings", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "update_tax_settings", "settings", "tax", map[string]interface{}{
+		"from": current,
+		"to":   body,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -5,13 +5,12 @@ import (
 	"net/http"
 	"strconv"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // GetAdminUsersHandler lists users for admin console.
 // Optional query params: role (array-contains), limit (int)
-func GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	role := r.URL.Query().Get("role")
 	limitStr := r.URL.Query().Get("limit")
@@ -22,31 +21,23 @@ func GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var iter *firestore.DocumentIterator
-	if role != "" {
-		iter = firebase.FirestoreClient.Collection("users").Where("roles", "array-contains", role).OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx)
-	} else {
-		iter = firebase.FirestoreClient.Collection("users").OrderBy("createdAt", firestore.Desc).Limit(limit).Documents(ctx)
-	}
-
-	docs, err := iter.GetAll()
+	users, err := h.users.ListUsers(ctx, repositories.UserFilter{Role: role, Sort: repositories.NewestFirst, Limit: limit})
 	if err != nil {
 		http.Error(w, "failed to query users", http.StatusInternalServerError)
 		return
 	}
 
-	out := make([]map[string]interface{}, 0, len(docs))
-	for _, d := range docs {
-		data := d.Data()
+	out := make([]map[string]interface{}, 0, len(users))
+	for _, u := range users {
 		// select fields to return
 		userMap := map[string]interface{}{
-			"uid":         data["uid"],
-			"email":       data["email"],
-			"name":        data["name"],
-			"roles":       data["roles"],
-			"description": data["description"],
-			"avatarUrl":   data["avatarUrl"],
-			"createdAt":   data["createdAt"],
+			"uid":         u.UID,
+			"email":       u.Email,
+			"name":        u.Name,
+			"roles":       u.Roles,
+			"description": u.Description,
+			"avatarUrl":   u.AvatarURL,
+			"createdAt":   u.CreatedAt,
 		}
 		out = append(out, userMap)
 	}
@@ -56,7 +47,7 @@ func GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAdminUserHandler returns a single user by uid (query param uid)
-func GetAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) GetAdminUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := r.URL.Query().Get("uid")
 	if uid == "" {
@@ -64,14 +55,14 @@ func GetAdminUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc, err := firebase.FirestoreClient.Collection("users").Doc(uid).Get(ctx)
+	user, err := h.users.GetUserByID(ctx, uid)
 	if err != nil {
 		http.Error(w, "user not found", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(doc.Data())
+	_ = json.NewEncoder(w).Encode(user)
 }
 
 type updateRolesRequest struct {
@@ -80,7 +71,7 @@ type updateRolesRequest struct {
 }
 
 // UpdateUserRolesHandler updates a user's roles and writes an admin action audit record
-func UpdateUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) UpdateUserRolesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body updateRolesRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -93,13 +84,13 @@ func UpdateUserRolesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updates := map[string]interface{}{"roles": body.Roles}
-	if _, err := firebase.FirestoreClient.Collection("users").Doc(body.UID).Set(ctx, updates, firestore.MergeAll); err != nil {
+	if err := h.users.UpdateUser(ctx, body.UID, updates); err != nil {
 		http.Error(w, "failed to update roles", http.StatusInternalServerError)
 		return
 	}
 
 	// write audit
-	writeAdminAction(ctx, r, "update_roles", "user", body.UID, map[string]interface{}{"roles": body.Roles})
+	writeAdminAction(ctx, h.logs, r, "update_roles", "user", body.UID, map[string]interface{}{"roles": body.Roles})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -109,7 +100,7 @@ type idRequest struct {
 }
 
 // DeactivateUserHandler marks a user as inactive (sets isActive=false) and logs the admin action
-func DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body idRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -122,17 +113,17 @@ func DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updates := map[string]interface{}{"isActive": false}
-	if _, err := firebase.FirestoreClient.Collection("users").Doc(body.UID).Set(ctx, updates, firestore.MergeAll); err != nil {
+	if err := h.users.UpdateUser(ctx, body.UID, updates); err != nil {
 		http.Error(w, "failed to deactivate user", http.StatusInternalServerError)
 		return
 	}
 
-	writeAdminAction(ctx, r, "deactivate", "user", body.UID, nil)
+	writeAdminAction(ctx, h.logs, r, "deactivate", "user", body.UID, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // ReactivateUserHandler sets isActive=true and logs the admin action
-func ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body idRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -145,12 +136,48 @@ func ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updates := map[string]interface{}{"isActive": true}
-	if _, err := firebase.FirestoreClient.Collection("users").Doc(body.UID).Set(ctx, updates, firestore.MergeAll); err != nil {
+	if err := h.users.UpdateUser(ctx, body.UID, updates); err != nil {
 		http.Error(w, "failed to reactivate user", http.StatusInternalServerError)
 		return
 	}
 
-	writeAdminAction(ctx, r, "reactivate", "user", body.UID, nil)
+	writeAdminAction(ctx, h.logs, r, "reactivate", "user", body.UID, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type taxExemptionRequest struct {
+	UID         string `json:"uid"`
+	TaxExempt   bool   `json:"taxExempt"`
+	TaxExemptID string `json:"taxExemptId"` // required when granting an exemption
+}
+
+// UpdateTaxExemptionHandler grants or revokes a buyer's tax exemption; it applies to later checkouts
+func (h *AdminHandler) UpdateTaxExemptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var body taxExemptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if body.UID == "" {
+		http.Error(w, "missing uid", http.StatusBadRequest)
+		return
+	}
+	if body.TaxExempt && body.TaxExemptID == "" {
+		http.Error(w, "taxExemptId required to grant an exemption", http.StatusBadRequest)
+		return
+	}
+	if !body.TaxExempt {
+		body.TaxExemptID = ""
+	}
+
+	updates := map[string]interface{}{"taxExempt": body.TaxExempt, "taxExemptId": body.TaxExemptID}
+	if err := h.users.UpdateUser(ctx, body.UID, updates); err != nil {
+		http.Error(w, "failed to update tax exemption", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminAction(ctx, h.logs, r, "update_tax_exemption", "user", body.UID, map[string]interface{}{"taxExempt": body.TaxExempt, "taxExemptId": body.TaxExemptID})
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -5,8 +5,8 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // ArtistWithArtworks represents the response shape for an artist and their artworks
@@ -18,49 +18,47 @@ type ArtistWithArtworks struct {
 	Artworks    []models.Artwork `json:"artworks"`
 }
 
+// ArtistHandler serves the public artist directory
+type ArtistHandler struct {
+	users    repositories.UserRepository
+	artworks repositories.ArtworkRepository
+}
+
+// NewArtistHandler creates a new artist handler
+func NewArtistHandler(store *repositories.Store) *ArtistHandler {
+	return &ArtistHandler{users: store.Users, artworks: store.Artworks}
+}
+
 // GetArtistsHandler fetches users with role `artist` and includes their artworks
-func GetArtistsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ArtistHandler) GetArtistsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log.Println("📥 GetArtistsHandler triggered")
 
 	// Query users where roles array contains artist
 	log.Printf("🔍 Fetching users with role '%s'...", models.Artist)
-	userSnapshots, err := firebase.FirestoreClient.Collection("users").Where("roles", "array-contains", models.Artist).Documents(ctx).GetAll()
+	artists, err := h.users.ListUsers(ctx, repositories.UserFilter{Role: models.Artist})
 	if err != nil {
 		log.Printf("❌ Failed to fetch artists: %v", err)
 		http.Error(w, "Failed to fetch artists", http.StatusInternalServerError)
 		return
 	}
-	log.Printf("✅ Fetched %d artists from Firestore", len(userSnapshots))
+	log.Printf("✅ Fetched %d artists from Firestore", len(artists))
 
 	var results []ArtistWithArtworks
 
-	for _, uDoc := range userSnapshots {
-		var u models.User
-		if err := uDoc.DataTo(&u); err != nil {
-			// skip malformed user doc but log it
-			log.Printf("⚠️ Skipping user doc %s: %v", uDoc.Ref.ID, err)
-			continue
-		}
-
-		uid := uDoc.Ref.ID
+	for _, u := range artists {
+		uid := u.UID
 
 		// Fetch artworks for this artist
-		artSnapshots, err := firebase.FirestoreClient.Collection("artworks").Where("artistId", "==", uid).Documents(ctx).GetAll()
+		artworks, err := h.artworks.ListArtworks(ctx, repositories.ArtworkFilter{ArtistID: uid})
 		if err != nil {
 			log.Printf("⚠️ Failed to fetch artworks for artist %s: %v", uid, err)
 			// continue but return empty artworks list
 		}
 
 		var arts []models.Artwork
-		for _, aDoc := range artSnapshots {
-			var art models.Artwork
-			if err := aDoc.DataTo(&art); err == nil {
-				art.ID = aDoc.Ref.ID
-				arts = append(arts, art)
-			} else {
-				log.Printf("⚠️ Skipping artwork doc %s: %v", aDoc.Ref.ID, err)
-			}
+		for _, art := range artworks {
+			arts = append(arts, *art)
 		}
 
 		results = append(results, ArtistWithArtworks{
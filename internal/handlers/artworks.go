@@ -7,18 +7,34 @@ import (
 	"os"
 	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
-func UploadArtHandler(w http.ResponseWriter, r *http.Request) {
+// ArtworkHandler serves artwork upload and browsing endpoints
+type ArtworkHandler struct {
+	artworks repositories.ArtworkRepository
+	users    repositories.UserRepository
+	queue    repositories.ProcessingQueueRepository
+}
+
+// NewArtworkHandler creates a new artwork handler
+func NewArtworkHandler(store *repositories.Store) *ArtworkHandler {
+	return &ArtworkHandler{
+		artworks: store.Artworks,
+		users:    store.Users,
+		queue:    store.Queue,
+	}
+}
+
+func (h *ArtworkHandler) UploadArtHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	userID := ctx.Value("userId").(string)
 
-	userDoc, err := firebase.FirestoreClient.Collection("users").Doc(userID).Get(ctx)
-	if err != nil || userDoc.Data()["userType"] != models.Artist {
+	user, err := h.users.GetUserByID(ctx, userID)
+	if err != nil || !user.HasRole(models.Artist) {
 		http.Error(w, "Only artists can upload artworks", http.StatusForbidden)
 		return
 	}
@@ -68,42 +84,39 @@ func UploadArtHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Persist artwork document with processing status = pending
-	artData := map[string]interface{}{
-		"title":              title,
-		"description":        description,
-		"artistId":           userID,
-		"imageUrl":           uploadResult.SecureURL,
-		"cloudinaryPublicId": uploadResult.PublicID,
-		"cloudinaryFolder":   originalFolder,
-		"isAvailable":        true,
-		"processingStatus":   "pending",
-		"processingErrors":   []string{},
-		"createdAt":          time.Now(),
-	}
-
-	docRef, _, err := firebase.FirestoreClient.Collection("artworks").Add(ctx, artData)
-	if err != nil {
+	artwork := &models.Artwork{
+		Title:              title,
+		Description:        description,
+		ArtistID:           userID,
+		ImageURL:           uploadResult.SecureURL,
+		CloudinaryPublicID: uploadResult.PublicID,
+		CloudinaryFolder:   originalFolder,
+		IsAvailable:        true,
+		ProcessingStatus:   "pending",
+		ProcessingErrors:   []string{},
+		CreatedAt:          time.Now(),
+	}
+
+	if err := h.artworks.CreateArtwork(ctx, artwork); err != nil {
 		log.Printf("❌ Saving artwork failed: %v", err)
 		http.Error(w, "Saving artwork failed", http.StatusInternalServerError)
 		return
 	}
 
-	// Enqueue a processing job in Firestore queue collection (simple queue)
-	queueDoc := map[string]interface{}{
-		"artworkId": docRef.ID,
-		"status":    "pending",
-		"createdAt": time.Now(),
-		"cloudinary": map[string]interface{}{
-			"secureUrl": uploadResult.SecureURL,
-			"publicId":  uploadResult.PublicID,
-			"folder":    originalFolder,
+	// Enqueue a processing job for the worker
+	job := &models.ProcessingJob{
+		ArtworkID: artwork.ID,
+		Cloudinary: models.CloudinaryAsset{
+			SecureURL: uploadResult.SecureURL,
+			PublicID:  uploadResult.PublicID,
+			Folder:    originalFolder,
 		},
 	}
-	if _, _, err := firebase.FirestoreClient.Collection("processing_queue").Add(ctx, queueDoc); err != nil {
-		log.Printf("⚠️ Failed to enqueue processing job for artwork %s: %v", docRef.ID, err)
+	if err := h.queue.Enqueue(ctx, job); err != nil {
+		log.Printf("⚠️ Failed to enqueue processing job for artwork %s: %v", artwork.ID, err)
 		// do not fail the upload — processing can be retried by a worker scanning artworks with pending status
 	} else {
-		log.Printf("✅ Enqueued processing job for artwork %s", docRef.ID)
+		log.Printf("✅ Enqueued processing job for artwork %s", artwork.ID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -111,15 +124,15 @@ func UploadArtHandler(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(map[string]string{
 		"url":              uploadResult.SecureURL,
-		"artworkId":        docRef.ID,
+		"artworkId":        artwork.ID,
 		"processingStatus": "pending",
 	})
 
-	log.Printf("Upload successful: %s (artworkId=%s)", uploadResult.SecureURL, docRef.ID)
+	log.Printf("Upload successful: %s (artworkId=%s)", uploadResult.SecureURL, artwork.ID)
 }
 
 // GetArtworkStatusHandler returns processing status and analysis for an artwork
-func GetArtworkStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ArtworkHandler) GetArtworkStatusHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	// accept artwork id via query param `id` or `artworkId`
 	id := r.URL.Query().Get("id")
@@ -131,20 +144,21 @@ func GetArtworkStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	doc, err := firebase.FirestoreClient.Collection("artworks").Doc(id).Get(ctx)
+	art, err := h.artworks.GetArtworkByID(ctx, id)
 	if err != nil {
 		log.Printf("❌ Failed to fetch artwork %s: %v", id, err)
 		http.Error(w, "Failed to fetch artwork", http.StatusInternalServerError)
 		return
 	}
 
-	data := doc.Data()
 	// pick relevant fields to return
-	resp := map[string]interface{}{}
-	for _, k := range []string{"processingStatus", "processingErrors", "analysis", "printReadyVersions", "imageUrl", "createdAt"} {
-		if v, ok := data[k]; ok {
-			resp[k] = v
-		}
+	resp := map[string]interface{}{
+		"processingStatus":   art.ProcessingStatus,
+		"processingErrors":   art.ProcessingErrors,
+		"analysis":           art.Analysis,
+		"printReadyVersions": art.PrintReadyVersions,
+		"imageUrl":           art.ImageURL,
+		"createdAt":          art.CreatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -155,24 +169,15 @@ func GetArtworkStatusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetArtworksHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ArtworkHandler) GetArtworksHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	snapshot, err := firebase.FirestoreClient.Collection("artworks").Documents(ctx).GetAll()
+	artworks, err := h.artworks.ListArtworks(ctx, repositories.ArtworkFilter{})
 	if err != nil {
 		log.Printf("❌ Failed to fetch artworks: %v", err)
 		http.Error(w, "Failed to fetch artworks", http.StatusInternalServerError)
 		return
 	}
 
-	var artworks []models.Artwork
-	for _, doc := range snapshot {
-		var art models.Artwork
-		if err := doc.DataTo(&art); err == nil {
-			art.ID = doc.Ref.ID // Set the unique Firestore doc ID
-			artworks = append(artworks, art)
-		}
-	}
-
 	log.Printf("✅ Fetched %d artworks", len(artworks)) // 👈 Log confirmation
 
 	w.Header().Set("Content-Type", "application/json")
@@ -9,9 +9,20 @@ import (
 	"firebase.google.com/go/auth"
 	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
-func SignUpHandler(w http.ResponseWriter, r *http.Request) {
+// AuthHandler serves account creation
+type AuthHandler struct {
+	users repositories.UserRepository
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(store *repositories.Store) *AuthHandler {
+	return &AuthHandler{users: store.Users}
+}
+
+func (h *AuthHandler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -43,7 +54,8 @@ func SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Created Firebase user: UID=%s", user.UID)
 
-	_, err = firebase.FirestoreClient.Collection("users").Doc(user.UID).Set(r.Context(), models.User{
+	err = h.users.CreateUser(r.Context(), &models.User{
+		UID:       user.UID,
 		Email:     req.Email,
 		Roles:     []string{req.UserType}, // assign single role as array
 		CreatedAt: time.Now(),
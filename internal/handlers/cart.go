@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"reflect"
-	"time"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
-	"github.com/cecvl/art-print-backend/internal/services/catalog"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/matching"
 	"github.com/cecvl/art-print-backend/internal/services/pricing"
+	"github.com/google/uuid"
 )
 
 // printOptionsEqual compares two PrintOrderOptions for equality
@@ -17,10 +24,84 @@ func printOptionsEqual(a, b models.PrintOrderOptions) bool {
 	return reflect.DeepEqual(a, b)
 }
 
-// AddToCartHandler adds or updates an item in the user's cart
-func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
+// Errors that abort a cart update
+var (
+	errCartNotFound = errors.New("cart not found")
+	errLineNotFound = errors.New("cart line not found")
+)
+
+// cartVersionFrom reads the cart version a client last saw from If-Match.
+// Without the header (or with "*") any version is accepted.
+func cartVersionFrom(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return repositories.AnyVersion, nil
+	}
+	return strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+}
+
+// writeCart sends the cart with its version as the ETag for the next If-Match
+func writeCart(w http.ResponseWriter, cart *models.Cart) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(cart.Version, 10)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
+
+// writeCartError maps cart update errors to HTTP responses
+func writeCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		http.Error(w, "cart has changed; reload it and retry", http.StatusPreconditionFailed)
+	case errors.Is(err, errCartNotFound), errors.Is(err, errLineNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("❌ Failed to update cart: %v", err)
+		http.Error(w, "failed to update cart", http.StatusInternalServerError)
+	}
+}
+
+// CartHandler serves the buyer's cart
+type CartHandler struct {
+	carts     repositories.CartRepository
+	artworks  repositories.ArtworkRepository
+	quoter    *pricing.Quoter
+	discovery *matching.ServiceDiscovery
+}
+
+// NewCartHandler creates a new cart handler; geocoder places buyers when previewing matches
+func NewCartHandler(store *repositories.Store, geocoder geo.Geocoder) *CartHandler {
+	return &CartHandler{
+		carts:     store.Carts,
+		artworks:  store.Artworks,
+		quoter:    pricing.NewQuoter(store.PrintShops, store.Frames),
+		discovery: matching.NewServiceDiscovery(store.PrintShops, store.Frames, store.Orders, geocoder),
+	}
+}
+
+// updateCart runs fn through UpdateCart after giving lines saved before line IDs existed an ID
+func (h *CartHandler) updateCart(ctx context.Context, buyerID string, version int64, fn repositories.CartFunc) (*models.Cart, error) {
+	return h.carts.UpdateCart(ctx, buyerID, version, func(cart *models.Cart, exists bool) error {
+		assignLineIDs(cart)
+		return fn(cart, exists)
+	})
+}
+
+// assignLineIDs gives every line without an ID a new one and reports whether any changed
+func assignLineIDs(cart *models.Cart) bool {
+	changed := false
+	for i := range cart.Items {
+		if cart.Items[i].LineID == "" {
+			cart.Items[i].LineID = uuid.NewString()
+			changed = true
+		}
+	}
+	return changed
+}
+
+// AddToCartHandler adds or updates an item in the user's cart.
+// Send the cart's ETag in If-Match to reject the change if the cart moved on meanwhile.
+func (h *CartHandler) AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.GetFirestoreClient()
 	uid := ctx.Value("userId")
 	if uid == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -28,67 +109,45 @@ func AddToCartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	buyerID := uid.(string)
 
+	version, err := cartVersionFrom(r)
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
 	var newItem models.CartItem
 	if err := json.NewDecoder(r.Body).Decode(&newItem); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Calculate price using legacy catalog pricing if price is not provided
-	if newItem.Price == 0 {
-		catSvc := catalog.NewCatalogService()
-		priceSvc := pricing.NewPricingService()
-		req := pricing.PriceRequest{
-			Size:      newItem.PrintOptions.Size,
-			Frame:     newItem.PrintOptions.Frame,
-			Material:  newItem.PrintOptions.Material,
-			Medium:    newItem.PrintOptions.Medium,
-			Quantity:  newItem.Quantity,
-			RushOrder: newItem.PrintOptions.RushOrder,
-		}
-		opts := catSvc.GetPrintOptions()
-		resp := priceSvc.Calculate(req, opts)
-		newItem.Price = float64(resp.Total)
-	}
+	// Client prices are ignored: show a catalog estimate, the final price is set at checkout
+	newItem.Price = h.quoter.EstimateUnitPrice(newItem.PrintOptions)
+	newItem.PriceBreakdown = nil
+	newItem.LineID = uuid.NewString()
 
-	cartRef := fsClient.Collection("carts").Doc(buyerID)
-	doc, err := cartRef.Get(ctx)
-
-	var cart models.Cart
-	if err == nil && doc.Exists() {
-		_ = doc.DataTo(&cart)
-		updated := false
+	cart, err := h.updateCart(ctx, buyerID, version, func(cart *models.Cart, exists bool) error {
 		for i, item := range cart.Items {
 			// Consider items identical only if artwork AND print options match
 			if item.ArtworkID == newItem.ArtworkID && printOptionsEqual(item.PrintOptions, newItem.PrintOptions) {
 				cart.Items[i].Quantity += newItem.Quantity
-				updated = true
-				break
+				return nil
 			}
 		}
-		if !updated {
-			cart.Items = append(cart.Items, newItem)
-		}
-	} else {
-		cart = models.Cart{
-			BuyerID: buyerID,
-			Items:   []models.CartItem{newItem},
-		}
-	}
-
-	cart.UpdatedAt = time.Now()
-	if _, err := cartRef.Set(ctx, cart); err != nil {
-		http.Error(w, "failed to update cart", http.StatusInternalServerError)
+		cart.Items = append(cart.Items, newItem)
+		return nil
+	})
+	if err != nil {
+		writeCartError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(cart)
+	writeCart(w, cart)
 }
 
 // GetCartHandler retrieves the user's cart
-func GetCartHandler(w http.ResponseWriter, r *http.Request) {
+func (h *CartHandler) GetCartHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.GetFirestoreClient()
 	uid := ctx.Value("userId")
 	if uid == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -96,21 +155,24 @@ func GetCartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	buyerID := uid.(string)
 
-	doc, err := fsClient.Collection("carts").Doc(buyerID).Get(ctx)
-	if err != nil || !doc.Exists() {
-		json.NewEncoder(w).Encode(models.Cart{BuyerID: buyerID, Items: []models.CartItem{}})
-		return
+	cart, err := h.carts.GetCart(ctx, buyerID)
+	if err != nil {
+		cart = &models.Cart{BuyerID: buyerID, Items: []models.CartItem{}}
+	} else if assignLineIDs(cart) {
+		// Persist IDs for older carts so they stay stable across reads
+		saved, err := h.updateCart(ctx, buyerID, cart.Version, func(*models.Cart, bool) error { return nil })
+		if err == nil {
+			cart = saved
+		}
 	}
 
-	var cart models.Cart
-	_ = doc.DataTo(&cart)
-	json.NewEncoder(w).Encode(cart)
+	writeCart(w, cart)
 }
 
-// RemoveFromCartHandler removes an item from the user's cart
-func RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
+// RemoveFromCartHandler removes a line by lineId, or else every line of an artwork
+// (only the variant with matching printOptions when useOptions is set). If-Match is honoured as for adds.
+func (h *CartHandler) RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.GetFirestoreClient()
 	uid := ctx.Value("userId")
 	if uid == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -118,7 +180,14 @@ func RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	buyerID := uid.(string)
 
+	version, err := cartVersionFrom(r)
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
 	var payload struct {
+		LineID       string                   `json:"lineId"`
 		ArtworkID    string                   `json:"artworkId"`
 		PrintOptions models.PrintOrderOptions `json:"printOptions,omitempty"`
 		UseOptions   bool                     `json:"useOptions"`
@@ -128,39 +197,41 @@ func RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cartRef := fsClient.Collection("carts").Doc(buyerID)
-	doc, err := cartRef.Get(ctx)
-	if err != nil || !doc.Exists() {
-		http.Error(w, "cart not found", http.StatusNotFound)
-		return
-	}
-
-	var cart models.Cart
-	_ = doc.DataTo(&cart)
-
-	newItems := []models.CartItem{}
-	for _, item := range cart.Items {
-		// If UseOptions is true, only remove items that match both artwork and print options
-		if payload.UseOptions {
-			if item.ArtworkID == payload.ArtworkID && printOptionsEqual(item.PrintOptions, payload.PrintOptions) {
-				continue // skip (remove) this specific variant
+	cart, err := h.updateCart(ctx, buyerID, version, func(cart *models.Cart, exists bool) error {
+		if !exists {
+			return errCartNotFound
+		}
+		if payload.LineID != "" {
+			i := cart.LineIndex(payload.LineID)
+			if i < 0 {
+				return errLineNotFound
 			}
-			newItems = append(newItems, item)
-		} else {
-			// Remove all items with the artwork id
-			if item.ArtworkID == payload.ArtworkID {
-				continue
+			cart.Items = slices.Delete(cart.Items, i, i+1)
+			return nil
+		}
+		newItems := []models.CartItem{}
+		for _, item := range cart.Items {
+			// If UseOptions is true, only remove items that match both artwork and print options
+			if payload.UseOptions {
+				if item.ArtworkID == payload.ArtworkID && printOptionsEqual(item.PrintOptions, payload.PrintOptions) {
+					continue // skip (remove) this specific variant
+				}
+				newItems = append(newItems, item)
+			} else {
+				// Remove all items with the artwork id
+				if item.ArtworkID == payload.ArtworkID {
+					continue
+				}
+				newItems = append(newItems, item)
 			}
-			newItems = append(newItems, item)
 		}
-	}
-	cart.Items = newItems
-	cart.UpdatedAt = time.Now()
-
-	if _, err := cartRef.Set(ctx, cart); err != nil {
-		http.Error(w, "failed to update cart", http.StatusInternalServerError)
+		cart.Items = newItems
+		return nil
+	})
+	if err != nil {
+		writeCartError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(cart)
+	writeCart(w, cart)
 }
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// Cart line issue codes reported by ValidateCart
+const (
+	lineIssueArtworkMissing     = "artwork_missing"
+	lineIssueArtworkUnavailable = "artwork_unavailable"
+	lineIssueNoMatchingShop     = "no_matching_shop"
+)
+
+// cartLineReq names the line a line endpoint acts on, plus the fields that endpoint reads
+type cartLineReq struct {
+	LineID       string                    `json:"lineId"`
+	Quantity     *int                      `json:"quantity,omitempty"`
+	PrintOptions *models.PrintOrderOptions `json:"printOptions,omitempty"`
+}
+
+// cartLineIssue flags a line that cannot be checked out as it stands
+type cartLineIssue struct {
+	LineID    string   `json:"lineId"`
+	ArtworkID string   `json:"artworkId"`
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	Fields    []string `json:"fields,omitempty"` // option fields shops rejected, for no_matching_shop
+}
+
+type cartValidationResp struct {
+	Valid  bool            `json:"valid"`
+	Issues []cartLineIssue `json:"issues"`
+}
+
+// UpdateLineQuantity sets the quantity of one line; a quantity of 0 removes the line
+func (h *CartHandler) UpdateLineQuantity(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeCartLineReq(w, r)
+	if !ok {
+		return
+	}
+	if body.Quantity == nil || *body.Quantity < 0 {
+		http.Error(w, "quantity must be 0 or more", http.StatusBadRequest)
+		return
+	}
+
+	h.updateLine(w, r, body.LineID, func(cart *models.Cart, i int) {
+		if *body.Quantity == 0 {
+			cart.Items = slices.Delete(cart.Items, i, i+1)
+			return
+		}
+		cart.Items[i].Quantity = *body.Quantity
+		if cart.Items[i].PrintOptions.Quantity != 0 {
+			cart.Items[i].PrintOptions.Quantity = *body.Quantity
+		}
+	})
+}
+
+// UpdateLineOptions replaces the print options of one line and re-estimates its price
+func (h *CartHandler) UpdateLineOptions(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeCartLineReq(w, r)
+	if !ok {
+		return
+	}
+	if body.PrintOptions == nil {
+		http.Error(w, "printOptions required", http.StatusBadRequest)
+		return
+	}
+
+	price := h.quoter.EstimateUnitPrice(*body.PrintOptions)
+	h.updateLine(w, r, body.LineID, func(cart *models.Cart, i int) {
+		cart.Items[i].PrintOptions = *body.PrintOptions
+		cart.Items[i].Price = price
+		cart.Items[i].PriceBreakdown = nil
+	})
+}
+
+// DuplicateLine copies one line into a new line placed right after it, so a variant
+// can be derived from it with UpdateLineOptions
+func (h *CartHandler) DuplicateLine(w http.ResponseWriter, r *http.Request) {
+	body, ok := decodeCartLineReq(w, r)
+	if !ok {
+		return
+	}
+
+	h.updateLine(w, r, body.LineID, func(cart *models.Cart, i int) {
+		line := cart.Items[i]
+		line.LineID = uuid.NewString()
+		cart.Items = slices.Insert(cart.Items, i+1, line)
+	})
+}
+
+// ClearCart removes every line. The cart document is kept so its version keeps counting up.
+func (h *CartHandler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	buyerID, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	version, err := cartVersionFrom(r)
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.updateCart(ctx, buyerID, version, func(cart *models.Cart, exists bool) error {
+		cart.Items = []models.CartItem{}
+		return nil
+	})
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, cart)
+}
+
+// ValidateCart flags lines whose artwork was removed or withdrawn from sale, and lines
+// whose print options no active shop service can currently fulfil
+func (h *CartHandler) ValidateCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	buyerID, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp := cartValidationResp{Valid: true, Issues: []cartLineIssue{}}
+	cart, err := h.carts.GetCart(ctx, buyerID)
+	if err == nil {
+		for _, item := range cart.Items {
+			if issue := h.checkLine(ctx, item); issue != nil {
+				resp.Issues = append(resp.Issues, *issue)
+			}
+		}
+		resp.Valid = len(resp.Issues) == 0
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkLine returns the first problem found with a line, or nil if it can be checked out
+func (h *CartHandler) checkLine(ctx context.Context, item models.CartItem) *cartLineIssue {
+	issue := &cartLineIssue{LineID: item.LineID, ArtworkID: item.ArtworkID}
+
+	artwork, err := h.artworks.GetArtworkByID(ctx, item.ArtworkID)
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		issue.Code, issue.Message = lineIssueArtworkMissing, "artwork no longer exists"
+		return issue
+	case err != nil:
+		log.Printf("⚠️ Failed to load artwork %s for cart validation: %v", item.ArtworkID, err)
+	case !artwork.IsAvailable:
+		issue.Code, issue.Message = lineIssueArtworkUnavailable, "artwork is no longer available"
+		return issue
+	}
+
+	options := item.PrintOptions
+	if options.Quantity == 0 {
+		options.Quantity = item.Quantity
+	}
+	matches, rejections, err := h.discovery.DiscoverServices(ctx, options)
+	if err != nil {
+		log.Printf("⚠️ Failed to match cart line %s: %v", item.LineID, err)
+		return nil
+	}
+	if len(matches) > 0 {
+		return nil
+	}
+
+	issue.Code, issue.Message = lineIssueNoMatchingShop, "no print shop can currently fulfil these print options"
+	seen := make(map[string]bool)
+	for _, rej := range rejections {
+		if !seen[rej.Field] {
+			seen[rej.Field] = true
+			issue.Fields = append(issue.Fields, rej.Field)
+		}
+	}
+	sort.Strings(issue.Fields)
+	return issue
+}
+
+// decodeCartLineReq reads a line request body and requires a lineId
+func decodeCartLineReq(w http.ResponseWriter, r *http.Request) (cartLineReq, bool) {
+	var body cartLineReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.LineID == "" {
+		http.Error(w, "lineId required", http.StatusBadRequest)
+		return body, false
+	}
+	return body, true
+}
+
+// updateLine applies change to the buyer's line with the given ID, honouring If-Match
+func (h *CartHandler) updateLine(w http.ResponseWriter, r *http.Request, lineID string, change func(cart *models.Cart, i int)) {
+	ctx := r.Context()
+	buyerID, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	version, err := cartVersionFrom(r)
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
+	cart, err := h.updateCart(ctx, buyerID, version, func(cart *models.Cart, exists bool) error {
+		if !exists {
+			return errCartNotFound
+		}
+		i := cart.LineIndex(lineID)
+		if i < 0 {
+			return errLineNotFound
+		}
+		change(cart, i)
+		return nil
+	})
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+	writeCart(w, cart)
+}
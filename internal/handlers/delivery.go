@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/delivery"
+	"github.com/cecvl/art-print-backend/internal/services/delivery/providers"
+)
+
+// DeliveryHandler serves packing, courier webhooks and buyer tracking
+type DeliveryHandler struct {
+	orders   repositories.OrderRepository
+	shops    repositories.PrintShopRepository
+	delivery *delivery.DeliveryService
+}
+
+// NewDeliveryHandler creates a new delivery handler shipping with the given courier, which may
+// be nil when none is configured
+func NewDeliveryHandler(store *repositories.Store, courier providers.CourierProvider) *DeliveryHandler {
+	return &DeliveryHandler{
+		orders:   store.Orders,
+		shops:    store.PrintShops,
+		delivery: delivery.NewDeliveryService(store, courier),
+	}
+}
+
+// parcelTracking is the delivery state of one shop's part of an order
+type parcelTracking struct {
+	OrderID        string           `json:"orderId"`
+	PrintShopID    string           `json:"printShopId"`
+	DeliveryStatus string           `json:"deliveryStatus"`
+	PickupLocation string           `json:"pickupLocation,omitempty"`
+	Shipment       *models.Shipment `json:"shipment,omitempty"` // includes the tracking timeline
+}
+
+type orderTrackingResp struct {
+	OrderID        string           `json:"orderId"`
+	DeliveryMethod string           `json:"deliveryMethod"`
+	Parcels        []parcelTracking `json:"parcels"`
+}
+
+// PackOrder marks an accepted order of the authenticated shop as packed. Shipping orders are
+// booked with the courier and the shipment is returned; pickup orders become ready to collect.
+func (h *DeliveryHandler) PackOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body inboxOrderReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.OrderID == "" {
+		http.Error(w, "orderId required", http.StatusBadRequest)
+		return
+	}
+	shop, err := h.shops.GetShopByOwnerID(ctx, ctx.Value("shopOwnerId").(string))
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+	order, err := h.orders.GetOrderByID(ctx, body.OrderID)
+	if err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if order.PrintShopID != shop.ID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if order.AssignmentStatus != "" && order.AssignmentStatus != models.AssignmentAccepted {
+		http.Error(w, "accept the order before packing it", http.StatusConflict)
+		return
+	}
+
+	shipment, err := h.delivery.MarkPacked(ctx, order, userIDFrom(ctx))
+	switch {
+	case errors.Is(err, delivery.ErrNotInProduction), errors.Is(err, delivery.ErrNoShippingAddress):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, delivery.ErrNoCourier):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, repositories.ErrIllegalTransition):
+		writeTransitionError(w, order.OrderID, err)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to pack order %s: %v", order.OrderID, err)
+		http.Error(w, "failed to pack order", http.StatusInternalServerError)
+		return
+	}
+
+	if shipment == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// CourierWebhookHandler handles tracking updates pushed by the courier. Updates must be signed;
+// the signature is read from the courier's signature header.
+func (h *DeliveryHandler) CourierWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+	var webhook models.CourierWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil || webhook.TrackingNumber == "" {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+	webhook.Signature = r.Header.Get(providers.CourierSignatureHeader)
+
+	err = h.delivery.ProcessCourierWebhook(ctx, payload, webhook)
+	switch {
+	case errors.Is(err, delivery.ErrInvalidWebhook):
+		http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	case errors.Is(err, delivery.ErrUnknownStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "Unknown tracking number", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to process courier webhook: %v", err)
+		http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// GetTrackingHandler returns the delivery timeline of one of the buyer's orders. A parent order
+// lists one parcel per sub-order, since each shop ships separately.
+func (h *DeliveryHandler) GetTrackingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orderID := r.URL.Query().Get("orderId")
+	if orderID == "" {
+		http.Error(w, "orderId required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orders.GetOrderByID(ctx, orderID)
+	if err != nil || order.BuyerID != uid {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+
+	parcels := []*models.Order{order}
+	if order.IsParent() {
+		parcels, err = h.orders.ListOrders(ctx, repositories.OrderFilter{ParentOrderID: order.OrderID})
+		if err != nil {
+			log.Printf("❌ Failed to load sub-orders of %s: %v", order.OrderID, err)
+			http.Error(w, "failed to load tracking", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := orderTrackingResp{OrderID: order.OrderID, DeliveryMethod: order.DeliveryMethod, Parcels: []parcelTracking{}}
+	for _, p := range parcels {
+		parcel := parcelTracking{
+			OrderID:        p.OrderID,
+			PrintShopID:    p.PrintShopID,
+			DeliveryStatus: p.DeliveryStatus,
+			PickupLocation: p.PickupLocation,
+		}
+		if shipment, err := h.delivery.Shipment(ctx, p.OrderID); err == nil {
+			parcel.Shipment = shipment
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("⚠️ Failed to load shipment of order %s: %v", p.OrderID, err)
+		}
+		resp.Parcels = append(resp.Parcels, parcel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -7,12 +7,22 @@ import (
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
-	"google.golang.org/api/iterator"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
-func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+// ProfileHandler serves the authenticated user's own profile
+type ProfileHandler struct {
+	users    repositories.UserRepository
+	artworks repositories.ArtworkRepository
+}
+
+// NewProfileHandler creates a new profile handler
+func NewProfileHandler(store *repositories.Store) *ProfileHandler {
+	return &ProfileHandler{users: store.Users, artworks: store.Artworks}
+}
+
+func (h *ProfileHandler) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
 	defer cancel()
 
@@ -26,56 +36,36 @@ func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔍 Fetching profile for user: %s", uid)
 
 	// Get user document
-	userDoc, err := firebase.FirestoreClient.Collection("users").Doc(uid).Get(ctx)
+	user, err := h.users.GetUserByID(ctx, uid)
+	if err == repositories.ErrNotFound {
+		log.Printf("❌ User document doesn't exist for UID: %s", uid)
+		http.Error(w, `{"error": "User document not found"}`, http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("❌ User fetch error: %v", err)
 		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
 		return
 	}
-
-	if !userDoc.Exists() {
-		log.Printf("❌ User document doesn't exist for UID: %s", uid)
-		http.Error(w, `{"error": "User document not found"}`, http.StatusNotFound)
-		return
-	}
-
-	userData := userDoc.Data()
-	log.Printf("📋 User data: %+v", userData) // Debug log
+	log.Printf("📋 User data: %+v", user) // Debug log
 
 	// Get artworks
-	artworks := make([]map[string]interface{}, 0)
-	iter := firebase.FirestoreClient.Collection("artworks").
-		Where("artistId", "==", uid).
-		OrderBy("createdAt", firestore.Desc).
-		Limit(6).
-		Documents(ctx)
-
-	defer iter.Stop()
-
-	artworkCount := 0
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("⚠️ Artwork document error: %v", err)
-			break
-		}
-		artworkCount++
-		artworkData := doc.Data()
-		//log.Printf("🎨 Artwork %d: %+v", artworkCount, artworkData) // Debug log
-		artworkData["id"] = doc.Ref.ID
-		artworks = append(artworks, artworkData)
+	artworks, err := h.artworks.ListArtworks(ctx, repositories.ArtworkFilter{
+		ArtistID: uid,
+		Sort:     repositories.NewestFirst,
+		Limit:    6,
+	})
+	if err != nil {
+		log.Printf("⚠️ Artwork document error: %v", err)
+		artworks = []*models.Artwork{}
 	}
 
-	log.Printf("✅ Found %d artworks for user %s", artworkCount, uid)
+	log.Printf("✅ Found %d artworks for user %s", len(artworks), uid)
 
 	response := map[string]interface{}{
-		"user":     userData,
+		"user":     user,
 		"artworks": artworks,
 	}
-	response["user"].(map[string]interface{})["uid"] = uid
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
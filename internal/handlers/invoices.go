@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// invoiceLine is one item billed on an invoice
+type invoiceLine struct {
+	LineID      string       `json:"lineId,omitempty"`
+	Description string       `json:"description"`
+	Quantity    int          `json:"quantity"`
+	UnitPrice   models.Money `json:"unitPrice"`
+	Amount      models.Money `json:"amount"`
+	Discount    models.Money `json:"discount"`
+}
+
+// invoiceResp is the buyer's invoice for an order, built from what was frozen at checkout
+type invoiceResp struct {
+	InvoiceNumber string                 `json:"invoiceNumber"`
+	OrderID       string                 `json:"orderId"`
+	IssuedAt      time.Time              `json:"issuedAt"`
+	BillTo        *models.Address        `json:"billTo,omitempty"`
+	Lines         []invoiceLine          `json:"lines"`
+	Subtotal      models.Money           `json:"subtotal"`
+	Shipping      models.Money           `json:"shipping"`
+	Discounts     []models.OrderDiscount `json:"discounts,omitempty"`
+	DiscountTotal models.Money           `json:"discountTotal"`
+	TaxLines      []models.TaxLine       `json:"taxLines,omitempty"`
+	TaxTotal      models.Money           `json:"taxTotal"`
+	TaxExempt     bool                   `json:"taxExempt,omitempty"`
+	Total         models.Money           `json:"total"`
+	PaymentStatus string                 `json:"paymentStatus"`
+}
+
+// GetInvoiceHandler returns the invoice of one of the buyer's orders (?orderId=), with its
+// discounts and tax lines. Inclusive tax is listed but already part of the line amounts.
+func (h *OrderHandler) GetInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid, ok := ctx.Value("userId").(string)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	orderID := r.URL.Query().Get("orderId")
+	if orderID == "" {
+		http.Error(w, "orderId required", http.StatusBadRequest)
+		return
+	}
+	order, err := h.orders.GetOrderByID(ctx, orderID)
+	if err != nil || order.BuyerID != uid {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+
+	inv := invoiceResp{
+		InvoiceNumber: invoiceNumber(order),
+		OrderID:       order.OrderID,
+		IssuedAt:      order.CreatedAt,
+		BillTo:        order.ShippingAddress,
+		Lines:         []invoiceLine{},
+		Shipping:      order.ShippingCost,
+		Discounts:     order.Discounts,
+		DiscountTotal: order.DiscountTotal,
+		TaxLines:      order.TaxLines,
+		TaxTotal:      order.TaxTotal,
+		TaxExempt:     order.TaxExempt,
+		Total:         order.TotalAmount,
+		PaymentStatus: order.PaymentStatus,
+	}
+	for _, item := range order.Items {
+		line := invoiceLine{
+			LineID:      item.LineID,
+			Description: item.ArtworkID,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
+			Amount:      item.LineTotal(),
+			Discount:    order.LineDiscount(item.LineID),
+		}
+		if artwork, err := h.artworks.GetArtworkByID(ctx, item.ArtworkID); err == nil && artwork.Title != "" {
+			line.Description = artwork.Title
+		}
+		if size := item.PrintOptions.Size; size != "" {
+			line.Description += " (" + size + ")"
+		}
+		inv.Subtotal = inv.Subtotal.Add(line.Amount)
+		inv.Lines = append(inv.Lines, line)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv)
+}
+
+// invoiceNumber derives a stable, human-readable invoice number from the order
+func invoiceNumber(order *models.Order) string {
+	id := order.OrderID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return "INV-" + order.CreatedAt.Format("20060102") + "-" + id
+}
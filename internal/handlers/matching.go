@@ -6,27 +6,29 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cecvl/art-print-backend/internal/services/config"
-	"github.com/cecvl/art-print-backend/internal/services/matching"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
 	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
 )
 
 // MatchingHandler handles order matching operations
 type MatchingHandler struct {
+	orders       repositories.OrderRepository
+	shops        repositories.PrintShopRepository
 	orderService *orders.OrderService
-	discovery    *matching.ServiceDiscovery
+	assignments  *orders.Assignments
 }
 
 // NewMatchingHandler creates a new matching handler
-func NewMatchingHandler() *MatchingHandler {
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	configService := config.NewDefaultConfigService()
+func NewMatchingHandler(store *repositories.Store, cfg config.ConfigService, geocoder geo.Geocoder, rates shipping.RateProvider) *MatchingHandler {
 	return &MatchingHandler{
-		orderService: orders.NewOrderService(configService),
-		discovery:    matching.NewServiceDiscovery(repo),
+		orders:       store.Orders,
+		shops:        store.PrintShops,
+		orderService: orders.NewOrderService(cfg, store, geocoder),
+		assignments:  orders.NewAssignments(cfg, store, geocoder, rates),
 	}
 }
 
@@ -64,7 +66,6 @@ func (h *MatchingHandler) GetOrderMatches(w http.ResponseWriter, r *http.Request
 // AssignShopToOrder manually assigns a shop to an order (for manual mode)
 func (h *MatchingHandler) AssignShopToOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.FirestoreClient
 
 	var req struct {
 		OrderID string `json:"orderId"`
@@ -77,31 +78,27 @@ func (h *MatchingHandler) AssignShopToOrder(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Get order
-	orderDoc, err := fsClient.Collection("orders").Doc(req.OrderID).Get(ctx)
-	if err != nil || !orderDoc.Exists() {
+	order, err := h.orders.GetOrderByID(ctx, req.OrderID)
+	if err != nil {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
-	var order models.Order
-	if err := orderDoc.DataTo(&order); err != nil {
-		http.Error(w, "Invalid order data", http.StatusInternalServerError)
+	if order.IsParent() {
+		http.Error(w, "Assign a shop to a sub-order", http.StatusBadRequest)
 		return
 	}
 
 	// Verify shop exists and is active
-	repo := repositories.NewPrintShopRepository(fsClient)
-	shop, err := repo.GetShopByID(ctx, req.ShopID)
+	shop, err := h.shops.GetShopByID(ctx, req.ShopID)
 	if err != nil || !shop.IsActive {
 		http.Error(w, "Shop not found or inactive", http.StatusBadRequest)
 		return
 	}
 
-	// Update order with shop assignment
-	order.PrintShopID = req.ShopID
+	// Offer the order to the shop; it is recorded in the assignments log
 	order.UpdatedAt = time.Now()
-
-	if _, err := fsClient.Collection("orders").Doc(req.OrderID).Set(ctx, order); err != nil {
+	if err := h.assignments.Assign(ctx, order, req.ShopID, userIDFrom(ctx), "manual assignment"); err != nil {
 		log.Printf("❌ Failed to update order: %v", err)
 		http.Error(w, "Failed to assign shop", http.StatusInternalServerError)
 		return
@@ -4,10 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 )
 
@@ -18,7 +15,7 @@ type selectPrintShopReq struct {
 
 // SelectPrintShopHandler allows an authorized user to set the print shop for an order
 // Authorization: buyer who created the order OR an artist who owns any artwork in the order
-func SelectPrintShopHandler(w http.ResponseWriter, r *http.Request) {
+func (h *OrderHandler) SelectPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ""
 	if v := ctx.Value("userId"); v != nil {
@@ -42,19 +39,20 @@ func SelectPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// fetch order
-	doc, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Get(ctx)
+	order, err := h.orders.GetOrderByID(ctx, body.OrderID)
 	if err != nil {
 		http.Error(w, "order not found", http.StatusNotFound)
 		return
 	}
-	var order models.Order
-	if err := doc.DataTo(&order); err != nil {
-		http.Error(w, "invalid order data", http.StatusInternalServerError)
+
+	// shops fulfil sub-orders; a parent order has no shop of its own
+	if order.IsParent() {
+		http.Error(w, "select a print shop for a sub-order", http.StatusBadRequest)
 		return
 	}
 
 	// only allow change if order is pending/confirmed (not completed)
-	if order.Status == "completed" || order.Status == "cancelled" {
+	if order.Status == models.OrderStatusCompleted || order.Status == models.OrderStatusCancelled {
 		http.Error(w, "order cannot be reassigned", http.StatusBadRequest)
 		return
 	}
@@ -66,16 +64,13 @@ func SelectPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		// check artwork ownership
 		for _, it := range order.Items {
-			artDoc, err := firebase.FirestoreClient.Collection("artworks").Doc(it.ArtworkID).Get(ctx)
+			art, err := h.artworks.GetArtworkByID(ctx, it.ArtworkID)
 			if err != nil {
 				continue
 			}
-			var art models.Artwork
-			if artDoc.DataTo(&art) == nil {
-				if art.ArtistID == uid {
-					authorized = true
-					break
-				}
+			if art.ArtistID == uid {
+				authorized = true
+				break
 			}
 		}
 	}
@@ -84,15 +79,14 @@ func SelectPrintShopHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// set printShopId
-	_, err = firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Update(ctx, []firestore.Update{{Path: "printShopId", Value: body.PrintShopID}, {Path: "updatedAt", Value: time.Now()}})
-	if err != nil {
+	// offer the order to the selected shop, which still has to accept it
+	if err := h.assignments.Assign(ctx, order, body.PrintShopID, uid, "selected by customer"); err != nil {
 		log.Printf("❌ failed to set printshop for order: %v", err)
 		http.Error(w, "failed to set printshop", http.StatusInternalServerError)
 		return
 	}
 
-	writeAdminAction(ctx, r, "select_printshop", "order", body.OrderID, map[string]interface{}{"printShopId": body.PrintShopID})
+	writeAdminAction(ctx, h.logs, r, "select_printshop", "order", body.OrderID, map[string]interface{}{"printShopId": body.PrintShopID})
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// systemActorCheckout is recorded as the actor when checkout offers sub-orders to matched shops
+const systemActorCheckout = "system:checkout"
+
+// writeTransitionError maps a TransitionStatus error to an HTTP response
+func writeTransitionError(w http.ResponseWriter, orderID string, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrIllegalTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("❌ failed to change status of order %s: %v", orderID, err)
+		http.Error(w, "failed to update order", http.StatusInternalServerError)
+	}
+}
@@ -2,21 +2,77 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
 	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/pricing"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+	"github.com/cecvl/art-print-backend/internal/services/tax"
 	"github.com/google/uuid"
 )
 
-// CheckoutHandler converts the user's cart into an order and assigns a print shop
-func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
+// OrderHandler serves buyer-facing order endpoints
+type OrderHandler struct {
+	orders   repositories.OrderRepository
+	carts    repositories.CartRepository
+	artworks repositories.ArtworkRepository
+	users    repositories.UserRepository
+	logs     repositories.ActivityLogRepository
+	quoter   *pricing.Quoter
+	matcher  *orders.OrderService
+
+	assignments *orders.Assignments
+	geocoder    geo.Geocoder
+	shipping    *shipping.ShippingService
+	promotions  *pricing.PromotionEngine
+	tax         *tax.TaxService
+}
+
+// NewOrderHandler creates a new order handler
+func NewOrderHandler(store *repositories.Store, cfg config.ConfigService, geocoder geo.Geocoder, rates shipping.RateProvider) *OrderHandler {
+	return &OrderHandler{
+		orders:   store.Orders,
+		carts:    store.Carts,
+		artworks: store.Artworks,
+		users:    store.Users,
+		logs:     store.ActivityLog,
+		quoter:   pricing.NewQuoter(store.PrintShops, store.Frames),
+		matcher:  orders.NewOrderService(cfg, store, geocoder),
+
+		assignments: orders.NewAssignments(cfg, store, geocoder, rates),
+		geocoder:    geocoder,
+		shipping:    shipping.NewShippingService(rates, store.PrintShops),
+		promotions:  pricing.NewPromotionEngine(store),
+		tax:         tax.NewTaxService(cfg, store),
+	}
+}
+
+// Delivery methods a buyer can choose at checkout
+const (
+	deliveryShipping = models.DeliveryMethodShipping
+	deliveryPickup   = models.DeliveryMethodPickup
+)
+
+// defaultPickupRadiusKm applies when a pickup order does not choose a radius
+const defaultPickupRadiusKm = 10
+
+// CheckoutHandler converts the user's cart into a parent order and matches each item to a
+// print shop, grouping items that go to the same shop into one fulfillment sub-order.
+// Every line is repriced from its shop; the response lists lines whose price drifted from the cart.
+// Shipping orders go to one of the buyer's saved addresses and each sub-order adds its own
+// shipping charge; pickup orders are collected from each sub-order's shop. Without a delivery
+// method or address nothing is charged for delivery, as before shipping was priced. An optional
+// couponCode is checked and its discount taken off the totals before tax is worked out on
+// what remains.
+func (h *OrderHandler) CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.GetFirestoreClient()
 	uid := ctx.Value("userId")
 	if uid == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -24,84 +80,207 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	buyerID := uid.(string)
 
-	// Parse request to get print options
+	version, err := cartVersionFrom(r)
+	if err != nil {
+		http.Error(w, "invalid If-Match header", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request to get default print options for items that carry none
 	var checkoutReq struct {
-		PrintOptions models.PrintOrderOptions `json:"printOptions"`
+		PrintOptions     models.PrintOrderOptions `json:"printOptions"`
+		DeliveryLocation *models.Location         `json:"deliveryLocation"` // optional; lets matching favour nearby shops
+		DeliveryMethod   string                   `json:"deliveryMethod"`   // "shipping" or "pickup"; omitted = shipping, charged only when addressId is given
+		AddressID        string                   `json:"addressId"`        // shipping only; defaults to the buyer's default address
+		PickupRadiusKm   float64                  `json:"pickupRadiusKm"`   // pickup only; defaults to defaultPickupRadiusKm
+		CouponCode       string                   `json:"couponCode"`       // optional; one promotion per order
 	}
 
 	// Try to decode print options (optional - can use defaults)
 	json.NewDecoder(r.Body).Decode(&checkoutReq)
 
-	cartDoc, err := fsClient.Collection("carts").Doc(buyerID).Get(ctx)
-	if err != nil || !cartDoc.Exists() {
-		http.Error(w, "cart is empty", http.StatusBadRequest)
-		return
+	// Shipping orders need an address; it also places the buyer for matching unless a location
+	// was given. Checkouts that name neither a delivery method nor an address keep working as
+	// before shipping was charged: no address is needed and nothing is added for delivery.
+	var address *models.Address
+	if checkoutReq.DeliveryMethod == deliveryShipping || (checkoutReq.DeliveryMethod == "" && checkoutReq.AddressID != "") {
+		user, err := h.users.GetUserByID(ctx, buyerID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("❌ Failed to load buyer %s for checkout: %v", buyerID, err)
+			http.Error(w, "failed to load shipping address", http.StatusInternalServerError)
+			return
+		}
+		if user != nil {
+			if checkoutReq.AddressID != "" {
+				if address = user.Address(checkoutReq.AddressID); address == nil {
+					http.Error(w, "address not found", http.StatusNotFound)
+					return
+				}
+			} else {
+				address = user.DefaultAddress()
+			}
+		}
+		if address == nil {
+			http.Error(w, "shipping orders need a saved shipping address", http.StatusBadRequest)
+			return
+		}
+		if checkoutReq.DeliveryLocation == nil {
+			loc := address.Location
+			checkoutReq.DeliveryLocation = &loc
+		}
 	}
 
-	var cart models.Cart
-	_ = cartDoc.DataTo(&cart)
+	// Place the buyer so matching can measure distances; pickup orders must be placeable
+	located := geo.Resolve(ctx, h.geocoder, checkoutReq.DeliveryLocation)
+	switch checkoutReq.DeliveryMethod {
+	case "":
+		if address != nil {
+			checkoutReq.DeliveryMethod = deliveryShipping
+		}
+		checkoutReq.PickupRadiusKm = 0
+	case deliveryShipping:
+		checkoutReq.PickupRadiusKm = 0
+	case deliveryPickup:
+		if !located {
+			http.Error(w, "pickup orders need a deliveryLocation that can be located", http.StatusBadRequest)
+			return
+		}
+		if checkoutReq.PickupRadiusKm <= 0 {
+			checkoutReq.PickupRadiusKm = defaultPickupRadiusKm
+		}
+	default:
+		http.Error(w, "deliveryMethod must be shipping or pickup", http.StatusBadRequest)
+		return
+	}
 
-	// If print options not provided, try to extract from first cart item
-	if checkoutReq.PrintOptions.Size == "" && len(cart.Items) > 0 && cart.Items[0].PrintOptions.Size != "" {
-		checkoutReq.PrintOptions = cart.Items[0].PrintOptions
+	cart, err := h.carts.GetCart(ctx, buyerID)
+	if err != nil || len(cart.Items) == 0 {
+		http.Error(w, "cart is empty", http.StatusBadRequest)
+		return
+	}
+	if version != repositories.AnyVersion && version != cart.Version {
+		http.Error(w, "cart has changed; reload it and retry", http.StatusPreconditionFailed)
+		return
 	}
 
-	// Set defaults if still empty
-	if checkoutReq.PrintOptions.Quantity == 0 {
-		checkoutReq.PrintOptions.Quantity = 1
-		if len(cart.Items) > 0 {
-			checkoutReq.PrintOptions.Quantity = cart.Items[0].Quantity
+	items := make([]models.CartItem, len(cart.Items))
+	for i, item := range cart.Items {
+		if item.PrintOptions.Size == "" && checkoutReq.PrintOptions.Size != "" {
+			item.PrintOptions = checkoutReq.PrintOptions
+		}
+		if item.PrintOptions.Quantity == 0 {
+			item.PrintOptions.Quantity = item.Quantity
+		}
+		if checkoutReq.DeliveryLocation != nil {
+			item.PrintOptions.DeliveryLocation = checkoutReq.DeliveryLocation
 		}
+		item.PrintOptions.PickupRadiusKm = checkoutReq.PickupRadiusKm
+		if item.LineID == "" {
+			item.LineID = uuid.NewString() // discounts are recorded against line IDs
+		}
+		items[i] = item
 	}
 
-	var total float64
-	for _, item := range cart.Items {
-		total += item.Price * float64(item.Quantity)
+	// Match every item on its own print options; unmatched items can be assigned manually later
+	shopIDs := h.matcher.MatchItemShops(ctx, items)
+
+	// Reprice every line from its matched shop; cart prices are only used to report drift
+	drift := h.quoter.Reprice(ctx, items, shopIDs)
+	total := models.NewMoney(0, models.DefaultCurrency)
+	for _, item := range items {
+		total = total.Add(item.LineTotal())
 	}
 
 	order := models.Order{
 		OrderID:        uuid.NewString(),
 		BuyerID:        buyerID,
-		Items:          cart.Items,
-		PrintOptions:   checkoutReq.PrintOptions,
+		Items:          items,
+		PrintOptions:   items[0].PrintOptions, // Legacy: per-item options live on Items
 		TotalAmount:    total,
-		Status:         "pending",
+		Status:         models.OrderStatusPending,
 		PaymentMethod:  "unpaid",  // Legacy field
 		PaymentStatus:  "unpaid",  // New field
 		DeliveryStatus: "pending", // Initialize delivery status
+		DeliveryMethod: checkoutReq.DeliveryMethod,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
+	subOrders := orders.GroupByShop(&order, shopIDs)
+	if address != nil {
+		if err := h.shipping.ApplyShipping(ctx, &order, subOrders, *address); err != nil {
+			if errors.Is(err, shipping.ErrNoRate) {
+				http.Error(w, "shipping is not available to this address", http.StatusBadRequest)
+				return
+			}
+			log.Printf("❌ Failed to price shipping for order %s: %v", order.OrderID, err)
+			http.Error(w, "failed to price shipping", http.StatusInternalServerError)
+			return
+		}
+	} else if order.DeliveryMethod == deliveryPickup {
+		h.shipping.ApplyPickup(ctx, &order, subOrders)
+	}
+	var promotion *models.Promotion
+	if checkoutReq.CouponCode != "" {
+		promotion, err = h.promotions.Apply(ctx, checkoutReq.CouponCode, &order, subOrders)
+		if err != nil {
+			writePromotionError(w, order.OrderID, err)
+			return
+		}
+	}
+	h.tax.ApplyTax(ctx, &order, subOrders)
+	for _, sub := range subOrders {
+		h.assignments.PrepareOffer(ctx, sub, order.CreatedAt)
+	}
 
-	// Assign print shop using matching service
-	configService := config.NewDefaultConfigService()
-	orderService := orders.NewOrderService(configService)
-
-	if err := orderService.AssignShopForOrder(ctx, &order); err != nil {
-		log.Printf("⚠️ Failed to assign shop for order %s: %v", order.OrderID, err)
-		// Continue without assignment - order can be manually assigned later
+	// Redeem before saving so limited coupons cannot be oversold; the use is given back if saving fails
+	if promotion != nil {
+		if err := h.promotions.Redeem(ctx, promotion, &order); err != nil {
+			writePromotionError(w, order.OrderID, err)
+			return
+		}
 	}
 
-	// Save order to Firestore
-	if _, err := fsClient.Collection("orders").Doc(order.OrderID).Set(ctx, order); err != nil {
+	// Persist parent and sub-orders and clear the cart in one step, unless the cart changed meanwhile
+	if err := h.carts.CheckoutCart(ctx, buyerID, cart.Version, &order, subOrders); err != nil {
+		if promotion != nil {
+			h.promotions.Release(ctx, order.OrderID)
+		}
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			http.Error(w, "cart changed during checkout; review it and retry", http.StatusConflict)
+			return
+		}
+		log.Printf("❌ Failed to create order %s: %v", order.OrderID, err)
 		http.Error(w, "failed to create order", http.StatusInternalServerError)
 		return
 	}
-
-	// Clear cart
-	if _, err := fsClient.Collection("carts").Doc(buyerID).Delete(ctx); err != nil {
-		log.Printf("⚠️ Failed to clear cart: %v", err)
-		// Don't fail the request if cart clearing fails
+	order.SubOrders = subOrders
+	for _, sub := range subOrders {
+		h.assignments.RecordOffer(ctx, sub, systemActorCheckout, "matched at checkout")
+	}
+	if len(drift) > 0 {
+		log.Printf("⚠️ Order %s repriced %d cart line(s) at checkout", order.OrderID, len(drift))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(order)
+	json.NewEncoder(w).Encode(struct {
+		models.Order
+		PriceDrift []pricing.LineDrift `json:"priceDrift,omitempty"`
+	}{order, drift})
 }
 
-// GetOrdersHandler fetches all orders for the authenticated user
-func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
+// writePromotionError reports why a coupon was refused, or a server error
+func writePromotionError(w http.ResponseWriter, orderID string, err error) {
+	if errors.Is(err, pricing.ErrPromotionNotApplicable) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("❌ Failed to apply coupon to order %s: %v", orderID, err)
+	http.Error(w, "failed to apply coupon", http.StatusInternalServerError)
+}
+
+// GetOrdersHandler fetches all orders for the authenticated user, with sub-orders nested under their parents
+func (h *OrderHandler) GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	fsClient := firebase.GetFirestoreClient()
 	uid := ctx.Value("userId")
 	if uid == nil {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -109,18 +288,32 @@ func GetOrdersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	buyerID := uid.(string)
 
-	snaps, err := fsClient.Collection("orders").Where("buyerId", "==", buyerID).Documents(ctx).GetAll()
+	all, err := h.orders.ListOrders(ctx, repositories.OrderFilter{BuyerID: buyerID})
 	if err != nil {
 		http.Error(w, "failed to fetch orders", http.StatusInternalServerError)
 		return
 	}
 
-	var orders []models.Order
-	for _, snap := range snaps {
-		var o models.Order
-		_ = snap.DataTo(&o)
-		orders = append(orders, o)
+	json.NewEncoder(w).Encode(nestSubOrders(all))
+}
+
+// nestSubOrders attaches sub-orders to their parents and returns only top-level orders.
+// Sub-orders whose parent is not in the list are returned as top-level orders.
+func nestSubOrders(all []*models.Order) []*models.Order {
+	parents := make(map[string]*models.Order)
+	for _, o := range all {
+		if o.IsParent() {
+			parents[o.OrderID] = o
+		}
 	}
 
-	json.NewEncoder(w).Encode(orders)
+	out := make([]*models.Order, 0, len(all))
+	for _, o := range all {
+		if parent, ok := parents[o.ParentOrderID]; ok && o.IsSubOrder() {
+			parent.SubOrders = append(parent.SubOrders, o)
+			continue
+		}
+		out = append(out, o)
+	}
+	return out
 }
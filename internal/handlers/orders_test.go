@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+)
+
+// serveAs runs handler for a request signed in as buyerID, as the auth middleware would
+func serveAs(t *testing.T, handler http.HandlerFunc, buyerID, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "userId", buyerID))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestCheckoutFromCart(t *testing.T) {
+	ctx := context.Background()
+	store := repositories.NewMemoryStore()
+	if err := store.Artworks.CreateArtwork(ctx, &models.Artwork{ID: "art-1", ArtistID: "artist-1"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewDefaultConfigService()
+	geocoder := geo.NewTableGeocoder()
+	carts := NewCartHandler(store, geocoder)
+	orders := NewOrderHandler(store, cfg, geocoder, shipping.NewTableRateProvider())
+
+	// Two identical adds share one line; the client's price is ignored
+	for range 2 {
+		rec := serveAs(t, carts.AddToCartHandler, "buyer", http.MethodPost, "/cart/add",
+			`{"ArtworkID":"art-1","Quantity":1,"Price":{"amount":1,"currency":"KES"},"PrintOptions":{"size":"A4","material":"paper"}}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("add to cart: %d %s", rec.Code, rec.Body)
+		}
+	}
+	cart, err := store.Carts.GetCart(ctx, "buyer")
+	if err != nil || len(cart.Items) != 1 || cart.Items[0].Quantity != 2 {
+		t.Fatalf("cart = %+v (err %v), want one line of 2", cart, err)
+	}
+	if cart.Items[0].Price == models.NewMoney(1, "KES") {
+		t.Errorf("cart kept the client's price")
+	}
+
+	rec := serveAs(t, orders.CheckoutHandler, "buyer", http.MethodPost, "/checkout", `{}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("checkout: %d %s", rec.Code, rec.Body)
+	}
+	var placed models.Order
+	if err := json.NewDecoder(rec.Body).Decode(&placed); err != nil {
+		t.Fatal(err)
+	}
+	if placed.OrderID == "" || placed.Status != models.OrderStatusPending || placed.PaymentStatus != "unpaid" {
+		t.Errorf("placed order = %s %s %s", placed.OrderID, placed.Status, placed.PaymentStatus)
+	}
+	// No shop can print it yet, so the line waits in one unassigned sub-order
+	if len(placed.SubOrders) != 1 || placed.SubOrders[0].ParentOrderID != placed.OrderID || placed.SubOrders[0].PrintShopID != "" {
+		t.Errorf("sub-orders = %+v, want one unassigned sub-order", placed.SubOrders)
+	}
+	if len(placed.Items) != 1 || placed.Items[0].PriceBreakdown == nil || placed.TotalAmount != placed.Items[0].LineTotal() {
+		t.Errorf("placed order items %+v total %s, want one repriced line making up the total", placed.Items, placed.TotalAmount)
+	}
+	if cart, err := store.Carts.GetCart(ctx, "buyer"); err == nil && len(cart.Items) > 0 {
+		t.Errorf("cart still holds %d lines after checkout", len(cart.Items))
+	}
+
+	rec = serveAs(t, orders.GetOrdersHandler, "buyer", http.MethodGet, "/orders", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get orders: %d %s", rec.Code, rec.Body)
+	}
+	var listed []models.Order
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 {
+		t.Fatalf("listed %d orders, want the one placed", len(listed))
+	}
+	got := listed[0]
+	if got.OrderID != placed.OrderID || got.TotalAmount != placed.TotalAmount || len(got.Items) != 1 || got.Items[0].PriceBreakdown == nil {
+		t.Errorf("listed order %s total %s items %+v, want %s as placed", got.OrderID, got.TotalAmount, got.Items, placed.OrderID)
+	}
+	if len(got.SubOrders) != 1 || got.SubOrders[0].OrderID != placed.SubOrders[0].OrderID {
+		t.Errorf("listed sub-orders = %+v, want %s nested under its parent", got.SubOrders, placed.SubOrders[0].OrderID)
+	}
+
+	if rec := serveAs(t, orders.CheckoutHandler, "buyer", http.MethodPost, "/checkout", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("second checkout of the emptied cart: %d, want 400", rec.Code)
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cecvl/art-print-backend/internal/services/payment"
@@ -15,18 +18,33 @@ import (
 
 // PaymentHandler handles payment-related requests
 type PaymentHandler struct {
+	orders         repositories.OrderRepository
+	payments       repositories.PaymentRepository
 	paymentService *payment.PaymentService
 }
 
-// NewPaymentHandler creates a new payment handler
-func NewPaymentHandler() *PaymentHandler {
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	simulatedProvider := providers.NewSimulatedProvider()
-	paymentService := payment.NewPaymentService(paymentRepo, simulatedProvider)
-
+// NewPaymentHandler creates a new payment handler that routes payments through the registry
+func NewPaymentHandler(store *repositories.Store, registry *providers.Registry) *PaymentHandler {
 	return &PaymentHandler{
-		paymentService: paymentService,
+		orders:         store.Orders,
+		payments:       store.Payments,
+		paymentService: payment.NewPaymentService(store, registry),
+	}
+}
+
+// writePaymentError answers 400 for requests a payment method cannot take (unknown or disabled
+// method, bad details, unsupported or oversized refund, invalid webhook) and logs anything else as
+// a server error
+func writePaymentError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, providers.ErrUnknownMethod), errors.Is(err, providers.ErrMethodDisabled),
+		errors.Is(err, providers.ErrInvalidPaymentDetails), errors.Is(err, providers.ErrRefundNotSupported),
+		errors.Is(err, providers.ErrInvalidWebhook), errors.Is(err, repositories.ErrNotRefundable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	log.Printf("❌ Failed to %s: %v", action, err)
+	http.Error(w, "Failed to "+action, http.StatusInternalServerError)
 }
 
 // CreatePaymentHandler creates a payment for an order
@@ -46,19 +64,13 @@ func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Get order to verify ownership and get amount
-	fsClient := firebase.FirestoreClient
-	orderDoc, err := fsClient.Collection("orders").Doc(req.OrderID).Get(ctx)
+	order, err := h.payableOrder(ctx, req.OrderID)
 	if err != nil {
 		log.Printf("❌ Order not found: %v", err)
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
-
-	var order models.Order
-	if err := orderDoc.DataTo(&order); err != nil {
-		http.Error(w, "Invalid order data", http.StatusInternalServerError)
-		return
-	}
+	req.OrderID = order.OrderID
 
 	// Verify order ownership
 	if order.BuyerID != buyerID {
@@ -69,16 +81,14 @@ func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Req
 	// Create payment
 	payment, err := h.paymentService.CreatePayment(ctx, req, order.TotalAmount)
 	if err != nil {
-		log.Printf("❌ Failed to create payment: %v", err)
-		http.Error(w, "Failed to create payment", http.StatusInternalServerError)
+		writePaymentError(w, "create payment", err)
 		return
 	}
 
 	// Set buyer ID
 	payment.BuyerID = buyerID
 	// Update payment with buyer ID
-	paymentRepo := repositories.NewPaymentRepository(firebase.FirestoreClient)
-	if err := paymentRepo.UpdatePayment(ctx, payment.ID, map[string]interface{}{
+	if err := h.payments.UpdatePayment(ctx, payment.ID, map[string]interface{}{
 		"buyerId":   buyerID,
 		"updatedAt": time.Now(),
 	}); err != nil {
@@ -86,25 +96,23 @@ func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Update order with payment ID
-	order.PaymentID = payment.ID
-	order.UpdatedAt = time.Now()
-	if _, err := fsClient.Collection("orders").Doc(req.OrderID).Set(ctx, order); err != nil {
+	if err := h.orders.UpdateOrder(ctx, req.OrderID, map[string]interface{}{
+		"paymentId": payment.ID,
+	}); err != nil {
 		log.Printf("⚠️ Failed to update order with payment ID: %v", err)
 	}
 
-	// For simulated provider, automatically verify after creation
-	if req.PaymentMethod == "simulated" {
+	// For simulated provider, automatically verify after creation. The request is over by then,
+	// so the check must not inherit its cancellation.
+	if payment.PaymentMethod == "simulated" {
+		ctx := context.WithoutCancel(ctx)
 		go func() {
 			time.Sleep(2 * time.Second) // Wait for simulated payment to complete
 			verifiedPayment, err := h.paymentService.VerifyPayment(ctx, payment.ID)
 			if err == nil && verifiedPayment.Status == models.PaymentStatusCompleted {
-				// Update order status
-				order.Status = "confirmed"
-				paymentStatus, _, _ := h.paymentService.CalculatePaymentStatus(ctx, req.OrderID, order.TotalAmount)
-				order.PaymentStatus = paymentStatus
-				order.UpdatedAt = time.Now()
-				fsClient.Collection("orders").Doc(req.OrderID).Set(ctx, order)
-				log.Printf("✅ Order %s confirmed after payment", req.OrderID)
+				if err := h.paymentService.SyncOrderPayment(ctx, req.OrderID); err != nil {
+					log.Printf("⚠️ Failed to update order %s after payment: %v", req.OrderID, err)
+				}
 			}
 		}()
 	}
@@ -115,6 +123,7 @@ func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Req
 		Amount:        payment.Amount,
 		Status:        payment.Status,
 		TransactionID: payment.TransactionID,
+		PaymentURL:    payment.PaymentURL,
 		ProviderData:  payment.ProviderData,
 	}
 
@@ -156,17 +165,8 @@ func (h *PaymentHandler) VerifyPaymentHandler(w http.ResponseWriter, r *http.Req
 
 	// Update order status if payment completed
 	if payment.Status == models.PaymentStatusCompleted {
-		fsClient := firebase.FirestoreClient
-		orderDoc, err := fsClient.Collection("orders").Doc(payment.OrderID).Get(ctx)
-		if err == nil {
-			var order models.Order
-			if err := orderDoc.DataTo(&order); err == nil {
-				order.Status = "confirmed"
-				paymentStatus, _, _ := h.paymentService.CalculatePaymentStatus(ctx, payment.OrderID, order.TotalAmount)
-				order.PaymentStatus = paymentStatus
-				order.UpdatedAt = time.Now()
-				fsClient.Collection("orders").Doc(payment.OrderID).Set(ctx, order)
-			}
+		if err := h.paymentService.SyncOrderPayment(ctx, payment.OrderID); err != nil {
+			log.Printf("⚠️ Failed to update order %s after payment: %v", payment.OrderID, err)
 		}
 	}
 
@@ -174,6 +174,19 @@ func (h *PaymentHandler) VerifyPaymentHandler(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(payment)
 }
 
+// payableOrder returns the order payments are taken against: the parent for a sub-order,
+// otherwise the order itself
+func (h *PaymentHandler) payableOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	order, err := h.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.IsSubOrder() {
+		return h.orders.GetOrderByID(ctx, order.ParentOrderID)
+	}
+	return order, nil
+}
+
 // GetPaymentsHandler retrieves payments for an order
 func (h *PaymentHandler) GetPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -191,18 +204,12 @@ func (h *PaymentHandler) GetPaymentsHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Verify order ownership
-	fsClient := firebase.FirestoreClient
-	orderDoc, err := fsClient.Collection("orders").Doc(orderID).Get(ctx)
+	order, err := h.payableOrder(ctx, orderID)
 	if err != nil {
 		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
-
-	var order models.Order
-	if err := orderDoc.DataTo(&order); err != nil {
-		http.Error(w, "Invalid order data", http.StatusInternalServerError)
-		return
-	}
+	orderID = order.OrderID
 
 	if order.BuyerID != buyerID {
 		http.Error(w, "Access denied", http.StatusForbidden)
@@ -221,48 +228,26 @@ func (h *PaymentHandler) GetPaymentsHandler(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(payments)
 }
 
-// PaymentWebhookHandler handles webhooks from payment providers
+// maxWebhookBytes caps the size of a payment webhook body
+const maxWebhookBytes = 1 << 20
+
+// PaymentWebhookHandler handles webhooks from payment providers at /payments/webhook/{method}
 func (h *PaymentHandler) PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	method := strings.Trim(strings.TrimPrefix(r.URL.Path, "/payments/webhook/"), "/")
 
-	var webhook models.PaymentWebhook
-	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
 		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
 		return
 	}
 
-	// Process webhook
-	if err := h.paymentService.ProcessPaymentWebhook(ctx, webhook); err != nil {
-		log.Printf("❌ Failed to process webhook: %v", err)
-		http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
+	// Process webhook; the service updates the payment and its order
+	if _, err := h.paymentService.ProcessPaymentWebhook(ctx, method, providers.WebhookRequest{Payload: payload, Header: r.Header, Query: r.URL.Query()}); err != nil {
+		writePaymentError(w, "process webhook", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
-
-// RefundPaymentHandler processes a refund (admin only)
-func (h *PaymentHandler) RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	var req struct {
-		PaymentID string  `json:"paymentId"`
-		Amount    float64 `json:"amount,omitempty"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Process refund
-	if err := h.paymentService.RefundPayment(ctx, req.PaymentID, req.Amount); err != nil {
-		log.Printf("❌ Failed to process refund: %v", err)
-		http.Error(w, "Failed to process refund", http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Refund processed successfully"})
-}
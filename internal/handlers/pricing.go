@@ -5,7 +5,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cecvl/art-print-backend/internal/services/catalog"
@@ -15,14 +14,16 @@ import (
 type PricingHandler struct {
 	catalog *catalog.CatalogService
 	pricing *pricing.PricingService
-	repo    *repositories.PrintShopRepository
+	quoter  *pricing.Quoter
+	repo    repositories.PrintShopRepository
 }
 
-func NewPricingHandler() *PricingHandler {
+func NewPricingHandler(store *repositories.Store) *PricingHandler {
 	return &PricingHandler{
 		catalog: catalog.NewCatalogService(),
 		pricing: pricing.NewPricingService(),
-		repo:    repositories.NewPrintShopRepository(firebase.FirestoreClient),
+		quoter:  pricing.NewQuoter(store.PrintShops, store.Frames),
+		repo:    store.PrintShops,
 	}
 }
 
@@ -62,12 +63,13 @@ func (h *PricingHandler) CalculatePrice(w http.ResponseWriter, r *http.Request)
 			options.Quantity = 1
 		}
 
-		totalPrice := h.pricing.CalculateShopPrice(service, options)
-		breakdown := h.pricing.CalculateShopPriceWithBreakdown(service, options)
+		// Zero breakdown when the service does not offer the size
+		breakdown, _ := h.quoter.QuoteService(ctx, service, options)
+		totalPrice := breakdown.Total
 
 		response := map[string]interface{}{
 			"serviceId":  req.ServiceID,
-			"total":      int(totalPrice),
+			"total":      totalPrice,
 			"totalPrice": totalPrice,
 			"breakdown":  breakdown,
 		}
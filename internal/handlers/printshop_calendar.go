@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// shopCalendarResp is the shop's production calendar and backlog limit
+type shopCalendarResp struct {
+	Calendar       *models.BusinessCalendar `json:"calendar"`
+	MaxBacklogDays int                      `json:"maxBacklogDays,omitempty"`
+	IsDefault      bool                     `json:"isDefault"` // the shop has not declared a calendar yet
+}
+
+type updateCalendarReq struct {
+	Calendar       *models.BusinessCalendar `json:"calendar"`
+	MaxBacklogDays *int                     `json:"maxBacklogDays,omitempty"`
+}
+
+// GetCalendar returns the business hours, closures and backlog limit matching uses for the shop
+func (h *PrintShopConsoleHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shop, err := h.repo.GetShopByOwnerID(ctx, ctx.Value("shopOwnerId").(string))
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	resp := shopCalendarResp{Calendar: shop.Calendar, MaxBacklogDays: shop.MaxBacklogDays}
+	if resp.Calendar == nil {
+		resp.Calendar, resp.IsDefault = models.DefaultBusinessCalendar(), true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateCalendar validates and replaces the shop's calendar and, when given, its backlog limit
+func (h *PrintShopConsoleHandler) UpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shop, err := h.repo.GetShopByOwnerID(ctx, ctx.Value("shopOwnerId").(string))
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	var body updateCalendarReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Calendar == nil {
+		http.Error(w, "calendar required", http.StatusBadRequest)
+		return
+	}
+	if err := body.Calendar.Validate(); err != nil {
+		http.Error(w, "invalid calendar: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updates := map[string]interface{}{"calendar": body.Calendar}
+	if body.MaxBacklogDays != nil {
+		if *body.MaxBacklogDays < 0 {
+			http.Error(w, "maxBacklogDays must not be negative", http.StatusBadRequest)
+			return
+		}
+		updates["maxBacklogDays"] = *body.MaxBacklogDays
+	}
+	if err := h.repo.UpdateShop(ctx, shop.ID, updates); err != nil {
+		log.Printf("❌ Failed to update calendar of shop %s: %v", shop.ID, err)
+		http.Error(w, "Failed to update calendar", http.StatusInternalServerError)
+		return
+	}
+
+	updatedShop, _ := h.repo.GetShopByID(ctx, shop.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedShop)
+}
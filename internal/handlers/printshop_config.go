@@ -6,20 +6,21 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
 // PrintShopConfigHandler handles configuration management (frames, sizes, materials)
 type PrintShopConfigHandler struct {
-	repo *repositories.PrintShopRepository
+	repo   repositories.PrintShopRepository
+	frames repositories.FrameRepository
 }
 
 // NewPrintShopConfigHandler creates a new config handler
-func NewPrintShopConfigHandler() *PrintShopConfigHandler {
+func NewPrintShopConfigHandler(store *repositories.Store) *PrintShopConfigHandler {
 	return &PrintShopConfigHandler{
-		repo: repositories.NewPrintShopRepository(firebase.FirestoreClient),
+		repo:   store.PrintShops,
+		frames: store.Frames,
 	}
 }
 
@@ -36,7 +37,7 @@ func (h *PrintShopConfigHandler) GetFrames(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	frames, err := h.repo.GetFramesByShopID(ctx, shop.ID)
+	frames, err := h.frames.ListFrames(ctx, repositories.FrameFilter{ShopID: shop.ID})
 	if err != nil {
 		log.Printf("❌ Failed to get frames: %v", err)
 		http.Error(w, "Failed to get frames", http.StatusInternalServerError)
@@ -67,7 +68,7 @@ func (h *PrintShopConfigHandler) CreateFrame(w http.ResponseWriter, r *http.Requ
 	frame.ShopID = shop.ID
 	frame.IsActive = true
 
-	if err := h.repo.CreateFrame(ctx, &frame); err != nil {
+	if err := h.frames.CreateFrame(ctx, &frame); err != nil {
 		log.Printf("❌ Failed to create frame: %v", err)
 		http.Error(w, "Failed to create frame", http.StatusInternalServerError)
 		return
@@ -86,39 +87,40 @@ func (h *PrintShopConfigHandler) UpdateFrame(w http.ResponseWriter, r *http.Requ
 	pathParts := strings.Split(r.URL.Path, "/")
 	frameID := pathParts[len(pathParts)-1]
 
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
 	// Get frame to verify ownership
-	frames, err := h.repo.GetFramesByShopID(ctx, "")
-	if err == nil {
-		// Find frame and verify shop ownership
-		shop, _ := h.repo.GetShopByOwnerID(ctx, ownerID)
-		for _, f := range frames {
-			if f.ID == frameID && f.ShopID == shop.ID {
-				var updates map[string]interface{}
-				if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-					http.Error(w, "Invalid request body", http.StatusBadRequest)
-					return
-				}
-				delete(updates, "shopId")
-				delete(updates, "id")
+	frame, err := h.frames.GetFrameByID(ctx, frameID)
+	if err != nil || frame.ShopID != shop.ID {
+		http.Error(w, "Frame not found or access denied", http.StatusNotFound)
+		return
+	}
 
-				if err := h.repo.UpdateFrame(ctx, frameID, updates); err != nil {
-					http.Error(w, "Failed to update frame", http.StatusInternalServerError)
-					return
-				}
+	var updates map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	delete(updates, "shopId")
+	delete(updates, "id")
 
-				updatedFrame, _ := h.repo.GetFramesByShopID(ctx, shop.ID)
-				for _, f := range updatedFrame {
-					if f.ID == frameID {
-						w.Header().Set("Content-Type", "application/json")
-						json.NewEncoder(w).Encode(f)
-						return
-					}
-				}
-			}
-		}
+	if err := h.frames.UpdateFrame(ctx, frameID, updates); err != nil {
+		http.Error(w, "Failed to update frame", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := h.frames.GetFrameByID(ctx, frameID)
+	if err != nil {
+		http.Error(w, "Failed to reload frame", http.StatusInternalServerError)
+		return
 	}
 
-	http.Error(w, "Frame not found or access denied", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
 }
 
 // DeleteFrame deletes a frame configuration
@@ -136,19 +138,17 @@ func (h *PrintShopConfigHandler) DeleteFrame(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Verify ownership
-	frames, _ := h.repo.GetFramesByShopID(ctx, shop.ID)
-	for _, f := range frames {
-		if f.ID == frameID {
-			if err := h.repo.DeleteFrame(ctx, frameID); err != nil {
-				http.Error(w, "Failed to delete frame", http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+	frame, err := h.frames.GetFrameByID(ctx, frameID)
+	if err != nil || frame.ShopID != shop.ID {
+		http.Error(w, "Frame not found", http.StatusNotFound)
+		return
 	}
 
-	http.Error(w, "Frame not found", http.StatusNotFound)
+	if err := h.frames.DeleteFrame(ctx, frameID); err != nil {
+		http.Error(w, "Failed to delete frame", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // ==================== Size Management ====================
@@ -410,3 +410,133 @@ func (h *PrintShopConfigHandler) DeleteMaterial(w http.ResponseWriter, r *http.R
 
 	http.Error(w, "Material not found", http.StatusNotFound)
 }
+
+// ==================== Medium Management ====================
+
+// GetMediums retrieves all mediums for the authenticated shop
+func (h *PrintShopConfigHandler) GetMediums(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID := ctx.Value("shopOwnerId").(string)
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	mediums, err := h.repo.GetMediumsByShopID(ctx, shop.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get mediums: %v", err)
+		http.Error(w, "Failed to get mediums", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mediums)
+}
+
+// CreateMedium creates a new medium configuration
+func (h *PrintShopConfigHandler) CreateMedium(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID := ctx.Value("shopOwnerId").(string)
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	var medium models.Medium
+	if err := json.NewDecoder(r.Body).Decode(&medium); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	medium.ShopID = shop.ID
+	medium.IsActive = true
+
+	if err := h.repo.CreateMedium(ctx, &medium); err != nil {
+		log.Printf("❌ Failed to create medium: %v", err)
+		http.Error(w, "Failed to create medium", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(medium)
+}
+
+// UpdateMedium updates a medium configuration
+func (h *PrintShopConfigHandler) UpdateMedium(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID := ctx.Value("shopOwnerId").(string)
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	mediumID := pathParts[len(pathParts)-1]
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	// Verify ownership
+	mediums, _ := h.repo.GetMediumsByShopID(ctx, shop.ID)
+	for _, m := range mediums {
+		if m.ID == mediumID {
+			var updates map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			delete(updates, "shopId")
+			delete(updates, "id")
+
+			if err := h.repo.UpdateMedium(ctx, mediumID, updates); err != nil {
+				http.Error(w, "Failed to update medium", http.StatusInternalServerError)
+				return
+			}
+
+			updatedMediums, _ := h.repo.GetMediumsByShopID(ctx, shop.ID)
+			for _, m := range updatedMediums {
+				if m.ID == mediumID {
+					w.Header().Set("Content-Type", "application/json")
+					json.NewEncoder(w).Encode(m)
+					return
+				}
+			}
+		}
+	}
+
+	http.Error(w, "Medium not found", http.StatusNotFound)
+}
+
+// DeleteMedium deletes a medium configuration
+func (h *PrintShopConfigHandler) DeleteMedium(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID := ctx.Value("shopOwnerId").(string)
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	mediumID := pathParts[len(pathParts)-1]
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	// Verify ownership
+	mediums, _ := h.repo.GetMediumsByShopID(ctx, shop.ID)
+	for _, m := range mediums {
+		if m.ID == mediumID {
+			if err := h.repo.DeleteMedium(ctx, mediumID); err != nil {
+				http.Error(w, "Failed to delete medium", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	http.Error(w, "Medium not found", http.StatusNotFound)
+}
@@ -6,20 +6,34 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/delivery"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
 )
 
 // PrintShopConsoleHandler handles print shop console operations
 type PrintShopConsoleHandler struct {
-	repo *repositories.PrintShopRepository
+	repo        repositories.PrintShopRepository
+	orders      repositories.OrderRepository
+	assignments *orders.Assignments
+	lifecycle   *orders.Lifecycle
+	delivery    *delivery.DeliveryService
+	geocoder    geo.Geocoder
 }
 
 // NewPrintShopConsoleHandler creates a new print shop console handler
-func NewPrintShopConsoleHandler() *PrintShopConsoleHandler {
+func NewPrintShopConsoleHandler(store *repositories.Store, cfg config.ConfigService, geocoder geo.Geocoder, rates shipping.RateProvider) *PrintShopConsoleHandler {
 	return &PrintShopConsoleHandler{
-		repo: repositories.NewPrintShopRepository(firebase.FirestoreClient),
+		repo:        store.PrintShops,
+		orders:      store.Orders,
+		assignments: orders.NewAssignments(cfg, store, geocoder, rates),
+		lifecycle:   orders.NewLifecycle(store),
+		delivery:    delivery.NewDeliveryService(store, nil), // only tracks status; packing books couriers
+		geocoder:    geocoder,
 	}
 }
 
@@ -60,9 +74,22 @@ func (h *PrintShopConsoleHandler) UpdateShopProfile(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Don't allow updating ownerId or ID
+	// Don't allow updating ownerId or ID; the calendar is validated through UpdateCalendar
 	delete(updates, "ownerId")
 	delete(updates, "id")
+	delete(updates, "calendar")
+
+	// Place a changed location on the map unless the shop sent its own coordinates
+	if raw, ok := updates["location"]; ok {
+		var location models.Location
+		encoded, _ := json.Marshal(raw)
+		if err := json.Unmarshal(encoded, &location); err != nil {
+			http.Error(w, "Invalid location", http.StatusBadRequest)
+			return
+		}
+		geo.Resolve(ctx, h.geocoder, &location)
+		updates["location"] = location
+	}
 
 	// Update shop
 	if err := h.repo.UpdateShop(ctx, shop.ID, updates); err != nil {
@@ -100,6 +127,7 @@ func (h *PrintShopConsoleHandler) CreateShopProfile(w http.ResponseWriter, r *ht
 	shop.OwnerID = ownerID
 	shop.IsActive = true
 	shop.Services = []string{} // Initialize empty services list
+	geo.Resolve(ctx, h.geocoder, &shop.Location)
 
 	// Create shop
 	if err := h.repo.CreateShop(ctx, &shop); err != nil {
@@ -160,6 +188,11 @@ func (h *PrintShopConsoleHandler) CreateService(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if service.DailyCapacity < 0 || service.LeadTimeDays < 0 {
+		http.Error(w, "dailyCapacity and leadTimeDays must not be negative", http.StatusBadRequest)
+		return
+	}
+
 	// Set shop ID and ensure it matches authenticated shop
 	service.ShopID = shop.ID
 	service.IsActive = true
@@ -258,3 +291,31 @@ func (h *PrintShopConsoleHandler) DeleteService(w http.ResponseWriter, r *http.R
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetShopOrders lists the orders (fulfillment sub-orders and legacy single-shop orders)
+// assigned to the authenticated shop. Optional query params: status, assignment
+func (h *PrintShopConsoleHandler) GetShopOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ownerID := ctx.Value("shopOwnerId").(string)
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ownerID)
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+
+	orders, err := h.orders.ListOrders(ctx, repositories.OrderFilter{
+		PrintShopID: shop.ID,
+		Status:      models.OrderStatus(r.URL.Query().Get("status")),
+		Assignment:  models.AssignmentStatus(r.URL.Query().Get("assignment")),
+		Sort:        repositories.NewestFirst,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to get shop orders: %v", err)
+		http.Error(w, "Failed to get orders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
@@ -7,14 +7,25 @@ import (
 	"os"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
+// PrintShopFrameHandler serves frame image uploads and frame edits for print shops
+type PrintShopFrameHandler struct {
+	frames repositories.FrameRepository
+	queue  repositories.ProcessingQueueRepository
+}
+
+// NewPrintShopFrameHandler creates a new print shop frame handler
+func NewPrintShopFrameHandler(store *repositories.Store) *PrintShopFrameHandler {
+	return &PrintShopFrameHandler{frames: store.Frames, queue: store.Queue}
+}
+
 // UploadFrameHandler allows authenticated print shop owners to upload frame images
-func UploadFrameHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopFrameHandler) UploadFrameHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ctx.Value("userId")
 	if uid == nil {
@@ -61,46 +72,43 @@ func UploadFrameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// persist frame doc
-	frameData := map[string]interface{}{
-		"shopId":             shopID,
-		"name":               name,
-		"description":        description,
-		"imageUrl":           uploadRes.SecureURL,
-		"cloudinaryPublicId": uploadRes.PublicID,
-		"cloudinaryFolder":   folder,
-		"processingStatus":   "pending",
-		"processingErrors":   []string{},
-		"createdAt":          time.Now(),
-	}
-	docRef, _, err := firebase.FirestoreClient.Collection("frames").Add(ctx, frameData)
-	if err != nil {
+	frame := &models.Frame{
+		ShopID:             shopID,
+		Name:               name,
+		Description:        description,
+		ImageURL:           uploadRes.SecureURL,
+		CloudinaryPublicID: uploadRes.PublicID,
+		CloudinaryFolder:   folder,
+		ProcessingStatus:   "pending",
+		ProcessingErrors:   []string{},
+		CreatedAt:          time.Now(),
+	}
+	if err := h.frames.CreateFrame(ctx, frame); err != nil {
 		log.Printf("❌ failed to save frame doc: %v", err)
 		http.Error(w, "save failed", http.StatusInternalServerError)
 		return
 	}
 
 	// enqueue processing job
-	queueDoc := map[string]interface{}{
-		"frameId":   docRef.ID,
-		"status":    "pending",
-		"createdAt": time.Now(),
-		"cloudinary": map[string]interface{}{
-			"secureUrl": uploadRes.SecureURL,
-			"publicId":  uploadRes.PublicID,
-			"folder":    folder,
+	job := &models.ProcessingJob{
+		FrameID: frame.ID,
+		Cloudinary: models.CloudinaryAsset{
+			SecureURL: uploadRes.SecureURL,
+			PublicID:  uploadRes.PublicID,
+			Folder:    folder,
 		},
 	}
-	if _, _, err := firebase.FirestoreClient.Collection("processing_queue").Add(ctx, queueDoc); err != nil {
+	if err := h.queue.Enqueue(ctx, job); err != nil {
 		log.Printf("⚠️ failed to enqueue frame processing job: %v", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"frameId": docRef.ID, "url": uploadRes.SecureURL})
+	json.NewEncoder(w).Encode(map[string]string{"frameId": frame.ID, "url": uploadRes.SecureURL})
 }
 
 // GetFramesHandler lists frames for the authenticated print shop
-func GetFramesHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopFrameHandler) GetFramesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ctx.Value("userId")
 	if uid == nil {
@@ -109,24 +117,18 @@ func GetFramesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	shopID := uid.(string)
 
-	snaps, err := firebase.FirestoreClient.Collection("frames").Where("shopId", "==", shopID).OrderBy("createdAt", firestore.Desc).Documents(ctx).GetAll()
+	results, err := h.frames.ListFrames(ctx, repositories.FrameFilter{ShopID: shopID, Sort: repositories.NewestFirst})
 	if err != nil {
 		log.Printf("❌ failed to fetch frames: %v", err)
 		http.Error(w, "failed", http.StatusInternalServerError)
 		return
 	}
-	var results []map[string]interface{}
-	for _, s := range snaps {
-		d := s.Data()
-		d["id"] = s.Ref.ID
-		results = append(results, d)
-	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
 
 // RemoveFrameHandler removes a frame (soft-delete could be added instead)
-func RemoveFrameHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopFrameHandler) RemoveFrameHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ctx.Value("userId")
 	if uid == nil {
@@ -148,18 +150,17 @@ func RemoveFrameHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// verify ownership
-	docRef := firebase.FirestoreClient.Collection("frames").Doc(payload.FrameID)
-	doc, err := docRef.Get(ctx)
-	if err != nil || !doc.Exists() {
+	frame, err := h.frames.GetFrameByID(ctx, payload.FrameID)
+	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	if doc.Data()["shopId"] != shopID {
+	if frame.ShopID != shopID {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if _, err := docRef.Delete(ctx); err != nil {
+	if err := h.frames.DeleteFrame(ctx, payload.FrameID); err != nil {
 		log.Printf("❌ failed to delete frame %s: %v", payload.FrameID, err)
 		http.Error(w, "delete failed", http.StatusInternalServerError)
 		return
@@ -168,7 +169,7 @@ func RemoveFrameHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateFramePricingHandler allows print shop to update frame pricing
-func UpdateFramePricingHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopFrameHandler) UpdateFramePricingHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ctx.Value("userId")
 	if uid == nil {
@@ -192,43 +193,43 @@ func UpdateFramePricingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify ownership
-	docRef := firebase.FirestoreClient.Collection("frames").Doc(payload.FrameID)
-	doc, err := docRef.Get(ctx)
-	if err != nil || !doc.Exists() {
+	frame, err := h.frames.GetFrameByID(ctx, payload.FrameID)
+	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	if doc.Data()["shopId"] != shopID {
+	if frame.ShopID != shopID {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
 	// Update pricing
-	updates := []firestore.Update{
-		{Path: "basePrice", Value: payload.BasePrice},
-		{Path: "updatedAt", Value: time.Now()},
+	updates := map[string]interface{}{
+		"basePrice": payload.BasePrice,
 	}
 	if payload.SizePricing != nil {
-		updates = append(updates, firestore.Update{Path: "sizePricing", Value: payload.SizePricing})
+		updates["sizePricing"] = payload.SizePricing
 	}
 
-	if _, err := docRef.Update(ctx, updates); err != nil {
+	if err := h.frames.UpdateFrame(ctx, payload.FrameID, updates); err != nil {
 		log.Printf("❌ failed to update frame pricing %s: %v", payload.FrameID, err)
 		http.Error(w, "update failed", http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated frame
-	updatedDoc, _ := docRef.Get(ctx)
-	result := updatedDoc.Data()
-	result["id"] = updatedDoc.Ref.ID
+	result, err := h.frames.GetFrameByID(ctx, payload.FrameID)
+	if err != nil {
+		log.Printf("⚠️ failed to reload frame %s: %v", payload.FrameID, err)
+		result = frame
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 // UpdateFrameDetailsHandler allows print shop to update frame details (type, material, name, description)
-func UpdateFrameDetailsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopFrameHandler) UpdateFrameDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := ctx.Value("userId")
 	if uid == nil {
@@ -255,52 +256,51 @@ func UpdateFrameDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify ownership
-	docRef := firebase.FirestoreClient.Collection("frames").Doc(payload.FrameID)
-	doc, err := docRef.Get(ctx)
-	if err != nil || !doc.Exists() {
+	frame, err := h.frames.GetFrameByID(ctx, payload.FrameID)
+	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	if doc.Data()["shopId"] != shopID {
+	if frame.ShopID != shopID {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
 	// Build updates
-	updates := []firestore.Update{
-		{Path: "updatedAt", Value: time.Now()},
-	}
+	updates := map[string]interface{}{}
 	if payload.Type != "" {
-		updates = append(updates, firestore.Update{Path: "type", Value: payload.Type})
+		updates["type"] = payload.Type
 	}
 	if payload.Material != "" {
-		updates = append(updates, firestore.Update{Path: "material", Value: payload.Material})
+		updates["material"] = payload.Material
 	}
 	if payload.Name != "" {
-		updates = append(updates, firestore.Update{Path: "name", Value: payload.Name})
+		updates["name"] = payload.Name
 	}
 	if payload.Description != "" {
-		updates = append(updates, firestore.Update{Path: "description", Value: payload.Description})
+		updates["description"] = payload.Description
 	}
 	if payload.IsActive != nil {
-		updates = append(updates, firestore.Update{Path: "isActive", Value: *payload.IsActive})
+		updates["isActive"] = *payload.IsActive
 	}
 
-	if len(updates) == 1 {
+	if len(updates) == 0 {
 		http.Error(w, "no fields to update", http.StatusBadRequest)
 		return
 	}
 
-	if _, err := docRef.Update(ctx, updates); err != nil {
+	if err := h.frames.UpdateFrame(ctx, payload.FrameID, updates); err != nil {
 		log.Printf("❌ failed to update frame %s: %v", payload.FrameID, err)
 		http.Error(w, "update failed", http.StatusInternalServerError)
 		return
 	}
 
 	// Return updated frame
-	updatedDoc, _ := docRef.Get(ctx)
-	result := updatedDoc.Data()
-	result["id"] = updatedDoc.Ref.ID
+	result, err := h.frames.GetFrameByID(ctx, payload.FrameID)
+	if err != nil {
+		log.Printf("⚠️ failed to reload frame %s: %v", payload.FrameID, err)
+		result = frame
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
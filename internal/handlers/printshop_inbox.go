@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
+)
+
+type inboxOrderReq struct {
+	OrderID string `json:"orderId"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type orderStageReq struct {
+	OrderID string             `json:"orderId"`
+	Status  models.OrderStatus `json:"status"`
+	Note    string             `json:"note,omitempty"`
+}
+
+// shopStages are the production stages a shop may move its own orders through
+var shopStages = map[models.OrderStatus]bool{
+	models.OrderStatusProcessing: true,
+	models.OrderStatusReady:      true,
+	models.OrderStatusCompleted:  true,
+}
+
+// AcceptOrder commits the authenticated shop to an order offered to it
+func (h *PrintShopConsoleHandler) AcceptOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shop, body, ok := h.decodeInboxRequest(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.assignments.Accept(ctx, body.OrderID, shop.ID, userIDFrom(ctx))
+	if err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// DeclineOrder turns down an order offered to the authenticated shop; it is re-matched to another shop
+func (h *PrintShopConsoleHandler) DeclineOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shop, body, ok := h.decodeInboxRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.assignments.Decline(ctx, body.OrderID, shop.ID, userIDFrom(ctx), body.Reason); err != nil {
+		writeAssignmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateOrderStage moves an accepted order through processing, ready and completed
+func (h *PrintShopConsoleHandler) UpdateOrderStage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body orderStageReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if body.OrderID == "" || !shopStages[body.Status] {
+		http.Error(w, "orderId and a status of processing, ready or completed required", http.StatusBadRequest)
+		return
+	}
+
+	shop, err := h.repo.GetShopByOwnerID(ctx, ctx.Value("shopOwnerId").(string))
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return
+	}
+	order, err := h.orders.GetOrderByID(ctx, body.OrderID)
+	if err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if order.PrintShopID != shop.ID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	// Orders assigned before the inbox existed carry no assignment status and need no acceptance
+	if order.AssignmentStatus != "" && order.AssignmentStatus != models.AssignmentAccepted {
+		http.Error(w, "accept the order before starting production", http.StatusConflict)
+		return
+	}
+
+	event, err := h.lifecycle.Transition(ctx, body.OrderID, body.Status, userIDFrom(ctx), body.Note)
+	if err != nil {
+		writeTransitionError(w, body.OrderID, err)
+		return
+	}
+
+	// Keep the delivery status in step: production starts it, and a pickup order is
+	// delivered once the shop completes it at collection
+	deliveryStatus := ""
+	switch {
+	case body.Status == models.OrderStatusProcessing:
+		deliveryStatus = models.DeliveryProcessing
+	case body.Status == models.OrderStatusCompleted && order.DeliveryMethod == models.DeliveryMethodPickup:
+		deliveryStatus = models.DeliveryDelivered
+	}
+	if deliveryStatus != "" {
+		if err := h.delivery.SetDeliveryStatus(ctx, body.OrderID, deliveryStatus); err != nil {
+			log.Printf("⚠️ Failed to update delivery status of order %s: %v", body.OrderID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// decodeInboxRequest resolves the authenticated shop and reads the order it is responding to
+func (h *PrintShopConsoleHandler) decodeInboxRequest(w http.ResponseWriter, r *http.Request) (*models.PrintShopProfile, inboxOrderReq, bool) {
+	var body inboxOrderReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.OrderID == "" {
+		http.Error(w, "orderId required", http.StatusBadRequest)
+		return nil, body, false
+	}
+
+	ctx := r.Context()
+	shop, err := h.repo.GetShopByOwnerID(ctx, ctx.Value("shopOwnerId").(string))
+	if err != nil {
+		http.Error(w, "Shop not found", http.StatusNotFound)
+		return nil, body, false
+	}
+	return shop, body, true
+}
+
+// writeAssignmentError maps assignment errors to HTTP responses
+func writeAssignmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		http.Error(w, "order not found", http.StatusNotFound)
+	case errors.Is(err, orders.ErrNotAssigned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, orders.ErrNotOffered), errors.Is(err, orders.ErrOfferExpired):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("❌ Failed to respond to order assignment: %v", err)
+		http.Error(w, "failed to update assignment", http.StatusInternalServerError)
+	}
+}
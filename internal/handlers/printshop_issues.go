@@ -6,8 +6,9 @@ import (
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
 )
 
 type reportIssueReq struct {
@@ -16,8 +17,26 @@ type reportIssueReq struct {
 	Level   string `json:"level"` // e.g., "warning", "critical"
 }
 
+// PrintShopIssueHandler lets print shops flag problems with orders they are fulfilling
+type PrintShopIssueHandler struct {
+	orders    repositories.OrderRepository
+	shops     repositories.PrintShopRepository
+	logs      repositories.ActivityLogRepository
+	lifecycle *orders.Lifecycle
+}
+
+// NewPrintShopIssueHandler creates a new print shop issue handler
+func NewPrintShopIssueHandler(store *repositories.Store) *PrintShopIssueHandler {
+	return &PrintShopIssueHandler{
+		orders:    store.Orders,
+		shops:     store.PrintShops,
+		logs:      store.ActivityLog,
+		lifecycle: orders.NewLifecycle(store),
+	}
+}
+
 // PrintShopReportIssueHandler allows a print shop (authenticated) to report fulfillment issues
-func PrintShopReportIssueHandler(w http.ResponseWriter, r *http.Request) {
+func (h *PrintShopIssueHandler) PrintShopReportIssueHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var body reportIssueReq
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -29,19 +48,38 @@ func PrintShopReportIssueHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// shops fulfil sub-orders, so issues are reported against the sub-order assigned to them
+	order, err := h.orders.GetOrderByID(ctx, body.OrderID)
+	if err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	if order.IsParent() {
+		http.Error(w, "report issues against the sub-order assigned to your shop", http.StatusBadRequest)
+		return
+	}
+	if ownerID, ok := ctx.Value("shopOwnerId").(string); ok {
+		shop, err := h.shops.GetShopByOwnerID(ctx, ownerID)
+		if err != nil || shop.ID != order.PrintShopID {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
 	// record in printshop_issues collection
-	payload := map[string]interface{}{"orderId": body.OrderID, "issue": body.Issue, "level": body.Level, "createdAt": time.Now(), "reportedBy": r.Context().Value("userId")}
-	if _, _, err := firebase.FirestoreClient.Collection("printshop_issues").Add(ctx, payload); err != nil {
+	payload := map[string]interface{}{"orderId": body.OrderID, "shopId": order.PrintShopID, "issue": body.Issue, "level": body.Level, "createdAt": time.Now(), "reportedBy": r.Context().Value("userId")}
+	if err := h.logs.Record(ctx, repositories.LogPrintShopIssues, payload); err != nil {
 		log.Printf("❌ failed to record printshop issue: %v", err)
 		http.Error(w, "failed to record issue", http.StatusInternalServerError)
 		return
 	}
 
 	// append admin note to order (best-effort)
-	note := map[string]interface{}{"note": "printshop_issue: " + body.Issue, "createdAt": time.Now(), "createdBy": r.Context().Value("userId"), "level": body.Level}
-	_, err := firebase.FirestoreClient.Collection("orders").Doc(body.OrderID).Update(ctx, []firestore.Update{{Path: "adminNotes", Value: firestore.ArrayUnion(note)}, {Path: "status", Value: "error"}})
-	if err != nil {
+	note := models.AdminNote{Note: "printshop_issue: " + body.Issue, CreatedAt: time.Now(), CreatedBy: userIDFrom(ctx), Level: body.Level}
+	if err := h.orders.AppendAdminNote(ctx, body.OrderID, note); err != nil {
 		log.Printf("⚠️ failed to append issue note to order: %v", err)
+	} else if _, err := h.lifecycle.Transition(ctx, body.OrderID, models.OrderStatusError, userIDFrom(ctx), "printshop_issue: "+body.Issue); err != nil {
+		log.Printf("⚠️ failed to flag order %s as error: %v", body.OrderID, err)
 	}
 
 	w.WriteHeader(http.StatusNoContent)
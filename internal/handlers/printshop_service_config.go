@@ -6,20 +6,22 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/pricing"
 )
 
 // PrintShopServiceConfigHandler handles service pricing configuration
 type PrintShopServiceConfigHandler struct {
-	repo *repositories.PrintShopRepository
+	repo   repositories.PrintShopRepository
+	quoter *pricing.Quoter
 }
 
 // NewPrintShopServiceConfigHandler creates a new service config handler
-func NewPrintShopServiceConfigHandler() *PrintShopServiceConfigHandler {
+func NewPrintShopServiceConfigHandler(store *repositories.Store) *PrintShopServiceConfigHandler {
 	return &PrintShopServiceConfigHandler{
-		repo: repositories.NewPrintShopRepository(firebase.FirestoreClient),
+		repo:   store.PrintShops,
+		quoter: pricing.NewQuoter(store.PrintShops, store.Frames),
 	}
 }
 
@@ -146,45 +148,16 @@ func (h *PrintShopServiceConfigHandler) CalculateServicePrice(w http.ResponseWri
 		return
 	}
 
-	// Calculate price using service's price matrix
-	price := calculatePrice(service, options)
+	// Calculate price with the shared shop pricing engine (zero when the size is not offered)
+	breakdown, _ := h.quoter.QuoteService(ctx, service, options)
 
 	response := map[string]interface{}{
 		"serviceId":  serviceID,
 		"options":    options,
-		"totalPrice": price,
+		"totalPrice": breakdown.Total,
+		"breakdown":  breakdown,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
-
-// calculatePrice calculates the total price based on service and options
-func calculatePrice(service *models.PrintService, options models.PrintOrderOptions) float64 {
-	// Get base price for the size from the service's size pricing
-	price, ok := service.SizePricing[options.Size]
-	if !ok {
-		return 0 // Size not supported by this service
-	}
-
-	// Note: Frame pricing is handled separately and added by the caller if needed
-	// This allows frames to have their own size-specific pricing
-
-	// Apply quantity discount if configured
-	for _, tier := range service.QuantityTiers {
-		if options.Quantity >= tier.MinQuantity && (tier.MaxQuantity == 0 || options.Quantity <= tier.MaxQuantity) {
-			price *= (1.0 - tier.Discount) // Apply discount
-			break
-		}
-	}
-
-	// Add rush order fee if applicable
-	if options.RushOrder {
-		price += service.RushOrderFee
-	}
-
-	// Multiply by quantity
-	price *= float64(options.Quantity)
-
-	return price
-}
@@ -2,33 +2,50 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/matching"
 	"github.com/cecvl/art-print-backend/internal/services/pricing"
 )
 
 // PublicPrintShopHandler handles public endpoints for print shop discovery
 type PublicPrintShopHandler struct {
-	repo    *repositories.PrintShopRepository
-	pricing *pricing.PricingService
+	repo     repositories.PrintShopRepository
+	quoter   *pricing.Quoter
+	pipeline *matching.Pipeline
+	geocoder geo.Geocoder
 }
 
 // NewPublicPrintShopHandler creates a new public print shop handler
-func NewPublicPrintShopHandler() *PublicPrintShopHandler {
+func NewPublicPrintShopHandler(store *repositories.Store, cfg config.ConfigService, geocoder geo.Geocoder) *PublicPrintShopHandler {
 	return &PublicPrintShopHandler{
-		repo:    repositories.NewPrintShopRepository(firebase.FirestoreClient),
-		pricing: pricing.NewPricingService(),
+		repo:     store.PrintShops,
+		quoter:   pricing.NewQuoter(store.PrintShops, store.Frames),
+		pipeline: matching.NewPipeline(store, cfg, geocoder),
+		geocoder: geocoder,
 	}
 }
 
-// GetActiveShops returns all active print shops (public endpoint)
+// GetActiveShops returns all active print shops (public endpoint).
+// Optional radius search: radiusKm with either lat and lng, or near (a city name);
+// matching shops are returned nearest first with their distance.
 func (h *PublicPrintShopHandler) GetActiveShops(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	origin, radiusKm, err := h.parseRadiusSearch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	shops, err := h.repo.GetActiveShops(ctx)
 	if err != nil {
 		log.Printf("❌ Failed to get active shops: %v", err)
@@ -44,17 +61,34 @@ func (h *PublicPrintShopHandler) GetActiveShops(w http.ResponseWriter, r *http.R
 		Location     models.Location `json:"location"`
 		Rating       float64         `json:"rating"`
 		ServiceCount int             `json:"serviceCount"`
+		DistanceKm   *float64        `json:"distanceKm,omitempty"`
 	}
 
 	summaries := make([]ShopSummary, 0, len(shops))
 	for _, shop := range shops {
-		summaries = append(summaries, ShopSummary{
+		summary := ShopSummary{
 			ID:           shop.ID,
 			Name:         shop.Name,
 			Description:  shop.Description,
 			Location:     shop.Location,
 			Rating:       shop.Rating,
 			ServiceCount: len(shop.Services),
+		}
+		if origin != nil {
+			if !geo.Resolve(ctx, h.geocoder, &summary.Location) {
+				continue
+			}
+			km := geo.DistanceKm(summary.Location, *origin)
+			if km > radiusKm {
+				continue
+			}
+			summary.DistanceKm = &km
+		}
+		summaries = append(summaries, summary)
+	}
+	if origin != nil {
+		sort.SliceStable(summaries, func(i, j int) bool {
+			return *summaries[i].DistanceKm < *summaries[j].DistanceKm
 		})
 	}
 
@@ -62,6 +96,34 @@ func (h *PublicPrintShopHandler) GetActiveShops(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(summaries)
 }
 
+// parseRadiusSearch reads the radius search query parameters. It returns a nil origin
+// when no search was requested.
+func (h *PublicPrintShopHandler) parseRadiusSearch(r *http.Request) (*models.Location, float64, error) {
+	q := r.URL.Query()
+	if q.Get("radiusKm") == "" {
+		return nil, 0, nil
+	}
+	radiusKm, err := strconv.ParseFloat(q.Get("radiusKm"), 64)
+	if err != nil || radiusKm <= 0 {
+		return nil, 0, errors.New("radiusKm must be a positive number")
+	}
+
+	if near := q.Get("near"); near != "" {
+		origin := &models.Location{City: near}
+		if !geo.Resolve(r.Context(), h.geocoder, origin) {
+			return nil, 0, errors.New("could not locate " + strconv.Quote(near))
+		}
+		return origin, radiusKm, nil
+	}
+
+	lat, errLat := strconv.ParseFloat(q.Get("lat"), 64)
+	lng, errLng := strconv.ParseFloat(q.Get("lng"), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, 0, errors.New("radius search needs near, or valid lat and lng")
+	}
+	return &models.Location{Latitude: lat, Longitude: lng}, radiusKm, nil
+}
+
 // GetShopDetails returns detailed shop information including services
 func (h *PublicPrintShopHandler) GetShopDetails(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -146,8 +208,9 @@ func (h *PublicPrintShopHandler) CalculatePriceForService(w http.ResponseWriter,
 	}
 
 	// Calculate price
-	totalPrice := h.pricing.CalculateShopPrice(service, req.Options)
-	breakdown := h.pricing.CalculateShopPriceWithBreakdown(service, req.Options)
+	// Zero breakdown when the service does not offer the size
+	breakdown, _ := h.quoter.QuoteService(ctx, service, req.Options)
+	totalPrice := breakdown.Total
 
 	// Get shop info
 	shop, _ := h.repo.GetShopByID(ctx, service.ShopID)
@@ -166,7 +229,9 @@ func (h *PublicPrintShopHandler) CalculatePriceForService(w http.ResponseWriter,
 	json.NewEncoder(w).Encode(response)
 }
 
-// MatchShopsForOrder finds shops that can fulfill an order (public endpoint)
+// MatchShopsForOrder finds shops that can fulfill an order (public endpoint).
+// ?strategy=cheapest|fastest|smart picks the ranking (cheapest by default); with
+// ?explain=true the response also lists why each incompatible service was rejected.
 func (h *PublicPrintShopHandler) MatchShopsForOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -176,68 +241,26 @@ func (h *PublicPrintShopHandler) MatchShopsForOrder(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Get all active shops
-	shops, err := h.repo.GetActiveShops(ctx)
+	strategy := r.URL.Query().Get("strategy")
+	if strategy == "" {
+		strategy = matching.StrategyCheapest
+	}
+
+	result, err := h.pipeline.Match(ctx, options, strategy)
+	if errors.Is(err, matching.ErrUnknownStrategy) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("❌ Failed to get shops: %v", err)
 		http.Error(w, "Failed to get shops", http.StatusInternalServerError)
 		return
 	}
 
-	matches := make([]models.ShopMatch, 0)
-
-	// For each shop, find matching services
-	for _, shop := range shops {
-		services, err := h.repo.GetServicesByShopID(ctx, shop.ID)
-		if err != nil {
-			continue
-		}
-
-		// Check each service for compatibility
-		for _, service := range services {
-			if !service.IsActive {
-				continue
-			}
-
-			// Check if service supports the requested options
-			// matrix := service.PriceMatrix // Deprecated
-			
-			// Check size availability
-			_, hasSize := service.SizePricing[options.Size]
-			
-			// TODO: Resolve SubstrateID and MediumID to check against options.Material and options.Medium
-			// For now, we assume if the service is active and has the size, it's a candidate
-			// This needs to be updated to fetch Material/Medium details or cache them
-			hasMaterial := true 
-			hasMedium := true
-			hasFrame := true // Frame check also needs update as frames are separate
-
-			// If service supports all options, calculate price
-			if hasSize && hasMaterial && hasMedium && hasFrame {
-				totalPrice := h.pricing.CalculateShopPrice(service, options)
-
-				// Calculate match score (simple: based on price competitiveness)
-				// Lower price = higher score (will be enhanced in smart matching)
-				matchScore := 100.0 / (1.0 + totalPrice/100.0) // Normalize score
-
-				techType := ""
-				if service.Technology != nil {
-					techType = service.Technology.Type
-				}
-
-				matches = append(matches, models.ShopMatch{
-					ShopID:       shop.ID,
-					ShopName:     shop.Name,
-					ServiceID:    service.ID,
-					TotalPrice:   totalPrice,
-					MatchScore:   matchScore,
-					Technology:   techType,
-					DeliveryDays: 5, // Default, can be enhanced later
-				})
-			}
-		}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(matches)
+	if r.URL.Query().Get("explain") != "true" {
+		json.NewEncoder(w).Encode(result.Matches)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
 }
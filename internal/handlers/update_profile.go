@@ -7,14 +7,12 @@ import (
 	"os"
 	"time"
 
-	"cloud.google.com/go/firestore"
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
 // === UPDATE PROFILE ===
-func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (h *ProfileHandler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("📥 UpdateProfileHandler triggered")
 	ctx := r.Context()
 
@@ -97,9 +95,8 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	// 🕒 Add timestamp
 	updates["updatedAt"] = time.Now()
 
-	// 📝 Save to Firestore
-	_, err = firebase.FirestoreClient.Collection("users").Doc(uid).Set(ctx, updates, firestore.MergeAll)
-	if err != nil {
+	// 📝 Save profile
+	if err := h.users.UpdateUser(ctx, uid, updates); err != nil {
 		log.Printf("❌ Firestore update error: %v", err)
 		http.Error(w, "Profile update failed", http.StatusInternalServerError)
 		return
@@ -1,5 +1,11 @@
 package interfaces
 
+import (
+	"context"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
 type PrintSize struct {
 	Name       string  `json:"name"`
 	Multiplier float64 `json:"multiplier"`
@@ -49,9 +55,11 @@ type PricingCalculator interface {
 	ApplyQuantityDiscount(basePrice float64, quantity int, tiers []QuantityTier) float64
 }
 
-//type MatchingStrategy interface {
-//FindPrintShops(order PrintOrder, availableShops []PrintShopProfile) []ShopMatch
-//}
+// MatchingStrategy ranks the shop services able to fulfill an order, best match first
+type MatchingStrategy interface {
+	Name() string
+	FindPrintShops(ctx context.Context, options models.PrintOrderOptions, candidates []models.ShopMatch) []models.ShopMatch
+}
 
 // Supporting types for interfaces
 type PrintOrderOptions struct {
@@ -14,7 +14,8 @@ func CORS(next http.Handler) http.Handler {
 		if allowedOrigins[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, If-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed, ETag")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
 
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a POST safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	idempotencyTTL          = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes caps the request bodies read into memory to fingerprint them
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// Idempotency replays the stored response when an authenticated POST repeats an
+// Idempotency-Key. Keys are scoped to the user; reusing one with a different method,
+// path or body is rejected with 422, and a retry that arrives while the first request
+// is still running gets 409. Server errors and panics are not stored so the request can be
+// retried. Requests without the header, without an authenticated user, or with a multipart
+// body (uploads too large to fingerprint in memory) pass straight through; other bodies over
+// maxIdempotentBodyBytes are refused with 413.
+func Idempotency(repo repositories.IdempotencyRepository) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			userID, _ := r.Context().Value("userId").(string)
+			if r.Method != http.MethodPost || key == "" || userID == "" || isMultipart(r) {
+				next(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			id := fingerprint(userID, key)
+			record := &models.IdempotencyRecord{
+				Key:         key,
+				UserID:      userID,
+				RequestHash: fingerprint(r.Method, r.URL.Path, string(body)),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(idempotencyTTL),
+			}
+
+			existing, err := repo.Reserve(r.Context(), id, record)
+			if err != nil {
+				log.Printf("❌ Failed to reserve idempotency key: %v", err)
+				http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				replay(w, existing, record.RequestHash)
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// The client may have given up waiting; the outcome must be stored regardless.
+				// A handler that panicked releases the key so it is not left in flight.
+				ctx := context.WithoutCancel(r.Context())
+				if !completed || rec.status >= http.StatusInternalServerError {
+					if err := repo.Release(ctx, id); err != nil {
+						log.Printf("⚠️ Failed to release idempotency key: %v", err)
+					}
+					return
+				}
+				if err := repo.Complete(ctx, id, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+					log.Printf("⚠️ Failed to store idempotent response: %v", err)
+				}
+			}()
+			next(rec, r)
+			completed = true
+		}
+	}
+}
+
+// isMultipart reports whether a request carries a multipart body, such as a file upload
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/")
+}
+
+// replay answers a repeated key from the stored record
+func replay(w http.ResponseWriter, existing *models.IdempotencyRecord, requestHash string) {
+	switch {
+	case existing.RequestHash != requestHash:
+		http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+	case !existing.Completed:
+		http.Error(w, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+	default:
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.Body)
+	}
+}
+
+// fingerprint hashes its parts into a fixed-length hex ID
+func fingerprint(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter passes a response through while keeping a copy of its status and body
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
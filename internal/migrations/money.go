@@ -0,0 +1,173 @@
+// Package migrations holds one-off rewrites of stored documents after a model change
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// itemMoneyPaths are the amounts on a cart or order line
+var itemMoneyPaths = []string{
+	"price",
+	"priceBreakdown.basePrice",
+	"priceBreakdown.framePrice",
+	"priceBreakdown.rushOrderFee",
+	"priceBreakdown.subtotal",
+	"priceBreakdown.unitPrice",
+	"priceBreakdown.total",
+}
+
+// moneyPaths lists, per collection, the fields that used to hold amounts as decimal numbers.
+// A segment ending in "[]" is an array whose every element is walked.
+var moneyPaths = map[string][]string{
+	"orders": append(prefixed("items[].", itemMoneyPaths),
+		"totalAmount",
+		"shippingCost",
+		"shippingQuote.amount",
+		"discountTotal",
+		"discounts[].amount",
+		"discounts[].shipping",
+		"discounts[].lines[].amount",
+		"taxTotal",
+		"taxLines[].taxable",
+		"taxLines[].amount",
+	),
+	"carts":                 prefixed("items[].", itemMoneyPaths),
+	"payments":              {"amount"},
+	"promotion_redemptions": {"amount"},
+}
+
+func prefixed(prefix string, paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = prefix + p
+	}
+	return out
+}
+
+// MoneyReport counts the documents a money migration looked at and rewrote, per collection
+type MoneyReport struct {
+	Scanned   map[string]int `json:"scanned"`
+	Converted map[string]int `json:"converted"`
+}
+
+// MigrateMoney rewrites amounts stored as decimal major units (e.g. 1500.5) into Money maps
+// ({amount: 150050, currency: "KES"}) in the given currency. Amounts already in Money form are
+// left alone, so the migration can be re-run safely. Only the top-level fields that changed are
+// written, and only if the document was not modified since it was read. With dryRun nothing is
+// written and the report says what would change.
+func MigrateMoney(ctx context.Context, client *firestore.Client, currency string, dryRun bool) (*MoneyReport, error) {
+	report := &MoneyReport{Scanned: map[string]int{}, Converted: map[string]int{}}
+	for collection, paths := range moneyPaths {
+		if err := migrateCollection(ctx, client, collection, paths, currency, dryRun, report); err != nil {
+			return report, err
+		}
+		log.Printf("✅ %s: %d of %d documents converted", collection, report.Converted[collection], report.Scanned[collection])
+	}
+	return report, nil
+}
+
+func migrateCollection(ctx context.Context, client *firestore.Client, collection string, paths []string, currency string, dryRun bool, report *MoneyReport) error {
+	iter := client.Collection(collection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", collection, err)
+		}
+		report.Scanned[collection]++
+
+		data := doc.Data()
+		changed := ConvertMoneyFields(data, paths, currency)
+		if len(changed) == 0 {
+			continue
+		}
+		report.Converted[collection]++
+		if dryRun {
+			log.Printf("📋 Would convert %s/%s: %s", collection, doc.Ref.ID, strings.Join(changed, ", "))
+			continue
+		}
+
+		updates := make([]firestore.Update, 0, len(changed))
+		for _, field := range changed {
+			updates = append(updates, firestore.Update{Path: field, Value: data[field]})
+		}
+		if _, err := doc.Ref.Update(ctx, updates, firestore.LastUpdateTime(doc.UpdateTime)); err != nil {
+			return fmt.Errorf("failed to convert %s/%s: %w", collection, doc.Ref.ID, err)
+		}
+	}
+}
+
+// ConvertMoneyFields converts the numbers at the given paths of a raw document to Money maps in
+// place and returns the top-level fields it changed
+func ConvertMoneyFields(data map[string]interface{}, paths []string, currency string) []string {
+	var changed []string
+	for _, path := range paths {
+		top, _, _ := strings.Cut(path, ".")
+		top = strings.TrimSuffix(top, "[]")
+		if convertPath(data, strings.Split(path, "."), currency) && !slices.Contains(changed, top) {
+			changed = append(changed, top)
+		}
+	}
+	return changed
+}
+
+// convertPath walks one path through nested maps and arrays, converting the number at its end
+func convertPath(node map[string]interface{}, segments []string, currency string) bool {
+	key, isArray := strings.CutSuffix(segments[0], "[]")
+	value, ok := node[key]
+	if !ok || value == nil {
+		return false
+	}
+
+	if len(segments) == 1 {
+		money, ok := toMoney(value, currency)
+		if ok {
+			node[key] = money
+		}
+		return ok
+	}
+
+	converted := false
+	if isArray {
+		elems, _ := value.([]interface{})
+		for _, elem := range elems {
+			if child, ok := elem.(map[string]interface{}); ok && convertPath(child, segments[1:], currency) {
+				converted = true
+			}
+		}
+		return converted
+	}
+	if child, ok := value.(map[string]interface{}); ok {
+		converted = convertPath(child, segments[1:], currency)
+	}
+	return converted
+}
+
+// toMoney returns the Money map for a decimal or whole major-unit amount; other values,
+// including amounts already converted, are not touched
+func toMoney(value interface{}, currency string) (map[string]interface{}, bool) {
+	var major float64
+	switch v := value.(type) {
+	case float64:
+		major = v
+	case int64:
+		major = float64(v)
+	default:
+		return nil, false
+	}
+	m := models.FromMajor(major, currency)
+	return map[string]interface{}{"amount": m.Amount, "currency": m.Currency}, true
+}
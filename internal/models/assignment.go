@@ -0,0 +1,12 @@
+package models
+
+// AssignmentStatus tracks a print shop's response to an order offered to it
+type AssignmentStatus string
+
+const (
+	AssignmentOffered   AssignmentStatus = "offered"   // waiting for the shop to accept or decline
+	AssignmentAccepted  AssignmentStatus = "accepted"  // the shop committed to fulfilling the order
+	AssignmentDeclined  AssignmentStatus = "declined"  // the shop turned the order down
+	AssignmentExpired   AssignmentStatus = "expired"   // the shop did not answer within the SLA
+	AssignmentWithdrawn AssignmentStatus = "withdrawn" // the order was cancelled or refunded before the shop answered
+)
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// calendarDateLayout is the format of closure dates
+const calendarDateLayout = "2006-01-02"
+
+// BusinessHours is one weekday's opening hours in the shop's timezone, as 24-hour "HH:MM"
+type BusinessHours struct {
+	Weekday time.Weekday `firestore:"weekday" json:"weekday"` // 0 = Sunday
+	Open    string       `firestore:"open" json:"open"`
+	Close   string       `firestore:"close" json:"close"`
+}
+
+// Closure is a run of dates the shop is closed (holidays, maintenance), inclusive
+type Closure struct {
+	From   string `firestore:"from" json:"from"`                 // YYYY-MM-DD
+	To     string `firestore:"to,omitempty" json:"to,omitempty"` // YYYY-MM-DD; defaults to From
+	Reason string `firestore:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// BusinessCalendar describes when a shop produces orders. Shops without a calendar
+// are treated as open Monday to Friday, 09:00-17:00 UTC.
+type BusinessCalendar struct {
+	Timezone string          `firestore:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, e.g. "Africa/Nairobi"
+	Hours    []BusinessHours `firestore:"hours" json:"hours"`                           // weekdays without hours are closed
+	Closures []Closure       `firestore:"closures,omitempty" json:"closures,omitempty"`
+}
+
+// DefaultBusinessCalendar returns the calendar assumed for shops that have not declared one
+func DefaultBusinessCalendar() *BusinessCalendar {
+	cal := &BusinessCalendar{}
+	for day := time.Monday; day <= time.Friday; day++ {
+		cal.Hours = append(cal.Hours, BusinessHours{Weekday: day, Open: "09:00", Close: "17:00"})
+	}
+	return cal
+}
+
+// Validate checks the timezone, hours and closure dates
+func (c *BusinessCalendar) Validate() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	if len(c.Hours) == 0 {
+		return fmt.Errorf("at least one weekday needs business hours")
+	}
+	seen := make(map[time.Weekday]bool)
+	for _, h := range c.Hours {
+		if h.Weekday < time.Sunday || h.Weekday > time.Saturday {
+			return fmt.Errorf("weekday %d out of range", h.Weekday)
+		}
+		if seen[h.Weekday] {
+			return fmt.Errorf("%s has more than one set of hours", h.Weekday)
+		}
+		seen[h.Weekday] = true
+		open, err1 := parseClock(h.Open)
+		closing, err2 := parseClock(h.Close)
+		if err1 != nil || err2 != nil || closing <= open {
+			return fmt.Errorf("%s hours must be HH:MM with close after open", h.Weekday)
+		}
+	}
+	for _, cl := range c.Closures {
+		from, to, err := cl.dates()
+		if err != nil {
+			return err
+		}
+		if to.Before(from) {
+			return fmt.Errorf("closure %s ends before it starts", cl.From)
+		}
+	}
+	return nil
+}
+
+// Location returns the calendar's timezone, UTC when unset or unknown
+func (c *BusinessCalendar) Location() *time.Location {
+	if loc, err := time.LoadLocation(c.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// HoursOn returns the opening and closing times on t's date, and false if the shop is closed that day
+func (c *BusinessCalendar) HoursOn(t time.Time) (open, closing time.Time, ok bool) {
+	t = t.In(c.Location())
+	if c.closedOn(t) {
+		return time.Time{}, time.Time{}, false
+	}
+	for _, h := range c.Hours {
+		if h.Weekday != t.Weekday() {
+			continue
+		}
+		openAt, err1 := parseClock(h.Open)
+		closeAt, err2 := parseClock(h.Close)
+		if err1 != nil || err2 != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return midnight.Add(openAt), midnight.Add(closeAt), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func (c *BusinessCalendar) closedOn(t time.Time) bool {
+	day := t.Format(calendarDateLayout)
+	for _, cl := range c.Closures {
+		to := cl.To
+		if to == "" {
+			to = cl.From
+		}
+		if day >= cl.From && day <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl Closure) dates() (from, to time.Time, err error) {
+	if from, err = time.Parse(calendarDateLayout, cl.From); err != nil {
+		return from, to, fmt.Errorf("closure date %q must be YYYY-MM-DD", cl.From)
+	}
+	if cl.To == "" {
+		return from, from, nil
+	}
+	if to, err = time.Parse(calendarDateLayout, cl.To); err != nil {
+		return from, to, fmt.Errorf("closure date %q must be YYYY-MM-DD", cl.To)
+	}
+	return from, to, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 type FulfillmentMode string
 
 const (
@@ -7,3 +12,38 @@ const (
 	FulfillmentManual FulfillmentMode = "manual"
 	FulfillmentSmart  FulfillmentMode = "smart"
 )
+
+// IsValid reports whether m is one of the known fulfillment modes
+func (m FulfillmentMode) IsValid() bool {
+	switch m {
+	case FulfillmentAuto, FulfillmentManual, FulfillmentSmart:
+		return true
+	}
+	return false
+}
+
+// FulfillmentSettings is the settings/global document that controls how orders are matched.
+// Overrides take precedence over the global mode: shop overrides first, then category overrides.
+type FulfillmentSettings struct {
+	FulfillmentMode   FulfillmentMode            `firestore:"fulfillmentMode" json:"fulfillmentMode"`
+	ShopOverrides     map[string]FulfillmentMode `firestore:"shopOverrides,omitempty" json:"shopOverrides,omitempty"`         // shop ID -> mode
+	CategoryOverrides map[string]FulfillmentMode `firestore:"categoryOverrides,omitempty" json:"categoryOverrides,omitempty"` // print material -> mode
+	// AcceptanceSLAMinutes is how long a shop has to accept an offered order (0 = default)
+	AcceptanceSLAMinutes int       `firestore:"acceptanceSlaMinutes,omitempty" json:"acceptanceSlaMinutes,omitempty"`
+	UpdatedBy            string    `firestore:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt            time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// ModeFor resolves the mode for an order already tied to shopID (empty if none)
+// whose print material is category (empty if none)
+func (s *FulfillmentSettings) ModeFor(shopID, category string) FulfillmentMode {
+	if mode, ok := s.ShopOverrides[shopID]; ok && shopID != "" {
+		return mode
+	}
+	for key, mode := range s.CategoryOverrides {
+		if category != "" && strings.EqualFold(key, category) {
+			return mode
+		}
+	}
+	return s.FulfillmentMode
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request made with an Idempotency-Key header
+// so that a retry with the same key replays the response instead of repeating the work
+type IdempotencyRecord struct {
+	Key         string    `firestore:"key" json:"key"`
+	UserID      string    `firestore:"userId" json:"userId"`
+	RequestHash string    `firestore:"requestHash" json:"requestHash"` // method, path and body fingerprint
+	Completed   bool      `firestore:"completed" json:"completed"`     // false while the first request is running
+	StatusCode  int       `firestore:"statusCode,omitempty" json:"statusCode,omitempty"`
+	ContentType string    `firestore:"contentType,omitempty" json:"contentType,omitempty"`
+	Body        []byte    `firestore:"body,omitempty" json:"body,omitempty"`
+	CreatedAt   time.Time `firestore:"createdAt" json:"createdAt"`
+	ExpiresAt   time.Time `firestore:"expiresAt" json:"expiresAt"`
+}
+
+// Expired reports whether the record may be replaced by a new request
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
@@ -11,41 +11,104 @@ const (
 )
 
 type User struct {
-	UID           string    `firestore:"uid"`           // Firebase UID
-	Email         string    `firestore:"email"`         // Email address
-	Roles         []string  `firestore:"roles"`         // ["buyer", "artist", "printShop"]
-	Name          string    `firestore:"name"`          // Display name
-	DateOfBirth   string    `firestore:"dateOfBirth"`   // Format: YYYY-MM-DD
-	Description   string    `firestore:"description"`   // Profile bio
-	AvatarURL     string    `firestore:"avatarUrl"`     // Cloudinary avatar image
-	BackgroundURL string    `firestore:"backgroundUrl"` // Cloudinary cover image REMOVE FILE
-	CreatedAt     time.Time `firestore:"createdAt"`     // Account creation time
+	UID           string    `firestore:"uid" json:"uid"`                                     // Firebase UID
+	Email         string    `firestore:"email" json:"email"`                                 // Email address
+	Roles         []string  `firestore:"roles" json:"roles"`                                 // ["buyer", "artist", "printShop"]
+	Name          string    `firestore:"name" json:"name"`                                   // Display name
+	DateOfBirth   string    `firestore:"dateOfBirth" json:"dateOfBirth"`                     // Format: YYYY-MM-DD
+	Description   string    `firestore:"description" json:"description"`                     // Profile bio
+	AvatarURL     string    `firestore:"avatarUrl" json:"avatarUrl"`                         // Cloudinary avatar image
+	BackgroundURL string    `firestore:"backgroundUrl" json:"backgroundUrl"`                 // Cloudinary cover image REMOVE FILE
+	IsActive      bool      `firestore:"isActive,omitempty" json:"isActive"`                 // false until reviewed / when deactivated
+	Addresses     []Address `firestore:"addresses,omitempty" json:"addresses,omitempty"`     // saved shipping addresses
+	TaxExempt     bool      `firestore:"taxExempt,omitempty" json:"taxExempt,omitempty"`     // set by admins for verified exempt buyers
+	TaxExemptID   string    `firestore:"taxExemptId,omitempty" json:"taxExemptId,omitempty"` // exemption certificate or registration number
+	CreatedAt     time.Time `firestore:"createdAt" json:"createdAt"`                         // Account creation time
+	UpdatedAt     time.Time `firestore:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+// HasRole reports whether the user has been granted the given role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 type Artwork struct {
-	ID           string                 `json:"id,omitempty"`
-	ArtistID     string                 `firestore:"artistId"`
-	Title        string                 `firestore:"title"`
-	Description  string                 `firestore:"description"`
+	ID           string                 `firestore:"-" json:"id,omitempty"`
+	ArtistID     string                 `firestore:"artistId" json:"artistId"`
+	Title        string                 `firestore:"title" json:"title"`
+	Description  string                 `firestore:"description" json:"description"`
 	ImageURL     string                 `firestore:"imageUrl" json:"imageUrl"`
-	PrintOptions map[string]interface{} `firestore:"printOptions"`
-	IsAvailable  bool                   `firestore:"isAvailable"`
-	CreatedAt    time.Time              `firestore:"createdAt"`
+	PrintOptions map[string]interface{} `firestore:"printOptions" json:"printOptions"`
+	IsAvailable  bool                   `firestore:"isAvailable" json:"isAvailable"`
+	CreatedAt    time.Time              `firestore:"createdAt" json:"createdAt"`
+
+	// Processing pipeline state (written by the upload handler and the worker)
+	CloudinaryPublicID string                 `firestore:"cloudinaryPublicId,omitempty" json:"cloudinaryPublicId,omitempty"`
+	CloudinaryFolder   string                 `firestore:"cloudinaryFolder,omitempty" json:"cloudinaryFolder,omitempty"`
+	ProcessingStatus   string                 `firestore:"processingStatus,omitempty" json:"processingStatus,omitempty"` // "pending", "ready", "failed"
+	ProcessingErrors   []string               `firestore:"processingErrors" json:"processingErrors,omitempty"`
+	Analysis           map[string]interface{} `firestore:"analysis,omitempty" json:"analysis,omitempty"`
+	PrintReadyVersions interface{}            `firestore:"printReadyVersions,omitempty" json:"printReadyVersions,omitempty"`
+	Admin              *AdminResolution       `firestore:"admin,omitempty" json:"admin,omitempty"`
+	AssignedTo         *ArtworkAssignment     `firestore:"assignedTo,omitempty" json:"assignedTo,omitempty"`
+}
+
+// AdminResolution records an admin review decision on an uploaded asset
+type AdminResolution struct {
+	ResolvedBy     string    `firestore:"resolvedBy" json:"resolvedBy"`
+	ResolvedAt     time.Time `firestore:"resolvedAt" json:"resolvedAt"`
+	ResolutionNote string    `firestore:"resolutionNote" json:"resolutionNote"`
+}
+
+// ArtworkAssignment links an artwork to the print shop that will produce it
+type ArtworkAssignment struct {
+	PrintShopID string    `firestore:"printShopId" json:"printShopId"`
+	AssignedAt  time.Time `firestore:"assignedAt" json:"assignedAt"`
+	AssignedBy  string    `firestore:"assignedBy" json:"assignedBy"`
 }
 
 // Utilize []CartItem in Order Struct
 type CartItem struct {
-	ArtworkID string  `firestore:"artworkId"`
-	Quantity  int     `firestore:"quantity"`
-	Price     float64 `firestore:"price"`
+	// LineID identifies the line for its whole life in the cart, and on the order it becomes
+	LineID    string `firestore:"lineId,omitempty"`
+	ArtworkID string `firestore:"artworkId"`
+	Quantity  int    `firestore:"quantity"`
+	Price     Money  `firestore:"price"` // unit price
 	// Print options for this item (can be extracted from artwork or set by user)
 	PrintOptions PrintOrderOptions `firestore:"printOptions,omitempty"`
+	// PriceBreakdown is computed by the server at checkout and frozen on the order line
+	PriceBreakdown *PriceBreakdown `firestore:"priceBreakdown,omitempty"`
+}
+
+// LineTotal returns the frozen checkout total when the line has been priced, else price × quantity
+func (i CartItem) LineTotal() Money {
+	if i.PriceBreakdown != nil {
+		return i.PriceBreakdown.Total
+	}
+	return i.Price.Mul(i.Quantity)
 }
 
 type Cart struct {
 	BuyerID   string     `firestore:"buyerId"`
 	Items     []CartItem `firestore:"items"`
 	UpdatedAt time.Time  `firestore:"updatedAt"`
+	// Version increases with every change; clients send it back in If-Match to avoid lost updates
+	Version int64 `firestore:"version"`
+}
+
+// LineIndex returns the position of the line with the given ID, or -1 if there is none
+func (c *Cart) LineIndex(lineID string) int {
+	for i, item := range c.Items {
+		if lineID != "" && item.LineID == lineID {
+			return i
+		}
+	}
+	return -1
 }
 
 type Order struct {
@@ -54,15 +117,68 @@ type Order struct {
 	PrintShopID    string            `firestore:"printShopId"`
 	Items          []CartItem        `firestore:"items"`
 	PrintOptions   PrintOrderOptions `firestore:"printOptions"` // Print configuration for the order
-	TotalAmount    float64           `firestore:"totalAmount"`
+	TotalAmount    Money             `firestore:"totalAmount"`
 	PaymentMethod  string            `firestore:"paymentMethod"`  // Legacy: "unpaid", "paid"
 	TransactionID  string            `firestore:"transactionId"`  // Legacy: kept for backward compatibility
-	PaymentStatus  string            `firestore:"paymentStatus"`  // "unpaid", "partial", "paid"
+	PaymentStatus  string            `firestore:"paymentStatus"`  // "unpaid", "partial", "paid", "partially_refunded", "refunded"
 	PaymentID      string            `firestore:"paymentId"`      // Latest payment ID
-	DeliveryStatus string            `firestore:"deliveryStatus"` // "pending", "processing", "ready", "delivered"
+	DeliveryStatus string            `firestore:"deliveryStatus"` // see the Delivery* constants in shipment.go
 	DeliveryMethod string            `firestore:"deliveryMethod"` // "pickup", "shipping"
 	PickupLocation string            `firestore:"pickupLocation"` // For pickup orders
-	Status         string            `firestore:"status"`         // "pending", "confirmed", "processing", "ready", "completed"
+	Status         OrderStatus       `firestore:"status"`         // see order_status.go; change only via OrderRepository.TransitionStatus
+	AdminNotes     []AdminNote       `firestore:"adminNotes,omitempty"`
 	CreatedAt      time.Time         `firestore:"createdAt"`
 	UpdatedAt      time.Time         `firestore:"updatedAt"`
+
+	// Checkout splits a cart into one parent order (what the buyer pays for) and one
+	// fulfillment sub-order per print shop. Orders created before the split have neither field.
+	ParentOrderID string   `firestore:"parentOrderId,omitempty"`
+	SubOrderIDs   []string `firestore:"subOrderIds,omitempty"`
+
+	// SubOrders is filled in by handlers when returning a parent order; it is never stored
+	SubOrders []*Order `firestore:"-" json:",omitempty"`
+
+	// Shop inbox state for sub-orders: the assigned shop must accept before the deadline,
+	// otherwise the order is reassigned away from every shop in DeclinedShopIDs
+	AssignmentStatus   AssignmentStatus `firestore:"assignmentStatus,omitempty"`
+	AssignmentDeadline time.Time        `firestore:"assignmentDeadline,omitempty"`
+	DeclinedShopIDs    []string         `firestore:"declinedShopIds,omitempty"`
+
+	// Shipping orders: where they go and what shipping adds to TotalAmount. A parent's
+	// ShippingCost is the sum of its sub-orders', each of which ships as its own parcel.
+	ShippingAddress *Address       `firestore:"shippingAddress,omitempty"`
+	ShippingCost    Money          `firestore:"shippingCost"`
+	ShippingQuote   *ShippingQuote `firestore:"shippingQuote,omitempty"`
+
+	// Promotions applied at checkout; TotalAmount is already net of DiscountTotal
+	Discounts     []OrderDiscount `firestore:"discounts,omitempty"`
+	DiscountTotal Money           `firestore:"discountTotal"`
+
+	// Tax charged at checkout, net of discounts. Inclusive lines are already part of the
+	// item and shipping prices; exclusive lines were added to TotalAmount.
+	TaxLines  []TaxLine `firestore:"taxLines,omitempty"`
+	TaxTotal  Money     `firestore:"taxTotal"`
+	TaxExempt bool      `firestore:"taxExempt,omitempty"`
+
+	// Set on sub-orders once the shop packs them and a courier shipment is booked
+	ShipmentID     string `firestore:"shipmentId,omitempty"`
+	TrackingNumber string `firestore:"trackingNumber,omitempty"`
+}
+
+// IsParent reports whether the order groups fulfillment sub-orders
+func (o *Order) IsParent() bool {
+	return len(o.SubOrderIDs) > 0
+}
+
+// IsSubOrder reports whether the order is a per-shop fulfillment part of a parent order
+func (o *Order) IsSubOrder() bool {
+	return o.ParentOrderID != ""
+}
+
+// AdminNote is a free-form note appended to an order by admins or print shops
+type AdminNote struct {
+	Note      string    `firestore:"note" json:"note"`
+	Level     string    `firestore:"level,omitempty" json:"level,omitempty"` // e.g., "warning", "critical"
+	CreatedBy string    `firestore:"createdBy" json:"createdBy"`
+	CreatedAt time.Time `firestore:"createdAt" json:"createdAt"`
 }
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultCurrency is the currency prices are charged in unless a shop or order says otherwise
+const DefaultCurrency = "KES"
+
+// Money is an amount in the minor units of an ISO 4217 currency (cents for KES), so totals,
+// deposits and refunds add up exactly. Amounts that are charged, paid or reported are Money;
+// configuration typed in by shops and admins (price lists, rate tables, promotion values) stays
+// in major units and is converted with FromMajor when it is priced.
+//
+// Arithmetic on two amounts requires the same currency; the zero Money has no currency and
+// takes on the other operand's. Mixing currencies is a programming error and panics.
+type Money struct {
+	Amount   int64  `firestore:"amount" json:"amount"`     // minor units
+	Currency string `firestore:"currency" json:"currency"` // ISO 4217 code, e.g. "KES"
+}
+
+// RoundingMode says how an amount that falls between two minor units is settled
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero. Prices, discounts and shipping use it.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the even unit, so rounding errors cancel out over many
+	// lines. Tax uses it.
+	RoundHalfEven
+	// RoundDown truncates toward zero, never charging more than the exact amount
+	RoundDown
+)
+
+// currencyExponents lists currencies whose minor unit is not a hundredth
+var currencyExponents = map[string]int{
+	"UGX": 0,
+	"RWF": 0,
+	"JPY": 0,
+	"KWD": 3,
+}
+
+// Exponent returns how many decimal places the currency's minor unit has
+func Exponent(currency string) int {
+	if e, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// NewMoney returns an amount already in minor units
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: strings.ToUpper(currency)}
+}
+
+// FromMajor converts an amount in major units (e.g. 1500.50 shillings) to Money, rounding half
+// away from zero to the nearest minor unit
+func FromMajor(major float64, currency string) Money {
+	return NewMoney(Round(major*scale(currency), RoundHalfUp), currency)
+}
+
+// Round settles a fractional number of minor units using the given mode
+func Round(v float64, mode RoundingMode) int64 {
+	switch mode {
+	case RoundHalfEven:
+		return int64(math.RoundToEven(v))
+	case RoundDown:
+		return int64(math.Trunc(v))
+	}
+	return int64(math.Round(v))
+}
+
+func scale(currency string) float64 {
+	return math.Pow10(Exponent(currency))
+}
+
+// Major returns the amount in major units, for display and for providers that want decimals
+func (m Money) Major() float64 {
+	return float64(m.Amount) / scale(m.Currency)
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool { return m.Amount == 0 }
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// currencyWith returns the currency two amounts share
+func (m Money) currencyWith(o Money) string {
+	switch {
+	case m.Currency == "" || m.Currency == o.Currency:
+		return o.Currency
+	case o.Currency == "":
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch: %s and %s", m.Currency, o.Currency))
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Mul returns m × n, e.g. a unit price times a quantity
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// MulRate returns m × rate rounded with the given mode, e.g. a percentage or tax rate
+func (m Money) MulRate(rate float64, mode RoundingMode) Money {
+	return Money{Amount: Round(float64(m.Amount)*rate, mode), Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o
+func (m Money) Cmp(o Money) int {
+	m.currencyWith(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return m
+	}
+	return o
+}
+
+// Max returns the larger of m and o
+func (m Money) Max(o Money) Money {
+	if m.Cmp(o) >= 0 {
+		return m
+	}
+	return o
+}
+
+// Allocate splits m into parts proportional to weights without losing or inventing a minor
+// unit: each part is rounded down and the units left over go, one each, to the parts with
+// the largest remainders (earlier parts win ties). All zero weights split evenly.
+func (m Money) Allocate(weights ...int64) []Money {
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 {
+		weights = make([]int64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		sum = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(weights))
+	left := m.Amount
+	for i, w := range weights {
+		parts[i] = Money{Amount: m.Amount * w / sum, Currency: m.Currency}
+		remainders[i] = m.Amount * w % sum
+		left -= parts[i].Amount
+	}
+	step := int64(1)
+	if left < 0 {
+		step = -1
+	}
+	for left != 0 {
+		best := 0
+		for i := range remainders {
+			if remainders[i]*step > remainders[best]*step {
+				best = i
+			}
+		}
+		parts[best].Amount += step
+		remainders[best] = 0
+		left -= step
+	}
+	return parts
+}
+
+// String formats the amount with its currency, e.g. "KES 1500.50"
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+	s := fmt.Sprintf("%.*f", exp, m.Major())
+	if m.Currency == "" {
+		return s
+	}
+	return m.Currency + " " + s
+}
+
+// SumMoney adds up amounts of one currency
+func SumMoney(amounts ...Money) Money {
+	var total Money
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	return total
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  Money
+		weights []int64
+		want    []int64
+	}{
+		{"even split", NewMoney(900, "KES"), []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"leftover goes to the earliest of equal remainders", NewMoney(100, "KES"), []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"leftover goes to the largest remainder", NewMoney(1000, "KES"), []int64{1, 2, 4}, []int64{143, 286, 571}},
+		{"proportional to line totals", NewMoney(500, "KES"), []int64{3000, 1000}, []int64{375, 125}},
+		{"zero weight gets nothing", NewMoney(101, "KES"), []int64{1, 0, 1}, []int64{51, 0, 50}},
+		{"all zero weights split evenly", NewMoney(10, "KES"), []int64{0, 0, 0}, []int64{4, 3, 3}},
+		{"negative amount", NewMoney(-100, "KES"), []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"zero amount", NewMoney(0, "KES"), []int64{2, 3}, []int64{0, 0}},
+		{"no weights", NewMoney(100, "KES"), nil, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := tt.amount.Allocate(tt.weights...)
+			got := make([]int64, len(parts))
+			var sum int64
+			for i, p := range parts {
+				if p.Currency != tt.amount.Currency {
+					t.Errorf("part %d currency = %q, want %q", i, p.Currency, tt.amount.Currency)
+				}
+				got[i] = p.Amount
+				sum += p.Amount
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Allocate(%v) = %v, want %v", tt.weights, got, tt.want)
+			}
+			if len(parts) > 0 && sum != tt.amount.Amount {
+				t.Errorf("parts sum to %d, want %d", sum, tt.amount.Amount)
+			}
+		})
+	}
+}
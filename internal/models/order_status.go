@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// OrderStatus represents where an order is in its lifecycle
+type OrderStatus string
+
+const (
+	OrderStatusPending    OrderStatus = "pending"
+	OrderStatusConfirmed  OrderStatus = "confirmed"
+	OrderStatusProcessing OrderStatus = "processing"
+	OrderStatusReady      OrderStatus = "ready"
+	OrderStatusCompleted  OrderStatus = "completed"
+	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusError      OrderStatus = "error"
+	OrderStatusRefunded   OrderStatus = "refunded"
+)
+
+// orderTransitions lists the statuses each status may move to.
+// The happy path is pending → confirmed → processing → ready → completed;
+// error is recoverable, cancelled and completed orders can only be refunded.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:    {OrderStatusConfirmed, OrderStatusCancelled, OrderStatusError},
+	OrderStatusConfirmed:  {OrderStatusProcessing, OrderStatusCancelled, OrderStatusError, OrderStatusRefunded},
+	OrderStatusProcessing: {OrderStatusReady, OrderStatusCancelled, OrderStatusError},
+	OrderStatusReady:      {OrderStatusCompleted, OrderStatusError},
+	OrderStatusCompleted:  {OrderStatusRefunded},
+	OrderStatusCancelled:  {OrderStatusRefunded},
+	OrderStatusError:      {OrderStatusConfirmed, OrderStatusProcessing, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusRefunded:   {},
+}
+
+// IsValid reports whether s is a known order status
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an order may move from s to next.
+// Legacy orders without a status are treated as pending.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == "" {
+		s = OrderStatusPending
+	}
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderEvent records a single status transition, stored under orders/{id}/events
+type OrderEvent struct {
+	ID        string      `firestore:"id" json:"id"`
+	OrderID   string      `firestore:"orderId" json:"orderId"`
+	From      OrderStatus `firestore:"from" json:"from"`
+	To        OrderStatus `firestore:"to" json:"to"`
+	Actor     string      `firestore:"actor" json:"actor"` // user ID, or "system:<component>" for automated changes
+	Reason    string      `firestore:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time   `firestore:"createdAt" json:"createdAt"`
+}
+
+// IsOpen reports whether an order in status s still occupies its print shop
+func (s OrderStatus) IsOpen() bool {
+	switch s {
+	case OrderStatusCompleted, OrderStatusCancelled, OrderStatusRefunded:
+		return false
+	}
+	return true
+}
+
+// OpenOrderStatuses lists the statuses IsOpen reports true for, for queries that select
+// the orders still occupying a shop
+var OpenOrderStatuses = []OrderStatus{
+	"", OrderStatusPending, OrderStatusConfirmed, OrderStatusProcessing, OrderStatusReady, OrderStatusError,
+}
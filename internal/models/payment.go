@@ -4,33 +4,97 @@ import "time"
 
 // Payment represents a payment transaction
 type Payment struct {
-	ID            string                 `firestore:"id" json:"id"`
-	OrderID       string                 `firestore:"orderId" json:"orderId"`
-	BuyerID       string                 `firestore:"buyerId" json:"buyerId"`
-	Amount        float64                `firestore:"amount" json:"amount"`
-	PaymentMethod string                 `firestore:"paymentMethod" json:"paymentMethod"` // "stripe", "mpesa", "simulated"
-	Status        PaymentStatus          `firestore:"status" json:"status"`               // "pending", "processing", "completed", "failed", "refunded"
-	TransactionID string                 `firestore:"transactionId" json:"transactionId"` // External provider transaction ID
-	PaymentType   PaymentType            `firestore:"paymentType" json:"paymentType"`     // "deposit" (50%), "full" (100%), "remaining" (remaining 50%)
-	ProviderData  map[string]interface{} `firestore:"providerData" json:"providerData"`   // Provider-specific data
-	CreatedAt     time.Time              `firestore:"createdAt" json:"createdAt"`
-	UpdatedAt     time.Time              `firestore:"updatedAt" json:"updatedAt"`
-	CompletedAt   *time.Time             `firestore:"completedAt,omitempty" json:"completedAt,omitempty"`
-	FailedAt      *time.Time             `firestore:"failedAt,omitempty" json:"failedAt,omitempty"`
-	FailureReason string                 `firestore:"failureReason,omitempty" json:"failureReason,omitempty"`
+	ID             string                 `firestore:"id" json:"id"`
+	OrderID        string                 `firestore:"orderId" json:"orderId"`
+	BuyerID        string                 `firestore:"buyerId" json:"buyerId"`
+	Amount         Money                  `firestore:"amount" json:"amount"`
+	PaymentMethod  string                 `firestore:"paymentMethod" json:"paymentMethod"`               // "card", "mpesa", "simulated"
+	Status         PaymentStatus          `firestore:"status" json:"status"`                             // "pending", "requires_action", "processing", "completed", "failed", "cancelled", "partially_refunded", "refunded"
+	TransactionID  string                 `firestore:"transactionId" json:"transactionId"`               // External provider transaction ID
+	PaymentURL     string                 `firestore:"paymentUrl,omitempty" json:"paymentUrl,omitempty"` // Hosted page the buyer pays on, for redirect providers
+	PaymentType    PaymentType            `firestore:"paymentType" json:"paymentType"`                   // "deposit" (50%), "full" (100%), "remaining" (remaining 50%)
+	ProviderData   map[string]interface{} `firestore:"providerData" json:"providerData"`                 // Provider-specific data
+	CreatedAt      time.Time              `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time              `firestore:"updatedAt" json:"updatedAt"`
+	CompletedAt    *time.Time             `firestore:"completedAt,omitempty" json:"completedAt,omitempty"`
+	FailedAt       *time.Time             `firestore:"failedAt,omitempty" json:"failedAt,omitempty"`
+	FailureReason  string                 `firestore:"failureReason,omitempty" json:"failureReason,omitempty"`
+	RefundedAmount Money                  `firestore:"refundedAmount" json:"refundedAmount"` // Sum of the refunds the provider did not refuse
 }
 
 // PaymentStatus represents the status of a payment
 type PaymentStatus string
 
 const (
-	PaymentStatusPending    PaymentStatus = "pending"
-	PaymentStatusProcessing PaymentStatus = "processing"
-	PaymentStatusCompleted  PaymentStatus = "completed"
-	PaymentStatusFailed     PaymentStatus = "failed"
-	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusRequiresAction    PaymentStatus = "requires_action" // buyer must complete a step, e.g. 3-D Secure
+	PaymentStatusProcessing        PaymentStatus = "processing"
+	PaymentStatusCompleted         PaymentStatus = "completed"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusCancelled         PaymentStatus = "cancelled"          // buyer or provider abandoned the payment before it was made
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded" // captured, and refundable again up to what is left
+	PaymentStatusRefunded          PaymentStatus = "refunded"
 )
 
+// IsFinal reports whether a payment's status is settled: captured, or failed or cancelled
+func (s PaymentStatus) IsFinal() bool {
+	switch s {
+	case PaymentStatusCompleted, PaymentStatusFailed, PaymentStatusCancelled, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// AcceptsWebhook reports whether a provider webhook may move a payment from s to the status to.
+// Captured payments never change this way. Failed and cancelled payments only accept a capture:
+// a buyer whose card was declined may retry on the same hosted checkout and pay after all.
+func (s PaymentStatus) AcceptsWebhook(to PaymentStatus) bool {
+	switch s {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return false
+	case PaymentStatusFailed, PaymentStatusCancelled:
+		return to == PaymentStatusCompleted
+	}
+	return true
+}
+
+// IsCaptured reports whether the payment collected its money, whether or not some was refunded since
+func (s PaymentStatus) IsCaptured() bool {
+	switch s {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Refunded returns how much of the payment has been refunded. Payments refunded before refunds
+// were recorded count as refunded in full.
+func (p *Payment) Refunded() Money {
+	if p.Status == PaymentStatusRefunded && p.RefundedAmount.IsZero() {
+		return p.Amount
+	}
+	return NewMoney(p.RefundedAmount.Amount, p.Amount.Currency)
+}
+
+// NetCaptured returns what a payment has collected and kept: its amount once captured, less refunds
+func (p *Payment) NetCaptured() Money {
+	if !p.Status.IsCaptured() {
+		return NewMoney(0, p.Amount.Currency)
+	}
+	return p.Amount.Sub(p.Refunded())
+}
+
+// RefundedStatus returns the status a captured payment has after refunds of refunded in total
+func (p *Payment) RefundedStatus(refunded Money) PaymentStatus {
+	switch {
+	case !refunded.IsPositive():
+		return PaymentStatusCompleted
+	case refunded.Cmp(p.Amount) >= 0:
+		return PaymentStatusRefunded
+	}
+	return PaymentStatusPartiallyRefunded
+}
+
 // PaymentType represents the type of payment
 type PaymentType string
 
@@ -43,8 +107,8 @@ const (
 // PaymentRequest represents a request to create a payment
 type PaymentRequest struct {
 	OrderID       string            `json:"orderId"`
-	Amount        float64           `json:"amount,omitempty"`   // Optional: if not provided, calculated from order
-	PaymentMethod string            `json:"paymentMethod"`      // "stripe", "mpesa", "simulated"
+	Amount        *Money            `json:"amount,omitempty"`   // Optional: if not provided, calculated from order
+	PaymentMethod string            `json:"paymentMethod"`      // "card", "mpesa", "simulated"
 	PaymentType   string            `json:"paymentType"`        // "deposit", "full", "remaining"
 	Metadata      map[string]string `json:"metadata,omitempty"` // Additional metadata for provider
 }
@@ -53,7 +117,7 @@ type PaymentRequest struct {
 type PaymentResponse struct {
 	PaymentID     string                 `json:"paymentId"`
 	OrderID       string                 `json:"orderId"`
-	Amount        float64                `json:"amount"`
+	Amount        Money                  `json:"amount"`
 	Status        PaymentStatus          `json:"status"`
 	TransactionID string                 `json:"transactionId"`
 	PaymentURL    string                 `json:"paymentUrl,omitempty"` // For providers that need redirect
@@ -64,7 +128,71 @@ type PaymentResponse struct {
 type PaymentWebhook struct {
 	TransactionID string                 `json:"transactionId"`
 	Status        string                 `json:"status"`
-	Amount        float64                `json:"amount"`
+	Amount        Money                  `json:"amount"`
 	Metadata      map[string]interface{} `json:"metadata"`
 	Signature     string                 `json:"signature,omitempty"` // For webhook verification
+	Refund        *WebhookRefund         `json:"refund,omitempty"`    // set when the webhook is about a refund, not the payment
+}
+
+// WebhookRefund is a provider's update on a refund of the webhook's payment
+type WebhookRefund struct {
+	RefundID         string       `json:"refundId,omitempty"` // our refund ID, echoed back from the idempotency key
+	ProviderRefundID string       `json:"providerRefundId"`
+	Status           RefundStatus `json:"status"`
+	FailureReason    string       `json:"failureReason,omitempty"`
+}
+
+// PaymentEvent is a raw provider webhook as received, kept in payment_events so it can be
+// inspected and replayed. The payload is stored exactly as it arrived.
+type PaymentEvent struct {
+	ID            string              `firestore:"id" json:"id"`
+	PaymentMethod string              `firestore:"paymentMethod" json:"paymentMethod"`
+	Payload       string              `firestore:"payload" json:"payload"`
+	Header        map[string][]string `firestore:"header,omitempty" json:"header,omitempty"`
+	Query         string              `firestore:"query,omitempty" json:"query,omitempty"` // raw query string of the webhook URL
+	TransactionID string              `firestore:"transactionId,omitempty" json:"transactionId,omitempty"`
+	Status        string              `firestore:"status,omitempty" json:"status,omitempty"` // status the webhook reported
+	PaymentID     string              `firestore:"paymentId,omitempty" json:"paymentId,omitempty"`
+	OrderID       string              `firestore:"orderId,omitempty" json:"orderId,omitempty"`
+	Verified      bool                `firestore:"verified" json:"verified"` // signature checked, or the provider does not sign
+	Outcome       PaymentEventOutcome `firestore:"outcome" json:"outcome"`
+	Error         string              `firestore:"error,omitempty" json:"error,omitempty"`
+	Replays       int                 `firestore:"replays" json:"replays"`
+	ReceivedAt    time.Time           `firestore:"receivedAt" json:"receivedAt"`
+	ProcessedAt   *time.Time          `firestore:"processedAt,omitempty" json:"processedAt,omitempty"`
 }
+
+// PaymentEventOutcome records what processing a webhook did
+type PaymentEventOutcome string
+
+const (
+	PaymentEventReceived PaymentEventOutcome = "received" // stored, not processed yet
+	PaymentEventApplied  PaymentEventOutcome = "applied"  // changed the payment's status
+	PaymentEventIgnored  PaymentEventOutcome = "ignored"  // repeated, or about a payment already final
+	PaymentEventRejected PaymentEventOutcome = "rejected" // invalid: unknown method or payment, bad signature or payload
+	PaymentEventFailed   PaymentEventOutcome = "failed"   // could not be processed; safe to replay
+)
+
+// Refund is money returned from a captured payment. A payment may be refunded several times,
+// in part, up to the amount it captured.
+type Refund struct {
+	ID               string       `firestore:"id" json:"id"`
+	PaymentID        string       `firestore:"paymentId" json:"paymentId"`
+	OrderID          string       `firestore:"orderId" json:"orderId"`
+	Amount           Money        `firestore:"amount" json:"amount"`
+	Status           RefundStatus `firestore:"status" json:"status"`
+	ProviderRefundID string       `firestore:"providerRefundId,omitempty" json:"providerRefundId,omitempty"` // provider's reference for the refund
+	Reason           string       `firestore:"reason,omitempty" json:"reason,omitempty"`
+	FailureReason    string       `firestore:"failureReason,omitempty" json:"failureReason,omitempty"`
+	CreatedAt        time.Time    `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time    `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// RefundStatus represents the status of a refund
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"   // recorded; the provider has not confirmed it yet
+	RefundStatusSucceeded RefundStatus = "succeeded" // the provider returned the money
+	RefundStatusFailed    RefundStatus = "failed"    // the provider refused; its amount is refundable again
+)
@@ -0,0 +1,27 @@
+package models
+
+// Price sources recorded on a PriceBreakdown
+const (
+	PriceSourceShop    = "shop"    // matched shop's PrintService and frame pricing
+	PriceSourceCatalog = "catalog" // legacy hardcoded catalog, used when no shop can price the line
+)
+
+// PriceBreakdown explains how a price was computed. At checkout it is frozen onto each
+// order line so later changes to shop pricing never alter what the buyer agreed to pay.
+type PriceBreakdown struct {
+	Source           string  `firestore:"source,omitempty" json:"source,omitempty"`
+	ShopID           string  `firestore:"shopId,omitempty" json:"shopId,omitempty"`
+	ServiceID        string  `firestore:"serviceId,omitempty" json:"serviceId,omitempty"`
+	FrameID          string  `firestore:"frameId,omitempty" json:"frameId,omitempty"`
+	BasePrice        Money   `firestore:"basePrice" json:"basePrice"`           // per unit
+	SizeModifier     float64 `firestore:"sizeModifier" json:"sizeModifier"`     // multiplier
+	MaterialMarkup   float64 `firestore:"materialMarkup" json:"materialMarkup"` // multiplier
+	MediumMarkup     float64 `firestore:"mediumMarkup" json:"mediumMarkup"`     // multiplier
+	FramePrice       Money   `firestore:"framePrice" json:"framePrice"`         // per unit
+	Quantity         int     `firestore:"quantity" json:"quantity"`
+	QuantityDiscount float64 `firestore:"quantityDiscount" json:"quantityDiscount"` // fraction, e.g. 0.1
+	RushOrderFee     Money   `firestore:"rushOrderFee" json:"rushOrderFee"`         // per unit
+	Subtotal         Money   `firestore:"subtotal" json:"subtotal"`                 // before quantity discount and rush fees
+	UnitPrice        Money   `firestore:"unitPrice" json:"unitPrice"`
+	Total            Money   `firestore:"total" json:"total"`
+}
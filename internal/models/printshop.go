@@ -16,6 +16,12 @@ type PrintShopProfile struct {
 	Capabilities []string    `firestore:"capabilities" json:"capabilities"`
 	CreatedAt    time.Time   `firestore:"createdAt" json:"createdAt"`
 	UpdatedAt    time.Time   `firestore:"updatedAt" json:"updatedAt"`
+
+	// Calendar sets the days and hours the shop produces orders; nil means DefaultBusinessCalendar.
+	// MaxBacklogDays is how many working days of queued work a service may hold before
+	// matching stops offering it new orders; 0 means the matching default.
+	Calendar       *BusinessCalendar `firestore:"calendar,omitempty" json:"calendar,omitempty"`
+	MaxBacklogDays int               `firestore:"maxBacklogDays,omitempty" json:"maxBacklogDays,omitempty"`
 }
 
 // PrintService represents a specific service offered by a print shop
@@ -34,6 +40,10 @@ type PrintService struct {
 	IsActive      bool               `firestore:"isActive" json:"isActive"`
 	CreatedAt     time.Time          `firestore:"createdAt" json:"createdAt"`
 	UpdatedAt     time.Time          `firestore:"updatedAt" json:"updatedAt"`
+
+	// Throughput used for delivery estimates; 0 leaves capacity unlimited and lead time at the default
+	DailyCapacity int `firestore:"dailyCapacity,omitempty" json:"dailyCapacity,omitempty"` // units produced per working day
+	LeadTimeDays  int `firestore:"leadTimeDays,omitempty" json:"leadTimeDays,omitempty"`   // working days to turn a job around
 }
 
 // TechnologyDetails provides optional metadata about production method
@@ -68,6 +78,11 @@ type PrintOrderOptions struct {
 	Frame     string `firestore:"frame" json:"frame"`
 	Quantity  int    `firestore:"quantity" json:"quantity"`
 	RushOrder bool   `firestore:"rushOrder" json:"rushOrder"`
+
+	// DeliveryLocation is where the buyer wants the order, or collects it from for pickup
+	DeliveryLocation *Location `firestore:"deliveryLocation,omitempty" json:"deliveryLocation,omitempty"`
+	// PickupRadiusKm limits pickup orders to shops within this distance of DeliveryLocation; 0 means no limit
+	PickupRadiusKm float64 `firestore:"pickupRadiusKm,omitempty" json:"pickupRadiusKm,omitempty"`
 }
 
 // ShopMatch represents a matched print shop for an order
@@ -75,10 +90,26 @@ type ShopMatch struct {
 	ShopID       string  `firestore:"shopId" json:"shopId"`
 	ShopName     string  `firestore:"shopName" json:"shopName"`
 	ServiceID    string  `firestore:"serviceId" json:"serviceId"`
-	TotalPrice   float64 `firestore:"totalPrice" json:"totalPrice"`
+	TotalPrice   Money   `firestore:"totalPrice" json:"totalPrice"`
 	DeliveryDays int     `firestore:"deliveryDays" json:"deliveryDays"`
 	MatchScore   float64 `firestore:"matchScore" json:"matchScore"`
 	Technology   string  `firestore:"technology" json:"technology"`
+
+	// ReadyBy is when the shop's queue and calendar let it finish the order; DeliveryDays counts to it
+	ReadyBy time.Time `firestore:"readyBy" json:"readyBy"`
+	// DistanceKm from the buyer's location, when both locations have coordinates
+	DistanceKm *float64 `firestore:"distanceKm,omitempty" json:"distanceKm,omitempty"`
+
+	// ScoreBreakdown explains MatchScore factor by factor (smart matching only)
+	ScoreBreakdown []ScoreFactor `firestore:"scoreBreakdown,omitempty" json:"scoreBreakdown,omitempty"`
+}
+
+// ServiceRejection explains why a shop service cannot fulfill the requested print options
+type ServiceRejection struct {
+	ShopID    string `json:"shopId"`
+	ServiceID string `json:"serviceId"`
+	Field     string `json:"field"` // size, material, medium, frame, capacity, distance
+	Reason    string `json:"reason"`
 }
 
 // Supporting types
@@ -87,6 +118,15 @@ type Location struct {
 	City    string `firestore:"city" json:"city"`
 	State   string `firestore:"state" json:"state"`
 	Country string `firestore:"country" json:"country"`
+
+	// Coordinates in decimal degrees, filled in by a geocoder when not supplied
+	Latitude  float64 `firestore:"latitude,omitempty" json:"latitude,omitempty"`
+	Longitude float64 `firestore:"longitude,omitempty" json:"longitude,omitempty"`
+}
+
+// HasCoordinates reports whether the location has been placed on the map
+func (l Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
 }
 
 type ContactInfo struct {
@@ -109,6 +149,14 @@ type Frame struct {
 	IsActive    bool               `firestore:"isActive" json:"isActive"`
 	CreatedAt   time.Time          `firestore:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time          `firestore:"updatedAt" json:"updatedAt"`
+
+	// Image processing state for frames uploaded through /printshop/frames/upload
+	CloudinaryPublicID string                 `firestore:"cloudinaryPublicId,omitempty" json:"cloudinaryPublicId,omitempty"`
+	CloudinaryFolder   string                 `firestore:"cloudinaryFolder,omitempty" json:"cloudinaryFolder,omitempty"`
+	ProcessingStatus   string                 `firestore:"processingStatus,omitempty" json:"processingStatus,omitempty"`
+	ProcessingErrors   []string               `firestore:"processingErrors,omitempty" json:"processingErrors,omitempty"`
+	Analysis           map[string]interface{} `firestore:"analysis,omitempty" json:"analysis,omitempty"`
+	Admin              *AdminResolution       `firestore:"admin,omitempty" json:"admin,omitempty"`
 }
 
 // PrintSize configuration for print shops
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ProcessingJob is an entry in the processing_queue collection, consumed by the worker
+type ProcessingJob struct {
+	ID         string          `firestore:"-" json:"id"`
+	ArtworkID  string          `firestore:"artworkId,omitempty" json:"artworkId,omitempty"`
+	FrameID    string          `firestore:"frameId,omitempty" json:"frameId,omitempty"`
+	Status     string          `firestore:"status" json:"status"` // "pending", "processing", "done", "failed"
+	Cloudinary CloudinaryAsset `firestore:"cloudinary" json:"cloudinary"`
+	Error      string          `firestore:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time       `firestore:"createdAt" json:"createdAt"`
+	StartedAt  *time.Time      `firestore:"startedAt,omitempty" json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `firestore:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// CloudinaryAsset identifies an uploaded original on Cloudinary
+type CloudinaryAsset struct {
+	SecureURL string `firestore:"secureUrl" json:"secureUrl"`
+	PublicID  string `firestore:"publicId" json:"publicId"`
+	Folder    string `firestore:"folder" json:"folder"`
+}
+
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusDone       = "done"
+	JobStatusFailed     = "failed"
+)
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// PromotionType is how a promotion discounts an order
+type PromotionType string
+
+const (
+	PromotionPercentage   PromotionType = "percentage"    // Value percent off eligible lines
+	PromotionFixed        PromotionType = "fixed"         // Value off eligible lines, spread across them
+	PromotionFreeShipping PromotionType = "free_shipping" // waives shipping on parcels with eligible lines
+)
+
+// Promotion is an admin-managed coupon redeemed by code at checkout
+type Promotion struct {
+	ID          string        `firestore:"id" json:"id"`
+	Code        string        `firestore:"code" json:"code"` // stored upper-case; unique
+	Description string        `firestore:"description,omitempty" json:"description,omitempty"`
+	Type        PromotionType `firestore:"type" json:"type"`
+	Value       float64       `firestore:"value" json:"value"`                                 // percent, or major units off; unused for free shipping
+	MaxDiscount float64       `firestore:"maxDiscount,omitempty" json:"maxDiscount,omitempty"` // major units; caps percentage discounts; 0 = no cap
+	MinSubtotal float64       `firestore:"minSubtotal,omitempty" json:"minSubtotal,omitempty"` // major units the eligible lines must add up to
+
+	// Scope: when set, only lines of this artist's artworks or fulfilled by this shop are discounted
+	ArtistID       string `firestore:"artistId,omitempty" json:"artistId,omitempty"`
+	ShopID         string `firestore:"shopId,omitempty" json:"shopId,omitempty"`
+	FirstOrderOnly bool   `firestore:"firstOrderOnly" json:"firstOrderOnly"`
+
+	// Limits; 0 means unlimited and zero times mean no bound
+	UsageLimit      int       `firestore:"usageLimit" json:"usageLimit"`
+	PerBuyerLimit   int       `firestore:"perBuyerLimit" json:"perBuyerLimit"`
+	RedemptionCount int       `firestore:"redemptionCount" json:"redemptionCount"`
+	StartsAt        time.Time `firestore:"startsAt,omitempty" json:"startsAt,omitempty"`
+	ExpiresAt       time.Time `firestore:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	IsActive  bool      `firestore:"isActive" json:"isActive"`
+	CreatedBy string    `firestore:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedAt time.Time `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// NormalizeCode returns the canonical form of a coupon code
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the promotion is well formed
+func (p *Promotion) Validate() error {
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("percentage value must be between 0 and 100")
+		}
+	case PromotionFixed:
+		if p.Value <= 0 {
+			return errors.New("fixed value must be positive")
+		}
+	case PromotionFreeShipping:
+	default:
+		return errors.New("type must be percentage, fixed or free_shipping")
+	}
+	if p.MaxDiscount < 0 || p.MinSubtotal < 0 || p.UsageLimit < 0 || p.PerBuyerLimit < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if !p.StartsAt.IsZero() && !p.ExpiresAt.IsZero() && !p.ExpiresAt.After(p.StartsAt) {
+		return errors.New("expiresAt must be after startsAt")
+	}
+	return nil
+}
+
+// PromotionRedemption records one use of a promotion on an order
+type PromotionRedemption struct {
+	ID          string    `firestore:"id" json:"id"`
+	PromotionID string    `firestore:"promotionId" json:"promotionId"`
+	Code        string    `firestore:"code" json:"code"`
+	BuyerID     string    `firestore:"buyerId" json:"buyerId"`
+	OrderID     string    `firestore:"orderId" json:"orderId"`
+	Amount      Money     `firestore:"amount" json:"amount"`
+	CreatedAt   time.Time `firestore:"createdAt" json:"createdAt"`
+}
+
+// OrderDiscount is a promotion applied to an order. The amount is split across the lines and
+// shipping it discounted so each part is taxed on what was actually paid for it.
+type OrderDiscount struct {
+	PromotionID string         `firestore:"promotionId" json:"promotionId"`
+	Code        string         `firestore:"code" json:"code"`
+	Type        PromotionType  `firestore:"type" json:"type"`
+	Amount      Money          `firestore:"amount" json:"amount"`
+	Lines       []LineDiscount `firestore:"lines,omitempty" json:"lines,omitempty"`
+	Shipping    Money          `firestore:"shipping" json:"shipping"` // share taken off shipping
+}
+
+// LineDiscount is the share of a discount taken off one order line
+type LineDiscount struct {
+	LineID string `firestore:"lineId" json:"lineId"`
+	Amount Money  `firestore:"amount" json:"amount"`
+}
+
+// LineDiscount returns the total discount taken off the line with the given ID
+func (o *Order) LineDiscount(lineID string) Money {
+	var total Money
+	for _, d := range o.Discounts {
+		for _, l := range d.Lines {
+			if l.LineID == lineID {
+				total = total.Add(l.Amount)
+			}
+		}
+	}
+	return total
+}
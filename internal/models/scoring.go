@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Smart scoring factor names, as reported in ShopMatch.ScoreBreakdown
+const (
+	FactorPrice      = "price"
+	FactorRating     = "rating"
+	FactorDelivery   = "delivery"
+	FactorTechnology = "technology"
+	FactorDistance   = "distance"
+	FactorLoad       = "load"
+	FactorIssueRate  = "issueRate"
+)
+
+// ScoringWeights sets how much each factor contributes to a smart match score.
+// Weights are divided by their sum, so they do not need to add up to 1.
+type ScoringWeights struct {
+	Price      float64 `firestore:"price" json:"price"`
+	Rating     float64 `firestore:"rating" json:"rating"`
+	Delivery   float64 `firestore:"delivery" json:"delivery"`
+	Technology float64 `firestore:"technology" json:"technology"`
+	Distance   float64 `firestore:"distance" json:"distance"`
+	Load       float64 `firestore:"load" json:"load"`
+	IssueRate  float64 `firestore:"issueRate" json:"issueRate"`
+}
+
+// Sum returns the total of all weights
+func (w ScoringWeights) Sum() float64 {
+	return w.Price + w.Rating + w.Delivery + w.Technology + w.Distance + w.Load + w.IssueRate
+}
+
+// SmartScoringConfig holds the tunable weights and tables the smart matcher scores with.
+// It is stored in settings/scoring and edited by admins.
+type SmartScoringConfig struct {
+	Weights                ScoringWeights     `firestore:"weights" json:"weights"`
+	RatingScale            float64            `firestore:"ratingScale" json:"ratingScale"`                         // highest possible shop rating
+	MaxDeliveryDays        int                `firestore:"maxDeliveryDays" json:"maxDeliveryDays"`                 // deliveries this slow or slower score 0
+	TechnologyScores       map[string]float64 `firestore:"technologyScores" json:"technologyScores"`               // technology type -> 0-100
+	DefaultTechnologyScore float64            `firestore:"defaultTechnologyScore" json:"defaultTechnologyScore"`   // score for unlisted technologies
+	MaxOpenOrders          int                `firestore:"maxOpenOrders" json:"maxOpenOrders"`                     // open orders at which the load score reaches 0
+	MaxDistanceKm          float64            `firestore:"maxDistanceKm,omitempty" json:"maxDistanceKm,omitempty"` // distance at which the distance score reaches 0
+	UpdatedBy              string             `firestore:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt              time.Time          `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// DefaultMaxDistanceKm applies to scoring configs saved before maxDistanceKm existed
+const DefaultMaxDistanceKm = 100
+
+// DefaultSmartScoringConfig returns the scoring used until admins save their own. It keeps the
+// original 40/30/20/10 split of price, rating, delivery and technology; distance, load and issue
+// rate are scored and reported but weigh nothing until admins give them a weight.
+func DefaultSmartScoringConfig() *SmartScoringConfig {
+	return &SmartScoringConfig{
+		Weights: ScoringWeights{
+			Price:      0.4,
+			Rating:     0.3,
+			Delivery:   0.2,
+			Technology: 0.1,
+		},
+		RatingScale:     5,
+		MaxDeliveryDays: 10,
+		TechnologyScores: map[string]float64{
+			"giclée":          90.0,
+			"dye-sublimation": 85.0,
+			"inkjet":          80.0,
+			"laser":           75.0,
+			"offset":          70.0,
+		},
+		DefaultTechnologyScore: 70,
+		MaxOpenOrders:          20,
+		MaxDistanceKm:          DefaultMaxDistanceKm,
+	}
+}
+
+// Validate checks that the config can produce meaningful scores
+func (c *SmartScoringConfig) Validate() error {
+	w := c.Weights
+	for _, v := range []float64{w.Price, w.Rating, w.Delivery, w.Technology, w.Distance, w.Load, w.IssueRate} {
+		if v < 0 {
+			return errors.New("weights must not be negative")
+		}
+	}
+	if w.Sum() <= 0 {
+		return errors.New("at least one weight must be positive")
+	}
+	if c.RatingScale <= 0 {
+		return errors.New("ratingScale must be positive")
+	}
+	if c.MaxDeliveryDays <= 0 {
+		return errors.New("maxDeliveryDays must be positive")
+	}
+	if c.MaxOpenOrders <= 0 {
+		return errors.New("maxOpenOrders must be positive")
+	}
+	if c.MaxDistanceKm < 0 {
+		return errors.New("maxDistanceKm must not be negative")
+	}
+	for tech, score := range c.TechnologyScores {
+		if score < 0 || score > 100 {
+			return errors.New("technology score for " + tech + " must be between 0 and 100")
+		}
+	}
+	return nil
+}
+
+// ScoreFactor is one factor's contribution to a smart match score
+type ScoreFactor struct {
+	Factor       string  `firestore:"factor" json:"factor"`
+	Score        float64 `firestore:"score" json:"score"`               // 0-100
+	Weight       float64 `firestore:"weight" json:"weight"`             // normalised share of the total
+	Contribution float64 `firestore:"contribution" json:"contribution"` // score × weight
+	Detail       string  `firestore:"detail,omitempty" json:"detail,omitempty"`
+}
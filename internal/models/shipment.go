@@ -0,0 +1,73 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Delivery methods a buyer can choose at checkout, stored in Order.DeliveryMethod
+const (
+	DeliveryMethodShipping = "shipping"
+	DeliveryMethodPickup   = "pickup"
+)
+
+// Order delivery statuses, stored in Order.DeliveryStatus. Pickup orders stop at ready.
+const (
+	DeliveryPending    = "pending"
+	DeliveryProcessing = "processing"
+	DeliveryReady      = "ready" // packed; waiting for the courier or for the buyer to collect
+	DeliveryInTransit  = "in_transit"
+	DeliveryDelivered  = "delivered"
+	DeliveryFailed     = "failed" // the courier could not deliver; the shop or an admin follows up
+)
+
+// Shipment is a parcel booked with a courier for one sub-order
+type Shipment struct {
+	ID             string          `firestore:"id" json:"id"`
+	OrderID        string          `firestore:"orderId" json:"orderId"`
+	BuyerID        string          `firestore:"buyerId" json:"buyerId"`
+	PrintShopID    string          `firestore:"printShopId" json:"printShopId"`
+	Courier        string          `firestore:"courier" json:"courier"` // provider name, e.g. "simulated"
+	TrackingNumber string          `firestore:"trackingNumber" json:"trackingNumber"`
+	Status         string          `firestore:"status" json:"status"` // latest delivery status reported
+	Events         []TrackingEvent `firestore:"events" json:"events"` // oldest first
+	CreatedAt      time.Time       `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time       `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// Record adds a tracking event in time order and takes the status of the latest one.
+// It reports false, changing nothing, when an event with the same ID is already recorded.
+func (s *Shipment) Record(event TrackingEvent) bool {
+	for _, e := range s.Events {
+		if e.ID == event.ID {
+			return false
+		}
+	}
+	i := len(s.Events)
+	for i > 0 && s.Events[i-1].OccurredAt.After(event.OccurredAt) {
+		i--
+	}
+	s.Events = slices.Insert(s.Events, i, event)
+	s.Status = s.Events[len(s.Events)-1].Status
+	return true
+}
+
+// TrackingEvent is one step of a shipment's journey
+type TrackingEvent struct {
+	ID          string    `firestore:"id" json:"id"` // courier event ID; repeats are ignored
+	Status      string    `firestore:"status" json:"status"`
+	Description string    `firestore:"description,omitempty" json:"description,omitempty"`
+	Location    string    `firestore:"location,omitempty" json:"location,omitempty"`
+	OccurredAt  time.Time `firestore:"occurredAt" json:"occurredAt"`
+}
+
+// CourierWebhook represents a tracking update pushed by a courier
+type CourierWebhook struct {
+	EventID        string    `json:"eventId"`
+	TrackingNumber string    `json:"trackingNumber"`
+	Status         string    `json:"status"`
+	Description    string    `json:"description,omitempty"`
+	Location       string    `json:"location,omitempty"`
+	OccurredAt     time.Time `json:"occurredAt"`
+	Signature      string    `json:"signature,omitempty"` // For webhook verification
+}
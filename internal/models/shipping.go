@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Address is a shipping address saved on a buyer's profile
+type Address struct {
+	ID            string   `firestore:"id" json:"id"`
+	Label         string   `firestore:"label,omitempty" json:"label,omitempty"` // e.g. "Home", "Studio"
+	RecipientName string   `firestore:"recipientName" json:"recipientName"`
+	Phone         string   `firestore:"phone,omitempty" json:"phone,omitempty"`
+	Location      Location `firestore:"location" json:"location"`
+	PostalCode    string   `firestore:"postalCode,omitempty" json:"postalCode,omitempty"`
+	IsDefault     bool     `firestore:"isDefault" json:"isDefault"`
+}
+
+// Validate checks that the address is complete enough to ship to
+func (a *Address) Validate() error {
+	switch {
+	case strings.TrimSpace(a.RecipientName) == "":
+		return errors.New("recipientName is required")
+	case strings.TrimSpace(a.Location.Address) == "":
+		return errors.New("location.address is required")
+	case strings.TrimSpace(a.Location.City) == "":
+		return errors.New("location.city is required")
+	case strings.TrimSpace(a.Location.Country) == "":
+		return errors.New("location.country is required")
+	}
+	return nil
+}
+
+// Address returns the saved address with the given ID, or nil
+func (u *User) Address(id string) *Address {
+	for i := range u.Addresses {
+		if u.Addresses[i].ID == id {
+			return &u.Addresses[i]
+		}
+	}
+	return nil
+}
+
+// DefaultAddress returns the address marked default, else the first one, or nil when none are saved
+func (u *User) DefaultAddress() *Address {
+	for i := range u.Addresses {
+		if u.Addresses[i].IsDefault {
+			return &u.Addresses[i]
+		}
+	}
+	if len(u.Addresses) > 0 {
+		return &u.Addresses[0]
+	}
+	return nil
+}
+
+// ShippingQuote is the price of shipping one parcel, as quoted by a shipping rate provider
+type ShippingQuote struct {
+	Provider      string  `firestore:"provider" json:"provider"`
+	Zone          string  `firestore:"zone" json:"zone"` // e.g. local, domestic, international
+	WeightKg      float64 `firestore:"weightKg" json:"weightKg"`
+	Amount        Money   `firestore:"amount" json:"amount"`
+	EstimatedDays int     `firestore:"estimatedDays" json:"estimatedDays"` // transit time once the parcel ships
+}
+
+// Format renders the location as a single comma-separated line
+func (l Location) Format() string {
+	var parts []string
+	for _, p := range []string{l.Address, l.City, l.State, l.Country} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
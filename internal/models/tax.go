@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaxClass groups what is sold so each class can be taxed at its own rate
+type TaxClass string
+
+const (
+	TaxClassPrint    TaxClass = "print"    // the print itself and any rush or finishing fees
+	TaxClassFrame    TaxClass = "frame"    // frames sold with a print
+	TaxClassShipping TaxClass = "shipping" // delivery charges
+)
+
+// TaxLine is the tax charged on one class of an order in one jurisdiction
+type TaxLine struct {
+	Jurisdiction string   `firestore:"jurisdiction" json:"jurisdiction"`     // e.g. "KE" or "US-CA"
+	Name         string   `firestore:"name,omitempty" json:"name,omitempty"` // e.g. "VAT"
+	Class        TaxClass `firestore:"class" json:"class"`
+	Rate         float64  `firestore:"rate" json:"rate"`       // fraction, e.g. 0.16
+	Taxable      Money    `firestore:"taxable" json:"taxable"` // amount taxed, net of discounts and of the tax itself
+	Amount       Money    `firestore:"amount" json:"amount"`
+	Inclusive    bool     `firestore:"inclusive" json:"inclusive"` // already part of the prices rather than added to them
+}
+
+// AddedTax returns the tax that was added on top of prices, as opposed to included in them
+func (o *Order) AddedTax() Money {
+	var total Money
+	for _, t := range o.TaxLines {
+		if !t.Inclusive {
+			total = total.Add(t.Amount)
+		}
+	}
+	return total
+}
+
+// TaxJurisdiction is how one country, or one state of a country, taxes sales
+type TaxJurisdiction struct {
+	Country   string               `firestore:"country" json:"country"`                 // ISO code or name, e.g. "KE"
+	State     string               `firestore:"state,omitempty" json:"state,omitempty"` // overrides the country's entry when set
+	Name      string               `firestore:"name" json:"name"`                       // tax name shown on tax lines, e.g. "VAT"
+	Rates     map[TaxClass]float64 `firestore:"rates" json:"rates"`                     // fraction per class; a missing class is not taxed
+	Inclusive bool                 `firestore:"inclusive" json:"inclusive"`             // prices already include the tax
+}
+
+// TaxSettings lists the jurisdictions orders are taxed in, stored in settings/tax
+type TaxSettings struct {
+	Jurisdictions []TaxJurisdiction `firestore:"jurisdictions" json:"jurisdictions"`
+	UpdatedBy     string            `firestore:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+	UpdatedAt     time.Time         `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// DefaultTaxSettings applies until admins save their own: Kenyan VAT at 16%, included in prices
+func DefaultTaxSettings() *TaxSettings {
+	return &TaxSettings{Jurisdictions: []TaxJurisdiction{{
+		Country: "KE",
+		Name:    "VAT",
+		Rates: map[TaxClass]float64{
+			TaxClassPrint:    0.16,
+			TaxClassFrame:    0.16,
+			TaxClassShipping: 0.16,
+		},
+		Inclusive: true,
+	}}}
+}
+
+// Validate checks every jurisdiction names a country and taxes known classes at rates below 100%
+func (s *TaxSettings) Validate() error {
+	seen := make(map[string]bool)
+	for _, j := range s.Jurisdictions {
+		if strings.TrimSpace(j.Country) == "" {
+			return errors.New("every jurisdiction needs a country")
+		}
+		key := strings.ToUpper(strings.TrimSpace(j.Country)) + "|" + strings.ToUpper(strings.TrimSpace(j.State))
+		if seen[key] {
+			return fmt.Errorf("jurisdiction %s is listed twice", strings.Trim(strings.ReplaceAll(key, "|", "-"), "-"))
+		}
+		seen[key] = true
+		for class, rate := range j.Rates {
+			switch class {
+			case TaxClassPrint, TaxClassFrame, TaxClassShipping:
+			default:
+				return fmt.Errorf("unknown tax class %q", class)
+			}
+			if rate < 0 || rate >= 1 {
+				return fmt.Errorf("rate for %s in %s must be a fraction from 0 up to 1", class, j.Country)
+			}
+		}
+	}
+	return nil
+}
@@ -6,58 +6,63 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	vision "cloud.google.com/go/vision/apiv1"
-	"github.com/cecvl/art-print-backend/internal/firebase"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 	visionpb "google.golang.org/genproto/googleapis/cloud/vision/v1"
 )
 
-// StartWorker polls the Firestore processing_queue for pending jobs and processes them.
+// Worker consumes the processing queue and writes analysis results back to artworks and frames
+type Worker struct {
+	queue    repositories.ProcessingQueueRepository
+	artworks repositories.ArtworkRepository
+	frames   repositories.FrameRepository
+}
+
+// NewWorker creates a worker over the given repositories
+func NewWorker(queue repositories.ProcessingQueueRepository, artworks repositories.ArtworkRepository, frames repositories.FrameRepository) *Worker {
+	return &Worker{queue: queue, artworks: artworks, frames: frames}
+}
+
+// Start polls the processing queue for pending jobs and processes them.
 // This is intentionally minimal: it runs SafeSearch on the image URL and writes results
 // to the artwork document under `analysis.safeSearch`.
-func StartWorker(ctx context.Context) error {
+func (wk *Worker) Start(ctx context.Context) error {
 	client, err := vision.NewImageAnnotatorClient(ctx)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	fs := firebase.FirestoreClient
-
 	log.Println("▶️ Processing worker started")
 	for {
 		// find pending jobs
-		q := fs.Collection("processing_queue").Where("status", "==", "pending").Limit(5)
-		snaps, err := q.Documents(ctx).GetAll()
+		jobs, err := wk.queue.GetPendingJobs(ctx, 5)
 		if err != nil {
 			log.Printf("⚠️ Failed to query processing_queue: %v", err)
 			time.Sleep(5 * time.Second)
 			continue
 		}
 
-		if len(snaps) == 0 {
+		if len(jobs) == 0 {
 			time.Sleep(2 * time.Second)
 			continue
 		}
 
-		for _, s := range snaps {
-			go func(s *firestore.DocumentSnapshot) {
-				jobRef := s.Ref
-				jobData := s.Data()
-				artworkId, _ := jobData["artworkId"].(string)
-				frameId, _ := jobData["frameId"].(string)
-				cloudInfo, _ := jobData["cloudinary"].(map[string]interface{})
-				imgUrl, _ := cloudInfo["secureUrl"].(string)
+		for _, job := range jobs {
+			go func(job *models.ProcessingJob) {
+				artworkId := job.ArtworkID
+				frameId := job.FrameID
+				imgUrl := job.Cloudinary.SecureURL
 
 				// mark job processing
-				jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "processing"}, {Path: "startedAt", Value: time.Now()}})
-
+				wk.queue.MarkProcessing(ctx, job.ID)
 				// run SafeSearch
 				visImg := vision.NewImageFromURI(imgUrl)
 				res, err := client.DetectSafeSearch(ctx, visImg, nil)
 				if err != nil {
 					log.Printf("❌ SafeSearch failed for artwork %s: %v", artworkId, err)
-					jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "failed"}, {Path: "error", Value: err.Error()}})
+					wk.queue.MarkFailed(ctx, job.ID, err.Error())
 					return
 				}
 
@@ -85,7 +90,7 @@ func StartWorker(ctx context.Context) error {
 				img, format, err := fetchImage(imgUrl)
 				if err != nil {
 					log.Printf("❌ Failed to fetch image for artwork %s: %v", artworkId, err)
-					jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "failed"}, {Path: "error", Value: err.Error()}})
+					wk.queue.MarkFailed(ctx, job.ID, err.Error())
 					return
 				}
 
@@ -151,31 +156,29 @@ func StartWorker(ctx context.Context) error {
 					}
 
 					// Persist to frames doc
-					frameRef := firebase.FirestoreClient.Collection("frames").Doc(frameId)
-					_, err = frameRef.Set(ctx, map[string]interface{}{"analysis": analysis, "processingStatus": procStatus, "processingErrors": procErrors}, firestore.MergeAll)
+					err = wk.frames.UpdateFrame(ctx, frameId, map[string]interface{}{"analysis": analysis, "processingStatus": procStatus, "processingErrors": procErrors})
 					if err != nil {
 						log.Printf("❌ Failed to update frame %s after analysis: %v", frameId, err)
-						jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "failed"}, {Path: "error", Value: err.Error()}})
+						wk.queue.MarkFailed(ctx, job.ID, err.Error())
 						return
 					}
 
-					jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "done"}, {Path: "finishedAt", Value: time.Now()}})
-					log.Printf("✅ Processed frame %s (job %s) - status=%s", frameId, jobRef.ID, procStatus)
+					wk.queue.MarkDone(ctx, job.ID)
+					log.Printf("✅ Processed frame %s (job %s) - status=%s", frameId, job.ID, procStatus)
 					return
 				}
 
 				// Persist results to artwork doc
-				artRef := firebase.FirestoreClient.Collection("artworks").Doc(artworkId)
-				_, err = artRef.Set(ctx, map[string]interface{}{"analysis": analysis, "processingStatus": procStatus, "processingErrors": procErrors}, firestore.MergeAll)
+				err = wk.artworks.UpdateArtwork(ctx, artworkId, map[string]interface{}{"analysis": analysis, "processingStatus": procStatus, "processingErrors": procErrors})
 				if err != nil {
 					log.Printf("❌ Failed to update artwork %s after analysis: %v", artworkId, err)
-					jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "failed"}, {Path: "error", Value: err.Error()}})
+					wk.queue.MarkFailed(ctx, job.ID, err.Error())
 					return
 				}
 
-				jobRef.Update(ctx, []firestore.Update{{Path: "status", Value: "done"}, {Path: "finishedAt", Value: time.Now()}})
-				log.Printf("✅ Processed artwork %s (job %s) - status=%s", artworkId, jobRef.ID, procStatus)
-			}(s)
+				wk.queue.MarkDone(ctx, job.ID)
+				log.Printf("✅ Processed artwork %s (job %s) - status=%s", artworkId, job.ID, procStatus)
+			}(job)
 		}
 
 		// small sleep to permit other loops
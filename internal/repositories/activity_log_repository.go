@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Append-only collections written through ActivityLogRepository
+const (
+	LogAdminActions    = "admin_actions"
+	LogAssignments     = "assignments"
+	LogServiceChanges  = "services_changes"
+	LogPrintShopIssues = "printshop_issues"
+)
+
+// ActivityLogRepository appends audit-style entries to named logs.
+// Entries are free-form maps; a createdAt timestamp is added when missing.
+type ActivityLogRepository interface {
+	Record(ctx context.Context, logName string, entry map[string]interface{}) error
+	// Count returns how many entries in the named log have field equal to value and were
+	// created at or after since; a zero since counts every entry
+	Count(ctx context.Context, logName, field string, value interface{}, since time.Time) (int, error)
+}
+
+// FirestoreActivityLogRepository writes each log to its own collection
+type FirestoreActivityLogRepository struct {
+	client *firestore.Client
+}
+
+// NewActivityLogRepository creates a new Firestore-backed activity log
+func NewActivityLogRepository(client *firestore.Client) *FirestoreActivityLogRepository {
+	return &FirestoreActivityLogRepository{client: client}
+}
+
+// Record adds an entry to the named log collection
+func (r *FirestoreActivityLogRepository) Record(ctx context.Context, logName string, entry map[string]interface{}) error {
+	if _, ok := entry["createdAt"]; !ok {
+		entry["createdAt"] = time.Now()
+	}
+	if _, _, err := r.client.Collection(logName).Add(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write %s entry: %w", logName, err)
+	}
+	return nil
+}
+
+// Count returns how many entries in the named log collection have field equal to value
+// and were created at or after since
+func (r *FirestoreActivityLogRepository) Count(ctx context.Context, logName, field string, value interface{}, since time.Time) (int, error) {
+	q := r.client.Collection(logName).Where(field, "==", value)
+	if !since.IsZero() {
+		q = q.Where("createdAt", ">=", since)
+	}
+	iter := q.Select().Documents(ctx)
+	defer iter.Stop()
+
+	count := 0
+	for {
+		_, err := iter.Next()
+		if err == iterator.Done {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s entries: %w", logName, err)
+		}
+		count++
+	}
+}
+
+// MemoryActivityLogRepository keeps log entries in process memory
+type MemoryActivityLogRepository struct {
+	mu      sync.Mutex
+	entries map[string][]map[string]interface{}
+}
+
+// NewMemoryActivityLogRepository creates an empty in-memory activity log
+func NewMemoryActivityLogRepository() *MemoryActivityLogRepository {
+	return &MemoryActivityLogRepository{entries: make(map[string][]map[string]interface{})}
+}
+
+func (r *MemoryActivityLogRepository) Record(ctx context.Context, logName string, entry map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := entry["createdAt"]; !ok {
+		entry["createdAt"] = time.Now()
+	}
+	r.entries[logName] = append(r.entries[logName], entry)
+	return nil
+}
+
+func (r *MemoryActivityLogRepository) Count(ctx context.Context, logName, field string, value interface{}, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, entry := range r.entries[logName] {
+		if entry[field] != value {
+			continue
+		}
+		if createdAt, ok := entry["createdAt"].(time.Time); ok && createdAt.Before(since) {
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Entries returns everything recorded to the named log, oldest first
+func (r *MemoryActivityLogRepository) Entries(logName string) []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]interface{}(nil), r.entries[logName]...)
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ArtworkRepository stores artist uploads and their processing state
+type ArtworkRepository interface {
+	CreateArtwork(ctx context.Context, artwork *models.Artwork) error
+	GetArtworkByID(ctx context.Context, artworkID string) (*models.Artwork, error)
+	ListArtworks(ctx context.Context, filter ArtworkFilter) ([]*models.Artwork, error)
+	// UpdateArtwork merges the given fields into the artwork
+	UpdateArtwork(ctx context.Context, artworkID string, updates map[string]interface{}) error
+}
+
+// ArtworkFilter narrows ListArtworks; zero values are ignored
+type ArtworkFilter struct {
+	ArtistID         string
+	ProcessingStatus string
+	CreatedAfter     time.Time
+	CreatedBefore    time.Time
+	Sort             SortOrder
+	Limit            int
+}
+
+// FirestoreArtworkRepository handles artwork data operations in Firestore
+type FirestoreArtworkRepository struct {
+	client *firestore.Client
+}
+
+// NewArtworkRepository creates a new Firestore-backed artwork repository
+func NewArtworkRepository(client *firestore.Client) *FirestoreArtworkRepository {
+	return &FirestoreArtworkRepository{client: client}
+}
+
+// CreateArtwork creates a new artwork document
+func (r *FirestoreArtworkRepository) CreateArtwork(ctx context.Context, artwork *models.Artwork) error {
+	ref := r.client.Collection("artworks").NewDoc()
+	if artwork.ID != "" {
+		ref = r.client.Collection("artworks").Doc(artwork.ID)
+	}
+	artwork.ID = ref.ID
+	if artwork.CreatedAt.IsZero() {
+		artwork.CreatedAt = time.Now()
+	}
+
+	if _, err := ref.Set(ctx, artwork); err != nil {
+		return fmt.Errorf("failed to create artwork: %w", err)
+	}
+	return nil
+}
+
+// GetArtworkByID retrieves an artwork by its ID
+func (r *FirestoreArtworkRepository) GetArtworkByID(ctx context.Context, artworkID string) (*models.Artwork, error) {
+	doc, err := r.client.Collection("artworks").Doc(artworkID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get artwork: %w", err)
+	}
+
+	var artwork models.Artwork
+	if err := doc.DataTo(&artwork); err != nil {
+		return nil, fmt.Errorf("failed to parse artwork data: %w", err)
+	}
+	artwork.ID = doc.Ref.ID
+	return &artwork, nil
+}
+
+// ListArtworks queries artworks with optional filters
+func (r *FirestoreArtworkRepository) ListArtworks(ctx context.Context, filter ArtworkFilter) ([]*models.Artwork, error) {
+	q := r.client.Collection("artworks").Query
+	if filter.ArtistID != "" {
+		q = q.Where("artistId", "==", filter.ArtistID)
+	}
+	if filter.ProcessingStatus != "" {
+		q = q.Where("processingStatus", "==", filter.ProcessingStatus)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q = q.Where("createdAt", ">=", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q = q.Where("createdAt", "<=", filter.CreatedBefore)
+	}
+	q = applySort(q, filter.Sort)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artworks: %w", err)
+	}
+
+	artworks := make([]*models.Artwork, 0, len(docs))
+	for _, doc := range docs {
+		var artwork models.Artwork
+		if err := doc.DataTo(&artwork); err != nil {
+			continue // Skip invalid documents
+		}
+		artwork.ID = doc.Ref.ID
+		artworks = append(artworks, &artwork)
+	}
+	return artworks, nil
+}
+
+// UpdateArtwork merges fields into an artwork
+func (r *FirestoreArtworkRepository) UpdateArtwork(ctx context.Context, artworkID string, updates map[string]interface{}) error {
+	if _, err := r.client.Collection("artworks").Doc(artworkID).Set(ctx, updates, firestore.MergeAll); err != nil {
+		return fmt.Errorf("failed to update artwork: %w", err)
+	}
+	return nil
+}
+
+// MemoryArtworkRepository keeps artworks in process memory
+type MemoryArtworkRepository struct {
+	artworks *memoryCollection[models.Artwork]
+}
+
+// NewMemoryArtworkRepository creates an empty in-memory artwork repository
+func NewMemoryArtworkRepository() *MemoryArtworkRepository {
+	return &MemoryArtworkRepository{artworks: newMemoryCollection(func(a models.Artwork) models.Artwork {
+		a.ProcessingErrors = append([]string(nil), a.ProcessingErrors...)
+		return a
+	})}
+}
+
+func (r *MemoryArtworkRepository) CreateArtwork(ctx context.Context, artwork *models.Artwork) error {
+	if artwork.ID == "" {
+		artwork.ID = uuid.NewString()
+	}
+	if artwork.CreatedAt.IsZero() {
+		artwork.CreatedAt = time.Now()
+	}
+	r.artworks.set(artwork.ID, *artwork)
+	return nil
+}
+
+func (r *MemoryArtworkRepository) GetArtworkByID(ctx context.Context, artworkID string) (*models.Artwork, error) {
+	return r.artworks.get(artworkID)
+}
+
+func (r *MemoryArtworkRepository) ListArtworks(ctx context.Context, filter ArtworkFilter) ([]*models.Artwork, error) {
+	artworks := r.artworks.filter(func(a *models.Artwork) bool {
+		if filter.ArtistID != "" && a.ArtistID != filter.ArtistID {
+			return false
+		}
+		if filter.ProcessingStatus != "" && a.ProcessingStatus != filter.ProcessingStatus {
+			return false
+		}
+		if !filter.CreatedAfter.IsZero() && a.CreatedAt.Before(filter.CreatedAfter) {
+			return false
+		}
+		if !filter.CreatedBefore.IsZero() && a.CreatedAt.After(filter.CreatedBefore) {
+			return false
+		}
+		return true
+	})
+	return sortAndLimit(artworks, filter.Sort, filter.Limit, func(a *models.Artwork) time.Time { return a.CreatedAt }), nil
+}
+
+func (r *MemoryArtworkRepository) UpdateArtwork(ctx context.Context, artworkID string, updates map[string]interface{}) error {
+	return r.artworks.update(artworkID, updates)
+}
@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// CartFunc changes a cart inside UpdateCart. exists is false when the buyer has no cart yet,
+// in which case cart is empty. Returning an error aborts the update.
+type CartFunc func(cart *models.Cart, exists bool) error
+
+// CartRepository stores one cart per buyer, keyed by buyer ID
+type CartRepository interface {
+	GetCart(ctx context.Context, buyerID string) (*models.Cart, error)
+	SaveCart(ctx context.Context, cart *models.Cart) error
+	DeleteCart(ctx context.Context, buyerID string) error
+	// UpdateCart applies fn to the buyer's cart atomically and bumps its version. With a version
+	// other than AnyVersion it fails with ErrVersionConflict if the cart has moved on.
+	UpdateCart(ctx context.Context, buyerID string, version int64, fn CartFunc) (*models.Cart, error)
+	// CheckoutCart writes the order group and deletes the cart atomically, failing with
+	// ErrVersionConflict if the cart changed after version was read
+	CheckoutCart(ctx context.Context, buyerID string, version int64, parent *models.Order, subOrders []*models.Order) error
+}
+
+// FirestoreCartRepository handles cart data operations in Firestore
+type FirestoreCartRepository struct {
+	client *firestore.Client
+}
+
+// NewCartRepository creates a new Firestore-backed cart repository
+func NewCartRepository(client *firestore.Client) *FirestoreCartRepository {
+	return &FirestoreCartRepository{client: client}
+}
+
+// GetCart retrieves the cart for a buyer
+func (r *FirestoreCartRepository) GetCart(ctx context.Context, buyerID string) (*models.Cart, error) {
+	doc, err := r.client.Collection("carts").Doc(buyerID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	var cart models.Cart
+	if err := doc.DataTo(&cart); err != nil {
+		return nil, fmt.Errorf("failed to parse cart data: %w", err)
+	}
+	return &cart, nil
+}
+
+// SaveCart writes the whole cart document without checking for concurrent changes
+func (r *FirestoreCartRepository) SaveCart(ctx context.Context, cart *models.Cart) error {
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+	if _, err := r.client.Collection("carts").Doc(cart.BuyerID).Set(ctx, cart); err != nil {
+		return fmt.Errorf("failed to save cart: %w", err)
+	}
+	return nil
+}
+
+// DeleteCart removes a buyer's cart
+func (r *FirestoreCartRepository) DeleteCart(ctx context.Context, buyerID string) error {
+	if _, err := r.client.Collection("carts").Doc(buyerID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+	return nil
+}
+
+// UpdateCart reads, changes and writes the cart in one transaction. Firestore retries the
+// transaction when another writer gets in first, so concurrent changes are never lost.
+func (r *FirestoreCartRepository) UpdateCart(ctx context.Context, buyerID string, version int64, fn CartFunc) (*models.Cart, error) {
+	ref := r.client.Collection("carts").Doc(buyerID)
+	var cart models.Cart
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, exists, err := getCartTx(tx, ref)
+		if err != nil {
+			return err
+		}
+		cart = models.Cart{BuyerID: buyerID}
+		if exists {
+			cart = *stored
+		}
+		if err := checkCartVersion(cart.Version, version); err != nil {
+			return err
+		}
+		if err := fn(&cart, exists); err != nil {
+			return err
+		}
+		cart.UpdatedAt = time.Now()
+		cart.Version++
+		return tx.Set(ref, &cart)
+	})
+	if err != nil {
+		return nil, wrapCartTxError("failed to update cart", err)
+	}
+	return &cart, nil
+}
+
+// CheckoutCart writes the parent order and sub-orders and deletes the cart in one transaction
+func (r *FirestoreCartRepository) CheckoutCart(ctx context.Context, buyerID string, version int64, parent *models.Order, subOrders []*models.Order) error {
+	prepareOrderGroup(parent, subOrders)
+
+	ref := r.client.Collection("carts").Doc(buyerID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, exists, err := getCartTx(tx, ref)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrVersionConflict
+		}
+		if err := checkCartVersion(stored.Version, version); err != nil {
+			return err
+		}
+		orders := r.client.Collection("orders")
+		for _, sub := range subOrders {
+			if err := tx.Set(orders.Doc(sub.OrderID), sub); err != nil {
+				return err
+			}
+		}
+		if err := tx.Set(orders.Doc(parent.OrderID), parent); err != nil {
+			return err
+		}
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return wrapCartTxError("failed to check out cart", err)
+	}
+	return nil
+}
+
+// getCartTx reads a cart inside a transaction; exists is false when there is none
+func getCartTx(tx *firestore.Transaction, ref *firestore.DocumentRef) (*models.Cart, bool, error) {
+	doc, err := tx.Get(ref)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var cart models.Cart
+	if err := doc.DataTo(&cart); err != nil {
+		return nil, false, fmt.Errorf("failed to parse cart data: %w", err)
+	}
+	return &cart, true, nil
+}
+
+// wrapCartTxError passes version conflicts through unchanged; other errors, including
+// those returned by a CartFunc, stay matchable with errors.Is
+func wrapCartTxError(msg string, err error) error {
+	if errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// MemoryCartRepository keeps carts in process memory. Checkout writes orders to the
+// in-memory order repository it was created with.
+type MemoryCartRepository struct {
+	carts  *memoryCollection[models.Cart]
+	orders *MemoryOrderRepository
+}
+
+// NewMemoryCartRepository creates an empty in-memory cart repository
+func NewMemoryCartRepository(orders *MemoryOrderRepository) *MemoryCartRepository {
+	return &MemoryCartRepository{
+		carts: newMemoryCollection(func(c models.Cart) models.Cart {
+			c.Items = append([]models.CartItem(nil), c.Items...)
+			return c
+		}),
+		orders: orders,
+	}
+}
+
+func (r *MemoryCartRepository) GetCart(ctx context.Context, buyerID string) (*models.Cart, error) {
+	return r.carts.get(buyerID)
+}
+
+func (r *MemoryCartRepository) SaveCart(ctx context.Context, cart *models.Cart) error {
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+	r.carts.set(cart.BuyerID, *cart)
+	return nil
+}
+
+func (r *MemoryCartRepository) UpdateCart(ctx context.Context, buyerID string, version int64, fn CartFunc) (*models.Cart, error) {
+	r.carts.mu.Lock()
+	defer r.carts.mu.Unlock()
+
+	stored, exists := r.carts.docs[buyerID]
+	cart := models.Cart{BuyerID: buyerID}
+	if exists {
+		cart = r.carts.clone(stored)
+	}
+	if err := checkCartVersion(cart.Version, version); err != nil {
+		return nil, err
+	}
+	if err := fn(&cart, exists); err != nil {
+		return nil, err
+	}
+	cart.UpdatedAt = time.Now()
+	cart.Version++
+	r.carts.docs[buyerID] = r.carts.clone(cart)
+	return &cart, nil
+}
+
+func (r *MemoryCartRepository) CheckoutCart(ctx context.Context, buyerID string, version int64, parent *models.Order, subOrders []*models.Order) error {
+	r.carts.mu.Lock()
+	defer r.carts.mu.Unlock()
+
+	stored, exists := r.carts.docs[buyerID]
+	if !exists {
+		return ErrVersionConflict
+	}
+	if err := checkCartVersion(stored.Version, version); err != nil {
+		return err
+	}
+	if err := r.orders.CreateOrderGroup(ctx, parent, subOrders); err != nil {
+		return err
+	}
+	delete(r.carts.docs, buyerID)
+	return nil
+}
+
+// checkCartVersion compares a stored cart version with the one the caller expects
+func checkCartVersion(stored, expected int64) error {
+	if expected != AnyVersion && stored != expected {
+		return fmt.Errorf("%w: cart is at version %d, not %d", ErrVersionConflict, stored, expected)
+	}
+	return nil
+}
+
+func (r *MemoryCartRepository) DeleteCart(ctx context.Context, buyerID string) error {
+	if err := r.carts.delete(buyerID); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
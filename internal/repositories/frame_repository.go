@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// FrameRepository stores the frames print shops offer, including uploaded frame images
+type FrameRepository interface {
+	CreateFrame(ctx context.Context, frame *models.Frame) error
+	GetFrameByID(ctx context.Context, frameID string) (*models.Frame, error)
+	ListFrames(ctx context.Context, filter FrameFilter) ([]*models.Frame, error)
+	UpdateFrame(ctx context.Context, frameID string, updates map[string]interface{}) error
+	DeleteFrame(ctx context.Context, frameID string) error
+}
+
+// FrameFilter narrows ListFrames; zero values are ignored
+type FrameFilter struct {
+	ShopID           string
+	ProcessingStatus string
+	CreatedAfter     time.Time
+	CreatedBefore    time.Time
+	Sort             SortOrder
+	Limit            int
+}
+
+// FirestoreFrameRepository handles frame data operations in Firestore
+type FirestoreFrameRepository struct {
+	client *firestore.Client
+}
+
+// NewFrameRepository creates a new Firestore-backed frame repository
+func NewFrameRepository(client *firestore.Client) *FirestoreFrameRepository {
+	return &FirestoreFrameRepository{client: client}
+}
+
+// CreateFrame creates a new frame configuration
+func (r *FirestoreFrameRepository) CreateFrame(ctx context.Context, frame *models.Frame) error {
+	if frame.ID == "" {
+		frame.ID = uuid.New().String()
+	}
+	if frame.CreatedAt.IsZero() {
+		frame.CreatedAt = time.Now()
+	}
+	if frame.UpdatedAt.IsZero() {
+		frame.UpdatedAt = time.Now()
+	}
+
+	if _, err := r.client.Collection("frames").Doc(frame.ID).Set(ctx, frame); err != nil {
+		return fmt.Errorf("failed to create frame: %w", err)
+	}
+	return nil
+}
+
+// GetFrameByID retrieves a frame by its ID
+func (r *FirestoreFrameRepository) GetFrameByID(ctx context.Context, frameID string) (*models.Frame, error) {
+	doc, err := r.client.Collection("frames").Doc(frameID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get frame: %w", err)
+	}
+
+	var frame models.Frame
+	if err := doc.DataTo(&frame); err != nil {
+		return nil, fmt.Errorf("failed to parse frame data: %w", err)
+	}
+	frame.ID = doc.Ref.ID
+	return &frame, nil
+}
+
+// ListFrames queries frames with optional filters
+func (r *FirestoreFrameRepository) ListFrames(ctx context.Context, filter FrameFilter) ([]*models.Frame, error) {
+	q := r.client.Collection("frames").Query
+	if filter.ShopID != "" {
+		q = q.Where("shopId", "==", filter.ShopID)
+	}
+	if filter.ProcessingStatus != "" {
+		q = q.Where("processingStatus", "==", filter.ProcessingStatus)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q = q.Where("createdAt", ">=", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q = q.Where("createdAt", "<=", filter.CreatedBefore)
+	}
+	q = applySort(q, filter.Sort)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query frames: %w", err)
+	}
+
+	frames := make([]*models.Frame, 0, len(docs))
+	for _, doc := range docs {
+		var frame models.Frame
+		if err := doc.DataTo(&frame); err != nil {
+			continue
+		}
+		frame.ID = doc.Ref.ID
+		frames = append(frames, &frame)
+	}
+	return frames, nil
+}
+
+// UpdateFrame updates a frame configuration
+func (r *FirestoreFrameRepository) UpdateFrame(ctx context.Context, frameID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	if _, err := r.client.Collection("frames").Doc(frameID).Update(ctx, toFirestoreUpdates(updates)); err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update frame: %w", err)
+	}
+	return nil
+}
+
+// DeleteFrame deletes a frame configuration
+func (r *FirestoreFrameRepository) DeleteFrame(ctx context.Context, frameID string) error {
+	if _, err := r.client.Collection("frames").Doc(frameID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete frame: %w", err)
+	}
+	return nil
+}
+
+// MemoryFrameRepository keeps frames in process memory
+type MemoryFrameRepository struct {
+	frames *memoryCollection[models.Frame]
+}
+
+// NewMemoryFrameRepository creates an empty in-memory frame repository
+func NewMemoryFrameRepository() *MemoryFrameRepository {
+	return &MemoryFrameRepository{frames: newMemoryCollection(func(f models.Frame) models.Frame {
+		if f.SizePricing != nil {
+			pricing := make(map[string]float64, len(f.SizePricing))
+			for k, v := range f.SizePricing {
+				pricing[k] = v
+			}
+			f.SizePricing = pricing
+		}
+		f.ProcessingErrors = append([]string(nil), f.ProcessingErrors...)
+		return f
+	})}
+}
+
+func (r *MemoryFrameRepository) CreateFrame(ctx context.Context, frame *models.Frame) error {
+	if frame.ID == "" {
+		frame.ID = uuid.New().String()
+	}
+	if frame.CreatedAt.IsZero() {
+		frame.CreatedAt = time.Now()
+	}
+	if frame.UpdatedAt.IsZero() {
+		frame.UpdatedAt = time.Now()
+	}
+	r.frames.set(frame.ID, *frame)
+	return nil
+}
+
+func (r *MemoryFrameRepository) GetFrameByID(ctx context.Context, frameID string) (*models.Frame, error) {
+	return r.frames.get(frameID)
+}
+
+func (r *MemoryFrameRepository) ListFrames(ctx context.Context, filter FrameFilter) ([]*models.Frame, error) {
+	frames := r.frames.filter(func(f *models.Frame) bool {
+		if filter.ShopID != "" && f.ShopID != filter.ShopID {
+			return false
+		}
+		if filter.ProcessingStatus != "" && f.ProcessingStatus != filter.ProcessingStatus {
+			return false
+		}
+		if !filter.CreatedAfter.IsZero() && f.CreatedAt.Before(filter.CreatedAfter) {
+			return false
+		}
+		if !filter.CreatedBefore.IsZero() && f.CreatedAt.After(filter.CreatedBefore) {
+			return false
+		}
+		return true
+	})
+	return sortAndLimit(frames, filter.Sort, filter.Limit, func(f *models.Frame) time.Time { return f.CreatedAt }), nil
+}
+
+func (r *MemoryFrameRepository) UpdateFrame(ctx context.Context, frameID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.frames.update(frameID, updates)
+}
+
+func (r *MemoryFrameRepository) DeleteFrame(ctx context.Context, frameID string) error {
+	return r.frames.delete(frameID)
+}
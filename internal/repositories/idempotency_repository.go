@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// IdempotencyRepository stores idempotency records by an ID derived from the caller and key
+type IdempotencyRepository interface {
+	// Reserve saves record under id unless an unexpired record already holds it.
+	// It returns the existing record in that case, or nil when the reservation succeeded.
+	Reserve(ctx context.Context, id string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Complete stores the response of a reserved request
+	Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error
+	// Release deletes a reservation so the request can be retried
+	Release(ctx context.Context, id string) error
+}
+
+// FirestoreIdempotencyRepository handles idempotency records in Firestore
+type FirestoreIdempotencyRepository struct {
+	client *firestore.Client
+}
+
+// NewIdempotencyRepository creates a new Firestore-backed idempotency repository
+func NewIdempotencyRepository(client *firestore.Client) *FirestoreIdempotencyRepository {
+	return &FirestoreIdempotencyRepository{client: client}
+}
+
+// Reserve claims id in a transaction so concurrent retries cannot both run
+func (r *FirestoreIdempotencyRepository) Reserve(ctx context.Context, id string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ref := r.client.Collection("idempotency_keys").Doc(id)
+	var existing *models.IdempotencyRecord
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing = nil
+		doc, err := tx.Get(ref)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		if err == nil {
+			var stored models.IdempotencyRecord
+			if err := doc.DataTo(&stored); err != nil {
+				return fmt.Errorf("failed to parse idempotency record: %w", err)
+			}
+			if !stored.Expired(time.Now()) {
+				existing = &stored
+				return nil
+			}
+		}
+		return tx.Set(ref, record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return existing, nil
+}
+
+// Complete stores the response of a reserved request
+func (r *FirestoreIdempotencyRepository) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	_, err := r.client.Collection("idempotency_keys").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "completed", Value: true},
+		{Path: "statusCode", Value: statusCode},
+		{Path: "contentType", Value: contentType},
+		{Path: "body", Value: body},
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+	return nil
+}
+
+// Release deletes a reservation
+func (r *FirestoreIdempotencyRepository) Release(ctx context.Context, id string) error {
+	if _, err := r.client.Collection("idempotency_keys").Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// MemoryIdempotencyRepository keeps idempotency records in process memory
+type MemoryIdempotencyRepository struct {
+	records *memoryCollection[models.IdempotencyRecord]
+}
+
+// NewMemoryIdempotencyRepository creates an empty in-memory idempotency repository
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{records: newMemoryCollection(func(rec models.IdempotencyRecord) models.IdempotencyRecord {
+		rec.Body = append([]byte(nil), rec.Body...)
+		return rec
+	})}
+}
+
+func (r *MemoryIdempotencyRepository) Reserve(ctx context.Context, id string, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	r.records.mu.Lock()
+	defer r.records.mu.Unlock()
+	if stored, ok := r.records.docs[id]; ok && !stored.Expired(time.Now()) {
+		existing := r.records.clone(stored)
+		return &existing, nil
+	}
+	r.records.docs[id] = r.records.clone(*record)
+	return nil, nil
+}
+
+func (r *MemoryIdempotencyRepository) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	return r.records.mutate(id, func(rec *models.IdempotencyRecord) error {
+		rec.Completed = true
+		rec.StatusCode = statusCode
+		rec.ContentType = contentType
+		rec.Body = append([]byte(nil), body...)
+		return nil
+	})
+}
+
+func (r *MemoryIdempotencyRepository) Release(ctx context.Context, id string) error {
+	if err := r.records.delete(id); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryCollection is a goroutine-safe map of documents used by the in-memory repositories.
+// Documents are stored by value and cloned on the way in and out so callers never share
+// slices or maps with the store.
+type memoryCollection[T any] struct {
+	mu    sync.RWMutex
+	docs  map[string]T
+	clone func(T) T
+}
+
+func newMemoryCollection[T any](clone func(T) T) *memoryCollection[T] {
+	if clone == nil {
+		clone = func(v T) T { return v }
+	}
+	return &memoryCollection[T]{docs: make(map[string]T), clone: clone}
+}
+
+func (c *memoryCollection[T]) get(id string) (*T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	doc, ok := c.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	out := c.clone(doc)
+	return &out, nil
+}
+
+func (c *memoryCollection[T]) set(id string, doc T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs[id] = c.clone(doc)
+}
+
+func (c *memoryCollection[T]) delete(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.docs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(c.docs, id)
+	return nil
+}
+
+// update applies a field map to a stored document, keyed by firestore tag names
+func (c *memoryCollection[T]) update(id string, updates map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc = c.clone(doc)
+	if err := applyUpdates(&doc, updates); err != nil {
+		return err
+	}
+	c.docs[id] = doc
+	return nil
+}
+
+// mutate runs fn against a stored document under the write lock
+func (c *memoryCollection[T]) mutate(id string, fn func(doc *T) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	doc = c.clone(doc)
+	if err := fn(&doc); err != nil {
+		return err
+	}
+	c.docs[id] = doc
+	return nil
+}
+
+// filter returns clones of every document accepted by keep
+func (c *memoryCollection[T]) filter(keep func(doc *T) bool) []*T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]*T, 0)
+	for _, doc := range c.docs {
+		d := c.clone(doc)
+		if keep == nil || keep(&d) {
+			out = append(out, &d)
+		}
+	}
+	return out
+}
+
+// sortAndLimit orders documents by their createdAt timestamp and truncates to limit (0 = no limit)
+func sortAndLimit[T any](docs []*T, order SortOrder, limit int, createdAt func(*T) time.Time) []*T {
+	switch order {
+	case NewestFirst:
+		sort.SliceStable(docs, func(i, j int) bool { return createdAt(docs[i]).After(createdAt(docs[j])) })
+	case OldestFirst:
+		sort.SliceStable(docs, func(i, j int) bool { return createdAt(docs[i]).Before(createdAt(docs[j])) })
+	}
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+// applyUpdates sets top-level struct fields on dst (a pointer to a struct) by their firestore tag
+// names, mirroring what a Firestore Update/MergeAll does to a stored document. Fields that are not
+// part of the struct are dropped, just as DataTo would drop them on the next read.
+func applyUpdates(dst interface{}, updates map[string]interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for path, value := range updates {
+		idx := fieldIndexByTag(t, path)
+		if idx < 0 {
+			continue
+		}
+		field := v.Field(idx)
+		if err := assignValue(field, value); err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func fieldIndexByTag(t reflect.Type, name string) int {
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("firestore"), ",")[0]
+		if tag == name || (tag == "" && t.Field(i).Name == name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func assignValue(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	val := reflect.ValueOf(value)
+	ft := field.Type()
+	switch {
+	case val.Type().AssignableTo(ft):
+		field.Set(val)
+		return nil
+	case sameKindFamily(val.Kind(), ft.Kind()) && val.Type().ConvertibleTo(ft):
+		field.Set(val.Convert(ft))
+		return nil
+	case ft.Kind() == reflect.Ptr && val.Type().AssignableTo(ft.Elem()):
+		p := reflect.New(ft.Elem())
+		p.Elem().Set(val)
+		field.Set(p)
+		return nil
+	}
+
+	// Fall back to a JSON round trip for decoded request bodies (map[string]interface{}, []interface{})
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	p := reflect.New(ft)
+	if err := json.Unmarshal(raw, p.Interface()); err != nil {
+		return fmt.Errorf("cannot assign %T to %s", value, ft)
+	}
+	field.Set(p.Elem())
+	return nil
+}
+
+func sameKindFamily(a, b reflect.Kind) bool {
+	isNumber := func(k reflect.Kind) bool {
+		return (k >= reflect.Int && k <= reflect.Uint64) || k == reflect.Float32 || k == reflect.Float64
+	}
+	if isNumber(a) && isNumber(b) {
+		return true
+	}
+	return a == b
+}
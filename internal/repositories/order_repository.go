@@ -0,0 +1,522 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// OrderRepository stores buyer orders
+type OrderRepository interface {
+	// CreateOrder writes the full order document (overwriting any existing one)
+	CreateOrder(ctx context.Context, order *models.Order) error
+	// CreateOrderGroup atomically writes a parent order and its fulfillment sub-orders,
+	// linking them through ParentOrderID and SubOrderIDs
+	CreateOrderGroup(ctx context.Context, parent *models.Order, subOrders []*models.Order) error
+	GetOrderByID(ctx context.Context, orderID string) (*models.Order, error)
+	// UpdateOrder merges the given fields into the order and bumps updatedAt
+	UpdateOrder(ctx context.Context, orderID string, updates map[string]interface{}) error
+	// UpdateOrderIf reads the order and merges the fields fn returns for it in one transaction,
+	// so the check fn makes still holds when the write lands. An error from fn aborts the
+	// update and is returned as is.
+	UpdateOrderIf(ctx context.Context, orderID string, fn func(order *models.Order) (map[string]interface{}, error)) error
+	ListOrders(ctx context.Context, filter OrderFilter) ([]*models.Order, error)
+	// CountOrders returns how many orders match the filter; Sort and Limit are ignored
+	CountOrders(ctx context.Context, filter OrderFilter) (int, error)
+	AppendAdminNote(ctx context.Context, orderID string, note models.AdminNote) error
+	// TransitionStatus moves event.OrderID to event.To if the lifecycle allows it and records
+	// the event in the order's history. From, ID and CreatedAt are filled in on the event.
+	// Returns ErrIllegalTransition (wrapped) when the move is not allowed.
+	TransitionStatus(ctx context.Context, event *models.OrderEvent) error
+	// ListOrderEvents returns an order's status history, oldest first
+	ListOrderEvents(ctx context.Context, orderID string) ([]*models.OrderEvent, error)
+}
+
+// OrderFilter narrows ListOrders; zero values are ignored
+type OrderFilter struct {
+	Status models.OrderStatus
+	// Statuses matches any of the listed statuses (at most 30)
+	Statuses      []models.OrderStatus
+	BuyerID       string
+	PrintShopID   string
+	ParentOrderID string
+	Assignment    models.AssignmentStatus
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Sort          SortOrder
+	Limit         int
+}
+
+// FirestoreOrderRepository handles order data operations in Firestore
+type FirestoreOrderRepository struct {
+	client *firestore.Client
+}
+
+// NewOrderRepository creates a new Firestore-backed order repository
+func NewOrderRepository(client *firestore.Client) *FirestoreOrderRepository {
+	return &FirestoreOrderRepository{client: client}
+}
+
+// CreateOrder creates (or overwrites) an order record
+func (r *FirestoreOrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	if order.OrderID == "" {
+		order.OrderID = uuid.NewString()
+	}
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now()
+	}
+	if order.UpdatedAt.IsZero() {
+		order.UpdatedAt = order.CreatedAt
+	}
+
+	if _, err := r.client.Collection("orders").Doc(order.OrderID).Set(ctx, order); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	return nil
+}
+
+// CreateOrderGroup writes a parent order and its sub-orders in a single transaction
+func (r *FirestoreOrderRepository) CreateOrderGroup(ctx context.Context, parent *models.Order, subOrders []*models.Order) error {
+	prepareOrderGroup(parent, subOrders)
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for _, sub := range subOrders {
+			if err := tx.Set(r.client.Collection("orders").Doc(sub.OrderID), sub); err != nil {
+				return err
+			}
+		}
+		return tx.Set(r.client.Collection("orders").Doc(parent.OrderID), parent)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create order group: %w", err)
+	}
+	return nil
+}
+
+// GetOrderByID retrieves an order by its ID
+func (r *FirestoreOrderRepository) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
+	doc, err := r.client.Collection("orders").Doc(orderID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	var order models.Order
+	if err := doc.DataTo(&order); err != nil {
+		return nil, fmt.Errorf("failed to parse order data: %w", err)
+	}
+	if order.OrderID == "" {
+		order.OrderID = doc.Ref.ID
+	}
+	return &order, nil
+}
+
+// UpdateOrder merges fields into an order
+func (r *FirestoreOrderRepository) UpdateOrder(ctx context.Context, orderID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+
+	if _, err := r.client.Collection("orders").Doc(orderID).Update(ctx, toFirestoreUpdates(updates)); err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	return nil
+}
+
+// UpdateOrderIf merges the fields fn derives from the stored order in one transaction
+func (r *FirestoreOrderRepository) UpdateOrderIf(ctx context.Context, orderID string, fn func(order *models.Order) (map[string]interface{}, error)) error {
+	ref := r.client.Collection("orders").Doc(orderID)
+	var fnErr error
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		fnErr = nil
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var order models.Order
+		if err := doc.DataTo(&order); err != nil {
+			return fmt.Errorf("failed to parse order data: %w", err)
+		}
+		if order.OrderID == "" {
+			order.OrderID = doc.Ref.ID
+		}
+		updates, err := fn(&order)
+		if err != nil {
+			fnErr = err
+			return err
+		}
+		updates["updatedAt"] = time.Now()
+		return tx.Update(ref, toFirestoreUpdates(updates))
+	})
+	if err != nil {
+		if fnErr != nil || errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	return nil
+}
+
+// orderQuery builds the query selecting the orders that match filter
+func (r *FirestoreOrderRepository) orderQuery(filter OrderFilter) firestore.Query {
+	q := r.client.Collection("orders").Query
+	if filter.Status != "" {
+		q = q.Where("status", "==", string(filter.Status))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			statuses[i] = string(s)
+		}
+		q = q.Where("status", "in", statuses)
+	}
+	if filter.BuyerID != "" {
+		q = q.Where("buyerId", "==", filter.BuyerID)
+	}
+	if filter.PrintShopID != "" {
+		q = q.Where("printShopId", "==", filter.PrintShopID)
+	}
+	if filter.ParentOrderID != "" {
+		q = q.Where("parentOrderId", "==", filter.ParentOrderID)
+	}
+	if filter.Assignment != "" {
+		q = q.Where("assignmentStatus", "==", filter.Assignment)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q = q.Where("createdAt", ">=", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q = q.Where("createdAt", "<=", filter.CreatedBefore)
+	}
+	return q
+}
+
+// ListOrders queries orders with optional filters
+func (r *FirestoreOrderRepository) ListOrders(ctx context.Context, filter OrderFilter) ([]*models.Order, error) {
+	q := applySort(r.orderQuery(filter), filter.Sort)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+
+	orders := make([]*models.Order, 0, len(docs))
+	for _, doc := range docs {
+		var order models.Order
+		if err := doc.DataTo(&order); err != nil {
+			continue // Skip invalid documents
+		}
+		if order.OrderID == "" {
+			order.OrderID = doc.Ref.ID
+		}
+		orders = append(orders, &order)
+	}
+	return orders, nil
+}
+
+// CountOrders counts the matching orders with an aggregation query, without reading them
+func (r *FirestoreOrderRepository) CountOrders(ctx context.Context, filter OrderFilter) (int, error) {
+	q := r.orderQuery(filter)
+	result, err := q.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+	value, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("failed to count orders: unexpected result %T", result["count"])
+	}
+	return int(value.GetIntegerValue()), nil
+}
+
+// AppendAdminNote adds a note to the order's adminNotes array
+func (r *FirestoreOrderRepository) AppendAdminNote(ctx context.Context, orderID string, note models.AdminNote) error {
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+	_, err := r.client.Collection("orders").Doc(orderID).Update(ctx, []firestore.Update{
+		{Path: "adminNotes", Value: firestore.ArrayUnion(note)},
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to append admin note: %w", err)
+	}
+	return nil
+}
+
+// TransitionStatus changes an order's status and writes orders/{id}/events in one transaction
+func (r *FirestoreOrderRepository) TransitionStatus(ctx context.Context, event *models.OrderEvent) error {
+	ref := r.client.Collection("orders").Doc(event.OrderID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var order models.Order
+		if err := doc.DataTo(&order); err != nil {
+			return fmt.Errorf("failed to parse order data: %w", err)
+		}
+		if err := prepareOrderEvent(event, order.Status); err != nil {
+			return err
+		}
+
+		if err := tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: string(event.To)},
+			{Path: "updatedAt", Value: event.CreatedAt},
+		}); err != nil {
+			return err
+		}
+		return tx.Create(ref.Collection("events").Doc(event.ID), event)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrIllegalTransition) {
+			return err
+		}
+		return fmt.Errorf("failed to transition order: %w", err)
+	}
+	return nil
+}
+
+// ListOrderEvents returns the status history of an order, oldest first
+func (r *FirestoreOrderRepository) ListOrderEvents(ctx context.Context, orderID string) ([]*models.OrderEvent, error) {
+	docs, err := r.client.Collection("orders").Doc(orderID).Collection("events").
+		OrderBy("createdAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order events: %w", err)
+	}
+
+	events := make([]*models.OrderEvent, 0, len(docs))
+	for _, doc := range docs {
+		var event models.OrderEvent
+		if err := doc.DataTo(&event); err != nil {
+			continue // Skip invalid documents
+		}
+		event.ID = doc.Ref.ID
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// prepareOrderGroup assigns IDs and timestamps and links a parent order to its sub-orders
+func prepareOrderGroup(parent *models.Order, subOrders []*models.Order) {
+	now := time.Now()
+	stamp := func(o *models.Order) {
+		if o.OrderID == "" {
+			o.OrderID = uuid.NewString()
+		}
+		if o.CreatedAt.IsZero() {
+			o.CreatedAt = now
+		}
+		if o.UpdatedAt.IsZero() {
+			o.UpdatedAt = o.CreatedAt
+		}
+	}
+
+	stamp(parent)
+	parent.SubOrderIDs = make([]string, 0, len(subOrders))
+	for _, sub := range subOrders {
+		stamp(sub)
+		sub.ParentOrderID = parent.OrderID
+		parent.SubOrderIDs = append(parent.SubOrderIDs, sub.OrderID)
+	}
+}
+
+// prepareOrderEvent validates a transition from the current status and fills in the event
+func prepareOrderEvent(event *models.OrderEvent, current models.OrderStatus) error {
+	if current == "" {
+		current = models.OrderStatusPending
+	}
+	if !current.CanTransitionTo(event.To) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, current, event.To)
+	}
+	event.From = current
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	event.CreatedAt = time.Now()
+	return nil
+}
+
+// MemoryOrderRepository keeps orders in process memory
+type MemoryOrderRepository struct {
+	orders *memoryCollection[models.Order]
+
+	mu     sync.Mutex
+	events map[string][]models.OrderEvent
+}
+
+// NewMemoryOrderRepository creates an empty in-memory order repository
+func NewMemoryOrderRepository() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders: newMemoryCollection(cloneOrder),
+		events: make(map[string][]models.OrderEvent),
+	}
+}
+
+func cloneOrder(o models.Order) models.Order {
+	o.Items = append([]models.CartItem(nil), o.Items...)
+	for i := range o.Items {
+		o.Items[i].PriceBreakdown = clonePtr(o.Items[i].PriceBreakdown)
+		o.Items[i].PrintOptions.DeliveryLocation = clonePtr(o.Items[i].PrintOptions.DeliveryLocation)
+	}
+	o.AdminNotes = append([]models.AdminNote(nil), o.AdminNotes...)
+	o.SubOrderIDs = append([]string(nil), o.SubOrderIDs...)
+	o.DeclinedShopIDs = append([]string(nil), o.DeclinedShopIDs...)
+	o.ShippingAddress = clonePtr(o.ShippingAddress)
+	o.ShippingQuote = clonePtr(o.ShippingQuote)
+	o.Discounts = append([]models.OrderDiscount(nil), o.Discounts...)
+	for i := range o.Discounts {
+		o.Discounts[i].Lines = append([]models.LineDiscount(nil), o.Discounts[i].Lines...)
+	}
+	o.TaxLines = append([]models.TaxLine(nil), o.TaxLines...)
+	o.SubOrders = nil
+	return o
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil for nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+func (r *MemoryOrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	if order.OrderID == "" {
+		order.OrderID = uuid.NewString()
+	}
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now()
+	}
+	if order.UpdatedAt.IsZero() {
+		order.UpdatedAt = order.CreatedAt
+	}
+	r.orders.set(order.OrderID, *order)
+	return nil
+}
+
+func (r *MemoryOrderRepository) CreateOrderGroup(ctx context.Context, parent *models.Order, subOrders []*models.Order) error {
+	prepareOrderGroup(parent, subOrders)
+	for _, sub := range subOrders {
+		r.orders.set(sub.OrderID, *sub)
+	}
+	r.orders.set(parent.OrderID, *parent)
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
+	return r.orders.get(orderID)
+}
+
+func (r *MemoryOrderRepository) UpdateOrder(ctx context.Context, orderID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.orders.update(orderID, updates)
+}
+
+func (r *MemoryOrderRepository) UpdateOrderIf(ctx context.Context, orderID string, fn func(order *models.Order) (map[string]interface{}, error)) error {
+	return r.orders.mutate(orderID, func(o *models.Order) error {
+		snapshot := cloneOrder(*o)
+		updates, err := fn(&snapshot)
+		if err != nil {
+			return err
+		}
+		updates["updatedAt"] = time.Now()
+		return applyUpdates(o, updates)
+	})
+}
+
+func (r *MemoryOrderRepository) ListOrders(ctx context.Context, filter OrderFilter) ([]*models.Order, error) {
+	orders := r.orders.filter(filter.matches)
+	return sortAndLimit(orders, filter.Sort, filter.Limit, func(o *models.Order) time.Time { return o.CreatedAt }), nil
+}
+
+func (r *MemoryOrderRepository) CountOrders(ctx context.Context, filter OrderFilter) (int, error) {
+	return len(r.orders.filter(filter.matches)), nil
+}
+
+// matches applies the filter to an order held in memory
+func (filter OrderFilter) matches(o *models.Order) bool {
+	if filter.Status != "" && o.Status != filter.Status {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, o.Status) {
+		return false
+	}
+	if filter.BuyerID != "" && o.BuyerID != filter.BuyerID {
+		return false
+	}
+	if filter.PrintShopID != "" && o.PrintShopID != filter.PrintShopID {
+		return false
+	}
+	if filter.ParentOrderID != "" && o.ParentOrderID != filter.ParentOrderID {
+		return false
+	}
+	if filter.Assignment != "" && o.AssignmentStatus != filter.Assignment {
+		return false
+	}
+	if !filter.CreatedAfter.IsZero() && o.CreatedAt.Before(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && o.CreatedAt.After(filter.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func (r *MemoryOrderRepository) AppendAdminNote(ctx context.Context, orderID string, note models.AdminNote) error {
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now()
+	}
+	return r.orders.mutate(orderID, func(o *models.Order) error {
+		o.AdminNotes = append(o.AdminNotes, note)
+		return nil
+	})
+}
+
+func (r *MemoryOrderRepository) TransitionStatus(ctx context.Context, event *models.OrderEvent) error {
+	return r.orders.mutate(event.OrderID, func(o *models.Order) error {
+		if err := prepareOrderEvent(event, o.Status); err != nil {
+			return err
+		}
+		o.Status = event.To
+		o.UpdatedAt = event.CreatedAt
+
+		r.mu.Lock()
+		r.events[event.OrderID] = append(r.events[event.OrderID], *event)
+		r.mu.Unlock()
+		return nil
+	})
+}
+
+func (r *MemoryOrderRepository) ListOrderEvents(ctx context.Context, orderID string) ([]*models.OrderEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]*models.OrderEvent, 0, len(r.events[orderID]))
+	for i := range r.events[orderID] {
+		e := r.events[orderID][i]
+		events = append(events, &e)
+	}
+	return events, nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+func TestMemoryOrderRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+	repo := NewMemoryOrderRepository()
+
+	order := &models.Order{
+		OrderID: "order",
+		Items: []models.CartItem{{
+			LineID:         "line",
+			PrintOptions:   models.PrintOrderOptions{DeliveryLocation: &models.Location{City: "Nairobi"}},
+			PriceBreakdown: &models.PriceBreakdown{Total: kes(1000)},
+		}},
+		ShippingAddress: &models.Address{RecipientName: "Wanjiru"},
+		ShippingQuote:   &models.ShippingQuote{Amount: kes(300)},
+		Discounts:       []models.OrderDiscount{{PromotionID: "promo", Lines: []models.LineDiscount{{LineID: "line", Amount: kes(100)}}}},
+		TaxLines:        []models.TaxLine{{Jurisdiction: "KE", Amount: kes(138)}},
+	}
+	if err := repo.CreateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the caller's order or a fetched copy must not reach the stored order
+	order.Items[0].PriceBreakdown.Total = kes(1)
+	fetched, err := repo.GetOrderByID(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched.Items[0].PrintOptions.DeliveryLocation.City = "Mombasa"
+	fetched.ShippingAddress.RecipientName = "someone else"
+	fetched.ShippingQuote.Amount = kes(1)
+	fetched.Discounts[0].Lines[0].Amount = kes(1)
+	fetched.TaxLines[0].Amount = kes(1)
+
+	got, err := repo.GetOrderByID(ctx, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Items[0].PriceBreakdown.Total != kes(1000) || got.Items[0].PrintOptions.DeliveryLocation.City != "Nairobi" {
+		t.Errorf("item changed in the store: %+v", got.Items[0])
+	}
+	if got.ShippingAddress.RecipientName != "Wanjiru" || got.ShippingQuote.Amount != kes(300) {
+		t.Errorf("shipping changed in the store: %+v %+v", got.ShippingAddress, got.ShippingQuote)
+	}
+	if got.Discounts[0].Lines[0].Amount != kes(100) || got.TaxLines[0].Amount != kes(138) {
+		t.Errorf("discounts or tax changed in the store: %+v %+v", got.Discounts, got.TaxLines)
+	}
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// PaymentEventRepository stores raw payment webhooks in payment_events
+type PaymentEventRepository interface {
+	CreatePaymentEvent(ctx context.Context, event *models.PaymentEvent) error
+	GetPaymentEvent(ctx context.Context, eventID string) (*models.PaymentEvent, error)
+	UpdatePaymentEvent(ctx context.Context, eventID string, updates map[string]interface{}) error
+	// ListPaymentEvents returns events newest first, narrowed by filter
+	ListPaymentEvents(ctx context.Context, filter PaymentEventFilter) ([]*models.PaymentEvent, error)
+}
+
+// PaymentEventFilter narrows ListPaymentEvents; zero values are ignored
+type PaymentEventFilter struct {
+	PaymentID     string
+	TransactionID string
+	Outcome       models.PaymentEventOutcome
+	Limit         int
+}
+
+// FirestorePaymentEventRepository handles payment events in Firestore
+type FirestorePaymentEventRepository struct {
+	client *firestore.Client
+}
+
+// NewPaymentEventRepository creates a new Firestore-backed payment event repository
+func NewPaymentEventRepository(client *firestore.Client) *FirestorePaymentEventRepository {
+	return &FirestorePaymentEventRepository{client: client}
+}
+
+// CreatePaymentEvent writes a new payment event
+func (r *FirestorePaymentEventRepository) CreatePaymentEvent(ctx context.Context, event *models.PaymentEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = time.Now()
+	}
+	if _, err := r.client.Collection("payment_events").Doc(event.ID).Set(ctx, event); err != nil {
+		return fmt.Errorf("failed to create payment event: %w", err)
+	}
+	return nil
+}
+
+// GetPaymentEvent retrieves a payment event by its ID
+func (r *FirestorePaymentEventRepository) GetPaymentEvent(ctx context.Context, eventID string) (*models.PaymentEvent, error) {
+	doc, err := r.client.Collection("payment_events").Doc(eventID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get payment event: %w", err)
+	}
+	var event models.PaymentEvent
+	if err := doc.DataTo(&event); err != nil {
+		return nil, fmt.Errorf("failed to parse payment event data: %w", err)
+	}
+	return &event, nil
+}
+
+// UpdatePaymentEvent updates a payment event
+func (r *FirestorePaymentEventRepository) UpdatePaymentEvent(ctx context.Context, eventID string, updates map[string]interface{}) error {
+	_, err := r.client.Collection("payment_events").Doc(eventID).Update(ctx, toFirestoreUpdates(updates))
+	if isNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListPaymentEvents retrieves payment events, newest first
+func (r *FirestorePaymentEventRepository) ListPaymentEvents(ctx context.Context, filter PaymentEventFilter) ([]*models.PaymentEvent, error) {
+	q := r.client.Collection("payment_events").Query
+	if filter.PaymentID != "" {
+		q = q.Where("paymentId", "==", filter.PaymentID)
+	}
+	if filter.TransactionID != "" {
+		q = q.Where("transactionId", "==", filter.TransactionID)
+	}
+	if filter.Outcome != "" {
+		q = q.Where("outcome", "==", filter.Outcome)
+	}
+	q = q.OrderBy("receivedAt", firestore.Desc)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment events: %w", err)
+	}
+
+	events := make([]*models.PaymentEvent, 0, len(docs))
+	for _, doc := range docs {
+		var event models.PaymentEvent
+		if err := doc.DataTo(&event); err != nil {
+			continue
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// MemoryPaymentEventRepository keeps payment events in process memory
+type MemoryPaymentEventRepository struct {
+	events *memoryCollection[models.PaymentEvent]
+}
+
+// NewMemoryPaymentEventRepository creates an empty in-memory payment event repository
+func NewMemoryPaymentEventRepository() *MemoryPaymentEventRepository {
+	return &MemoryPaymentEventRepository{events: newMemoryCollection[models.PaymentEvent](nil)}
+}
+
+func (r *MemoryPaymentEventRepository) CreatePaymentEvent(ctx context.Context, event *models.PaymentEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.ReceivedAt.IsZero() {
+		event.ReceivedAt = time.Now()
+	}
+	r.events.set(event.ID, *event)
+	return nil
+}
+
+func (r *MemoryPaymentEventRepository) GetPaymentEvent(ctx context.Context, eventID string) (*models.PaymentEvent, error) {
+	return r.events.get(eventID)
+}
+
+func (r *MemoryPaymentEventRepository) UpdatePaymentEvent(ctx context.Context, eventID string, updates map[string]interface{}) error {
+	return r.events.update(eventID, updates)
+}
+
+func (r *MemoryPaymentEventRepository) ListPaymentEvents(ctx context.Context, filter PaymentEventFilter) ([]*models.PaymentEvent, error) {
+	events := r.events.filter(func(e *models.PaymentEvent) bool {
+		if filter.PaymentID != "" && e.PaymentID != filter.PaymentID {
+			return false
+		}
+		if filter.TransactionID != "" && e.TransactionID != filter.TransactionID {
+			return false
+		}
+		if filter.Outcome != "" && e.Outcome != filter.Outcome {
+			return false
+		}
+		return true
+	})
+	return sortAndLimit(events, NewestFirst, filter.Limit, paymentEventReceivedAt), nil
+}
+
+func paymentEventReceivedAt(e *models.PaymentEvent) time.Time { return e.ReceivedAt }
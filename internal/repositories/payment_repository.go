@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -10,18 +11,65 @@ import (
 	"github.com/google/uuid"
 )
 
-// PaymentRepository handles payment data operations
-type PaymentRepository struct {
+// ErrPaymentFinal is returned when changing a payment that already reached a final status
+var ErrPaymentFinal = errors.New("payment status is final")
+
+// ErrNotRefundable is returned for a refund of a payment that is not captured, or for more than
+// is left of it
+var ErrNotRefundable = errors.New("payment cannot be refunded")
+
+// PaymentRepository stores payment transactions
+type PaymentRepository interface {
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+	GetPaymentByID(ctx context.Context, paymentID string) (*models.Payment, error)
+	// GetPaymentByTransactionID finds the payment a provider knows by transactionID.
+	// Transaction IDs are only unique per provider, so the payment method is part of the key.
+	GetPaymentByTransactionID(ctx context.Context, method, transactionID string) (*models.Payment, error)
+	GetPaymentsByOrderID(ctx context.Context, orderID string) ([]*models.Payment, error)
+	GetPaymentsByBuyerID(ctx context.Context, buyerID string) ([]*models.Payment, error)
+	UpdatePayment(ctx context.Context, paymentID string, updates map[string]interface{}) error
+	UpdatePaymentStatus(ctx context.Context, paymentID string, status models.PaymentStatus) error
+	// TransitionPayment moves a payment to status to, applying updates in one transaction,
+	// unless its current status does not accept that move (see models.PaymentStatus.AcceptsWebhook),
+	// when ErrPaymentFinal is returned wrapped
+	TransitionPayment(ctx context.Context, paymentID string, to models.PaymentStatus, updates map[string]interface{}) error
+	GetPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]*models.Payment, error)
+	// ListPayments returns payments newest first, narrowed by filter
+	ListPayments(ctx context.Context, filter PaymentFilter) ([]*models.Payment, error)
+
+	// CreateRefund records a pending refund and adds it to its payment's refunded amount in one
+	// transaction. ErrNotRefundable is returned wrapped unless the payment is captured and the
+	// refund fits in what is left of it.
+	CreateRefund(ctx context.Context, refund *models.Refund) error
+	// FinishRefund records the provider's answer to a pending refund; refunds already finished are
+	// left alone. A failed refund is taken off its payment's refunded amount again.
+	FinishRefund(ctx context.Context, refundID string, status models.RefundStatus, providerRefundID, failureReason string) error
+	GetRefundsByPaymentID(ctx context.Context, paymentID string) ([]*models.Refund, error)
+	GetRefundsByOrderID(ctx context.Context, orderID string) ([]*models.Refund, error)
+	// GetRefundsByStatus retrieves refunds in a status, oldest first, e.g. those still pending
+	GetRefundsByStatus(ctx context.Context, status models.RefundStatus) ([]*models.Refund, error)
+}
+
+// PaymentFilter narrows ListPayments; zero values are ignored
+type PaymentFilter struct {
+	OrderID string
+	BuyerID string
+	Status  models.PaymentStatus
+	Limit   int
+}
+
+// FirestorePaymentRepository handles payment data operations in Firestore
+type FirestorePaymentRepository struct {
 	client *firestore.Client
 }
 
 // NewPaymentRepository creates a new payment repository
-func NewPaymentRepository(client *firestore.Client) *PaymentRepository {
-	return &PaymentRepository{client: client}
+func NewPaymentRepository(client *firestore.Client) *FirestorePaymentRepository {
+	return &FirestorePaymentRepository{client: client}
 }
 
 // CreatePayment creates a new payment record
-func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+func (r *FirestorePaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
 	if payment.ID == "" {
 		payment.ID = uuid.NewString()
 	}
@@ -35,13 +83,16 @@ func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *models.P
 }
 
 // GetPaymentByID retrieves a payment by its ID
-func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID string) (*models.Payment, error) {
+func (r *FirestorePaymentRepository) GetPaymentByID(ctx context.Context, paymentID string) (*models.Payment, error) {
 	doc, err := r.client.Collection("payments").Doc(paymentID).Get(ctx)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	if !doc.Exists() {
-		return nil, errors.New("payment not found")
+		return nil, ErrNotFound
 	}
 
 	var payment models.Payment
@@ -51,8 +102,30 @@ func (r *PaymentRepository) GetPaymentByID(ctx context.Context, paymentID string
 	return &payment, nil
 }
 
+// GetPaymentByTransactionID retrieves the payment a provider knows by transactionID
+func (r *FirestorePaymentRepository) GetPaymentByTransactionID(ctx context.Context, method, transactionID string) (*models.Payment, error) {
+	docs, err := r.client.Collection("payments").
+		Where("paymentMethod", "==", method).
+		Where("transactionId", "==", transactionID).
+		Limit(1).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var payment models.Payment
+	if err := docs[0].DataTo(&payment); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
 // GetPaymentsByOrderID retrieves all payments for an order
-func (r *PaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID string) ([]*models.Payment, error) {
+func (r *FirestorePaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID string) ([]*models.Payment, error) {
 	docs, err := r.client.Collection("payments").
 		Where("orderId", "==", orderID).
 		OrderBy("createdAt", firestore.Asc).
@@ -76,7 +149,7 @@ func (r *PaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID st
 }
 
 // GetPaymentsByBuyerID retrieves all payments for a buyer
-func (r *PaymentRepository) GetPaymentsByBuyerID(ctx context.Context, buyerID string) ([]*models.Payment, error) {
+func (r *FirestorePaymentRepository) GetPaymentsByBuyerID(ctx context.Context, buyerID string) ([]*models.Payment, error) {
 	docs, err := r.client.Collection("payments").
 		Where("buyerId", "==", buyerID).
 		OrderBy("createdAt", firestore.Desc).
@@ -100,23 +173,18 @@ func (r *PaymentRepository) GetPaymentsByBuyerID(ctx context.Context, buyerID st
 }
 
 // UpdatePayment updates a payment record
-func (r *PaymentRepository) UpdatePayment(ctx context.Context, paymentID string, updates map[string]interface{}) error {
+func (r *FirestorePaymentRepository) UpdatePayment(ctx context.Context, paymentID string, updates map[string]interface{}) error {
 	updates["updatedAt"] = time.Now()
 
-	updatesList := make([]firestore.Update, 0, len(updates))
-	for field, value := range updates {
-		updatesList = append(updatesList, firestore.Update{
-			Path:  field,
-			Value: value,
-		})
+	_, err := r.client.Collection("payments").Doc(paymentID).Update(ctx, toFirestoreUpdates(updates))
+	if isNotFound(err) {
+		return ErrNotFound
 	}
-
-	_, err := r.client.Collection("payments").Doc(paymentID).Update(ctx, updatesList)
 	return err
 }
 
 // UpdatePaymentStatus updates the payment status
-func (r *PaymentRepository) UpdatePaymentStatus(ctx context.Context, paymentID string, status models.PaymentStatus) error {
+func (r *FirestorePaymentRepository) UpdatePaymentStatus(ctx context.Context, paymentID string, status models.PaymentStatus) error {
 	updates := map[string]interface{}{
 		"status":    status,
 		"updatedAt": time.Now(),
@@ -133,8 +201,39 @@ func (r *PaymentRepository) UpdatePaymentStatus(ctx context.Context, paymentID s
 	return r.UpdatePayment(ctx, paymentID, updates)
 }
 
+// TransitionPayment updates a payment in a transaction, refusing moves its status does not accept
+func (r *FirestorePaymentRepository) TransitionPayment(ctx context.Context, paymentID string, to models.PaymentStatus, updates map[string]interface{}) error {
+	ref := r.client.Collection("payments").Doc(paymentID)
+	updates["status"] = to
+	updates["updatedAt"] = time.Now()
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var payment models.Payment
+		if err := doc.DataTo(&payment); err != nil {
+			return err
+		}
+		if !payment.Status.AcceptsWebhook(to) {
+			return fmt.Errorf("%w: payment %s is %s", ErrPaymentFinal, paymentID, payment.Status)
+		}
+		return tx.Update(ref, toFirestoreUpdates(updates))
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrPaymentFinal) {
+			return err
+		}
+		return fmt.Errorf("failed to transition payment: %w", err)
+	}
+	return nil
+}
+
 // GetPaymentsByStatus retrieves payments by status
-func (r *PaymentRepository) GetPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]*models.Payment, error) {
+func (r *FirestorePaymentRepository) GetPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]*models.Payment, error) {
 	docs, err := r.client.Collection("payments").
 		Where("status", "==", status).
 		OrderBy("createdAt", firestore.Desc).
@@ -156,3 +255,367 @@ func (r *PaymentRepository) GetPaymentsByStatus(ctx context.Context, status mode
 
 	return payments, nil
 }
+
+// ListPayments retrieves payments, newest first
+func (r *FirestorePaymentRepository) ListPayments(ctx context.Context, filter PaymentFilter) ([]*models.Payment, error) {
+	q := r.client.Collection("payments").Query
+	if filter.OrderID != "" {
+		q = q.Where("orderId", "==", filter.OrderID)
+	}
+	if filter.BuyerID != "" {
+		q = q.Where("buyerId", "==", filter.BuyerID)
+	}
+	if filter.Status != "" {
+		q = q.Where("status", "==", filter.Status)
+	}
+	q = q.OrderBy("createdAt", firestore.Desc)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	payments := make([]*models.Payment, 0, len(docs))
+	for _, doc := range docs {
+		var payment models.Payment
+		if err := doc.DataTo(&payment); err != nil {
+			continue
+		}
+		payments = append(payments, &payment)
+	}
+
+	return payments, nil
+}
+
+// CreateRefund writes a pending refund and reserves its amount on the payment in one transaction
+func (r *FirestorePaymentRepository) CreateRefund(ctx context.Context, refund *models.Refund) error {
+	prepareRefund(refund)
+	paymentRef := r.client.Collection("payments").Doc(refund.PaymentID)
+	refundRef := r.client.Collection("refunds").Doc(refund.ID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(paymentRef)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var payment models.Payment
+		if err := doc.DataTo(&payment); err != nil {
+			return err
+		}
+		updates, err := reserveRefund(&payment, refund)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(refundRef, refund); err != nil {
+			return err
+		}
+		return tx.Update(paymentRef, toFirestoreUpdates(updates))
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotRefundable) {
+			return err
+		}
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return nil
+}
+
+// FinishRefund records a provider's answer to a refund, releasing the amount of a failed one
+func (r *FirestorePaymentRepository) FinishRefund(ctx context.Context, refundID string, status models.RefundStatus, providerRefundID, failureReason string) error {
+	refundRef := r.client.Collection("refunds").Doc(refundID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(refundRef)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var refund models.Refund
+		if err := doc.DataTo(&refund); err != nil {
+			return err
+		}
+		if refund.Status != models.RefundStatusPending {
+			return nil
+		}
+
+		var paymentUpdates map[string]interface{}
+		paymentRef := r.client.Collection("payments").Doc(refund.PaymentID)
+		if status == models.RefundStatusFailed {
+			doc, err := tx.Get(paymentRef)
+			if err != nil {
+				return err
+			}
+			var payment models.Payment
+			if err := doc.DataTo(&payment); err != nil {
+				return err
+			}
+			paymentUpdates = releaseRefund(&payment, &refund)
+		}
+		if err := tx.Update(refundRef, toFirestoreUpdates(finishRefund(status, providerRefundID, failureReason))); err != nil {
+			return err
+		}
+		if paymentUpdates != nil {
+			return tx.Update(paymentRef, toFirestoreUpdates(paymentUpdates))
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to finish refund: %w", err)
+	}
+	return nil
+}
+
+// GetRefundsByPaymentID retrieves a payment's refunds, oldest first
+func (r *FirestorePaymentRepository) GetRefundsByPaymentID(ctx context.Context, paymentID string) ([]*models.Refund, error) {
+	return r.refunds(ctx, "paymentId", paymentID)
+}
+
+// GetRefundsByOrderID retrieves the refunds of an order's payments, oldest first
+func (r *FirestorePaymentRepository) GetRefundsByOrderID(ctx context.Context, orderID string) ([]*models.Refund, error) {
+	return r.refunds(ctx, "orderId", orderID)
+}
+
+// GetRefundsByStatus retrieves refunds in a status, oldest first
+func (r *FirestorePaymentRepository) GetRefundsByStatus(ctx context.Context, status models.RefundStatus) ([]*models.Refund, error) {
+	return r.refunds(ctx, "status", string(status))
+}
+
+func (r *FirestorePaymentRepository) refunds(ctx context.Context, field, value string) ([]*models.Refund, error) {
+	docs, err := r.client.Collection("refunds").
+		Where(field, "==", value).
+		OrderBy("createdAt", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	refunds := make([]*models.Refund, 0, len(docs))
+	for _, doc := range docs {
+		var refund models.Refund
+		if err := doc.DataTo(&refund); err != nil {
+			continue
+		}
+		refunds = append(refunds, &refund)
+	}
+	return refunds, nil
+}
+
+// MemoryPaymentRepository keeps payments in process memory
+type MemoryPaymentRepository struct {
+	payments *memoryCollection[models.Payment]
+	refunds  *memoryCollection[models.Refund]
+}
+
+// NewMemoryPaymentRepository creates an empty in-memory payment repository
+func NewMemoryPaymentRepository() *MemoryPaymentRepository {
+	return &MemoryPaymentRepository{payments: newMemoryCollection(func(p models.Payment) models.Payment {
+		if p.ProviderData != nil {
+			data := make(map[string]interface{}, len(p.ProviderData))
+			for k, v := range p.ProviderData {
+				data[k] = v
+			}
+			p.ProviderData = data
+		}
+		return p
+	}), refunds: newMemoryCollection[models.Refund](nil)}
+}
+
+func (r *MemoryPaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	if payment.ID == "" {
+		payment.ID = uuid.NewString()
+	}
+	if payment.CreatedAt.IsZero() {
+		payment.CreatedAt = time.Now()
+	}
+	payment.UpdatedAt = time.Now()
+	r.payments.set(payment.ID, *payment)
+	return nil
+}
+
+func (r *MemoryPaymentRepository) GetPaymentByID(ctx context.Context, paymentID string) (*models.Payment, error) {
+	return r.payments.get(paymentID)
+}
+
+func (r *MemoryPaymentRepository) GetPaymentByTransactionID(ctx context.Context, method, transactionID string) (*models.Payment, error) {
+	payments := r.payments.filter(func(p *models.Payment) bool {
+		return p.PaymentMethod == method && p.TransactionID == transactionID
+	})
+	if len(payments) == 0 {
+		return nil, ErrNotFound
+	}
+	return payments[0], nil
+}
+
+func (r *MemoryPaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID string) ([]*models.Payment, error) {
+	payments := r.payments.filter(func(p *models.Payment) bool { return p.OrderID == orderID })
+	return sortAndLimit(payments, OldestFirst, 0, paymentCreatedAt), nil
+}
+
+func (r *MemoryPaymentRepository) GetPaymentsByBuyerID(ctx context.Context, buyerID string) ([]*models.Payment, error) {
+	return r.ListPayments(ctx, PaymentFilter{BuyerID: buyerID})
+}
+
+func (r *MemoryPaymentRepository) UpdatePayment(ctx context.Context, paymentID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.payments.update(paymentID, updates)
+}
+
+func (r *MemoryPaymentRepository) UpdatePaymentStatus(ctx context.Context, paymentID string, status models.PaymentStatus) error {
+	updates := map[string]interface{}{"status": status}
+	now := time.Now()
+	if status == models.PaymentStatusCompleted {
+		updates["completedAt"] = now
+	} else if status == models.PaymentStatusFailed {
+		updates["failedAt"] = now
+	}
+	return r.UpdatePayment(ctx, paymentID, updates)
+}
+
+func (r *MemoryPaymentRepository) TransitionPayment(ctx context.Context, paymentID string, to models.PaymentStatus, updates map[string]interface{}) error {
+	updates["status"] = to
+	updates["updatedAt"] = time.Now()
+	return r.payments.mutate(paymentID, func(p *models.Payment) error {
+		if !p.Status.AcceptsWebhook(to) {
+			return fmt.Errorf("%w: payment %s is %s", ErrPaymentFinal, paymentID, p.Status)
+		}
+		return applyUpdates(p, updates)
+	})
+}
+
+func (r *MemoryPaymentRepository) GetPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]*models.Payment, error) {
+	return r.ListPayments(ctx, PaymentFilter{Status: status})
+}
+
+func (r *MemoryPaymentRepository) ListPayments(ctx context.Context, filter PaymentFilter) ([]*models.Payment, error) {
+	payments := r.payments.filter(func(p *models.Payment) bool {
+		if filter.OrderID != "" && p.OrderID != filter.OrderID {
+			return false
+		}
+		if filter.BuyerID != "" && p.BuyerID != filter.BuyerID {
+			return false
+		}
+		if filter.Status != "" && p.Status != filter.Status {
+			return false
+		}
+		return true
+	})
+	return sortAndLimit(payments, NewestFirst, filter.Limit, paymentCreatedAt), nil
+}
+
+func (r *MemoryPaymentRepository) CreateRefund(ctx context.Context, refund *models.Refund) error {
+	prepareRefund(refund)
+	err := r.payments.mutate(refund.PaymentID, func(p *models.Payment) error {
+		updates, err := reserveRefund(p, refund)
+		if err != nil {
+			return err
+		}
+		return applyUpdates(p, updates)
+	})
+	if err != nil {
+		return err
+	}
+	r.refunds.set(refund.ID, *refund)
+	return nil
+}
+
+func (r *MemoryPaymentRepository) FinishRefund(ctx context.Context, refundID string, status models.RefundStatus, providerRefundID, failureReason string) error {
+	return r.refunds.mutate(refundID, func(refund *models.Refund) error {
+		if refund.Status != models.RefundStatusPending {
+			return nil
+		}
+		if status == models.RefundStatusFailed {
+			err := r.payments.mutate(refund.PaymentID, func(p *models.Payment) error {
+				return applyUpdates(p, releaseRefund(p, refund))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return applyUpdates(refund, finishRefund(status, providerRefundID, failureReason))
+	})
+}
+
+func (r *MemoryPaymentRepository) GetRefundsByPaymentID(ctx context.Context, paymentID string) ([]*models.Refund, error) {
+	refunds := r.refunds.filter(func(rf *models.Refund) bool { return rf.PaymentID == paymentID })
+	return sortAndLimit(refunds, OldestFirst, 0, refundCreatedAt), nil
+}
+
+func (r *MemoryPaymentRepository) GetRefundsByOrderID(ctx context.Context, orderID string) ([]*models.Refund, error) {
+	refunds := r.refunds.filter(func(rf *models.Refund) bool { return rf.OrderID == orderID })
+	return sortAndLimit(refunds, OldestFirst, 0, refundCreatedAt), nil
+}
+
+func (r *MemoryPaymentRepository) GetRefundsByStatus(ctx context.Context, status models.RefundStatus) ([]*models.Refund, error) {
+	refunds := r.refunds.filter(func(rf *models.Refund) bool { return rf.Status == status })
+	return sortAndLimit(refunds, OldestFirst, 0, refundCreatedAt), nil
+}
+
+func paymentCreatedAt(p *models.Payment) time.Time { return p.CreatedAt }
+
+func refundCreatedAt(r *models.Refund) time.Time { return r.CreatedAt }
+
+// prepareRefund fills in a new refund's ID, timestamps and pending status
+func prepareRefund(refund *models.Refund) {
+	if refund.ID == "" {
+		refund.ID = uuid.NewString()
+	}
+	now := time.Now()
+	refund.Status = models.RefundStatusPending
+	refund.CreatedAt, refund.UpdatedAt = now, now
+}
+
+// reserveRefund checks a refund fits in what is left of its payment and returns the payment
+// updates that count it as refunded
+func reserveRefund(p *models.Payment, refund *models.Refund) (map[string]interface{}, error) {
+	if !p.Status.IsCaptured() {
+		return nil, fmt.Errorf("%w: payment %s is %s", ErrNotRefundable, p.ID, p.Status)
+	}
+	if !refund.Amount.IsPositive() || refund.Amount.Currency != p.Amount.Currency {
+		return nil, fmt.Errorf("%w: invalid refund amount %s for a %s payment", ErrNotRefundable, refund.Amount, p.Amount.Currency)
+	}
+	if left := p.NetCaptured(); refund.Amount.Cmp(left) > 0 {
+		return nil, fmt.Errorf("%w: refund of %s exceeds the %s left on payment %s", ErrNotRefundable, refund.Amount, left, p.ID)
+	}
+	refund.OrderID = p.OrderID
+	refunded := p.Refunded().Add(refund.Amount)
+	return map[string]interface{}{
+		"refundedAmount": refunded,
+		"status":         p.RefundedStatus(refunded),
+		"updatedAt":      time.Now(),
+	}, nil
+}
+
+// releaseRefund returns the payment updates that take a failed refund off its refunded amount
+func releaseRefund(p *models.Payment, refund *models.Refund) map[string]interface{} {
+	refunded := p.Refunded().Sub(refund.Amount).Max(models.NewMoney(0, p.Amount.Currency))
+	return map[string]interface{}{
+		"refundedAmount": refunded,
+		"status":         p.RefundedStatus(refunded),
+		"updatedAt":      time.Now(),
+	}
+}
+
+// finishRefund returns the refund updates recording a provider's answer
+func finishRefund(status models.RefundStatus, providerRefundID, failureReason string) map[string]interface{} {
+	updates := map[string]interface{}{
+		"status":    status,
+		"updatedAt": time.Now(),
+	}
+	if providerRefundID != "" {
+		updates["providerRefundId"] = providerRefundID
+	}
+	if failureReason != "" {
+		updates["failureReason"] = failureReason
+	}
+	return updates
+}
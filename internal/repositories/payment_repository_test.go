@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+func TestReserveRefund(t *testing.T) {
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+
+	tests := []struct {
+		name         string
+		status       models.PaymentStatus
+		refunded     models.Money
+		refund       models.Money
+		wantErr      bool
+		wantRefunded models.Money
+		wantStatus   models.PaymentStatus
+	}{
+		{"partial refund", models.PaymentStatusCompleted, models.Money{}, kes(3000), false, kes(3000), models.PaymentStatusPartiallyRefunded},
+		{"second partial refund", models.PaymentStatusPartiallyRefunded, kes(3000), kes(2000), false, kes(5000), models.PaymentStatusPartiallyRefunded},
+		{"refund of what is left", models.PaymentStatusPartiallyRefunded, kes(3000), kes(7000), false, kes(10000), models.PaymentStatusRefunded},
+		{"full refund", models.PaymentStatusCompleted, models.Money{}, kes(10000), false, kes(10000), models.PaymentStatusRefunded},
+		{"more than is left", models.PaymentStatusPartiallyRefunded, kes(3000), kes(7001), true, models.Money{}, ""},
+		{"payment already refunded in full", models.PaymentStatusRefunded, models.Money{}, kes(1), true, models.Money{}, ""},
+		{"payment not captured", models.PaymentStatusPending, models.Money{}, kes(1000), true, models.Money{}, ""},
+		{"zero amount", models.PaymentStatusCompleted, models.Money{}, kes(0), true, models.Money{}, ""},
+		{"negative amount", models.PaymentStatusCompleted, models.Money{}, kes(-100), true, models.Money{}, ""},
+		{"other currency", models.PaymentStatusCompleted, models.Money{}, models.NewMoney(1000, "USD"), true, models.Money{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &models.Payment{ID: "pay", OrderID: "order", Amount: kes(10000), Status: tt.status, RefundedAmount: tt.refunded}
+			refund := &models.Refund{PaymentID: "pay", Amount: tt.refund}
+
+			updates, err := reserveRefund(p, refund)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNotRefundable) {
+					t.Fatalf("err = %v, want ErrNotRefundable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := updates["refundedAmount"]; got != tt.wantRefunded {
+				t.Errorf("refundedAmount = %v, want %v", got, tt.wantRefunded)
+			}
+			if got := updates["status"]; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+			if refund.OrderID != "order" {
+				t.Errorf("refund OrderID = %q, want the payment's order", refund.OrderID)
+			}
+		})
+	}
+}
+
+func TestReleaseRefund(t *testing.T) {
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+
+	tests := []struct {
+		name         string
+		status       models.PaymentStatus
+		refunded     models.Money
+		refund       models.Money
+		wantRefunded models.Money
+		wantStatus   models.PaymentStatus
+	}{
+		{"only refund fails", models.PaymentStatusPartiallyRefunded, kes(3000), kes(3000), kes(0), models.PaymentStatusCompleted},
+		{"one of two refunds fails", models.PaymentStatusPartiallyRefunded, kes(5000), kes(2000), kes(3000), models.PaymentStatusPartiallyRefunded},
+		{"full refund fails", models.PaymentStatusRefunded, kes(10000), kes(10000), kes(0), models.PaymentStatusCompleted},
+		{"legacy full refund fails in part", models.PaymentStatusRefunded, models.Money{}, kes(4000), kes(6000), models.PaymentStatusPartiallyRefunded},
+		{"never below zero", models.PaymentStatusPartiallyRefunded, kes(1000), kes(3000), kes(0), models.PaymentStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &models.Payment{ID: "pay", Amount: kes(10000), Status: tt.status, RefundedAmount: tt.refunded}
+
+			updates := releaseRefund(p, &models.Refund{PaymentID: "pay", Amount: tt.refund})
+			if got := updates["refundedAmount"]; got != tt.wantRefunded {
+				t.Errorf("refundedAmount = %v, want %v", got, tt.wantRefunded)
+			}
+			if got := updates["status"]; got != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package repositories
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -12,14 +11,49 @@ import (
 	"google.golang.org/api/iterator"
 )
 
-// PrintShopRepository handles all Firestore operations for print shops
-type PrintShopRepository struct {
+// PrintShopRepository covers print shops and the catalog they configure (services, sizes, materials)
+type PrintShopRepository interface {
+	GetShopByOwnerID(ctx context.Context, ownerID string) (*models.PrintShopProfile, error)
+	GetShopByID(ctx context.Context, shopID string) (*models.PrintShopProfile, error)
+	CreateShop(ctx context.Context, shop *models.PrintShopProfile) error
+	UpdateShop(ctx context.Context, shopID string, updates map[string]interface{}) error
+	GetActiveShops(ctx context.Context) ([]*models.PrintShopProfile, error)
+	// ListShops returns every shop, newest first (limit 0 = no limit)
+	ListShops(ctx context.Context, limit int) ([]*models.PrintShopProfile, error)
+
+	GetServicesByShopID(ctx context.Context, shopID string) ([]*models.PrintService, error)
+	GetServiceByID(ctx context.Context, serviceID string) (*models.PrintService, error)
+	CreateService(ctx context.Context, service *models.PrintService) error
+	UpdateService(ctx context.Context, serviceID string, updates map[string]interface{}) error
+	DeleteService(ctx context.Context, serviceID string) error
+
+	GetSizesByShopID(ctx context.Context, shopID string) ([]*models.PrintSize, error)
+	CreateSize(ctx context.Context, size *models.PrintSize) error
+	UpdateSize(ctx context.Context, sizeID string, updates map[string]interface{}) error
+	DeleteSize(ctx context.Context, sizeID string) error
+
+	GetMaterialsByShopID(ctx context.Context, shopID string) ([]*models.Material, error)
+	CreateMaterial(ctx context.Context, material *models.Material) error
+	UpdateMaterial(ctx context.Context, materialID string, updates map[string]interface{}) error
+	DeleteMaterial(ctx context.Context, materialID string) error
+
+	// Medium operations
+	GetMediumsByShopID(ctx context.Context, shopID string) ([]*models.Medium, error)
+	CreateMedium(ctx context.Context, medium *models.Medium) error
+	UpdateMedium(ctx context.Context, mediumID string, updates map[string]interface{}) error
+	DeleteMedium(ctx context.Context, mediumID string) error
+
+	GetShopsByService(ctx context.Context, serviceID string) ([]*models.PrintShopProfile, error)
+}
+
+// FirestorePrintShopRepository handles all Firestore operations for print shops
+type FirestorePrintShopRepository struct {
 	client *firestore.Client
 }
 
 // NewPrintShopRepository creates a new repository instance
-func NewPrintShopRepository(client *firestore.Client) *PrintShopRepository {
-	return &PrintShopRepository{
+func NewPrintShopRepository(client *firestore.Client) *FirestorePrintShopRepository {
+	return &FirestorePrintShopRepository{
 		client: client,
 	}
 }
@@ -27,7 +61,7 @@ func NewPrintShopRepository(client *firestore.Client) *PrintShopRepository {
 // ==================== Shop Operations ====================
 
 // GetShopByOwnerID retrieves a print shop by owner's user ID
-func (r *PrintShopRepository) GetShopByOwnerID(ctx context.Context, ownerID string) (*models.PrintShopProfile, error) {
+func (r *FirestorePrintShopRepository) GetShopByOwnerID(ctx context.Context, ownerID string) (*models.PrintShopProfile, error) {
 	iter := r.client.Collection("printshops").
 		Where("ownerId", "==", ownerID).
 		Limit(1).
@@ -35,7 +69,7 @@ func (r *PrintShopRepository) GetShopByOwnerID(ctx context.Context, ownerID stri
 
 	doc, err := iter.Next()
 	if err == iterator.Done {
-		return nil, errors.New("print shop not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get shop: %w", err)
@@ -51,14 +85,17 @@ func (r *PrintShopRepository) GetShopByOwnerID(ctx context.Context, ownerID stri
 }
 
 // GetShopByID retrieves a print shop by its ID
-func (r *PrintShopRepository) GetShopByID(ctx context.Context, shopID string) (*models.PrintShopProfile, error) {
+func (r *FirestorePrintShopRepository) GetShopByID(ctx context.Context, shopID string) (*models.PrintShopProfile, error) {
 	doc, err := r.client.Collection("printshops").Doc(shopID).Get(ctx)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get shop: %w", err)
 	}
 
 	if !doc.Exists() {
-		return nil, errors.New("print shop not found")
+		return nil, ErrNotFound
 	}
 
 	var shop models.PrintShopProfile
@@ -71,7 +108,7 @@ func (r *PrintShopRepository) GetShopByID(ctx context.Context, shopID string) (*
 }
 
 // CreateShop creates a new print shop profile
-func (r *PrintShopRepository) CreateShop(ctx context.Context, shop *models.PrintShopProfile) error {
+func (r *FirestorePrintShopRepository) CreateShop(ctx context.Context, shop *models.PrintShopProfile) error {
 	if shop.ID == "" {
 		shop.ID = uuid.New().String()
 	}
@@ -91,19 +128,10 @@ func (r *PrintShopRepository) CreateShop(ctx context.Context, shop *models.Print
 }
 
 // UpdateShop updates a print shop profile
-func (r *PrintShopRepository) UpdateShop(ctx context.Context, shopID string, updates map[string]interface{}) error {
+func (r *FirestorePrintShopRepository) UpdateShop(ctx context.Context, shopID string, updates map[string]interface{}) error {
 	updates["updatedAt"] = time.Now()
 
-	// Convert map to []firestore.Update
-	var firestoreUpdates []firestore.Update
-	for path, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  path,
-			Value: value,
-		})
-	}
-
-	_, err := r.client.Collection("printshops").Doc(shopID).Update(ctx, firestoreUpdates)
+	_, err := r.client.Collection("printshops").Doc(shopID).Update(ctx, toFirestoreUpdates(updates))
 	if err != nil {
 		return fmt.Errorf("failed to update shop: %w", err)
 	}
@@ -112,7 +140,7 @@ func (r *PrintShopRepository) UpdateShop(ctx context.Context, shopID string, upd
 }
 
 // GetActiveShops retrieves all active print shops
-func (r *PrintShopRepository) GetActiveShops(ctx context.Context) ([]*models.PrintShopProfile, error) {
+func (r *FirestorePrintShopRepository) GetActiveShops(ctx context.Context) ([]*models.PrintShopProfile, error) {
 	iter := r.client.Collection("printshops").
 		Where("isActive", "==", true).
 		Documents(ctx)
@@ -138,10 +166,35 @@ func (r *PrintShopRepository) GetActiveShops(ctx context.Context) ([]*models.Pri
 	return shops, nil
 }
 
+// ListShops retrieves all print shops, newest first
+func (r *FirestorePrintShopRepository) ListShops(ctx context.Context, limit int) ([]*models.PrintShopProfile, error) {
+	q := r.client.Collection("printshops").OrderBy("createdAt", firestore.Desc)
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shops: %w", err)
+	}
+
+	shops := make([]*models.PrintShopProfile, 0, len(docs))
+	for _, doc := range docs {
+		var shop models.PrintShopProfile
+		if err := doc.DataTo(&shop); err != nil {
+			continue // Skip invalid documents
+		}
+		shop.ID = doc.Ref.ID
+		shops = append(shops, &shop)
+	}
+
+	return shops, nil
+}
+
 // ==================== Service Operations ====================
 
 // GetServicesByShopID retrieves all services for a print shop
-func (r *PrintShopRepository) GetServicesByShopID(ctx context.Context, shopID string) ([]*models.PrintService, error) {
+func (r *FirestorePrintShopRepository) GetServicesByShopID(ctx context.Context, shopID string) ([]*models.PrintService, error) {
 	iter := r.client.Collection("services").
 		Where("shopId", "==", shopID).
 		Documents(ctx)
@@ -168,14 +221,17 @@ func (r *PrintShopRepository) GetServicesByShopID(ctx context.Context, shopID st
 }
 
 // GetServiceByID retrieves a service by its ID
-func (r *PrintShopRepository) GetServiceByID(ctx context.Context, serviceID string) (*models.PrintService, error) {
+func (r *FirestorePrintShopRepository) GetServiceByID(ctx context.Context, serviceID string) (*models.PrintService, error) {
 	doc, err := r.client.Collection("services").Doc(serviceID).Get(ctx)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get service: %w", err)
 	}
 
 	if !doc.Exists() {
-		return nil, errors.New("service not found")
+		return nil, ErrNotFound
 	}
 
 	var service models.PrintService
@@ -188,7 +244,7 @@ func (r *PrintShopRepository) GetServiceByID(ctx context.Context, serviceID stri
 }
 
 // CreateService creates a new print service
-func (r *PrintShopRepository) CreateService(ctx context.Context, service *models.PrintService) error {
+func (r *FirestorePrintShopRepository) CreateService(ctx context.Context, service *models.PrintService) error {
 	if service.ID == "" {
 		service.ID = uuid.New().String()
 	}
@@ -226,19 +282,10 @@ func (r *PrintShopRepository) CreateService(ctx context.Context, service *models
 }
 
 // UpdateService updates a print service
-func (r *PrintShopRepository) UpdateService(ctx context.Context, serviceID string, updates map[string]interface{}) error {
+func (r *FirestorePrintShopRepository) UpdateService(ctx context.Context, serviceID string, updates map[string]interface{}) error {
 	updates["updatedAt"] = time.Now()
 
-	// Convert map to []firestore.Update
-	var firestoreUpdates []firestore.Update
-	for path, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  path,
-			Value: value,
-		})
-	}
-
-	_, err := r.client.Collection("services").Doc(serviceID).Update(ctx, firestoreUpdates)
+	_, err := r.client.Collection("services").Doc(serviceID).Update(ctx, toFirestoreUpdates(updates))
 	if err != nil {
 		return fmt.Errorf("failed to update service: %w", err)
 	}
@@ -247,7 +294,7 @@ func (r *PrintShopRepository) UpdateService(ctx context.Context, serviceID strin
 }
 
 // DeleteService deletes a print service
-func (r *PrintShopRepository) DeleteService(ctx context.Context, serviceID string) error {
+func (r *FirestorePrintShopRepository) DeleteService(ctx context.Context, serviceID string) error {
 	// Get service to find shop ID
 	service, err := r.GetServiceByID(ctx, serviceID)
 	if err != nil {
@@ -284,90 +331,10 @@ func (r *PrintShopRepository) DeleteService(ctx context.Context, serviceID strin
 	return nil
 }
 
-// ==================== Frame Operations ====================
-
-// GetFramesByShopID retrieves all frames for a print shop
-func (r *PrintShopRepository) GetFramesByShopID(ctx context.Context, shopID string) ([]*models.Frame, error) {
-	iter := r.client.Collection("frames").
-		Where("shopId", "==", shopID).
-		Documents(ctx)
-
-	var frames []*models.Frame
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate frames: %w", err)
-		}
-
-		var frame models.Frame
-		if err := doc.DataTo(&frame); err != nil {
-			continue
-		}
-		frame.ID = doc.Ref.ID
-		frames = append(frames, &frame)
-	}
-
-	return frames, nil
-}
-
-// CreateFrame creates a new frame configuration
-func (r *PrintShopRepository) CreateFrame(ctx context.Context, frame *models.Frame) error {
-	if frame.ID == "" {
-		frame.ID = uuid.New().String()
-	}
-	if frame.CreatedAt.IsZero() {
-		frame.CreatedAt = time.Now()
-	}
-	if frame.UpdatedAt.IsZero() {
-		frame.UpdatedAt = time.Now()
-	}
-
-	_, err := r.client.Collection("frames").Doc(frame.ID).Set(ctx, frame)
-	if err != nil {
-		return fmt.Errorf("failed to create frame: %w", err)
-	}
-
-	return nil
-}
-
-// UpdateFrame updates a frame configuration
-func (r *PrintShopRepository) UpdateFrame(ctx context.Context, frameID string, updates map[string]interface{}) error {
-	updates["updatedAt"] = time.Now()
-
-	// Convert map to []firestore.Update
-	var firestoreUpdates []firestore.Update
-	for path, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  path,
-			Value: value,
-		})
-	}
-
-	_, err := r.client.Collection("frames").Doc(frameID).Update(ctx, firestoreUpdates)
-	if err != nil {
-		return fmt.Errorf("failed to update frame: %w", err)
-	}
-
-	return nil
-}
-
-// DeleteFrame deletes a frame configuration
-func (r *PrintShopRepository) DeleteFrame(ctx context.Context, frameID string) error {
-	_, err := r.client.Collection("frames").Doc(frameID).Delete(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to delete frame: %w", err)
-	}
-
-	return nil
-}
-
 // ==================== Size Operations ====================
 
 // GetSizesByShopID retrieves all print sizes for a print shop
-func (r *PrintShopRepository) GetSizesByShopID(ctx context.Context, shopID string) ([]*models.PrintSize, error) {
+func (r *FirestorePrintShopRepository) GetSizesByShopID(ctx context.Context, shopID string) ([]*models.PrintSize, error) {
 	iter := r.client.Collection("sizes").
 		Where("shopId", "==", shopID).
 		Documents(ctx)
@@ -394,7 +361,7 @@ func (r *PrintShopRepository) GetSizesByShopID(ctx context.Context, shopID strin
 }
 
 // CreateSize creates a new print size configuration
-func (r *PrintShopRepository) CreateSize(ctx context.Context, size *models.PrintSize) error {
+func (r *FirestorePrintShopRepository) CreateSize(ctx context.Context, size *models.PrintSize) error {
 	if size.ID == "" {
 		size.ID = uuid.New().String()
 	}
@@ -414,19 +381,10 @@ func (r *PrintShopRepository) CreateSize(ctx context.Context, size *models.Print
 }
 
 // UpdateSize updates a print size configuration
-func (r *PrintShopRepository) UpdateSize(ctx context.Context, sizeID string, updates map[string]interface{}) error {
+func (r *FirestorePrintShopRepository) UpdateSize(ctx context.Context, sizeID string, updates map[string]interface{}) error {
 	updates["updatedAt"] = time.Now()
 
-	// Convert map to []firestore.Update
-	var firestoreUpdates []firestore.Update
-	for path, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  path,
-			Value: value,
-		})
-	}
-
-	_, err := r.client.Collection("sizes").Doc(sizeID).Update(ctx, firestoreUpdates)
+	_, err := r.client.Collection("sizes").Doc(sizeID).Update(ctx, toFirestoreUpdates(updates))
 	if err != nil {
 		return fmt.Errorf("failed to update size: %w", err)
 	}
@@ -435,7 +393,7 @@ func (r *PrintShopRepository) UpdateSize(ctx context.Context, sizeID string, upd
 }
 
 // DeleteSize deletes a print size configuration
-func (r *PrintShopRepository) DeleteSize(ctx context.Context, sizeID string) error {
+func (r *FirestorePrintShopRepository) DeleteSize(ctx context.Context, sizeID string) error {
 	_, err := r.client.Collection("sizes").Doc(sizeID).Delete(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to delete size: %w", err)
@@ -447,7 +405,7 @@ func (r *PrintShopRepository) DeleteSize(ctx context.Context, sizeID string) err
 // ==================== Material Operations ====================
 
 // GetMaterialsByShopID retrieves all materials for a print shop
-func (r *PrintShopRepository) GetMaterialsByShopID(ctx context.Context, shopID string) ([]*models.Material, error) {
+func (r *FirestorePrintShopRepository) GetMaterialsByShopID(ctx context.Context, shopID string) ([]*models.Material, error) {
 	iter := r.client.Collection("materials").
 		Where("shopId", "==", shopID).
 		Documents(ctx)
@@ -474,7 +432,7 @@ func (r *PrintShopRepository) GetMaterialsByShopID(ctx context.Context, shopID s
 }
 
 // CreateMaterial creates a new material configuration
-func (r *PrintShopRepository) CreateMaterial(ctx context.Context, material *models.Material) error {
+func (r *FirestorePrintShopRepository) CreateMaterial(ctx context.Context, material *models.Material) error {
 	if material.ID == "" {
 		material.ID = uuid.New().String()
 	}
@@ -494,19 +452,10 @@ func (r *PrintShopRepository) CreateMaterial(ctx context.Context, material *mode
 }
 
 // UpdateMaterial updates a material configuration
-func (r *PrintShopRepository) UpdateMaterial(ctx context.Context, materialID string, updates map[string]interface{}) error {
+func (r *FirestorePrintShopRepository) UpdateMaterial(ctx context.Context, materialID string, updates map[string]interface{}) error {
 	updates["updatedAt"] = time.Now()
 
-	// Convert map to []firestore.Update
-	var firestoreUpdates []firestore.Update
-	for path, value := range updates {
-		firestoreUpdates = append(firestoreUpdates, firestore.Update{
-			Path:  path,
-			Value: value,
-		})
-	}
-
-	_, err := r.client.Collection("materials").Doc(materialID).Update(ctx, firestoreUpdates)
+	_, err := r.client.Collection("materials").Doc(materialID).Update(ctx, toFirestoreUpdates(updates))
 	if err != nil {
 		return fmt.Errorf("failed to update material: %w", err)
 	}
@@ -515,7 +464,7 @@ func (r *PrintShopRepository) UpdateMaterial(ctx context.Context, materialID str
 }
 
 // DeleteMaterial deletes a material configuration
-func (r *PrintShopRepository) DeleteMaterial(ctx context.Context, materialID string) error {
+func (r *FirestorePrintShopRepository) DeleteMaterial(ctx context.Context, materialID string) error {
 	_, err := r.client.Collection("materials").Doc(materialID).Delete(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to delete material: %w", err)
@@ -524,10 +473,81 @@ func (r *PrintShopRepository) DeleteMaterial(ctx context.Context, materialID str
 	return nil
 }
 
+// ==================== Medium Operations ====================
+
+// GetMediumsByShopID retrieves all mediums for a print shop
+func (r *FirestorePrintShopRepository) GetMediumsByShopID(ctx context.Context, shopID string) ([]*models.Medium, error) {
+	iter := r.client.Collection("mediums").
+		Where("shopId", "==", shopID).
+		Documents(ctx)
+
+	var mediums []*models.Medium
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate mediums: %w", err)
+		}
+
+		var medium models.Medium
+		if err := doc.DataTo(&medium); err != nil {
+			continue
+		}
+		medium.ID = doc.Ref.ID
+		mediums = append(mediums, &medium)
+	}
+
+	return mediums, nil
+}
+
+// CreateMedium creates a new medium configuration
+func (r *FirestorePrintShopRepository) CreateMedium(ctx context.Context, medium *models.Medium) error {
+	if medium.ID == "" {
+		medium.ID = uuid.New().String()
+	}
+	if medium.CreatedAt.IsZero() {
+		medium.CreatedAt = time.Now()
+	}
+	if medium.UpdatedAt.IsZero() {
+		medium.UpdatedAt = time.Now()
+	}
+
+	_, err := r.client.Collection("mediums").Doc(medium.ID).Set(ctx, medium)
+	if err != nil {
+		return fmt.Errorf("failed to create medium: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateMedium updates a medium configuration
+func (r *FirestorePrintShopRepository) UpdateMedium(ctx context.Context, mediumID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+
+	_, err := r.client.Collection("mediums").Doc(mediumID).Update(ctx, toFirestoreUpdates(updates))
+	if err != nil {
+		return fmt.Errorf("failed to update medium: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMedium deletes a medium configuration
+func (r *FirestorePrintShopRepository) DeleteMedium(ctx context.Context, mediumID string) error {
+	_, err := r.client.Collection("mediums").Doc(mediumID).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete medium: %w", err)
+	}
+
+	return nil
+}
+
 // ==================== Matching Operations ====================
 
 // GetShopsByService retrieves all shops that offer a specific service
-func (r *PrintShopRepository) GetShopsByService(ctx context.Context, serviceID string) ([]*models.PrintShopProfile, error) {
+func (r *FirestorePrintShopRepository) GetShopsByService(ctx context.Context, serviceID string) ([]*models.PrintShopProfile, error) {
 	// First get the service to find shops that have it in their services list
 	service, err := r.GetServiceByID(ctx, serviceID)
 	if err != nil {
@@ -555,3 +575,234 @@ func (r *PrintShopRepository) GetShopsByService(ctx context.Context, serviceID s
 
 	return []*models.PrintShopProfile{shop}, nil
 }
+
+// ==================== In-Memory Implementation ====================
+
+// MemoryPrintShopRepository keeps shops and their catalog in process memory
+type MemoryPrintShopRepository struct {
+	shops     *memoryCollection[models.PrintShopProfile]
+	services  *memoryCollection[models.PrintService]
+	sizes     *memoryCollection[models.PrintSize]
+	materials *memoryCollection[models.Material]
+	mediums   *memoryCollection[models.Medium]
+}
+
+// NewMemoryPrintShopRepository creates an empty in-memory print shop repository
+func NewMemoryPrintShopRepository() *MemoryPrintShopRepository {
+	return &MemoryPrintShopRepository{
+		shops: newMemoryCollection(func(s models.PrintShopProfile) models.PrintShopProfile {
+			s.Services = append([]string(nil), s.Services...)
+			s.Capabilities = append([]string(nil), s.Capabilities...)
+			return s
+		}),
+		services: newMemoryCollection(func(s models.PrintService) models.PrintService {
+			pricing := make(map[string]float64, len(s.SizePricing))
+			for k, v := range s.SizePricing {
+				pricing[k] = v
+			}
+			s.SizePricing = pricing
+			s.QuantityTiers = append([]models.QuantityTier(nil), s.QuantityTiers...)
+			return s
+		}),
+		sizes:     newMemoryCollection[models.PrintSize](nil),
+		materials: newMemoryCollection[models.Material](nil),
+		mediums:   newMemoryCollection[models.Medium](nil),
+	}
+}
+
+func (r *MemoryPrintShopRepository) GetShopByOwnerID(ctx context.Context, ownerID string) (*models.PrintShopProfile, error) {
+	shops := r.shops.filter(func(s *models.PrintShopProfile) bool { return s.OwnerID == ownerID })
+	if len(shops) == 0 {
+		return nil, ErrNotFound
+	}
+	return shops[0], nil
+}
+
+func (r *MemoryPrintShopRepository) GetShopByID(ctx context.Context, shopID string) (*models.PrintShopProfile, error) {
+	return r.shops.get(shopID)
+}
+
+func (r *MemoryPrintShopRepository) CreateShop(ctx context.Context, shop *models.PrintShopProfile) error {
+	if shop.ID == "" {
+		shop.ID = uuid.New().String()
+	}
+	if shop.CreatedAt.IsZero() {
+		shop.CreatedAt = time.Now()
+	}
+	if shop.UpdatedAt.IsZero() {
+		shop.UpdatedAt = time.Now()
+	}
+	r.shops.set(shop.ID, *shop)
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) UpdateShop(ctx context.Context, shopID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.shops.update(shopID, updates)
+}
+
+func (r *MemoryPrintShopRepository) GetActiveShops(ctx context.Context) ([]*models.PrintShopProfile, error) {
+	return r.shops.filter(func(s *models.PrintShopProfile) bool { return s.IsActive }), nil
+}
+
+func (r *MemoryPrintShopRepository) ListShops(ctx context.Context, limit int) ([]*models.PrintShopProfile, error) {
+	shops := r.shops.filter(nil)
+	return sortAndLimit(shops, NewestFirst, limit, func(s *models.PrintShopProfile) time.Time { return s.CreatedAt }), nil
+}
+
+func (r *MemoryPrintShopRepository) GetServicesByShopID(ctx context.Context, shopID string) ([]*models.PrintService, error) {
+	return r.services.filter(func(s *models.PrintService) bool { return s.ShopID == shopID }), nil
+}
+
+func (r *MemoryPrintShopRepository) GetServiceByID(ctx context.Context, serviceID string) (*models.PrintService, error) {
+	return r.services.get(serviceID)
+}
+
+func (r *MemoryPrintShopRepository) CreateService(ctx context.Context, service *models.PrintService) error {
+	if service.ID == "" {
+		service.ID = uuid.New().String()
+	}
+	if service.CreatedAt.IsZero() {
+		service.CreatedAt = time.Now()
+	}
+	if service.UpdatedAt.IsZero() {
+		service.UpdatedAt = time.Now()
+	}
+	if service.SizePricing == nil {
+		service.SizePricing = make(map[string]float64)
+	}
+	r.services.set(service.ID, *service)
+
+	_ = r.shops.mutate(service.ShopID, func(shop *models.PrintShopProfile) error {
+		shop.Services = append(shop.Services, service.ID)
+		shop.UpdatedAt = time.Now()
+		return nil
+	})
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) UpdateService(ctx context.Context, serviceID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.services.update(serviceID, updates)
+}
+
+func (r *MemoryPrintShopRepository) DeleteService(ctx context.Context, serviceID string) error {
+	service, err := r.services.get(serviceID)
+	if err != nil {
+		return err
+	}
+	if err := r.services.delete(serviceID); err != nil {
+		return err
+	}
+
+	_ = r.shops.mutate(service.ShopID, func(shop *models.PrintShopProfile) error {
+		var newServices []string
+		for _, sID := range shop.Services {
+			if sID != serviceID {
+				newServices = append(newServices, sID)
+			}
+		}
+		shop.Services = newServices
+		shop.UpdatedAt = time.Now()
+		return nil
+	})
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) GetSizesByShopID(ctx context.Context, shopID string) ([]*models.PrintSize, error) {
+	return r.sizes.filter(func(s *models.PrintSize) bool { return s.ShopID == shopID }), nil
+}
+
+func (r *MemoryPrintShopRepository) CreateSize(ctx context.Context, size *models.PrintSize) error {
+	if size.ID == "" {
+		size.ID = uuid.New().String()
+	}
+	if size.CreatedAt.IsZero() {
+		size.CreatedAt = time.Now()
+	}
+	if size.UpdatedAt.IsZero() {
+		size.UpdatedAt = time.Now()
+	}
+	r.sizes.set(size.ID, *size)
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) UpdateSize(ctx context.Context, sizeID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.sizes.update(sizeID, updates)
+}
+
+func (r *MemoryPrintShopRepository) DeleteSize(ctx context.Context, sizeID string) error {
+	return r.sizes.delete(sizeID)
+}
+
+func (r *MemoryPrintShopRepository) GetMaterialsByShopID(ctx context.Context, shopID string) ([]*models.Material, error) {
+	return r.materials.filter(func(m *models.Material) bool { return m.ShopID == shopID }), nil
+}
+
+func (r *MemoryPrintShopRepository) CreateMaterial(ctx context.Context, material *models.Material) error {
+	if material.ID == "" {
+		material.ID = uuid.New().String()
+	}
+	if material.CreatedAt.IsZero() {
+		material.CreatedAt = time.Now()
+	}
+	if material.UpdatedAt.IsZero() {
+		material.UpdatedAt = time.Now()
+	}
+	r.materials.set(material.ID, *material)
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) UpdateMaterial(ctx context.Context, materialID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.materials.update(materialID, updates)
+}
+
+func (r *MemoryPrintShopRepository) DeleteMaterial(ctx context.Context, materialID string) error {
+	return r.materials.delete(materialID)
+}
+
+func (r *MemoryPrintShopRepository) GetMediumsByShopID(ctx context.Context, shopID string) ([]*models.Medium, error) {
+	return r.mediums.filter(func(m *models.Medium) bool { return m.ShopID == shopID }), nil
+}
+
+func (r *MemoryPrintShopRepository) CreateMedium(ctx context.Context, medium *models.Medium) error {
+	if medium.ID == "" {
+		medium.ID = uuid.New().String()
+	}
+	if medium.CreatedAt.IsZero() {
+		medium.CreatedAt = time.Now()
+	}
+	if medium.UpdatedAt.IsZero() {
+		medium.UpdatedAt = time.Now()
+	}
+	r.mediums.set(medium.ID, *medium)
+	return nil
+}
+
+func (r *MemoryPrintShopRepository) UpdateMedium(ctx context.Context, mediumID string, updates map[string]interface{}) error {
+	updates["updatedAt"] = time.Now()
+	return r.mediums.update(mediumID, updates)
+}
+
+func (r *MemoryPrintShopRepository) DeleteMedium(ctx context.Context, mediumID string) error {
+	return r.mediums.delete(mediumID)
+}
+
+func (r *MemoryPrintShopRepository) GetShopsByService(ctx context.Context, serviceID string) ([]*models.PrintShopProfile, error) {
+	service, err := r.GetServiceByID(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	shop, err := r.GetShopByID(ctx, service.ShopID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sID := range shop.Services {
+		if sID == serviceID {
+			return []*models.PrintShopProfile{shop}, nil
+		}
+	}
+	return []*models.PrintShopProfile{}, nil
+}
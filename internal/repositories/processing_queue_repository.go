@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ProcessingQueueRepository is the job queue between upload handlers and the processing worker
+type ProcessingQueueRepository interface {
+	Enqueue(ctx context.Context, job *models.ProcessingJob) error
+	GetPendingJobs(ctx context.Context, limit int) ([]*models.ProcessingJob, error)
+	MarkProcessing(ctx context.Context, jobID string) error
+	MarkDone(ctx context.Context, jobID string) error
+	MarkFailed(ctx context.Context, jobID string, reason string) error
+}
+
+// FirestoreProcessingQueueRepository stores jobs in the processing_queue collection
+type FirestoreProcessingQueueRepository struct {
+	client *firestore.Client
+}
+
+// NewProcessingQueueRepository creates a new Firestore-backed processing queue
+func NewProcessingQueueRepository(client *firestore.Client) *FirestoreProcessingQueueRepository {
+	return &FirestoreProcessingQueueRepository{client: client}
+}
+
+// Enqueue adds a pending job to the queue
+func (r *FirestoreProcessingQueueRepository) Enqueue(ctx context.Context, job *models.ProcessingJob) error {
+	job.Status = models.JobStatusPending
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	ref, _, err := r.client.Collection("processing_queue").Add(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	job.ID = ref.ID
+	return nil
+}
+
+// GetPendingJobs returns up to limit jobs waiting to be processed
+func (r *FirestoreProcessingQueueRepository) GetPendingJobs(ctx context.Context, limit int) ([]*models.ProcessingJob, error) {
+	docs, err := r.client.Collection("processing_queue").
+		Where("status", "==", models.JobStatusPending).
+		Limit(limit).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processing_queue: %w", err)
+	}
+
+	jobs := make([]*models.ProcessingJob, 0, len(docs))
+	for _, doc := range docs {
+		var job models.ProcessingJob
+		if err := doc.DataTo(&job); err != nil {
+			continue
+		}
+		job.ID = doc.Ref.ID
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// MarkProcessing flags a job as picked up by a worker
+func (r *FirestoreProcessingQueueRepository) MarkProcessing(ctx context.Context, jobID string) error {
+	return r.update(ctx, jobID, []firestore.Update{{Path: "status", Value: models.JobStatusProcessing}, {Path: "startedAt", Value: time.Now()}})
+}
+
+// MarkDone flags a job as successfully processed
+func (r *FirestoreProcessingQueueRepository) MarkDone(ctx context.Context, jobID string) error {
+	return r.update(ctx, jobID, []firestore.Update{{Path: "status", Value: models.JobStatusDone}, {Path: "finishedAt", Value: time.Now()}})
+}
+
+// MarkFailed flags a job as failed with a reason
+func (r *FirestoreProcessingQueueRepository) MarkFailed(ctx context.Context, jobID string, reason string) error {
+	return r.update(ctx, jobID, []firestore.Update{{Path: "status", Value: models.JobStatusFailed}, {Path: "error", Value: reason}})
+}
+
+func (r *FirestoreProcessingQueueRepository) update(ctx context.Context, jobID string, updates []firestore.Update) error {
+	if _, err := r.client.Collection("processing_queue").Doc(jobID).Update(ctx, updates); err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// MemoryProcessingQueueRepository keeps jobs in process memory
+type MemoryProcessingQueueRepository struct {
+	jobs *memoryCollection[models.ProcessingJob]
+}
+
+// NewMemoryProcessingQueueRepository creates an empty in-memory processing queue
+func NewMemoryProcessingQueueRepository() *MemoryProcessingQueueRepository {
+	return &MemoryProcessingQueueRepository{jobs: newMemoryCollection[models.ProcessingJob](nil)}
+}
+
+func (r *MemoryProcessingQueueRepository) Enqueue(ctx context.Context, job *models.ProcessingJob) error {
+	job.ID = uuid.NewString()
+	job.Status = models.JobStatusPending
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	r.jobs.set(job.ID, *job)
+	return nil
+}
+
+func (r *MemoryProcessingQueueRepository) GetPendingJobs(ctx context.Context, limit int) ([]*models.ProcessingJob, error) {
+	jobs := r.jobs.filter(func(j *models.ProcessingJob) bool { return j.Status == models.JobStatusPending })
+	return sortAndLimit(jobs, OldestFirst, limit, func(j *models.ProcessingJob) time.Time { return j.CreatedAt }), nil
+}
+
+func (r *MemoryProcessingQueueRepository) MarkProcessing(ctx context.Context, jobID string) error {
+	return r.jobs.mutate(jobID, func(j *models.ProcessingJob) error {
+		now := time.Now()
+		j.Status = models.JobStatusProcessing
+		j.StartedAt = &now
+		return nil
+	})
+}
+
+func (r *MemoryProcessingQueueRepository) MarkDone(ctx context.Context, jobID string) error {
+	return r.jobs.mutate(jobID, func(j *models.ProcessingJob) error {
+		now := time.Now()
+		j.Status = models.JobStatusDone
+		j.FinishedAt = &now
+		return nil
+	})
+}
+
+func (r *MemoryProcessingQueueRepository) MarkFailed(ctx context.Context, jobID string, reason string) error {
+	return r.jobs.mutate(jobID, func(j *models.ProcessingJob) error {
+		j.Status = models.JobStatusFailed
+		j.Error = reason
+		return nil
+	})
+}
@@ -0,0 +1,393 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrCodeTaken is returned when creating a promotion whose code is already in use
+var ErrCodeTaken = errors.New("promotion code already exists")
+
+// ErrRedemptionLimit is returned by Redeem when the promotion's usage or per-buyer limit is reached
+var ErrRedemptionLimit = errors.New("promotion redemption limit reached")
+
+// PromotionRepository stores promotions and the orders that redeemed them
+type PromotionRepository interface {
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	ListPromotions(ctx context.Context, filter PromotionFilter) ([]*models.Promotion, error)
+	UpdatePromotion(ctx context.Context, promotionID string, updates map[string]interface{}) error
+	// Redeem records a redemption and bumps the promotion's redemption count in one step,
+	// failing with ErrRedemptionLimit when either limit would be exceeded
+	Redeem(ctx context.Context, redemption *models.PromotionRedemption) error
+	// ReleaseRedemptions removes every redemption of an order and gives the uses back
+	ReleaseRedemptions(ctx context.Context, orderID string) error
+	CountRedemptions(ctx context.Context, promotionID, buyerID string) (int, error)
+	ListRedemptions(ctx context.Context, promotionID string, limit int) ([]*models.PromotionRedemption, error)
+}
+
+// PromotionFilter narrows ListPromotions; zero values are ignored
+type PromotionFilter struct {
+	ActiveOnly bool
+	Sort       SortOrder
+	Limit      int
+}
+
+// checkRedemptionLimits reports whether one more redemption fits the promotion's limits
+func checkRedemptionLimits(promotion *models.Promotion, buyerRedemptions int) error {
+	if promotion.UsageLimit > 0 && promotion.RedemptionCount >= promotion.UsageLimit {
+		return ErrRedemptionLimit
+	}
+	if promotion.PerBuyerLimit > 0 && buyerRedemptions >= promotion.PerBuyerLimit {
+		return ErrRedemptionLimit
+	}
+	return nil
+}
+
+// FirestorePromotionRepository handles promotion data operations in Firestore
+type FirestorePromotionRepository struct {
+	client *firestore.Client
+}
+
+// NewPromotionRepository creates a new Firestore-backed promotion repository
+func NewPromotionRepository(client *firestore.Client) *FirestorePromotionRepository {
+	return &FirestorePromotionRepository{client: client}
+}
+
+// CreatePromotion writes a new promotion, checking the code is unused in the same transaction
+func (r *FirestorePromotionRepository) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	if promotion.ID == "" {
+		promotion.ID = uuid.NewString()
+	}
+	if promotion.CreatedAt.IsZero() {
+		promotion.CreatedAt = time.Now()
+	}
+	promotion.UpdatedAt = time.Now()
+
+	coll := r.client.Collection("promotions")
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := tx.Documents(coll.Where("code", "==", promotion.Code).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return ErrCodeTaken
+		}
+		return tx.Create(coll.Doc(promotion.ID), promotion)
+	})
+	if err != nil {
+		if errors.Is(err, ErrCodeTaken) {
+			return err
+		}
+		return fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return nil
+}
+
+// GetPromotionByID retrieves a promotion by its ID
+func (r *FirestorePromotionRepository) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	doc, err := r.client.Collection("promotions").Doc(promotionID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+	var promotion models.Promotion
+	if err := doc.DataTo(&promotion); err != nil {
+		return nil, fmt.Errorf("failed to parse promotion data: %w", err)
+	}
+	return &promotion, nil
+}
+
+// GetPromotionByCode retrieves a promotion by its coupon code
+func (r *FirestorePromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	docs, err := r.client.Collection("promotions").
+		Where("code", "==", models.NormalizeCode(code)).
+		Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	var promotion models.Promotion
+	if err := docs[0].DataTo(&promotion); err != nil {
+		return nil, fmt.Errorf("failed to parse promotion data: %w", err)
+	}
+	return &promotion, nil
+}
+
+// ListPromotions queries promotions with optional filters
+func (r *FirestorePromotionRepository) ListPromotions(ctx context.Context, filter PromotionFilter) ([]*models.Promotion, error) {
+	q := r.client.Collection("promotions").Query
+	if filter.ActiveOnly {
+		q = q.Where("isActive", "==", true)
+	}
+	q = applySort(q, filter.Sort)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promotions: %w", err)
+	}
+	promotions := make([]*models.Promotion, 0, len(docs))
+	for _, doc := range docs {
+		var promotion models.Promotion
+		if err := doc.DataTo(&promotion); err != nil {
+			return nil, fmt.Errorf("failed to parse promotion data: %w", err)
+		}
+		promotions = append(promotions, &promotion)
+	}
+	return promotions, nil
+}
+
+// UpdatePromotion applies a partial update to a promotion
+func (r *FirestorePromotionRepository) UpdatePromotion(ctx context.Context, promotionID string, updates map[string]interface{}) error {
+	_, err := r.client.Collection("promotions").Doc(promotionID).Update(ctx, toFirestoreUpdates(updates))
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+	return nil
+}
+
+// Redeem checks the limits and records the redemption inside a transaction, so concurrent
+// checkouts cannot redeem a limited promotion more often than allowed
+func (r *FirestorePromotionRepository) Redeem(ctx context.Context, redemption *models.PromotionRedemption) error {
+	if redemption.ID == "" {
+		redemption.ID = uuid.NewString()
+	}
+	if redemption.CreatedAt.IsZero() {
+		redemption.CreatedAt = time.Now()
+	}
+
+	promoRef := r.client.Collection("promotions").Doc(redemption.PromotionID)
+	redemptions := r.client.Collection("promotion_redemptions")
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(promoRef)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var promotion models.Promotion
+		if err := doc.DataTo(&promotion); err != nil {
+			return fmt.Errorf("failed to parse promotion data: %w", err)
+		}
+		used := 0
+		if promotion.PerBuyerLimit > 0 {
+			docs, err := tx.Documents(redemptions.
+				Where("promotionId", "==", redemption.PromotionID).
+				Where("buyerId", "==", redemption.BuyerID)).GetAll()
+			if err != nil {
+				return err
+			}
+			used = len(docs)
+		}
+		if err := checkRedemptionLimits(&promotion, used); err != nil {
+			return err
+		}
+
+		if err := tx.Update(promoRef, []firestore.Update{
+			{Path: "redemptionCount", Value: firestore.Increment(1)},
+			{Path: "updatedAt", Value: time.Now()},
+		}); err != nil {
+			return err
+		}
+		return tx.Create(redemptions.Doc(redemption.ID), redemption)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrRedemptionLimit) {
+			return err
+		}
+		return fmt.Errorf("failed to redeem promotion: %w", err)
+	}
+	return nil
+}
+
+// ReleaseRedemptions deletes the order's redemptions and decrements each promotion's count
+func (r *FirestorePromotionRepository) ReleaseRedemptions(ctx context.Context, orderID string) error {
+	redemptions := r.client.Collection("promotion_redemptions")
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(redemptions.Where("orderId", "==", orderID)).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			var redemption models.PromotionRedemption
+			if err := doc.DataTo(&redemption); err != nil {
+				return fmt.Errorf("failed to parse redemption data: %w", err)
+			}
+			if err := tx.Update(r.client.Collection("promotions").Doc(redemption.PromotionID), []firestore.Update{
+				{Path: "redemptionCount", Value: firestore.Increment(-1)},
+				{Path: "updatedAt", Value: time.Now()},
+			}); err != nil {
+				return err
+			}
+			if err := tx.Delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release redemptions: %w", err)
+	}
+	return nil
+}
+
+// CountRedemptions counts a promotion's redemptions, optionally by one buyer
+func (r *FirestorePromotionRepository) CountRedemptions(ctx context.Context, promotionID, buyerID string) (int, error) {
+	q := r.client.Collection("promotion_redemptions").Where("promotionId", "==", promotionID)
+	if buyerID != "" {
+		q = q.Where("buyerId", "==", buyerID)
+	}
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count redemptions: %w", err)
+	}
+	return len(docs), nil
+}
+
+// ListRedemptions returns a promotion's redemptions, newest first
+func (r *FirestorePromotionRepository) ListRedemptions(ctx context.Context, promotionID string, limit int) ([]*models.PromotionRedemption, error) {
+	q := r.client.Collection("promotion_redemptions").
+		Where("promotionId", "==", promotionID).
+		OrderBy("createdAt", firestore.Desc)
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list redemptions: %w", err)
+	}
+	out := make([]*models.PromotionRedemption, 0, len(docs))
+	for _, doc := range docs {
+		var redemption models.PromotionRedemption
+		if err := doc.DataTo(&redemption); err != nil {
+			return nil, fmt.Errorf("failed to parse redemption data: %w", err)
+		}
+		out = append(out, &redemption)
+	}
+	return out, nil
+}
+
+// MemoryPromotionRepository keeps promotions and redemptions in process memory
+type MemoryPromotionRepository struct {
+	mu          sync.Mutex // serialises code checks and redemptions across both collections
+	promotions  *memoryCollection[models.Promotion]
+	redemptions *memoryCollection[models.PromotionRedemption]
+}
+
+// NewMemoryPromotionRepository creates an empty in-memory promotion repository
+func NewMemoryPromotionRepository() *MemoryPromotionRepository {
+	return &MemoryPromotionRepository{
+		promotions:  newMemoryCollection[models.Promotion](nil),
+		redemptions: newMemoryCollection[models.PromotionRedemption](nil),
+	}
+}
+
+func (r *MemoryPromotionRepository) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing := r.promotions.filter(func(p *models.Promotion) bool { return p.Code == promotion.Code }); len(existing) > 0 {
+		return ErrCodeTaken
+	}
+	if promotion.ID == "" {
+		promotion.ID = uuid.NewString()
+	}
+	if promotion.CreatedAt.IsZero() {
+		promotion.CreatedAt = time.Now()
+	}
+	promotion.UpdatedAt = time.Now()
+	r.promotions.set(promotion.ID, *promotion)
+	return nil
+}
+
+func (r *MemoryPromotionRepository) GetPromotionByID(ctx context.Context, promotionID string) (*models.Promotion, error) {
+	return r.promotions.get(promotionID)
+}
+
+func (r *MemoryPromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	code = models.NormalizeCode(code)
+	found := r.promotions.filter(func(p *models.Promotion) bool { return p.Code == code })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return found[0], nil
+}
+
+func (r *MemoryPromotionRepository) ListPromotions(ctx context.Context, filter PromotionFilter) ([]*models.Promotion, error) {
+	found := r.promotions.filter(func(p *models.Promotion) bool { return !filter.ActiveOnly || p.IsActive })
+	return sortAndLimit(found, filter.Sort, filter.Limit, func(p *models.Promotion) time.Time { return p.CreatedAt }), nil
+}
+
+func (r *MemoryPromotionRepository) UpdatePromotion(ctx context.Context, promotionID string, updates map[string]interface{}) error {
+	return r.promotions.update(promotionID, updates)
+}
+
+func (r *MemoryPromotionRepository) Redeem(ctx context.Context, redemption *models.PromotionRedemption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	used := len(r.redemptions.filter(func(d *models.PromotionRedemption) bool {
+		return d.PromotionID == redemption.PromotionID && d.BuyerID == redemption.BuyerID
+	}))
+	err := r.promotions.mutate(redemption.PromotionID, func(p *models.Promotion) error {
+		if err := checkRedemptionLimits(p, used); err != nil {
+			return err
+		}
+		p.RedemptionCount++
+		p.UpdatedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if redemption.ID == "" {
+		redemption.ID = uuid.NewString()
+	}
+	if redemption.CreatedAt.IsZero() {
+		redemption.CreatedAt = time.Now()
+	}
+	r.redemptions.set(redemption.ID, *redemption)
+	return nil
+}
+
+func (r *MemoryPromotionRepository) ReleaseRedemptions(ctx context.Context, orderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, redemption := range r.redemptions.filter(func(d *models.PromotionRedemption) bool { return d.OrderID == orderID }) {
+		r.promotions.mutate(redemption.PromotionID, func(p *models.Promotion) error {
+			p.RedemptionCount = max(p.RedemptionCount-1, 0)
+			p.UpdatedAt = time.Now()
+			return nil
+		})
+		r.redemptions.delete(redemption.ID)
+	}
+	return nil
+}
+
+func (r *MemoryPromotionRepository) CountRedemptions(ctx context.Context, promotionID, buyerID string) (int, error) {
+	return len(r.redemptions.filter(func(d *models.PromotionRedemption) bool {
+		return d.PromotionID == promotionID && (buyerID == "" || d.BuyerID == buyerID)
+	})), nil
+}
+
+func (r *MemoryPromotionRepository) ListRedemptions(ctx context.Context, promotionID string, limit int) ([]*models.PromotionRedemption, error) {
+	found := r.redemptions.filter(func(d *models.PromotionRedemption) bool { return d.PromotionID == promotionID })
+	return sortAndLimit(found, NewestFirst, limit, func(d *models.PromotionRedemption) time.Time { return d.CreatedAt }), nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotFound is returned by every repository when the requested document does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrIllegalTransition is returned when an order status change is not allowed by the lifecycle
+var ErrIllegalTransition = errors.New("illegal order status transition")
+
+// ErrVersionConflict is returned when a document changed since the version the caller expected
+var ErrVersionConflict = errors.New("version conflict")
+
+// AnyVersion tells versioned updates to skip the version check
+const AnyVersion int64 = -1
+
+// SortOrder controls how list queries order results by createdAt
+type SortOrder int
+
+const (
+	Unsorted SortOrder = iota
+	NewestFirst
+	OldestFirst
+)
+
+// Store bundles every repository the HTTP API and services depend on.
+// Use NewFirestoreStore in production and NewMemoryStore in tests.
+type Store struct {
+	Orders        OrderRepository
+	Carts         CartRepository
+	Artworks      ArtworkRepository
+	Users         UserRepository
+	Frames        FrameRepository
+	Queue         ProcessingQueueRepository
+	PrintShops    PrintShopRepository
+	Payments      PaymentRepository
+	PaymentEvents PaymentEventRepository
+	ActivityLog   ActivityLogRepository
+	Settings      SettingsRepository
+	Idempotency   IdempotencyRepository
+	Shipments     ShipmentRepository
+	Promotions    PromotionRepository
+}
+
+// NewFirestoreStore creates a store backed by Firestore
+func NewFirestoreStore(client *firestore.Client) *Store {
+	return &Store{
+		Orders:        NewOrderRepository(client),
+		Carts:         NewCartRepository(client),
+		Artworks:      NewArtworkRepository(client),
+		Users:         NewUserRepository(client),
+		Frames:        NewFrameRepository(client),
+		Queue:         NewProcessingQueueRepository(client),
+		PrintShops:    NewPrintShopRepository(client),
+		Payments:      NewPaymentRepository(client),
+		PaymentEvents: NewPaymentEventRepository(client),
+		ActivityLog:   NewActivityLogRepository(client),
+		Settings:      NewSettingsRepository(client),
+		Idempotency:   NewIdempotencyRepository(client),
+		Shipments:     NewShipmentRepository(client),
+		Promotions:    NewPromotionRepository(client),
+	}
+}
+
+// NewMemoryStore creates a store that keeps everything in process memory
+func NewMemoryStore() *Store {
+	orders := NewMemoryOrderRepository()
+	return &Store{
+		Orders:        orders,
+		Carts:         NewMemoryCartRepository(orders),
+		Artworks:      NewMemoryArtworkRepository(),
+		Users:         NewMemoryUserRepository(),
+		Frames:        NewMemoryFrameRepository(),
+		Queue:         NewMemoryProcessingQueueRepository(),
+		PrintShops:    NewMemoryPrintShopRepository(),
+		Payments:      NewMemoryPaymentRepository(),
+		PaymentEvents: NewMemoryPaymentEventRepository(),
+		ActivityLog:   NewMemoryActivityLogRepository(),
+		Settings:      NewMemorySettingsRepository(),
+		Idempotency:   NewMemoryIdempotencyRepository(),
+		Shipments:     NewMemoryShipmentRepository(),
+		Promotions:    NewMemoryPromotionRepository(),
+	}
+}
+
+// isNotFound reports whether a Firestore error means the document is missing
+func isNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// toFirestoreUpdates converts a field map to []firestore.Update
+func toFirestoreUpdates(updates map[string]interface{}) []firestore.Update {
+	firestoreUpdates := make([]firestore.Update, 0, len(updates))
+	for path, value := range updates {
+		firestoreUpdates = append(firestoreUpdates, firestore.Update{
+			Path:  path,
+			Value: value,
+		})
+	}
+	return firestoreUpdates
+}
+
+// applySort adds the createdAt ordering requested by a list filter
+func applySort(q firestore.Query, order SortOrder) firestore.Query {
+	switch order {
+	case NewestFirst:
+		return q.OrderBy("createdAt", firestore.Desc)
+	case OldestFirst:
+		return q.OrderBy("createdAt", firestore.Asc)
+	}
+	return q
+}
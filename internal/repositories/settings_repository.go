@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// Documents in the settings collection
+const (
+	globalSettingsID  = "global"  // fulfillment mode and overrides
+	scoringSettingsID = "scoring" // smart matcher weights and tables
+	taxSettingsID     = "tax"     // tax jurisdictions and rates
+)
+
+// SettingsRepository stores platform settings in the settings collection.
+// Getters return ErrNotFound until the settings have been saved once.
+type SettingsRepository interface {
+	GetFulfillmentSettings(ctx context.Context) (*models.FulfillmentSettings, error)
+	SaveFulfillmentSettings(ctx context.Context, settings *models.FulfillmentSettings) error
+	GetScoringConfig(ctx context.Context) (*models.SmartScoringConfig, error)
+	SaveScoringConfig(ctx context.Context, config *models.SmartScoringConfig) error
+	GetTaxSettings(ctx context.Context) (*models.TaxSettings, error)
+	SaveTaxSettings(ctx context.Context, settings *models.TaxSettings) error
+}
+
+// FirestoreSettingsRepository handles settings in Firestore
+type FirestoreSettingsRepository struct {
+	client *firestore.Client
+}
+
+// NewSettingsRepository creates a new Firestore-backed settings repository
+func NewSettingsRepository(client *firestore.Client) *FirestoreSettingsRepository {
+	return &FirestoreSettingsRepository{client: client}
+}
+
+// GetFulfillmentSettings reads the fulfillment settings from settings/global
+func (r *FirestoreSettingsRepository) GetFulfillmentSettings(ctx context.Context) (*models.FulfillmentSettings, error) {
+	doc, err := r.client.Collection("settings").Doc(globalSettingsID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	var settings models.FulfillmentSettings
+	if err := doc.DataTo(&settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveFulfillmentSettings merges the fulfillment settings into settings/global,
+// leaving any other settings stored in the document untouched
+func (r *FirestoreSettingsRepository) SaveFulfillmentSettings(ctx context.Context, settings *models.FulfillmentSettings) error {
+	settings.UpdatedAt = time.Now()
+	_, err := r.client.Collection("settings").Doc(globalSettingsID).Set(ctx, map[string]interface{}{
+		"fulfillmentMode":      settings.FulfillmentMode,
+		"shopOverrides":        settings.ShopOverrides,
+		"categoryOverrides":    settings.CategoryOverrides,
+		"acceptanceSlaMinutes": settings.AcceptanceSLAMinutes,
+		"updatedBy":            settings.UpdatedBy,
+		"updatedAt":            settings.UpdatedAt,
+	}, firestore.MergeAll)
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
+
+// GetScoringConfig reads the smart scoring config from settings/scoring
+func (r *FirestoreSettingsRepository) GetScoringConfig(ctx context.Context) (*models.SmartScoringConfig, error) {
+	doc, err := r.client.Collection("settings").Doc(scoringSettingsID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get scoring config: %w", err)
+	}
+
+	var config models.SmartScoringConfig
+	if err := doc.DataTo(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse scoring config: %w", err)
+	}
+	return &config, nil
+}
+
+// SaveScoringConfig replaces the smart scoring config in settings/scoring
+func (r *FirestoreSettingsRepository) SaveScoringConfig(ctx context.Context, config *models.SmartScoringConfig) error {
+	config.UpdatedAt = time.Now()
+	if _, err := r.client.Collection("settings").Doc(scoringSettingsID).Set(ctx, config); err != nil {
+		return fmt.Errorf("failed to save scoring config: %w", err)
+	}
+	return nil
+}
+
+// GetTaxSettings reads the tax jurisdictions from settings/tax
+func (r *FirestoreSettingsRepository) GetTaxSettings(ctx context.Context) (*models.TaxSettings, error) {
+	doc, err := r.client.Collection("settings").Doc(taxSettingsID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get tax settings: %w", err)
+	}
+
+	var settings models.TaxSettings
+	if err := doc.DataTo(&settings); err != nil {
+		return nil, fmt.Errorf("failed to parse tax settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveTaxSettings replaces the tax jurisdictions in settings/tax
+func (r *FirestoreSettingsRepository) SaveTaxSettings(ctx context.Context, settings *models.TaxSettings) error {
+	settings.UpdatedAt = time.Now()
+	if _, err := r.client.Collection("settings").Doc(taxSettingsID).Set(ctx, settings); err != nil {
+		return fmt.Errorf("failed to save tax settings: %w", err)
+	}
+	return nil
+}
+
+// MemorySettingsRepository keeps settings in process memory
+type MemorySettingsRepository struct {
+	settings *memoryCollection[models.FulfillmentSettings]
+	scoring  *memoryCollection[models.SmartScoringConfig]
+	tax      *memoryCollection[models.TaxSettings]
+}
+
+// NewMemorySettingsRepository creates an empty in-memory settings repository
+func NewMemorySettingsRepository() *MemorySettingsRepository {
+	return &MemorySettingsRepository{
+		settings: newMemoryCollection(func(s models.FulfillmentSettings) models.FulfillmentSettings {
+			s.ShopOverrides = copyModes(s.ShopOverrides)
+			s.CategoryOverrides = copyModes(s.CategoryOverrides)
+			return s
+		}),
+		scoring: newMemoryCollection(func(c models.SmartScoringConfig) models.SmartScoringConfig {
+			scores := make(map[string]float64, len(c.TechnologyScores))
+			for k, v := range c.TechnologyScores {
+				scores[k] = v
+			}
+			c.TechnologyScores = scores
+			return c
+		}),
+		tax: newMemoryCollection(func(s models.TaxSettings) models.TaxSettings {
+			jurisdictions := make([]models.TaxJurisdiction, len(s.Jurisdictions))
+			for i, j := range s.Jurisdictions {
+				rates := make(map[models.TaxClass]float64, len(j.Rates))
+				for k, v := range j.Rates {
+					rates[k] = v
+				}
+				j.Rates = rates
+				jurisdictions[i] = j
+			}
+			s.Jurisdictions = jurisdictions
+			return s
+		}),
+	}
+}
+
+func (r *MemorySettingsRepository) GetFulfillmentSettings(ctx context.Context) (*models.FulfillmentSettings, error) {
+	return r.settings.get(globalSettingsID)
+}
+
+func (r *MemorySettingsRepository) SaveFulfillmentSettings(ctx context.Context, settings *models.FulfillmentSettings) error {
+	settings.UpdatedAt = time.Now()
+	r.settings.set(globalSettingsID, *settings)
+	return nil
+}
+
+func (r *MemorySettingsRepository) GetScoringConfig(ctx context.Context) (*models.SmartScoringConfig, error) {
+	return r.scoring.get(scoringSettingsID)
+}
+
+func (r *MemorySettingsRepository) SaveScoringConfig(ctx context.Context, config *models.SmartScoringConfig) error {
+	config.UpdatedAt = time.Now()
+	r.scoring.set(scoringSettingsID, *config)
+	return nil
+}
+
+func (r *MemorySettingsRepository) GetTaxSettings(ctx context.Context) (*models.TaxSettings, error) {
+	return r.tax.get(taxSettingsID)
+}
+
+func (r *MemorySettingsRepository) SaveTaxSettings(ctx context.Context, settings *models.TaxSettings) error {
+	settings.UpdatedAt = time.Now()
+	r.tax.set(taxSettingsID, *settings)
+	return nil
+}
+
+func copyModes(m map[string]models.FulfillmentMode) map[string]models.FulfillmentMode {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]models.FulfillmentMode, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
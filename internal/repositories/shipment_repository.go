@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// ShipmentRepository stores courier shipments and their tracking events
+type ShipmentRepository interface {
+	CreateShipment(ctx context.Context, shipment *models.Shipment) error
+	GetShipmentByID(ctx context.Context, shipmentID string) (*models.Shipment, error)
+	// GetShipmentByOrderID returns the latest shipment booked for an order
+	GetShipmentByOrderID(ctx context.Context, orderID string) (*models.Shipment, error)
+	GetShipmentByTrackingNumber(ctx context.Context, courier, trackingNumber string) (*models.Shipment, error)
+	// RecordEvent adds a tracking event to the shipment (see Shipment.Record) and returns the
+	// updated shipment; recorded is false when the event had already been seen
+	RecordEvent(ctx context.Context, shipmentID string, event models.TrackingEvent) (shipment *models.Shipment, recorded bool, err error)
+}
+
+// FirestoreShipmentRepository handles shipment data operations in Firestore
+type FirestoreShipmentRepository struct {
+	client *firestore.Client
+}
+
+// NewShipmentRepository creates a new Firestore-backed shipment repository
+func NewShipmentRepository(client *firestore.Client) *FirestoreShipmentRepository {
+	return &FirestoreShipmentRepository{client: client}
+}
+
+// CreateShipment writes a new shipment document
+func (r *FirestoreShipmentRepository) CreateShipment(ctx context.Context, shipment *models.Shipment) error {
+	if shipment.ID == "" {
+		shipment.ID = uuid.NewString()
+	}
+	if shipment.CreatedAt.IsZero() {
+		shipment.CreatedAt = time.Now()
+	}
+	shipment.UpdatedAt = time.Now()
+	if _, err := r.client.Collection("shipments").Doc(shipment.ID).Set(ctx, shipment); err != nil {
+		return fmt.Errorf("failed to create shipment: %w", err)
+	}
+	return nil
+}
+
+// GetShipmentByID retrieves a shipment by its ID
+func (r *FirestoreShipmentRepository) GetShipmentByID(ctx context.Context, shipmentID string) (*models.Shipment, error) {
+	doc, err := r.client.Collection("shipments").Doc(shipmentID).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get shipment: %w", err)
+	}
+	var shipment models.Shipment
+	if err := doc.DataTo(&shipment); err != nil {
+		return nil, fmt.Errorf("failed to parse shipment data: %w", err)
+	}
+	return &shipment, nil
+}
+
+// GetShipmentByOrderID returns the newest shipment of an order
+func (r *FirestoreShipmentRepository) GetShipmentByOrderID(ctx context.Context, orderID string) (*models.Shipment, error) {
+	return r.first(ctx, r.client.Collection("shipments").
+		Where("orderId", "==", orderID).
+		OrderBy("createdAt", firestore.Desc))
+}
+
+// GetShipmentByTrackingNumber finds a courier's shipment by tracking number
+func (r *FirestoreShipmentRepository) GetShipmentByTrackingNumber(ctx context.Context, courier, trackingNumber string) (*models.Shipment, error) {
+	return r.first(ctx, r.client.Collection("shipments").
+		Where("courier", "==", courier).
+		Where("trackingNumber", "==", trackingNumber))
+}
+
+func (r *FirestoreShipmentRepository) first(ctx context.Context, q firestore.Query) (*models.Shipment, error) {
+	docs, err := q.Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipments: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	var shipment models.Shipment
+	if err := docs[0].DataTo(&shipment); err != nil {
+		return nil, fmt.Errorf("failed to parse shipment data: %w", err)
+	}
+	return &shipment, nil
+}
+
+// RecordEvent adds a tracking event inside a transaction so concurrent webhooks are not lost
+func (r *FirestoreShipmentRepository) RecordEvent(ctx context.Context, shipmentID string, event models.TrackingEvent) (*models.Shipment, bool, error) {
+	ref := r.client.Collection("shipments").Doc(shipmentID)
+	var shipment models.Shipment
+	var recorded bool
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if isNotFound(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		shipment = models.Shipment{}
+		if err := doc.DataTo(&shipment); err != nil {
+			return fmt.Errorf("failed to parse shipment data: %w", err)
+		}
+		if recorded = shipment.Record(event); !recorded {
+			return nil
+		}
+		shipment.UpdatedAt = time.Now()
+		return tx.Set(ref, &shipment)
+	})
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("failed to record tracking event: %w", err)
+	}
+	return &shipment, recorded, nil
+}
+
+// MemoryShipmentRepository keeps shipments in process memory
+type MemoryShipmentRepository struct {
+	shipments *memoryCollection[models.Shipment]
+}
+
+// NewMemoryShipmentRepository creates an empty in-memory shipment repository
+func NewMemoryShipmentRepository() *MemoryShipmentRepository {
+	return &MemoryShipmentRepository{shipments: newMemoryCollection(func(s models.Shipment) models.Shipment {
+		s.Events = append([]models.TrackingEvent(nil), s.Events...)
+		return s
+	})}
+}
+
+func (r *MemoryShipmentRepository) CreateShipment(ctx context.Context, shipment *models.Shipment) error {
+	if shipment.ID == "" {
+		shipment.ID = uuid.NewString()
+	}
+	if shipment.CreatedAt.IsZero() {
+		shipment.CreatedAt = time.Now()
+	}
+	shipment.UpdatedAt = time.Now()
+	r.shipments.set(shipment.ID, *shipment)
+	return nil
+}
+
+func (r *MemoryShipmentRepository) GetShipmentByID(ctx context.Context, shipmentID string) (*models.Shipment, error) {
+	return r.shipments.get(shipmentID)
+}
+
+func (r *MemoryShipmentRepository) GetShipmentByOrderID(ctx context.Context, orderID string) (*models.Shipment, error) {
+	found := r.shipments.filter(func(s *models.Shipment) bool { return s.OrderID == orderID })
+	return firstShipment(sortAndLimit(found, NewestFirst, 1, shipmentCreatedAt))
+}
+
+func (r *MemoryShipmentRepository) GetShipmentByTrackingNumber(ctx context.Context, courier, trackingNumber string) (*models.Shipment, error) {
+	return firstShipment(r.shipments.filter(func(s *models.Shipment) bool {
+		return s.Courier == courier && s.TrackingNumber == trackingNumber
+	}))
+}
+
+func (r *MemoryShipmentRepository) RecordEvent(ctx context.Context, shipmentID string, event models.TrackingEvent) (*models.Shipment, bool, error) {
+	var shipment models.Shipment
+	var recorded bool
+	err := r.shipments.mutate(shipmentID, func(s *models.Shipment) error {
+		if recorded = s.Record(event); recorded {
+			s.UpdatedAt = time.Now()
+		}
+		shipment = r.shipments.clone(*s)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &shipment, recorded, nil
+}
+
+func firstShipment(found []*models.Shipment) (*models.Shipment, error) {
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return found[0], nil
+}
+
+func shipmentCreatedAt(s *models.Shipment) time.Time { return s.CreatedAt }
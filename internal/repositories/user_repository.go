@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// UserRepository stores user profiles, keyed by Firebase UID
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, uid string) (*models.User, error)
+	ListUsers(ctx context.Context, filter UserFilter) ([]*models.User, error)
+	// UpdateUser merges the given fields into the user profile
+	UpdateUser(ctx context.Context, uid string, updates map[string]interface{}) error
+}
+
+// UserFilter narrows ListUsers; zero values are ignored
+type UserFilter struct {
+	Role     string
+	IsActive *bool
+	Sort     SortOrder
+	Limit    int
+}
+
+// FirestoreUserRepository handles user data operations in Firestore
+type FirestoreUserRepository struct {
+	client *firestore.Client
+}
+
+// NewUserRepository creates a new Firestore-backed user repository
+func NewUserRepository(client *firestore.Client) *FirestoreUserRepository {
+	return &FirestoreUserRepository{client: client}
+}
+
+// CreateUser writes a user profile document
+func (r *FirestoreUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	if _, err := r.client.Collection("users").Doc(user.UID).Set(ctx, user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUserByID retrieves a user profile by UID
+func (r *FirestoreUserRepository) GetUserByID(ctx context.Context, uid string) (*models.User, error) {
+	doc, err := r.client.Collection("users").Doc(uid).Get(ctx)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var user models.User
+	if err := doc.DataTo(&user); err != nil {
+		return nil, fmt.Errorf("failed to parse user data: %w", err)
+	}
+	user.UID = doc.Ref.ID
+	return &user, nil
+}
+
+// ListUsers queries users with optional filters
+func (r *FirestoreUserRepository) ListUsers(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	q := r.client.Collection("users").Query
+	if filter.Role != "" {
+		q = q.Where("roles", "array-contains", filter.Role)
+	}
+	if filter.IsActive != nil {
+		q = q.Where("isActive", "==", *filter.IsActive)
+	}
+	q = applySort(q, filter.Sort)
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+
+	users := make([]*models.User, 0, len(docs))
+	for _, doc := range docs {
+		var user models.User
+		if err := doc.DataTo(&user); err != nil {
+			continue // Skip invalid documents
+		}
+		user.UID = doc.Ref.ID
+		users = append(users, &user)
+	}
+	return users, nil
+}
+
+// UpdateUser merges fields into a user profile
+func (r *FirestoreUserRepository) UpdateUser(ctx context.Context, uid string, updates map[string]interface{}) error {
+	if _, err := r.client.Collection("users").Doc(uid).Set(ctx, updates, firestore.MergeAll); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// MemoryUserRepository keeps user profiles in process memory
+type MemoryUserRepository struct {
+	users *memoryCollection[models.User]
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: newMemoryCollection(func(u models.User) models.User {
+		u.Roles = append([]string(nil), u.Roles...)
+		return u
+	})}
+}
+
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	r.users.set(user.UID, *user)
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(ctx context.Context, uid string) (*models.User, error) {
+	return r.users.get(uid)
+}
+
+func (r *MemoryUserRepository) ListUsers(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	users := r.users.filter(func(u *models.User) bool {
+		if filter.Role != "" && !u.HasRole(filter.Role) {
+			return false
+		}
+		if filter.IsActive != nil && u.IsActive != *filter.IsActive {
+			return false
+		}
+		return true
+	})
+	return sortAndLimit(users, filter.Sort, filter.Limit, func(u *models.User) time.Time { return u.CreatedAt }), nil
+}
+
+func (r *MemoryUserRepository) UpdateUser(ctx context.Context, uid string, updates map[string]interface{}) error {
+	return r.users.update(uid, updates)
+}
@@ -1,11 +1,26 @@
 package config
 
-import "github.com/cecvl/art-print-backend/internal/models"
+import (
+	"context"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
 
 type ConfigService interface {
-	GetFulfillmentMode() models.FulfillmentMode
+	GetFulfillmentMode(ctx context.Context) models.FulfillmentMode
+	// FulfillmentModeFor applies any shop or category override on top of the global mode
+	FulfillmentModeFor(ctx context.Context, shopID, category string) models.FulfillmentMode
+	SmartScoringConfig(ctx context.Context) *models.SmartScoringConfig
+	// AcceptanceSLA is how long a shop has to accept an order offered to it
+	AcceptanceSLA(ctx context.Context) time.Duration
+	// TaxSettings lists the jurisdictions orders are taxed in
+	TaxSettings(ctx context.Context) *models.TaxSettings
 }
 
+// DefaultAcceptanceSLA applies until admins configure their own
+const DefaultAcceptanceSLA = 24 * time.Hour
+
 type DefaultConfigService struct {
 	Mode models.FulfillmentMode
 }
@@ -16,12 +31,26 @@ func NewDefaultConfigService() *DefaultConfigService {
 	}
 }
 
-func (c *DefaultConfigService) GetFulfillmentMode() models.FulfillmentMode {
-	// later: load from Firestore (settings/global)
+func (c *DefaultConfigService) GetFulfillmentMode(ctx context.Context) models.FulfillmentMode {
+	return c.Mode
+}
+
+func (c *DefaultConfigService) FulfillmentModeFor(ctx context.Context, shopID, category string) models.FulfillmentMode {
 	return c.Mode
 }
 
-// For admin to toggle later
+func (c *DefaultConfigService) SmartScoringConfig(ctx context.Context) *models.SmartScoringConfig {
+	return models.DefaultSmartScoringConfig()
+}
+
+func (c *DefaultConfigService) AcceptanceSLA(ctx context.Context) time.Duration {
+	return DefaultAcceptanceSLA
+}
+
+func (c *DefaultConfigService) TaxSettings(ctx context.Context) *models.TaxSettings {
+	return models.DefaultTaxSettings()
+}
+
 func (c *DefaultConfigService) SetFulfillmentMode(mode models.FulfillmentMode) {
 	c.Mode = mode
 }
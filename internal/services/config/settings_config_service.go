@@ -0,0 +1,228 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultSettingsTTL is how long settings are served from cache before being reloaded
+const DefaultSettingsTTL = 30 * time.Second
+
+// ErrInvalidSettings is returned when fulfillment settings name an unknown mode or a negative SLA
+var ErrInvalidSettings = errors.New("invalid fulfillment settings")
+
+// ErrInvalidScoring is returned when a smart scoring config fails validation
+var ErrInvalidScoring = errors.New("invalid scoring config")
+
+// ErrInvalidTax is returned when tax settings fail validation
+var ErrInvalidTax = errors.New("invalid tax settings")
+
+// SettingsConfigService reads settings from the settings collection, caching them for a
+// short TTL so matching does not hit Firestore for every order. Until settings are saved,
+// and whenever they cannot be loaded, it falls back to auto mode, default scoring and default tax.
+type SettingsConfigService struct {
+	repo repositories.SettingsRepository
+
+	fulfillment *cachedSetting[models.FulfillmentSettings]
+	scoring     *cachedSetting[models.SmartScoringConfig]
+	tax         *cachedSetting[models.TaxSettings]
+}
+
+// NewSettingsConfigService creates a settings-backed config service
+func NewSettingsConfigService(repo repositories.SettingsRepository, ttl time.Duration) *SettingsConfigService {
+	return &SettingsConfigService{
+		repo: repo,
+		fulfillment: &cachedSetting[models.FulfillmentSettings]{
+			name:     "fulfillment settings",
+			ttl:      ttl,
+			load:     repo.GetFulfillmentSettings,
+			fallback: defaultSettings,
+		},
+		scoring: &cachedSetting[models.SmartScoringConfig]{
+			name:     "scoring config",
+			ttl:      ttl,
+			load:     repo.GetScoringConfig,
+			fallback: models.DefaultSmartScoringConfig,
+		},
+		tax: &cachedSetting[models.TaxSettings]{
+			name:     "tax settings",
+			ttl:      ttl,
+			load:     repo.GetTaxSettings,
+			fallback: models.DefaultTaxSettings,
+		},
+	}
+}
+
+// GetFulfillmentMode returns the global fulfillment mode
+func (c *SettingsConfigService) GetFulfillmentMode(ctx context.Context) models.FulfillmentMode {
+	return c.fulfillment.get(ctx).FulfillmentMode
+}
+
+// FulfillmentModeFor returns the mode for an order, honouring shop and category overrides
+func (c *SettingsConfigService) FulfillmentModeFor(ctx context.Context, shopID, category string) models.FulfillmentMode {
+	return c.fulfillment.get(ctx).ModeFor(shopID, category)
+}
+
+// AcceptanceSLA returns how long a shop has to accept an offered order
+func (c *SettingsConfigService) AcceptanceSLA(ctx context.Context) time.Duration {
+	if minutes := c.fulfillment.get(ctx).AcceptanceSLAMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultAcceptanceSLA
+}
+
+// SmartScoringConfig returns the weights and tables the smart matcher scores with
+func (c *SettingsConfigService) SmartScoringConfig(ctx context.Context) *models.SmartScoringConfig {
+	return c.scoring.get(ctx)
+}
+
+// TaxSettings returns the jurisdictions orders are taxed in
+func (c *SettingsConfigService) TaxSettings(ctx context.Context) *models.TaxSettings {
+	return c.tax.get(ctx)
+}
+
+// Settings returns the stored fulfillment settings, bypassing the cache
+func (c *SettingsConfigService) Settings(ctx context.Context) (*models.FulfillmentSettings, error) {
+	return c.fulfillment.fresh(ctx)
+}
+
+// Update validates and saves new fulfillment settings, replacing the cached copy immediately
+func (c *SettingsConfigService) Update(ctx context.Context, settings *models.FulfillmentSettings) error {
+	if !settings.FulfillmentMode.IsValid() {
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidSettings, settings.FulfillmentMode)
+	}
+	if settings.AcceptanceSLAMinutes < 0 {
+		return fmt.Errorf("%w: acceptance SLA must not be negative", ErrInvalidSettings)
+	}
+	for shopID, mode := range settings.ShopOverrides {
+		if !mode.IsValid() {
+			return fmt.Errorf("%w: unknown mode %q for shop %s", ErrInvalidSettings, mode, shopID)
+		}
+	}
+	for category, mode := range settings.CategoryOverrides {
+		if !mode.IsValid() {
+			return fmt.Errorf("%w: unknown mode %q for category %s", ErrInvalidSettings, mode, category)
+		}
+	}
+
+	if err := c.repo.SaveFulfillmentSettings(ctx, settings); err != nil {
+		return err
+	}
+	c.fulfillment.set(settings)
+	return nil
+}
+
+// Scoring returns the stored smart scoring config, bypassing the cache
+func (c *SettingsConfigService) Scoring(ctx context.Context) (*models.SmartScoringConfig, error) {
+	return c.scoring.fresh(ctx)
+}
+
+// UpdateScoring validates and saves a new smart scoring config, replacing the cached copy immediately
+func (c *SettingsConfigService) UpdateScoring(ctx context.Context, config *models.SmartScoringConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScoring, err)
+	}
+	if err := c.repo.SaveScoringConfig(ctx, config); err != nil {
+		return err
+	}
+	c.scoring.set(config)
+	return nil
+}
+
+// Tax returns the stored tax settings, bypassing the cache
+func (c *SettingsConfigService) Tax(ctx context.Context) (*models.TaxSettings, error) {
+	return c.tax.fresh(ctx)
+}
+
+// UpdateTax validates and saves new tax settings, replacing the cached copy immediately
+func (c *SettingsConfigService) UpdateTax(ctx context.Context, settings *models.TaxSettings) error {
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTax, err)
+	}
+	if err := c.repo.SaveTaxSettings(ctx, settings); err != nil {
+		return err
+	}
+	c.tax.set(settings)
+	return nil
+}
+
+func defaultSettings() *models.FulfillmentSettings {
+	return &models.FulfillmentSettings{FulfillmentMode: models.FulfillmentAuto}
+}
+
+// settingsLoadTimeout bounds how long a reload may hold up the caller that triggered it
+const settingsLoadTimeout = 2 * time.Second
+
+// cachedSetting serves one settings document from memory for ttl before reloading it.
+// A failed reload keeps serving the previous copy until the next TTL expires. Concurrent
+// callers share a single reload, and none of them waits on the mutex while it runs.
+type cachedSetting[T any] struct {
+	name     string
+	ttl      time.Duration
+	load     func(ctx context.Context) (*T, error)
+	fallback func() *T
+
+	reload singleflight.Group
+
+	mu       sync.Mutex
+	value    *T
+	loadedAt time.Time
+	version  int // bumped by set so a slower reload cannot overwrite a newer value
+}
+
+func (c *cachedSetting[T]) get(ctx context.Context) *T {
+	c.mu.Lock()
+	value, loadedAt, version := c.value, c.loadedAt, c.version
+	c.mu.Unlock()
+	if value != nil && time.Since(loadedAt) < c.ttl {
+		return value
+	}
+
+	v, _, _ := c.reload.Do(c.name, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, settingsLoadTimeout)
+		defer cancel()
+		loaded, err := c.fresh(ctx)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.version != version {
+			return c.value, nil // saved meanwhile
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to load %s: %v", c.name, err)
+			if c.value == nil {
+				c.value = c.fallback()
+			}
+		} else {
+			c.value = loaded
+		}
+		c.loadedAt = time.Now()
+		return c.value, nil
+	})
+	return v.(*T)
+}
+
+// fresh loads the stored value, or the fallback when none has been saved yet
+func (c *cachedSetting[T]) fresh(ctx context.Context) (*T, error) {
+	value, err := c.load(ctx)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return c.fallback(), nil
+	}
+	return value, err
+}
+
+func (c *cachedSetting[T]) set(value *T) {
+	c.mu.Lock()
+	c.value = value
+	c.loadedAt = time.Now()
+	c.version++
+	c.mu.Unlock()
+}
@@ -0,0 +1,265 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/delivery/providers"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+)
+
+// systemActor is recorded on order transitions caused by courier updates
+const systemActor = "system:delivery"
+
+var (
+	// ErrNotInProduction is returned when an order is packed before production started
+	ErrNotInProduction = errors.New("order must be processing or ready to be packed")
+	// ErrNoShippingAddress is returned when a shipping order has no address to ship to
+	ErrNoShippingAddress = errors.New("order has no shipping address")
+	// ErrInvalidWebhook is returned when a courier update fails verification
+	ErrInvalidWebhook = errors.New("invalid courier webhook")
+	// ErrUnknownStatus is returned when a courier reports a status we do not track
+	ErrUnknownStatus = errors.New("unknown delivery status")
+	// ErrNoCourier is returned when a shipping order is packed but no courier is configured
+	ErrNoCourier = errors.New("no courier is configured")
+)
+
+// courierStatuses are the delivery statuses couriers may report
+var courierStatuses = map[string]bool{
+	models.DeliveryReady:     true,
+	models.DeliveryInTransit: true,
+	models.DeliveryDelivered: true,
+	models.DeliveryFailed:    true,
+}
+
+// DeliveryService books shipments with a courier and tracks them to the buyer
+type DeliveryService struct {
+	shipments repositories.ShipmentRepository
+	orders    repositories.OrderRepository
+	shops     repositories.PrintShopRepository
+	courier   providers.CourierProvider
+	lifecycle *orders.Lifecycle
+}
+
+// NewDeliveryService creates a new delivery service using the given courier. With a nil
+// courier pickup orders still work, but shipping orders cannot be packed.
+func NewDeliveryService(store *repositories.Store, courier providers.CourierProvider) *DeliveryService {
+	return &DeliveryService{
+		shipments: store.Shipments,
+		orders:    store.Orders,
+		shops:     store.PrintShops,
+		courier:   courier,
+		lifecycle: orders.NewLifecycle(store),
+	}
+}
+
+// MarkPacked records that a shop packed a sub-order, moving it to ready. Shipping orders are
+// booked with the courier and the shipment is returned; pickup orders, and orders placed
+// without a delivery method, return nil. Packing an order that already has a shipment
+// returns that shipment.
+func (s *DeliveryService) MarkPacked(ctx context.Context, order *models.Order, actor string) (*models.Shipment, error) {
+	switch order.Status {
+	case models.OrderStatusProcessing:
+		if _, err := s.lifecycle.Transition(ctx, order.OrderID, models.OrderStatusReady, actor, "packed"); err != nil {
+			return nil, err
+		}
+	case models.OrderStatusReady:
+	default:
+		return nil, ErrNotInProduction
+	}
+
+	// Pickup orders wait for the buyer; orders placed without a delivery method are handed over
+	// outside the platform, as before couriers were booked
+	if order.DeliveryMethod == models.DeliveryMethodPickup || order.DeliveryMethod == "" {
+		return nil, s.setDeliveryStatus(ctx, order.OrderID, models.DeliveryReady, nil)
+	}
+	if existing, err := s.shipments.GetShipmentByOrderID(ctx, order.OrderID); err == nil {
+		return existing, nil
+	}
+	if order.ShippingAddress == nil {
+		return nil, ErrNoShippingAddress
+	}
+	if s.courier == nil {
+		return nil, ErrNoCourier
+	}
+
+	req := providers.ShipmentRequest{
+		OrderID:     order.OrderID,
+		Destination: *order.ShippingAddress,
+		WeightKg:    shipping.ParcelWeightKg(order.Items),
+	}
+	if shop, err := s.shops.GetShopByID(ctx, order.PrintShopID); err == nil {
+		req.Origin = shop.Location
+	}
+	trackingNumber, err := s.courier.CreateShipment(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to book shipment with %s: %w", s.courier.GetProviderName(), err)
+	}
+
+	shipment := &models.Shipment{
+		OrderID:        order.OrderID,
+		BuyerID:        order.BuyerID,
+		PrintShopID:    order.PrintShopID,
+		Courier:        s.courier.GetProviderName(),
+		TrackingNumber: trackingNumber,
+	}
+	shipment.Record(models.TrackingEvent{
+		ID:          trackingNumber + "-booked",
+		Status:      models.DeliveryReady,
+		Description: "Packed by the print shop, awaiting courier collection",
+		OccurredAt:  time.Now(),
+	})
+	if err := s.shipments.CreateShipment(ctx, shipment); err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Booked shipment %s for order %s with %s", trackingNumber, order.OrderID, shipment.Courier)
+
+	return shipment, s.setDeliveryStatus(ctx, order.OrderID, models.DeliveryReady, map[string]interface{}{
+		"shipmentId":     shipment.ID,
+		"trackingNumber": trackingNumber,
+	})
+}
+
+// ProcessCourierWebhook records a courier tracking update and moves the order's delivery
+// status to the shipment's latest status. A delivered parcel completes the order.
+// Repeated updates are ignored. payload is the raw body the webhook was decoded from.
+func (s *DeliveryService) ProcessCourierWebhook(ctx context.Context, payload []byte, webhook models.CourierWebhook) error {
+	if s.courier == nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, ErrNoCourier)
+	}
+	if err := s.courier.VerifyWebhook(ctx, payload, webhook); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if !courierStatuses[webhook.Status] {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, webhook.Status)
+	}
+
+	shipment, err := s.shipments.GetShipmentByTrackingNumber(ctx, s.courier.GetProviderName(), webhook.TrackingNumber)
+	if err != nil {
+		return err
+	}
+
+	event := models.TrackingEvent{
+		ID:          webhook.EventID,
+		Status:      webhook.Status,
+		Description: webhook.Description,
+		Location:    webhook.Location,
+		OccurredAt:  webhook.OccurredAt,
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("%s-%s-%d", webhook.TrackingNumber, webhook.Status, event.OccurredAt.Unix())
+	}
+
+	shipment, recorded, err := s.shipments.RecordEvent(ctx, shipment.ID, event)
+	if err != nil || !recorded {
+		return err
+	}
+	if err := s.setDeliveryStatus(ctx, shipment.OrderID, shipment.Status, nil); err != nil {
+		return err
+	}
+
+	if shipment.Status == models.DeliveryDelivered {
+		_, err := s.lifecycle.Transition(ctx, shipment.OrderID, models.OrderStatusCompleted, systemActor, "delivered by "+shipment.Courier)
+		if err != nil && !errors.Is(err, repositories.ErrIllegalTransition) {
+			log.Printf("⚠️ Failed to complete delivered order %s: %v", shipment.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// Shipment returns the latest shipment of an order, or ErrNotFound
+func (s *DeliveryService) Shipment(ctx context.Context, orderID string) (*models.Shipment, error) {
+	return s.shipments.GetShipmentByOrderID(ctx, orderID)
+}
+
+// SetDeliveryStatus updates an order's delivery status and rolls it up to its parent
+func (s *DeliveryService) SetDeliveryStatus(ctx context.Context, orderID, status string) error {
+	return s.setDeliveryStatus(ctx, orderID, status, nil)
+}
+
+func (s *DeliveryService) setDeliveryStatus(ctx context.Context, orderID, status string, extra map[string]interface{}) error {
+	updates := map[string]interface{}{"deliveryStatus": status}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	if err := s.orders.UpdateOrder(ctx, orderID, updates); err != nil {
+		return fmt.Errorf("failed to update delivery status of order %s: %w", orderID, err)
+	}
+	s.rollUpDelivery(ctx, orderID)
+	return nil
+}
+
+// rollUpDelivery recomputes the delivery status of an order's parent from all its sub-orders.
+// Failures are logged; the sub-order's own status is already recorded.
+func (s *DeliveryService) rollUpDelivery(ctx context.Context, orderID string) {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil || !order.IsSubOrder() {
+		return
+	}
+	parent, err := s.orders.GetOrderByID(ctx, order.ParentOrderID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load parent order %s: %v", order.ParentOrderID, err)
+		return
+	}
+	subOrders, err := s.orders.ListOrders(ctx, repositories.OrderFilter{ParentOrderID: parent.OrderID})
+	if err != nil {
+		log.Printf("⚠️ Failed to load sub-orders of %s: %v", parent.OrderID, err)
+		return
+	}
+	status := RollUpDeliveryStatus(subOrders)
+	if status == "" || status == parent.DeliveryStatus {
+		return
+	}
+	if err := s.orders.UpdateOrder(ctx, parent.OrderID, map[string]interface{}{"deliveryStatus": status}); err != nil {
+		log.Printf("⚠️ Failed to roll delivery status of %s up to %s: %v", parent.OrderID, status, err)
+	}
+}
+
+// deliveryPath orders delivery statuses from least to most advanced
+var deliveryPath = []string{
+	models.DeliveryPending,
+	models.DeliveryProcessing,
+	models.DeliveryReady,
+	models.DeliveryInTransit,
+	models.DeliveryDelivered,
+}
+
+// RollUpDeliveryStatus derives a parent order's delivery status from its sub-orders: a failed
+// parcel wins, otherwise the least advanced parcel sets the pace. Cancelled and refunded
+// sub-orders ship nothing and are skipped; it returns "" when no parcel is left.
+func RollUpDeliveryStatus(subOrders []*models.Order) string {
+	slowest := -1
+	for _, sub := range subOrders {
+		if sub.Status == models.OrderStatusCancelled || sub.Status == models.OrderStatusRefunded {
+			continue
+		}
+		status := sub.DeliveryStatus
+		if status == "" {
+			status = models.DeliveryPending
+		}
+		if status == models.DeliveryFailed {
+			return models.DeliveryFailed
+		}
+		rank := slices.Index(deliveryPath, status)
+		if rank < 0 {
+			rank = 0
+		}
+		if slowest < 0 || rank < slowest {
+			slowest = rank
+		}
+	}
+	if slowest < 0 {
+		return ""
+	}
+	return deliveryPath[slowest]
+}
@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// NewCourierFromEnv builds the courier named by COURIER_PROVIDER, verifying webhooks with
+// COURIER_WEBHOOK_SECRET. When it is empty the simulated courier is used, and only outside
+// production. It returns nil, logging why, when no courier may be used in this environment;
+// shipping orders then cannot be packed.
+func NewCourierFromEnv() CourierProvider {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("COURIER_PROVIDER")))
+	env := os.Getenv("APP_ENV")
+	production := env == "" || env == "production" || env == "prod"
+	secret := os.Getenv("COURIER_WEBHOOK_SECRET")
+
+	courier, err := courierByName(name, production, secret)
+	if err != nil {
+		log.Printf("⚠️ No courier configured: %v", err)
+		return nil
+	}
+	if secret == "" {
+		log.Printf("⚠️ COURIER_WEBHOOK_SECRET is not set; courier webhooks will be refused")
+	}
+	log.Printf("✅ Courier: %s", courier.GetProviderName())
+	return courier
+}
+
+func courierByName(name string, production bool, secret string) (CourierProvider, error) {
+	switch name {
+	case "", "simulated":
+		if production {
+			return nil, fmt.Errorf("the simulated courier is not available in production")
+		}
+		return NewSimulatedCourier(secret), nil
+	}
+	return nil, fmt.Errorf("unknown courier %q", name)
+}
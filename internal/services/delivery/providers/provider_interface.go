@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// ShipmentRequest describes a parcel to book with a courier
+type ShipmentRequest struct {
+	OrderID     string
+	Origin      models.Location
+	Destination models.Address
+	WeightKg    float64
+}
+
+// CourierProvider defines the interface for courier providers
+type CourierProvider interface {
+	// CreateShipment books collection of a parcel and returns its tracking number
+	CreateShipment(ctx context.Context, req ShipmentRequest) (string, error)
+
+	// TrackShipment returns the tracking events the courier has for a parcel
+	TrackShipment(ctx context.Context, trackingNumber string) ([]models.TrackingEvent, error)
+
+	// CancelShipment cancels a booking the courier has not collected yet
+	CancelShipment(ctx context.Context, trackingNumber string) error
+
+	// VerifyWebhook checks that a tracking update really came from the courier, given the raw
+	// payload it was decoded from
+	VerifyWebhook(ctx context.Context, payload []byte, webhook models.CourierWebhook) error
+
+	// GetProviderName returns the name of the provider
+	GetProviderName() string
+}
@@ -0,0 +1,58 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// ErrNoMatch is returned when a geocoder cannot place a location
+var ErrNoMatch = errors.New("location could not be geocoded")
+
+// earthRadiusKm is the mean Earth radius used for great-circle distances
+const earthRadiusKm = 6371.0
+
+// Geocoder turns a textual location into coordinates
+type Geocoder interface {
+	// Geocode returns the latitude and longitude of loc, or ErrNoMatch
+	Geocode(ctx context.Context, loc models.Location) (lat, lng float64, err error)
+}
+
+// Resolve fills in loc's coordinates with the geocoder when they are missing.
+// It reports whether loc has coordinates afterwards; failures are logged.
+func Resolve(ctx context.Context, geocoder Geocoder, loc *models.Location) bool {
+	if loc == nil {
+		return false
+	}
+	if loc.HasCoordinates() {
+		return true
+	}
+	if loc.City == "" && loc.Address == "" {
+		return false
+	}
+	lat, lng, err := geocoder.Geocode(ctx, *loc)
+	if err != nil {
+		if !errors.Is(err, ErrNoMatch) {
+			log.Printf("⚠️ Failed to geocode %q: %v", loc.City, err)
+		}
+		return false
+	}
+	loc.Latitude, loc.Longitude = lat, lng
+	return true
+}
+
+// DistanceKm returns the great-circle distance between two located points
+func DistanceKm(a, b models.Location) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLng := radians(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,19 @@
+package geo
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// NewGeocoderFromEnv builds the geocoder named by GEOCODER_PROVIDER. The table geocoder is the
+// default and the only one available so far; an unknown name is logged and falls back to it.
+func NewGeocoderFromEnv() Geocoder {
+	switch name := strings.ToLower(strings.TrimSpace(os.Getenv("GEOCODER_PROVIDER"))); name {
+	case "", "table":
+	default:
+		log.Printf("⚠️ Unknown geocoder %q; using the table geocoder", name)
+	}
+	log.Printf("✅ Geocoder: table")
+	return NewTableGeocoder()
+}
@@ -0,0 +1,125 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/pricing"
+)
+
+// ShopCatalog holds the materials, mediums and frames a shop has configured.
+// It is loaded once per shop and reused for every service the shop offers.
+type ShopCatalog struct {
+	ShopID    string
+	materials map[string]*models.Material
+	mediums   map[string]*models.Medium
+	frames    []*models.Frame
+}
+
+// CompatibilityChecker decides whether a shop service can fulfill a set of print options
+type CompatibilityChecker struct {
+	repo   repositories.PrintShopRepository
+	frames repositories.FrameRepository
+}
+
+// NewCompatibilityChecker creates a new compatibility checker
+func NewCompatibilityChecker(repo repositories.PrintShopRepository, frames repositories.FrameRepository) *CompatibilityChecker {
+	return &CompatibilityChecker{repo: repo, frames: frames}
+}
+
+// LoadCatalog fetches a shop's materials, mediums and frames. Lookup failures are logged
+// and leave that part of the catalog empty, so services depending on it are rejected.
+func (c *CompatibilityChecker) LoadCatalog(ctx context.Context, shopID string) *ShopCatalog {
+	catalog := &ShopCatalog{
+		ShopID:    shopID,
+		materials: make(map[string]*models.Material),
+		mediums:   make(map[string]*models.Medium),
+	}
+
+	materials, err := c.repo.GetMaterialsByShopID(ctx, shopID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get materials for shop %s: %v", shopID, err)
+	}
+	for _, m := range materials {
+		catalog.materials[m.ID] = m
+	}
+
+	mediums, err := c.repo.GetMediumsByShopID(ctx, shopID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get mediums for shop %s: %v", shopID, err)
+	}
+	for _, m := range mediums {
+		catalog.mediums[m.ID] = m
+	}
+
+	frames, err := c.frames.ListFrames(ctx, repositories.FrameFilter{ShopID: shopID})
+	if err != nil {
+		log.Printf("⚠️ Failed to get frames for shop %s: %v", shopID, err)
+	}
+	catalog.frames = frames
+
+	return catalog
+}
+
+// Check returns one rejection per option the service cannot satisfy; none means compatible.
+// Options left empty place no constraint on the service.
+func (c *CompatibilityChecker) Check(catalog *ShopCatalog, service *models.PrintService, options models.PrintOrderOptions) []models.ServiceRejection {
+	var rejections []models.ServiceRejection
+	reject := func(field, format string, args ...interface{}) {
+		rejections = append(rejections, models.ServiceRejection{
+			ShopID:    service.ShopID,
+			ServiceID: service.ID,
+			Field:     field,
+			Reason:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	if _, ok := service.SizePricing[options.Size]; !ok {
+		reject("size", "size %q is not offered", options.Size)
+	}
+
+	if options.Material != "" {
+		material, ok := catalog.materials[service.SubstrateID]
+		switch {
+		case !ok:
+			reject("material", "substrate %q is not configured for this shop", service.SubstrateID)
+		case !material.IsActive:
+			reject("material", "substrate %q is inactive", material.Name)
+		case !matchesOption(options.Material, material.ID, material.Type, material.Name):
+			reject("material", "service prints on %q, not %q", material.Type, options.Material)
+		}
+	}
+
+	if options.Medium != "" {
+		medium, ok := catalog.mediums[service.MediumID]
+		switch {
+		case !ok:
+			reject("medium", "medium %q is not configured for this shop", service.MediumID)
+		case !medium.IsActive:
+			reject("medium", "medium %q is inactive", medium.Name)
+		case !matchesOption(options.Medium, medium.ID, medium.Type, medium.Name):
+			reject("medium", "service uses %q, not %q", medium.Type, options.Medium)
+		}
+	}
+
+	if pricing.WantsFrame(options.Frame) {
+		frame := pricing.ResolveFrame(catalog.frames, options.Frame)
+		switch {
+		case frame == nil:
+			reject("frame", "frame %q is not offered", options.Frame)
+		case !frame.IsActive:
+			reject("frame", "frame %q is inactive", options.Frame)
+		}
+	}
+
+	return rejections
+}
+
+// matchesOption reports whether a requested option names a configured item by ID, type or name
+func matchesOption(requested, id, itemType, name string) bool {
+	return requested == id || strings.EqualFold(requested, itemType) || strings.EqualFold(requested, name)
+}
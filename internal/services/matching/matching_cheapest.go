@@ -0,0 +1,40 @@
+package matching
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// CheapestMatcher ranks matches by total price, cheapest first
+type CheapestMatcher struct{}
+
+// NewCheapestMatcher creates a new cheapest-first matcher
+func NewCheapestMatcher() *CheapestMatcher {
+	return &CheapestMatcher{}
+}
+
+// Name returns the strategy name
+func (m *CheapestMatcher) Name() string {
+	return StrategyCheapest
+}
+
+// FindPrintShops scores matches on price alone and sorts them cheapest first
+func (m *CheapestMatcher) FindPrintShops(ctx context.Context, options models.PrintOrderOptions, candidates []models.ShopMatch) []models.ShopMatch {
+	for i := range candidates {
+		candidates[i].MatchScore = priceScore(candidates[i].TotalPrice.Major())
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].TotalPrice.Cmp(candidates[j].TotalPrice) < 0
+	})
+	return candidates
+}
+
+// priceScore maps a price onto 0-100: lower price = higher score
+func priceScore(price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	return 100.0 / (1.0 + price/100.0)
+}
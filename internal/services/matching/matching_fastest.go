@@ -0,0 +1,45 @@
+package matching
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// FastestMatcher ranks matches by when they can be ready, breaking ties on price
+type FastestMatcher struct{}
+
+// NewFastestMatcher creates a new fastest-first matcher
+func NewFastestMatcher() *FastestMatcher {
+	return &FastestMatcher{}
+}
+
+// Name returns the strategy name
+func (m *FastestMatcher) Name() string {
+	return StrategyFastest
+}
+
+// FindPrintShops scores matches on delivery time and sorts them fastest first
+func (m *FastestMatcher) FindPrintShops(ctx context.Context, options models.PrintOrderOptions, candidates []models.ShopMatch) []models.ShopMatch {
+	for i := range candidates {
+		candidates[i].MatchScore = deliveryScore(candidates[i].DeliveryDays, fastestMaxDeliveryDays)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].ReadyBy.Equal(candidates[j].ReadyBy) {
+			return candidates[i].ReadyBy.Before(candidates[j].ReadyBy)
+		}
+		return candidates[i].TotalPrice.Cmp(candidates[j].TotalPrice) < 0
+	})
+	return candidates
+}
+
+// fastestMaxDeliveryDays is the delivery time at which the fastest strategy scores 0
+const fastestMaxDeliveryDays = 10
+
+// deliveryScore maps delivery days onto 0-100: faster delivery = higher score,
+// reaching 0 at maxDays
+func deliveryScore(days, maxDays int) float64 {
+	return math.Max(0, 100.0*(1.0-float64(days)/float64(maxDays)))
+}
@@ -2,121 +2,181 @@ package matching
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"math"
 	"sort"
+	"strings"
+	"time"
 
-	"github.com/cecvl/art-print-backend/internal/firebase"
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
 )
 
-// SmartMatcher uses advanced scoring algorithm to find the best shop match
+// ScoringConfigSource supplies the smart scoring config currently in effect
+type ScoringConfigSource interface {
+	SmartScoringConfig(ctx context.Context) *models.SmartScoringConfig
+}
+
+// SmartMatcher uses advanced scoring algorithm to find the best shop match.
+// Weights and tables come from the admin-editable smart scoring config.
 type SmartMatcher struct {
-	discovery *ServiceDiscovery
+	repo    repositories.PrintShopRepository
+	orders  repositories.OrderRepository
+	logs    repositories.ActivityLogRepository
+	scoring ScoringConfigSource
 }
 
 // NewSmartMatcher creates a new smart matcher
-func NewSmartMatcher() *SmartMatcher {
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	return &SmartMatcher{
-		discovery: NewServiceDiscovery(repo),
-	}
+func NewSmartMatcher(repo repositories.PrintShopRepository, orders repositories.OrderRepository, logs repositories.ActivityLogRepository, scoring ScoringConfigSource) *SmartMatcher {
+	return &SmartMatcher{repo: repo, orders: orders, logs: logs, scoring: scoring}
 }
 
-// Assign uses smart scoring to assign order to best matching shop
-func (m *SmartMatcher) Assign(ctx context.Context, order *models.Order, options models.PrintOrderOptions) error {
-	// Find all matching shops
-	matches, err := m.discovery.FindMatchingShops(ctx, options)
-	if err != nil {
-		log.Printf("❌ Failed to find matching shops: %v", err)
-		return err
-	}
+// Name returns the strategy name
+func (m *SmartMatcher) Name() string {
+	return StrategySmart
+}
+
+// statsWindow is how far back the issue rate looks, so an old track record neither
+// weighs forever nor makes the lookup grow with the shop's history
+const statsWindow = 180 * 24 * time.Hour
+
+// shopStats is what the smart matcher knows about a shop beyond the match itself
+type shopStats struct {
+	profile     *models.PrintShopProfile
+	openOrders  int
+	totalOrders int
+	issues      int
+}
 
-	if len(matches) == 0 {
-		log.Printf("⚠️ No matching shops found for order %s", order.OrderID)
-		return nil // Order remains unassigned
+// FindPrintShops scores every match factor by factor and sorts them highest first.
+// Each match carries its per-factor breakdown in ScoreBreakdown.
+func (m *SmartMatcher) FindPrintShops(ctx context.Context, options models.PrintOrderOptions, candidates []models.ShopMatch) []models.ShopMatch {
+	cfg := m.scoring.SmartScoringConfig(ctx)
+	if err := cfg.Validate(); err != nil {
+		log.Printf("⚠️ Stored scoring config is invalid (%v), using defaults", err)
+		cfg = models.DefaultSmartScoringConfig()
 	}
 
-	// Get shop details for scoring
-	repo := repositories.NewPrintShopRepository(firebase.FirestoreClient)
-	shopsMap := make(map[string]*models.PrintShopProfile)
-	for _, match := range matches {
-		shop, err := repo.GetShopByID(ctx, match.ShopID)
-		if err == nil {
-			shopsMap[match.ShopID] = shop
+	// Gather shop details once per shop for scoring
+	stats := make(map[string]*shopStats)
+	for _, match := range candidates {
+		if _, ok := stats[match.ShopID]; !ok {
+			stats[match.ShopID] = m.loadStats(ctx, match.ShopID)
 		}
 	}
 
 	// Calculate smart scores for each match
-	for i := range matches {
-		shop := shopsMap[matches[i].ShopID]
-		if shop != nil {
-			matches[i].MatchScore = m.calculateSmartScore(shop, &matches[i], options)
+	for i := range candidates {
+		st := stats[candidates[i].ShopID]
+		if st.profile == nil {
+			continue
+		}
+		candidates[i].ScoreBreakdown = m.scoreFactors(cfg, st, &candidates[i], options)
+		candidates[i].MatchScore = 0
+		for _, f := range candidates[i].ScoreBreakdown {
+			candidates[i].MatchScore += f.Contribution
 		}
 	}
 
 	// Sort by match score (highest first)
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].MatchScore > matches[j].MatchScore
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].MatchScore > candidates[j].MatchScore
 	})
 
-	// Assign to best match
-	bestMatch := matches[0]
-	order.PrintShopID = bestMatch.ShopID
+	return candidates
+}
 
-	log.Printf("✅ Smart-assigned order %s to shop %s (score: %.2f, price: %.2f)",
-		order.OrderID, bestMatch.ShopName, bestMatch.MatchScore, bestMatch.TotalPrice)
+// loadStats fetches a shop's profile, its open orders, and its orders and reported issues
+// within statsWindow. Failures are logged and leave the affected numbers at zero.
+func (m *SmartMatcher) loadStats(ctx context.Context, shopID string) *shopStats {
+	st := &shopStats{}
 
-	return nil
-}
+	shop, err := m.repo.GetShopByID(ctx, shopID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get shop %s for scoring: %v", shopID, err)
+		return st
+	}
+	st.profile = shop
+
+	if st.openOrders, err = m.orders.CountOrders(ctx, repositories.OrderFilter{PrintShopID: shopID, Statuses: models.OpenOrderStatuses}); err != nil {
+		log.Printf("⚠️ Failed to count open orders of shop %s for scoring: %v", shopID, err)
+	}
 
-// calculateSmartScore computes a comprehensive match score
-// Score components:
-// - Price competitiveness: 40%
-// - Shop rating: 30%
-// - Delivery time: 20%
-// - Service quality (technology): 10%
-func (m *SmartMatcher) calculateSmartScore(shop *models.PrintShopProfile, match *models.ShopMatch, options models.PrintOrderOptions) float64 {
-	score := 0.0
-
-	// 1. Price competitiveness (40% weight)
-	// Normalize price: lower price = higher score
-	// Use inverse relationship: score = 100 / (1 + price/100)
-	priceScore := 100.0 / (1.0 + match.TotalPrice/100.0)
-	score += priceScore * 0.4
-
-	// 2. Shop rating (30% weight)
-	// Normalize rating (assuming 0-5 scale)
-	ratingScore := (shop.Rating / 5.0) * 100.0
-	score += ratingScore * 0.3
-
-	// 3. Delivery time (20% weight)
-	// Faster delivery = higher score
-	// Normalize: score = 100 * (1 - deliveryDays/10)
-	deliveryScore := math.Max(0, 100.0*(1.0-float64(match.DeliveryDays)/10.0))
-	score += deliveryScore * 0.2
-
-	// 4. Service quality / Technology (10% weight)
-	// Premium technologies get higher scores
-	techScore := m.getTechnologyScore(match.Technology)
-	score += techScore * 0.1
-
-	return score
+	since := time.Now().Add(-statsWindow)
+	if st.totalOrders, err = m.orders.CountOrders(ctx, repositories.OrderFilter{PrintShopID: shopID, CreatedAfter: since}); err != nil {
+		log.Printf("⚠️ Failed to count orders of shop %s for scoring: %v", shopID, err)
+	}
+	if st.issues, err = m.logs.Count(ctx, repositories.LogPrintShopIssues, "shopId", shopID, since); err != nil {
+		log.Printf("⚠️ Failed to count issues of shop %s for scoring: %v", shopID, err)
+	}
+
+	return st
 }
 
-// getTechnologyScore returns a score based on technology type
-func (m *SmartMatcher) getTechnologyScore(technology string) float64 {
-	techScores := map[string]float64{
-		"giclée":          90.0,
-		"dye-sublimation": 85.0,
-		"inkjet":          80.0,
-		"laser":           75.0,
-		"offset":          70.0,
+// scoreFactors scores each factor on 0-100 and weights it by its share of the configured weights
+func (m *SmartMatcher) scoreFactors(cfg *models.SmartScoringConfig, st *shopStats, match *models.ShopMatch, options models.PrintOrderOptions) []models.ScoreFactor {
+	total := cfg.Weights.Sum()
+	factor := func(name string, weight, score float64, detail string) models.ScoreFactor {
+		share := weight / total
+		return models.ScoreFactor{
+			Factor:       name,
+			Score:        score,
+			Weight:       share,
+			Contribution: score * share,
+			Detail:       detail,
+		}
+	}
+
+	// Lower price, faster delivery, lower load and fewer issues all score higher
+	techDetail := match.Technology
+	tech, ok := cfg.TechnologyScores[match.Technology]
+	if !ok {
+		tech, techDetail = cfg.DefaultTechnologyScore, "unlisted technology"
+	}
+	distance, distanceDetail := proximityScore(st.profile.Location, options.DeliveryLocation, match.DistanceKm, cfg.MaxDistanceKm)
+	issueRate, issueDetail := 100.0, "no order history"
+	if st.totalOrders > 0 {
+		rate := math.Min(1, float64(st.issues)/float64(st.totalOrders))
+		issueRate, issueDetail = 100*(1-rate), fmt.Sprintf("issues: %d of %d orders", st.issues, st.totalOrders)
 	}
 
-	if score, ok := techScores[technology]; ok {
-		return score
+	return []models.ScoreFactor{
+		factor(models.FactorPrice, cfg.Weights.Price, priceScore(match.TotalPrice.Major()), match.TotalPrice.String()),
+		factor(models.FactorRating, cfg.Weights.Rating, clampScore(st.profile.Rating/cfg.RatingScale*100), fmt.Sprintf("%.1f of %.0f", st.profile.Rating, cfg.RatingScale)),
+		factor(models.FactorDelivery, cfg.Weights.Delivery, deliveryScore(match.DeliveryDays, cfg.MaxDeliveryDays), fmt.Sprintf("%d days", match.DeliveryDays)),
+		factor(models.FactorTechnology, cfg.Weights.Technology, tech, techDetail),
+		factor(models.FactorDistance, cfg.Weights.Distance, distance, distanceDetail),
+		factor(models.FactorLoad, cfg.Weights.Load, clampScore(100*(1-float64(st.openOrders)/float64(cfg.MaxOpenOrders))), fmt.Sprintf("open orders: %d", st.openOrders)),
+		factor(models.FactorIssueRate, cfg.Weights.IssueRate, issueRate, issueDetail),
 	}
-	return 70.0 // Default score
+}
+
+// proximityScore scores how close a shop is to the buyer. With a measured distance the score
+// falls linearly from 100 to 0 at maxKm. Otherwise locations are compared by name: same city 100,
+// same state 70, same country 40, otherwise 0. Unknown locations score a neutral 50.
+func proximityScore(shop models.Location, buyer *models.Location, distanceKm *float64, maxKm float64) (float64, string) {
+	if distanceKm != nil {
+		if maxKm <= 0 {
+			maxKm = models.DefaultMaxDistanceKm
+		}
+		return clampScore(100 * (1 - *distanceKm/maxKm)), fmt.Sprintf("%.1f km", *distanceKm)
+	}
+	if buyer == nil || (buyer.City == "" && buyer.State == "" && buyer.Country == "") {
+		return 50, "buyer location unknown"
+	}
+	same := func(a, b string) bool { return a != "" && strings.EqualFold(a, b) }
+	switch {
+	case same(shop.City, buyer.City) && (buyer.Country == "" || same(shop.Country, buyer.Country)):
+		return 100, "same city"
+	case same(shop.State, buyer.State):
+		return 70, "same state"
+	case same(shop.Country, buyer.Country):
+		return 40, "same country"
+	}
+	return 0, "different country"
+}
+
+func clampScore(v float64) float64 {
+	return math.Max(0, math.Min(100, v))
 }
@@ -0,0 +1,150 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cecvl/art-print-backend/internal/interfaces"
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+)
+
+// Built-in matching strategy names
+const (
+	StrategyCheapest = "cheapest"
+	StrategyFastest  = "fastest"
+	StrategySmart    = "smart"
+)
+
+// ErrUnknownStrategy is returned when a caller asks for a strategy that is not registered
+var ErrUnknownStrategy = errors.New("unknown matching strategy")
+
+// MatchResult is the outcome of one pipeline run
+type MatchResult struct {
+	Strategy   string                    `json:"strategy"`
+	Matches    []models.ShopMatch        `json:"matches"`    // best match first
+	Rejections []models.ServiceRejection `json:"rejections"` // incompatible services and why
+}
+
+// Best returns the top-ranked match, or nil when nothing matched
+func (r *MatchResult) Best() *models.ShopMatch {
+	if len(r.Matches) == 0 {
+		return nil
+	}
+	return &r.Matches[0]
+}
+
+// Pipeline is the single matching path: service discovery finds and prices every
+// compatible service, then a MatchingStrategy scores and orders the candidates.
+type Pipeline struct {
+	discovery  *ServiceDiscovery
+	strategies map[string]interfaces.MatchingStrategy
+}
+
+// NewPipeline creates a matching pipeline with the cheapest, fastest and smart strategies
+// registered, placing buyers with geocoder
+func NewPipeline(store *repositories.Store, scoring ScoringConfigSource, geocoder geo.Geocoder) *Pipeline {
+	p := &Pipeline{
+		discovery:  NewServiceDiscovery(store.PrintShops, store.Frames, store.Orders, geocoder),
+		strategies: make(map[string]interfaces.MatchingStrategy),
+	}
+	p.Register(NewCheapestMatcher())
+	p.Register(NewFastestMatcher())
+	p.Register(NewSmartMatcher(store.PrintShops, store.Orders, store.ActivityLog, scoring))
+	return p
+}
+
+// Register adds a strategy, replacing any registered under the same name
+func (p *Pipeline) Register(strategy interfaces.MatchingStrategy) {
+	p.strategies[strategy.Name()] = strategy
+}
+
+// Match discovers the services able to fulfill the options and ranks them with the named strategy.
+// Services of shops listed in excludeShopIDs are never returned.
+func (p *Pipeline) Match(ctx context.Context, options models.PrintOrderOptions, strategy string, excludeShopIDs ...string) (*MatchResult, error) {
+	s, ok := p.strategies[strategy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, strategy)
+	}
+
+	candidates, rejections, err := p.discovery.DiscoverServices(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(excludeShopIDs) > 0 {
+		candidates = excludeShops(candidates, excludeShopIDs)
+	}
+
+	return &MatchResult{
+		Strategy:   s.Name(),
+		Matches:    s.FindPrintShops(ctx, options, candidates),
+		Rejections: rejections,
+	}, nil
+}
+
+// StrategyFunc names the strategy a shop's services are ranked with, or "" to leave the shop
+// out of automatic matching
+type StrategyFunc func(shopID string) string
+
+// MatchPerShop discovers the services able to fulfill the options and ranks each shop's services
+// with the strategy strategyFor picks for that shop. Shops without a strategy are rejected. When
+// shops use different strategies the candidates are merged by match score, which every strategy
+// scores from 0 to 100; the result's strategy then lists them all.
+func (p *Pipeline) MatchPerShop(ctx context.Context, options models.PrintOrderOptions, strategyFor StrategyFunc, excludeShopIDs ...string) (*MatchResult, error) {
+	candidates, rejections, err := p.discovery.DiscoverServices(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(excludeShopIDs) > 0 {
+		candidates = excludeShops(candidates, excludeShopIDs)
+	}
+
+	groups := make(map[string][]models.ShopMatch)
+	var names []string
+	for _, c := range candidates {
+		name := strategyFor(c.ShopID)
+		if name == "" {
+			rejections = append(rejections, models.ServiceRejection{
+				ShopID: c.ShopID, ServiceID: c.ServiceID, Field: "mode", Reason: "shop takes orders by manual assignment only",
+			})
+			continue
+		}
+		if _, ok := p.strategies[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], c)
+	}
+	sort.Strings(names)
+
+	result := &MatchResult{Strategy: strings.Join(names, "+"), Matches: []models.ShopMatch{}, Rejections: rejections}
+	for _, name := range names {
+		result.Matches = append(result.Matches, p.strategies[name].FindPrintShops(ctx, options, groups[name])...)
+	}
+	if len(names) > 1 {
+		sort.SliceStable(result.Matches, func(i, j int) bool {
+			return result.Matches[i].MatchScore > result.Matches[j].MatchScore
+		})
+	}
+	return result, nil
+}
+
+func excludeShops(matches []models.ShopMatch, shopIDs []string) []models.ShopMatch {
+	excluded := make(map[string]bool, len(shopIDs))
+	for _, id := range shopIDs {
+		excluded[id] = true
+	}
+	kept := matches[:0]
+	for _, m := range matches {
+		if !excluded[m.ShopID] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
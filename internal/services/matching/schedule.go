@@ -0,0 +1,160 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+const (
+	// defaultLeadTimeDays applies to services that do not declare a lead time; rush orders take half
+	defaultLeadTimeDays = 5
+	// defaultMaxBacklogDays applies to shops that do not declare how much queued work they accept
+	defaultMaxBacklogDays = 10
+	// Services without a declared capacity take bulkExtraDays longer for more than bulkQuantity units
+	bulkQuantity  = 20
+	bulkExtraDays = 2
+	// scheduleHorizonDays bounds the search for working days in a calendar with few or none
+	scheduleHorizonDays = 366
+)
+
+// Schedule is when a service can have an order ready
+type Schedule struct {
+	ReadyBy      time.Time
+	DeliveryDays int // calendar days from now until ReadyBy, in the shop's timezone
+}
+
+// ShopQueue counts the units a shop has committed to but not finished, per service
+type ShopQueue struct {
+	byService map[string]int
+	// unattributed lines were priced before the service was recorded on them and
+	// count against every service of the shop
+	unattributed int
+}
+
+// unitsFor returns the units queued ahead of a new order on the service
+func (q *ShopQueue) unitsFor(serviceID string) int {
+	return q.byService[serviceID] + q.unattributed
+}
+
+// Scheduler estimates ready-by dates from each shop's calendar and queue of accepted orders
+type Scheduler struct {
+	orders repositories.OrderRepository
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(orders repositories.OrderRepository) *Scheduler {
+	return &Scheduler{orders: orders}
+}
+
+// LoadQueue sums the units of the shop's accepted orders that are still in production.
+// A lookup failure is logged and treated as an empty queue.
+func (s *Scheduler) LoadQueue(ctx context.Context, shopID string) *ShopQueue {
+	queue := &ShopQueue{byService: make(map[string]int)}
+
+	orders, err := s.orders.ListOrders(ctx, repositories.OrderFilter{PrintShopID: shopID, Statuses: productionStatuses})
+	if err != nil {
+		log.Printf("⚠️ Failed to get orders of shop %s for scheduling: %v", shopID, err)
+		return queue
+	}
+	for _, o := range orders {
+		if !inProduction(o) {
+			continue
+		}
+		for _, item := range o.Items {
+			if item.PriceBreakdown != nil && item.PriceBreakdown.ServiceID != "" {
+				queue.byService[item.PriceBreakdown.ServiceID] += item.Quantity
+			} else {
+				queue.unattributed += item.Quantity
+			}
+		}
+	}
+	return queue
+}
+
+// Estimate schedules the options on a service behind the shop's queue, starting at now.
+// ok is false when the service is at capacity or its shop has no working days ahead;
+// reason then says why.
+func (s *Scheduler) Estimate(shop *models.PrintShopProfile, service *models.PrintService, queue *ShopQueue, options models.PrintOrderOptions, now time.Time) (schedule Schedule, reason string, ok bool) {
+	calendar := shop.Calendar
+	if calendar == nil {
+		calendar = models.DefaultBusinessCalendar()
+	}
+
+	days := service.LeadTimeDays
+	if days <= 0 {
+		days = defaultLeadTimeDays
+	}
+	if options.RushOrder {
+		days = max(1, days/2)
+	}
+
+	quantity := max(1, options.Quantity)
+	if service.DailyCapacity > 0 {
+		queued := queue.unitsFor(service.ID)
+		maxBacklog := shop.MaxBacklogDays
+		if maxBacklog <= 0 {
+			maxBacklog = defaultMaxBacklogDays
+		}
+		if backlog := queued / service.DailyCapacity; backlog >= maxBacklog {
+			return Schedule{}, fmt.Sprintf("at capacity: %d units queued, %d working days of backlog (limit %d)", queued, backlog, maxBacklog), false
+		}
+		// The lead time covers the first day's output; every further day of units adds a day
+		days += (queued+quantity+service.DailyCapacity-1)/service.DailyCapacity - 1
+	} else if quantity > bulkQuantity {
+		days += bulkExtraDays
+	}
+
+	readyBy, found := closeOfWorkingDay(calendar, now, days)
+	if !found {
+		return Schedule{}, "no business hours in the shop calendar within a year", false
+	}
+	return Schedule{ReadyBy: readyBy, DeliveryDays: calendarDaysBetween(now, readyBy)}, "", true
+}
+
+// productionStatuses are the statuses of orders a shop is still producing
+var productionStatuses = []models.OrderStatus{
+	"", models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusProcessing, models.OrderStatusError,
+}
+
+// inProduction reports whether an order occupies its shop's capacity: it is a fulfillment
+// order the shop has accepted (or one assigned before acceptance existed) and not yet ready
+func inProduction(o *models.Order) bool {
+	if o.IsParent() {
+		return false
+	}
+	if o.AssignmentStatus != "" && o.AssignmentStatus != models.AssignmentAccepted {
+		return false
+	}
+	return slices.Contains(productionStatuses, o.Status)
+}
+
+// closeOfWorkingDay returns the closing time of the days-th full working day after from.
+// Today counts only if the shop has not opened yet.
+func closeOfWorkingDay(calendar *models.BusinessCalendar, from time.Time, days int) (time.Time, bool) {
+	local := from.In(calendar.Location())
+	for i := 0; i < scheduleHorizonDays; i++ {
+		open, closing, ok := calendar.HoursOn(local.AddDate(0, 0, i))
+		if !ok || open.Before(from) {
+			continue
+		}
+		if days--; days <= 0 {
+			return closing, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// calendarDaysBetween counts the date changes from from to to, in to's timezone
+func calendarDaysBetween(from, to time.Time) int {
+	y1, m1, d1 := from.In(to.Location()).Date()
+	y2, m2, d2 := to.Date()
+	start := time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
@@ -2,36 +2,59 @@ package matching
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
 	"github.com/cecvl/art-print-backend/internal/services/pricing"
 )
 
 // ServiceDiscovery helps find shops and services that match order requirements
 type ServiceDiscovery struct {
-	repo    *repositories.PrintShopRepository
-	pricing *pricing.PricingService
+	repo          repositories.PrintShopRepository
+	quoter        *pricing.Quoter
+	compatibility *CompatibilityChecker
+	scheduler     *Scheduler
+	geocoder      geo.Geocoder
 }
 
-// NewServiceDiscovery creates a new service discovery instance
-func NewServiceDiscovery(repo *repositories.PrintShopRepository) *ServiceDiscovery {
+// NewServiceDiscovery creates a new service discovery instance placing buyers with geocoder
+func NewServiceDiscovery(repo repositories.PrintShopRepository, frames repositories.FrameRepository, orders repositories.OrderRepository, geocoder geo.Geocoder) *ServiceDiscovery {
 	return &ServiceDiscovery{
-		repo:    repo,
-		pricing: pricing.NewPricingService(),
+		repo:          repo,
+		quoter:        pricing.NewQuoter(repo, frames),
+		compatibility: NewCompatibilityChecker(repo, frames),
+		scheduler:     NewScheduler(orders),
+		geocoder:      geocoder,
 	}
 }
 
-// FindMatchingShops finds all shops that can fulfill an order with given options
-func (sd *ServiceDiscovery) FindMatchingShops(ctx context.Context, options models.PrintOrderOptions) ([]models.ShopMatch, error) {
+// DiscoverServices checks every active service of every active shop against the options.
+// It returns the compatible services priced and scheduled as unscored matches, plus the
+// reasons the rest were rejected, including services at capacity and, for pickup orders,
+// shops outside the buyer's radius. Scoring and ordering are left to a MatchingStrategy.
+func (sd *ServiceDiscovery) DiscoverServices(ctx context.Context, options models.PrintOrderOptions) ([]models.ShopMatch, []models.ServiceRejection, error) {
 	// Get all active shops
 	shops, err := sd.repo.GetActiveShops(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	matches := make([]models.ShopMatch, 0)
+	rejections := make([]models.ServiceRejection, 0)
+	now := time.Now()
+
+	// Place the buyer once; shops are measured against a copy so options stay untouched
+	var buyer *models.Location
+	if options.DeliveryLocation != nil {
+		loc := *options.DeliveryLocation
+		if geo.Resolve(ctx, sd.geocoder, &loc) {
+			buyer = &loc
+		}
+	}
 
 	// For each shop, find matching services
 	for _, shop := range shops {
@@ -41,102 +64,101 @@ func (sd *ServiceDiscovery) FindMatchingShops(ctx context.Context, options model
 			continue
 		}
 
+		distance := sd.distanceTo(ctx, shop, buyer)
+		if reason := outsidePickupRadius(distance, buyer, options.PickupRadiusKm); reason != "" {
+			for _, service := range services {
+				if service.IsActive {
+					rejections = append(rejections, models.ServiceRejection{ShopID: shop.ID, ServiceID: service.ID, Field: "distance", Reason: reason})
+				}
+			}
+			continue
+		}
+
+		catalog := sd.compatibility.LoadCatalog(ctx, shop.ID)
+		var queue *ShopQueue // loaded once the shop has a compatible service
+
 		// Check each service for compatibility
 		for _, service := range services {
 			if !service.IsActive {
 				continue
 			}
 
-			// Check if service supports the requested options
-			if sd.serviceSupportsOptions(service, options) {
-				totalPrice := sd.pricing.CalculateShopPrice(service, options)
-
-				// Calculate match score (basic - will be enhanced in smart matcher)
-				matchScore := sd.calculateBasicScore(shop, service, totalPrice)
-
-				techType := ""
-				if service.Technology != nil {
-					techType = service.Technology.Type
-				}
+			if rejected := sd.compatibility.Check(catalog, service, options); len(rejected) > 0 {
+				rejections = append(rejections, rejected...)
+				continue
+			}
 
-				matches = append(matches, models.ShopMatch{
-					ShopID:       shop.ID,
-					ShopName:     shop.Name,
-					ServiceID:    service.ID,
-					TotalPrice:   totalPrice,
-					MatchScore:   matchScore,
-					Technology:   techType,
-					DeliveryDays: sd.estimateDeliveryDays(shop, service, options),
+			breakdown, ok := sd.quoter.QuoteService(ctx, service, options)
+			if !ok {
+				rejections = append(rejections, models.ServiceRejection{
+					ShopID:    shop.ID,
+					ServiceID: service.ID,
+					Field:     "size",
+					Reason:    fmt.Sprintf("size %q cannot be priced", options.Size),
 				})
+				continue
 			}
-		}
-	}
-
-	return matches, nil
-}
+			totalPrice := breakdown.Total
 
-// serviceSupportsOptions checks if a service can fulfill the order options
-func (sd *ServiceDiscovery) serviceSupportsOptions(service *models.PrintService, options models.PrintOrderOptions) bool {
-	// Check size support
-	if len(service.SizePricing) > 0 {
-		if _, ok := service.SizePricing[options.Size]; !ok {
-			return false
-		}
-	}
-
-	// TODO: Check material and medium support by resolving SubstrateID and MediumID
-	// For now, we assume if the service is active and has the size, it's a candidate
-	// matrix := service.PriceMatrix // Deprecated
-
-	/*
-	// Check material support
-	if len(matrix.MaterialMarkups) > 0 {
-		if _, ok := matrix.MaterialMarkups[options.Material]; !ok {
-			return false
-		}
-	}
+			if queue == nil {
+				queue = sd.scheduler.LoadQueue(ctx, shop.ID)
+			}
+			schedule, reason, ok := sd.scheduler.Estimate(shop, service, queue, options, now)
+			if !ok {
+				rejections = append(rejections, models.ServiceRejection{
+					ShopID:    shop.ID,
+					ServiceID: service.ID,
+					Field:     "capacity",
+					Reason:    reason,
+				})
+				continue
+			}
 
-	// Check medium support
-	if len(matrix.MediumMarkups) > 0 {
-		if _, ok := matrix.MediumMarkups[options.Medium]; !ok {
-			return false
-		}
-	}
+			techType := ""
+			if service.Technology != nil {
+				techType = service.Technology.Type
+			}
 
-	// Check frame support
-	if len(matrix.FramePrices) > 0 {
-		if _, ok := matrix.FramePrices[options.Frame]; !ok {
-			return false
+			matches = append(matches, models.ShopMatch{
+				ShopID:       shop.ID,
+				ShopName:     shop.Name,
+				ServiceID:    service.ID,
+				TotalPrice:   totalPrice,
+				Technology:   techType,
+				DeliveryDays: schedule.DeliveryDays,
+				ReadyBy:      schedule.ReadyBy,
+				DistanceKm:   distance,
+			})
 		}
 	}
-	*/
 
-	return true
+	return matches, rejections, nil
 }
 
-// calculateBasicScore calculates a basic match score (used by auto matcher)
-func (sd *ServiceDiscovery) calculateBasicScore(shop *models.PrintShopProfile, service *models.PrintService, price float64) float64 {
-	// Simple score: lower price = higher score
-	// Normalize to 0-100 range
-	if price <= 0 {
-		return 0
+// distanceTo returns the shop's distance from the buyer, or nil when either cannot be placed
+func (sd *ServiceDiscovery) distanceTo(ctx context.Context, shop *models.PrintShopProfile, buyer *models.Location) *float64 {
+	if buyer == nil {
+		return nil
 	}
-	return 100.0 / (1.0 + price/100.0)
-}
-
-// estimateDeliveryDays estimates delivery time (can be enhanced with real data)
-func (sd *ServiceDiscovery) estimateDeliveryDays(shop *models.PrintShopProfile, service *models.PrintService, options models.PrintOrderOptions) int {
-	baseDays := 5 // Base delivery time
-
-	// Rush orders are faster
-	if options.RushOrder {
-		baseDays = 2
+	loc := shop.Location
+	if !geo.Resolve(ctx, sd.geocoder, &loc) {
+		return nil
 	}
+	km := geo.DistanceKm(loc, *buyer)
+	return &km
+}
 
-	// Larger quantities take longer
-	if options.Quantity > 20 {
-		baseDays += 2
+// outsidePickupRadius explains why a shop cannot take a pickup order, or returns "" if it can
+func outsidePickupRadius(distance *float64, buyer *models.Location, radiusKm float64) string {
+	switch {
+	case radiusKm <= 0:
+		return ""
+	case buyer == nil:
+		return "pickup location could not be placed"
+	case distance == nil:
+		return "shop location could not be placed"
+	case *distance > radiusKm:
+		return fmt.Sprintf("%.1f km away, outside the %.1f km pickup radius", *distance, radiusKm)
 	}
-
-	return baseDays
+	return ""
 }
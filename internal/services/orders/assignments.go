@@ -0,0 +1,420 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/pricing"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+	"github.com/cecvl/art-print-backend/internal/services/tax"
+)
+
+// Errors returned to shops responding to orders in their inbox
+var (
+	ErrNotAssigned  = errors.New("order is not assigned to this shop")
+	ErrNotOffered   = errors.New("order is not awaiting a response")
+	ErrOfferExpired = errors.New("offer has expired")
+)
+
+// assignmentUnassigned is logged when no shop is left to offer an order to
+const assignmentUnassigned = "unassigned"
+
+// Assignments offers sub-orders to print shops and handles their responses. A shop must accept
+// an offer within the acceptance SLA; a declined or expired offer is re-matched, skipping every
+// shop that already turned the order down and repricing the order for its new shop unless the
+// buyer has started paying. Each step is recorded in the assignments log.
+type Assignments struct {
+	orders   repositories.OrderRepository
+	payments repositories.PaymentRepository
+	logs     repositories.ActivityLogRepository
+	config   config.ConfigService
+	matcher  *OrderService
+	quoter   *pricing.Quoter
+	shipping *shipping.ShippingService
+	tax      *tax.TaxService
+}
+
+// NewAssignments creates a new assignment service that re-matches with geocoder and reprices
+// shipping with rates
+func NewAssignments(cfg config.ConfigService, store *repositories.Store, geocoder geo.Geocoder, rates shipping.RateProvider) *Assignments {
+	return &Assignments{
+		orders:   store.Orders,
+		payments: store.Payments,
+		logs:     store.ActivityLog,
+		config:   cfg,
+		matcher:  NewOrderService(cfg, store, geocoder),
+		quoter:   pricing.NewQuoter(store.PrintShops, store.Frames),
+		shipping: shipping.NewShippingService(rates, store.PrintShops),
+		tax:      tax.NewTaxService(cfg, store),
+	}
+}
+
+// PrepareOffer marks an order that has not been saved yet as offered to its shop.
+// Orders without a shop are left without an offer.
+func (a *Assignments) PrepareOffer(ctx context.Context, order *models.Order, now time.Time) {
+	if order.PrintShopID == "" {
+		order.AssignmentStatus = ""
+		order.AssignmentDeadline = time.Time{}
+		return
+	}
+	order.AssignmentStatus = models.AssignmentOffered
+	order.AssignmentDeadline = now.Add(a.config.AcceptanceSLA(ctx))
+}
+
+// RecordOffer logs an offer made with PrepareOffer once the order has been saved
+func (a *Assignments) RecordOffer(ctx context.Context, order *models.Order, actor, reason string) {
+	if order.PrintShopID != "" {
+		a.record(ctx, order.OrderID, order.PrintShopID, string(models.AssignmentOffered), actor, reason)
+	}
+}
+
+// Assign offers a saved order to shopID, replacing any earlier assignment.
+// An empty shopID leaves the order unassigned for manual selection.
+func (a *Assignments) Assign(ctx context.Context, order *models.Order, shopID, actor, reason string) error {
+	order.PrintShopID = shopID
+	a.PrepareOffer(ctx, order, time.Now())
+	if err := a.orders.UpdateOrder(ctx, order.OrderID, map[string]interface{}{
+		"printShopId":        order.PrintShopID,
+		"assignmentStatus":   order.AssignmentStatus,
+		"assignmentDeadline": order.AssignmentDeadline,
+	}); err != nil {
+		return err
+	}
+
+	if shopID == "" {
+		a.record(ctx, order.OrderID, "", assignmentUnassigned, actor, reason)
+		return nil
+	}
+	a.RecordOffer(ctx, order, actor, reason)
+	return nil
+}
+
+// Accept commits shopID to an order offered to it. An offer past its deadline is expired
+// and re-matched instead, and ErrOfferExpired is returned. The offer is checked and accepted
+// in one transaction, so a concurrent expiry or reassignment cannot be accepted by mistake.
+func (a *Assignments) Accept(ctx context.Context, orderID, shopID, actor string) (*models.Order, error) {
+	var order models.Order
+	err := a.orders.UpdateOrderIf(ctx, orderID, func(o *models.Order) (map[string]interface{}, error) {
+		if err := offeredTo(o, shopID); err != nil {
+			return nil, err
+		}
+		order = *o
+		if time.Now().After(o.AssignmentDeadline) {
+			return nil, ErrOfferExpired
+		}
+		return map[string]interface{}{"assignmentStatus": models.AssignmentAccepted}, nil
+	})
+	if errors.Is(err, ErrOfferExpired) {
+		a.release(ctx, orderID, shopID, models.AssignmentExpired, systemActor, "acceptance SLA elapsed")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	order.AssignmentStatus = models.AssignmentAccepted
+	a.record(ctx, orderID, shopID, string(models.AssignmentAccepted), actor, "")
+	return &order, nil
+}
+
+// Decline turns down an order offered to shopID and re-matches it to another shop
+func (a *Assignments) Decline(ctx context.Context, orderID, shopID, actor, reason string) (*models.Order, error) {
+	order, err := a.release(ctx, orderID, shopID, models.AssignmentDeclined, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ExpireOverdue releases every offer whose deadline has passed and re-matches those orders.
+// Orders cancelled or refunded in the meantime are skipped. It returns how many offers expired.
+func (a *Assignments) ExpireOverdue(ctx context.Context, now time.Time) (int, error) {
+	offered, err := a.orders.ListOrders(ctx, repositories.OrderFilter{Assignment: models.AssignmentOffered})
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range offered {
+		if !order.Status.IsOpen() || order.AssignmentDeadline.IsZero() || now.Before(order.AssignmentDeadline) {
+			continue
+		}
+		if _, err := a.release(ctx, order.OrderID, order.PrintShopID, models.AssignmentExpired, systemActor, "acceptance SLA elapsed"); err == nil {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// RunExpiryLoop calls ExpireOverdue every interval until ctx is cancelled
+func (a *Assignments) RunExpiryLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := a.ExpireOverdue(ctx, now)
+			if err != nil {
+				log.Printf("⚠️ Failed to expire overdue assignments: %v", err)
+			} else if expired > 0 {
+				log.Printf("📋 Expired %d overdue assignments", expired)
+			}
+		}
+	}
+}
+
+// offeredTo checks an open order is waiting for shopID to respond
+func offeredTo(order *models.Order, shopID string) error {
+	if order.PrintShopID != shopID {
+		return ErrNotAssigned
+	}
+	if order.AssignmentStatus != models.AssignmentOffered {
+		return ErrNotOffered
+	}
+	if !order.Status.IsOpen() {
+		return fmt.Errorf("%w: order is %s", ErrNotOffered, order.Status)
+	}
+	return nil
+}
+
+// release takes an order away from the shop it is offered to, recording why and excluding the
+// shop from future matches, then re-runs matching and reprices the order for the new shop.
+// Taking the order is one transaction that fails with ErrNotAssigned or ErrNotOffered once the
+// offer no longer stands, so only one of a racing decline, expiry or acceptance wins. Matching
+// failures are logged; the order is left unassigned if matching fails.
+func (a *Assignments) release(ctx context.Context, orderID, shopID string, outcome models.AssignmentStatus, actor, reason string) (*models.Order, error) {
+	var order models.Order
+	err := a.orders.UpdateOrderIf(ctx, orderID, func(o *models.Order) (map[string]interface{}, error) {
+		if err := offeredTo(o, shopID); err != nil {
+			return nil, err
+		}
+		order = *o
+		order.DeclinedShopIDs = append(order.DeclinedShopIDs, shopID)
+		order.PrintShopID = ""
+		order.AssignmentStatus = outcome
+		return map[string]interface{}{
+			"declinedShopIds":  order.DeclinedShopIDs,
+			"printShopId":      "",
+			"assignmentStatus": outcome,
+		}, nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotAssigned) && !errors.Is(err, ErrNotOffered) {
+			log.Printf("⚠️ Failed to release order %s from shop %s: %v", orderID, shopID, err)
+		}
+		return nil, err
+	}
+	a.record(ctx, orderID, shopID, string(outcome), actor, reason)
+
+	if err := a.matcher.AssignShopForOrder(ctx, &order); err != nil {
+		log.Printf("⚠️ Failed to re-match order %s: %v", orderID, err)
+		order.PrintShopID = ""
+	}
+
+	reassignReason := "shop " + shopID + " " + string(outcome)
+	if err := a.Assign(ctx, &order, order.PrintShopID, systemActor, reassignReason); err != nil {
+		log.Printf("❌ Failed to reassign order %s: %v", orderID, err)
+		return &order, nil
+	}
+	if order.PrintShopID == "" {
+		log.Printf("⚠️ Order %s has no shop left to offer it to; manual assignment needed", orderID)
+		return &order, nil
+	}
+	if err := a.reprice(ctx, &order); err != nil {
+		log.Printf("⚠️ Failed to reprice order %s for shop %s: %v", orderID, order.PrintShopID, err)
+	}
+	return &order, nil
+}
+
+// reprice quotes a reassigned sub-order's lines, shipping and tax again from its new shop, keeping
+// the discounts it was given at checkout, and brings its parent's totals in line. Once the buyer
+// has started paying, the checkout price stands so the order keeps matching its payments; the
+// new shop's quote is left in an admin note instead.
+func (a *Assignments) reprice(ctx context.Context, order *models.Order) error {
+	shopIDs := make([]string, len(order.Items))
+	for i := range shopIDs {
+		shopIDs[i] = order.PrintShopID
+	}
+	if a.paymentStarted(ctx, order) {
+		return a.noteDrift(ctx, order, shopIDs)
+	}
+	if drift := a.quoter.Reprice(ctx, order.Items, shopIDs); len(drift) > 0 {
+		log.Printf("⚠️ Order %s repriced %d line(s) for shop %s", order.OrderID, len(drift), order.PrintShopID)
+	}
+
+	total := models.NewMoney(0, order.TotalAmount.Currency)
+	for _, item := range order.Items {
+		total = total.Add(item.LineTotal())
+	}
+	order.TotalAmount = total.Sub(order.DiscountTotal).Max(models.NewMoney(0, total.Currency))
+	order.ShippingCost, order.ShippingQuote = models.Money{}, nil
+	order.TaxLines, order.TaxTotal = nil, models.Money{}
+
+	// Quote against a scratch parent; the real parent is totalled from its sub-orders below
+	scratch := &models.Order{OrderID: order.ParentOrderID, BuyerID: order.BuyerID}
+	subOrders := []*models.Order{order}
+	if order.ShippingAddress != nil {
+		if err := a.shipping.ApplyShipping(ctx, scratch, subOrders, *order.ShippingAddress); err != nil {
+			return err
+		}
+	} else if order.DeliveryMethod == models.DeliveryMethodPickup {
+		order.PickupLocation = ""
+		a.shipping.ApplyPickup(ctx, scratch, subOrders)
+	}
+	rewaiveShipping(order)
+	a.tax.ApplyTax(ctx, scratch, subOrders)
+
+	if err := a.orders.UpdateOrder(ctx, order.OrderID, map[string]interface{}{
+		"items":          order.Items,
+		"totalAmount":    order.TotalAmount,
+		"shippingCost":   order.ShippingCost,
+		"shippingQuote":  order.ShippingQuote,
+		"pickupLocation": order.PickupLocation,
+		"discounts":      order.Discounts,
+		"discountTotal":  order.DiscountTotal,
+		"taxLines":       order.TaxLines,
+		"taxTotal":       order.TaxTotal,
+	}); err != nil {
+		return err
+	}
+	if !order.IsSubOrder() {
+		return nil
+	}
+	return a.retotalParent(ctx, order.ParentOrderID)
+}
+
+// paymentStarted reports whether the order, or the parent it is paid through, has a payment
+// that is captured or may still be. A failed lookup counts as started, keeping the price.
+func (a *Assignments) paymentStarted(ctx context.Context, order *models.Order) bool {
+	payerID := order.OrderID
+	if order.IsSubOrder() {
+		payerID = order.ParentOrderID
+	}
+	payments, err := a.payments.GetPaymentsByOrderID(ctx, payerID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get payments of order %s; keeping its checkout price: %v", payerID, err)
+		return true
+	}
+	for _, p := range payments {
+		if p.Status != models.PaymentStatusFailed && p.Status != models.PaymentStatusCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// noteDrift leaves an admin note on an order reassigned after payment when its new shop quotes
+// its items differently from what the buyer was charged
+func (a *Assignments) noteDrift(ctx context.Context, order *models.Order, shopIDs []string) error {
+	quoted := append([]models.CartItem(nil), order.Items...)
+	if len(a.quoter.Reprice(ctx, quoted, shopIDs)) == 0 {
+		return nil
+	}
+	charged, quote := models.NewMoney(0, order.TotalAmount.Currency), models.NewMoney(0, order.TotalAmount.Currency)
+	for i := range order.Items {
+		charged = charged.Add(order.Items[i].LineTotal())
+		quote = quote.Add(quoted[i].LineTotal())
+	}
+	log.Printf("⚠️ Order %s was reassigned to shop %s after payment; its checkout price is kept", order.OrderID, order.PrintShopID)
+	return a.orders.AppendAdminNote(ctx, order.OrderID, models.AdminNote{
+		Note: fmt.Sprintf("Reassigned to shop %s after payment. Items were charged %s; the new shop quotes %s (%s). Checkout price kept.",
+			order.PrintShopID, charged, quote, quote.Sub(charged)),
+		Level:     "warning",
+		CreatedBy: systemActor,
+	})
+}
+
+// rewaiveShipping moves free-shipping discounts onto a re-quoted shipping cost, so the buyer
+// still pays nothing for shipping and no more is waived than the new quote
+func rewaiveShipping(order *models.Order) {
+	for i := range order.Discounts {
+		d := &order.Discounts[i]
+		if d.Type != models.PromotionFreeShipping {
+			continue
+		}
+		change := order.ShippingCost.Sub(d.Shipping)
+		d.Shipping = order.ShippingCost
+		d.Amount = d.Amount.Add(change)
+		order.DiscountTotal = order.DiscountTotal.Add(change)
+		order.TotalAmount = order.TotalAmount.Sub(change)
+	}
+}
+
+// retotalParent sets a parent order's totals to the sum of its sub-orders'
+func (a *Assignments) retotalParent(ctx context.Context, parentID string) error {
+	parent, err := a.orders.GetOrderByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	subOrders, err := a.orders.ListOrders(ctx, repositories.OrderFilter{ParentOrderID: parentID})
+	if err != nil {
+		return err
+	}
+
+	currency := parent.TotalAmount.Currency
+	total, shippingCost, taxTotal := models.NewMoney(0, currency), models.NewMoney(0, currency), models.NewMoney(0, currency)
+	discountTotal := models.NewMoney(0, currency)
+	pickup := ""
+	for _, sub := range subOrders {
+		total = total.Add(sub.TotalAmount)
+		shippingCost = shippingCost.Add(sub.ShippingCost)
+		taxTotal = taxTotal.Add(sub.TaxTotal)
+		discountTotal = discountTotal.Add(sub.DiscountTotal)
+	}
+	if len(subOrders) == 1 {
+		pickup = subOrders[0].PickupLocation
+	}
+	return a.orders.UpdateOrder(ctx, parentID, map[string]interface{}{
+		"totalAmount":    total,
+		"shippingCost":   shippingCost,
+		"discounts":      sumDiscounts(parent.Discounts, subOrders),
+		"discountTotal":  discountTotal,
+		"taxLines":       tax.SumTaxLines(subOrders),
+		"taxTotal":       taxTotal,
+		"pickupLocation": pickup,
+	})
+}
+
+// sumDiscounts totals the sub-orders' share of each of the parent's discounts, as checkout does
+func sumDiscounts(parentDiscounts []models.OrderDiscount, subOrders []*models.Order) []models.OrderDiscount {
+	discounts := make([]models.OrderDiscount, len(parentDiscounts))
+	for i, pd := range parentDiscounts {
+		total := models.OrderDiscount{PromotionID: pd.PromotionID, Code: pd.Code, Type: pd.Type}
+		for _, sub := range subOrders {
+			for _, d := range sub.Discounts {
+				if d.PromotionID != pd.PromotionID {
+					continue
+				}
+				total.Lines = append(total.Lines, d.Lines...)
+				total.Shipping = total.Shipping.Add(d.Shipping)
+				total.Amount = total.Amount.Add(d.Amount)
+			}
+		}
+		discounts[i] = total
+	}
+	return discounts
+}
+
+func (a *Assignments) record(ctx context.Context, orderID, shopID, status, actor, reason string) {
+	entry := map[string]interface{}{
+		"orderId":     orderID,
+		"printShopId": shopID,
+		"status":      status,
+		"createdAt":   time.Now(),
+		"createdBy":   actor,
+	}
+	if reason != "" {
+		entry["reason"] = reason
+	}
+	if err := a.logs.Record(ctx, repositories.LogAssignments, entry); err != nil {
+		log.Printf("⚠️ Failed to record assignment of order %s: %v", orderID, err)
+	}
+}
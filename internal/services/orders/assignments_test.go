@@ -0,0 +1,206 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+	"github.com/cecvl/art-print-backend/internal/services/shipping"
+)
+
+func TestExpireOverdueSkipsClosedOrders(t *testing.T) {
+	tests := []struct {
+		name       string
+		close      func(ctx context.Context, store *repositories.Store) error
+		wantStatus models.AssignmentStatus
+	}{
+		{
+			name: "sub-order cancelled",
+			close: func(ctx context.Context, store *repositories.Store) error {
+				_, err := NewLifecycle(store).Transition(ctx, "sub", models.OrderStatusCancelled, "buyer", "changed my mind")
+				return err
+			},
+			wantStatus: models.AssignmentWithdrawn,
+		},
+		{
+			name: "parent cancelled",
+			close: func(ctx context.Context, store *repositories.Store) error {
+				_, err := NewLifecycle(store).Transition(ctx, "parent", models.OrderStatusCancelled, "buyer", "changed my mind")
+				return err
+			},
+			wantStatus: models.AssignmentWithdrawn,
+		},
+		{
+			name: "parent refunded",
+			close: func(ctx context.Context, store *repositories.Store) error {
+				lifecycle := NewLifecycle(store)
+				if _, err := lifecycle.Transition(ctx, "parent", models.OrderStatusConfirmed, "system", "paid"); err != nil {
+					return err
+				}
+				_, err := lifecycle.Transition(ctx, "parent", models.OrderStatusRefunded, "admin", "refunded in full")
+				return err
+			},
+			wantStatus: models.AssignmentWithdrawn,
+		},
+		{
+			name: "cancelled without closing the offer",
+			close: func(ctx context.Context, store *repositories.Store) error {
+				return store.Orders.TransitionStatus(ctx, &models.OrderEvent{OrderID: "sub", To: models.OrderStatusCancelled, Actor: "legacy"})
+			},
+			wantStatus: models.AssignmentOffered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repositories.NewMemoryStore()
+			cfg := config.NewDefaultConfigService()
+			assignments := NewAssignments(cfg, store, geo.NewTableGeocoder(), shipping.NewTableRateProvider())
+
+			now := time.Now()
+			parent := &models.Order{OrderID: "parent", BuyerID: "buyer", Status: models.OrderStatusPending}
+			sub := &models.Order{
+				OrderID:            "sub",
+				BuyerID:            "buyer",
+				PrintShopID:        "shop-a",
+				Status:             models.OrderStatusPending,
+				AssignmentStatus:   models.AssignmentOffered,
+				AssignmentDeadline: now.Add(cfg.AcceptanceSLA(ctx)),
+			}
+			if err := store.Orders.CreateOrderGroup(ctx, parent, []*models.Order{sub}); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.close(ctx, store); err != nil {
+				t.Fatalf("closing the order: %v", err)
+			}
+
+			expired, err := assignments.ExpireOverdue(ctx, now.Add(2*cfg.AcceptanceSLA(ctx)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expired != 0 {
+				t.Errorf("ExpireOverdue expired %d offers, want 0", expired)
+			}
+
+			got, err := store.Orders.GetOrderByID(ctx, "sub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.PrintShopID != "shop-a" || len(got.DeclinedShopIDs) > 0 {
+				t.Errorf("closed order was re-matched: shop %q, declined %v", got.PrintShopID, got.DeclinedShopIDs)
+			}
+			if got.AssignmentStatus != tt.wantStatus {
+				t.Errorf("assignment status = %q, want %q", got.AssignmentStatus, tt.wantStatus)
+			}
+			if tt.wantStatus == models.AssignmentWithdrawn && !got.AssignmentDeadline.IsZero() {
+				t.Errorf("withdrawn offer kept its deadline %v", got.AssignmentDeadline)
+			}
+
+			if _, err := assignments.Accept(ctx, "sub", "shop-a", "shop-a"); !errors.Is(err, ErrNotOffered) {
+				t.Errorf("Accept on a closed order: err = %v, want ErrNotOffered", err)
+			}
+			if _, err := assignments.Decline(ctx, "sub", "shop-a", "shop-a", "busy"); !errors.Is(err, ErrNotOffered) {
+				t.Errorf("Decline on a closed order: err = %v, want ErrNotOffered", err)
+			}
+		})
+	}
+}
+
+func TestRepriceAfterReassignment(t *testing.T) {
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+
+	tests := []struct {
+		name    string
+		payment models.PaymentStatus // "" for no payment yet
+	}{
+		{name: "no payment yet"},
+		{name: "payment failed", payment: models.PaymentStatusFailed},
+		{name: "payment pending", payment: models.PaymentStatusPending},
+		{name: "paid", payment: models.PaymentStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repositories.NewMemoryStore()
+			assignments := NewAssignments(config.NewDefaultConfigService(), store, geo.NewTableGeocoder(), shipping.NewTableRateProvider())
+
+			// Checked out at 1.00 with 5.00 shipping waived by a free-shipping coupon
+			freeShipping := models.OrderDiscount{PromotionID: "promo", Code: "SHIPFREE", Type: models.PromotionFreeShipping, Amount: kes(500), Shipping: kes(500)}
+			address := &models.Address{Location: models.Location{City: "Mombasa", Country: "KE"}}
+			sub := &models.Order{
+				OrderID:         "sub",
+				BuyerID:         "buyer",
+				PrintShopID:     "shop-b",
+				Items:           []models.CartItem{{LineID: "line", ArtworkID: "art", Quantity: 1, Price: kes(100), PrintOptions: models.PrintOrderOptions{Size: "A4"}}},
+				DeliveryMethod:  models.DeliveryMethodShipping,
+				ShippingAddress: address,
+				ShippingCost:    kes(500),
+				Discounts:       []models.OrderDiscount{freeShipping},
+				DiscountTotal:   kes(500),
+				TotalAmount:     kes(100),
+			}
+			parent := &models.Order{
+				OrderID:       "parent",
+				BuyerID:       "buyer",
+				ShippingCost:  kes(500),
+				Discounts:     []models.OrderDiscount{freeShipping},
+				DiscountTotal: kes(500),
+				TotalAmount:   kes(100),
+			}
+			if err := store.Orders.CreateOrderGroup(ctx, parent, []*models.Order{sub}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.payment != "" {
+				if err := store.Payments.CreatePayment(ctx, &models.Payment{OrderID: "parent", Amount: kes(100), Status: tt.payment}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			order, err := store.Orders.GetOrderByID(ctx, "sub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := assignments.reprice(ctx, order); err != nil {
+				t.Fatalf("reprice: %v", err)
+			}
+			gotSub, _ := store.Orders.GetOrderByID(ctx, "sub")
+			gotParent, _ := store.Orders.GetOrderByID(ctx, "parent")
+
+			if tt.payment != "" && tt.payment != models.PaymentStatusFailed {
+				if gotSub.TotalAmount != kes(100) || gotSub.Items[0].Price != kes(100) || gotParent.TotalAmount != kes(100) {
+					t.Errorf("checkout price changed after payment: sub %s (line %s), parent %s", gotSub.TotalAmount, gotSub.Items[0].Price, gotParent.TotalAmount)
+				}
+				if len(gotSub.AdminNotes) != 1 {
+					t.Errorf("admin notes = %v, want one recording the new shop's quote", gotSub.AdminNotes)
+				}
+				return
+			}
+
+			items := gotSub.Items[0].LineTotal()
+			if items == kes(100) {
+				t.Fatalf("line was not repriced")
+			}
+			if !gotSub.ShippingCost.IsPositive() {
+				t.Fatalf("shipping was not quoted")
+			}
+			if len(gotSub.Discounts) != 1 || gotSub.Discounts[0].Shipping != gotSub.ShippingCost || gotSub.DiscountTotal != gotSub.ShippingCost {
+				t.Errorf("free shipping waives %v of %s shipping, want all of it", gotSub.Discounts, gotSub.ShippingCost)
+			}
+			if gotSub.TotalAmount != items {
+				t.Errorf("sub total = %s, want the items' %s with shipping waived", gotSub.TotalAmount, items)
+			}
+			if gotParent.TotalAmount != gotSub.TotalAmount || gotParent.DiscountTotal != gotSub.DiscountTotal ||
+				len(gotParent.Discounts) != 1 || gotParent.Discounts[0].Shipping != gotSub.ShippingCost {
+				t.Errorf("parent total %s, discount %s %v do not match its sub-order", gotParent.TotalAmount, gotParent.DiscountTotal, gotParent.Discounts)
+			}
+			if len(gotSub.AdminNotes) != 0 {
+				t.Errorf("unexpected admin notes %v", gotSub.AdminNotes)
+			}
+		})
+	}
+}
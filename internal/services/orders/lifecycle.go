@@ -0,0 +1,213 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// systemActor is recorded on transitions the lifecycle makes on its own
+const systemActor = "system:orders"
+
+// fulfillmentPath is the happy path a status roll-up may walk through
+var fulfillmentPath = []models.OrderStatus{
+	models.OrderStatusPending,
+	models.OrderStatusConfirmed,
+	models.OrderStatusProcessing,
+	models.OrderStatusReady,
+	models.OrderStatusCompleted,
+}
+
+// Lifecycle applies status transitions across parent orders and their fulfillment sub-orders.
+// Confirming, cancelling or refunding a parent cascades to its sub-orders; any change to a
+// sub-order rolls the parent's status up from all of its siblings. A cancelled order gives
+// back the coupon uses it redeemed, and a cancelled or refunded sub-order withdraws any offer
+// still waiting for a shop's answer.
+type Lifecycle struct {
+	orders     repositories.OrderRepository
+	promotions repositories.PromotionRepository
+}
+
+// NewLifecycle creates a new order lifecycle
+func NewLifecycle(store *repositories.Store) *Lifecycle {
+	return &Lifecycle{orders: store.Orders, promotions: store.Promotions}
+}
+
+// Transition moves an order to a new status and propagates it through the parent/sub-order tree.
+// The returned error wraps repositories.ErrIllegalTransition or ErrNotFound for the order itself;
+// propagation failures are logged, never returned.
+func (l *Lifecycle) Transition(ctx context.Context, orderID string, to models.OrderStatus, actor, reason string) (*models.OrderEvent, error) {
+	order, err := l.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &models.OrderEvent{OrderID: orderID, To: to, Actor: actor, Reason: reason}
+	if err := l.orders.TransitionStatus(ctx, event); err != nil {
+		return nil, err
+	}
+
+	if to == models.OrderStatusCancelled && !order.IsSubOrder() {
+		l.releaseRedemptions(ctx, orderID)
+	}
+	if closesOffer(to) && !order.IsParent() {
+		l.withdrawOffer(ctx, orderID)
+	}
+
+	switch {
+	case order.IsParent() && cascadesToSubOrders(to):
+		for _, subID := range order.SubOrderIDs {
+			sub := &models.OrderEvent{OrderID: subID, To: to, Actor: actor, Reason: fmt.Sprintf("parent order %s %s", orderID, to)}
+			if err := l.orders.TransitionStatus(ctx, sub); err != nil {
+				if !errors.Is(err, repositories.ErrIllegalTransition) {
+					log.Printf("⚠️ Failed to cascade %s to sub-order %s: %v", to, subID, err)
+				}
+				continue
+			}
+			if closesOffer(to) {
+				l.withdrawOffer(ctx, subID)
+			}
+		}
+	case order.IsSubOrder():
+		l.rollUp(ctx, order.ParentOrderID, fmt.Sprintf("sub-order %s %s", orderID, to))
+	}
+
+	return event, nil
+}
+
+// rollUp recomputes a parent's status from its sub-orders and walks it there
+func (l *Lifecycle) rollUp(ctx context.Context, parentID, reason string) {
+	parent, err := l.orders.GetOrderByID(ctx, parentID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load parent order %s: %v", parentID, err)
+		return
+	}
+	subOrders, err := l.orders.ListOrders(ctx, repositories.OrderFilter{ParentOrderID: parentID})
+	if err != nil || len(subOrders) == 0 {
+		if err != nil {
+			log.Printf("⚠️ Failed to load sub-orders of %s: %v", parentID, err)
+		}
+		return
+	}
+
+	current := parent.Status
+	if current == "" {
+		current = models.OrderStatusPending
+	}
+	for _, step := range stepsBetween(current, RollUpStatus(subOrders)) {
+		event := &models.OrderEvent{OrderID: parentID, To: step, Actor: systemActor, Reason: reason}
+		if err := l.orders.TransitionStatus(ctx, event); err != nil {
+			log.Printf("⚠️ Failed to roll parent order %s up to %s: %v", parentID, step, err)
+			return
+		}
+		if step == models.OrderStatusCancelled {
+			l.releaseRedemptions(ctx, parentID)
+		}
+	}
+}
+
+// releaseRedemptions gives back the coupon uses of a cancelled order, so limited coupons and
+// first-order coupons can be used again. Failures are logged; the cancellation stands.
+func (l *Lifecycle) releaseRedemptions(ctx context.Context, orderID string) {
+	if err := l.promotions.ReleaseRedemptions(ctx, orderID); err != nil {
+		log.Printf("⚠️ Failed to release promotion redemptions of cancelled order %s: %v", orderID, err)
+	}
+}
+
+// errNoOpenOffer aborts withdrawOffer's update when the order has no offer to withdraw
+var errNoOpenOffer = errors.New("no open offer")
+
+// withdrawOffer closes the offer of an order that was cancelled or refunded while a shop still
+// had to answer it, so the shop can no longer accept it and expiry does not re-match it.
+// Failures are logged; the transition stands.
+func (l *Lifecycle) withdrawOffer(ctx context.Context, orderID string) {
+	err := l.orders.UpdateOrderIf(ctx, orderID, func(o *models.Order) (map[string]interface{}, error) {
+		if o.AssignmentStatus != models.AssignmentOffered {
+			return nil, errNoOpenOffer
+		}
+		return map[string]interface{}{
+			"assignmentStatus":   models.AssignmentWithdrawn,
+			"assignmentDeadline": time.Time{},
+		}, nil
+	})
+	if err != nil && !errors.Is(err, errNoOpenOffer) {
+		log.Printf("⚠️ Failed to withdraw the offer of order %s: %v", orderID, err)
+	}
+}
+
+// RollUpStatus derives a parent order's status from its sub-orders: any error wins,
+// otherwise the least advanced sub-order still being fulfilled sets the pace.
+func RollUpStatus(subOrders []*models.Order) models.OrderStatus {
+	slowest := -1
+	allCancelled := true
+	for _, sub := range subOrders {
+		status := sub.Status
+		if status == "" {
+			status = models.OrderStatusPending
+		}
+		switch status {
+		case models.OrderStatusError:
+			return models.OrderStatusError
+		case models.OrderStatusCancelled:
+			continue
+		case models.OrderStatusRefunded:
+			allCancelled = false
+			continue
+		}
+		if rank := pathRank(status); slowest < 0 || rank < slowest {
+			slowest = rank
+		}
+	}
+
+	switch {
+	case slowest >= 0:
+		return fulfillmentPath[slowest]
+	case allCancelled:
+		return models.OrderStatusCancelled
+	default:
+		return models.OrderStatusRefunded
+	}
+}
+
+// stepsBetween lists the transitions needed to move from one status to another,
+// walking forward along the fulfillment path where both ends lie on it
+func stepsBetween(from, to models.OrderStatus) []models.OrderStatus {
+	if from == to {
+		return nil
+	}
+	fromRank, toRank := pathRank(from), pathRank(to)
+	if fromRank >= 0 && toRank > fromRank {
+		return fulfillmentPath[fromRank+1 : toRank+1]
+	}
+	if fromRank >= 0 && toRank >= 0 {
+		return nil // sub-orders never move the parent backwards
+	}
+	return []models.OrderStatus{to}
+}
+
+func pathRank(status models.OrderStatus) int {
+	for i, s := range fulfillmentPath {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// closesOffer reports whether an order moving to status no longer needs a shop
+func closesOffer(status models.OrderStatus) bool {
+	return status == models.OrderStatusCancelled || status == models.OrderStatusRefunded
+}
+
+func cascadesToSubOrders(status models.OrderStatus) bool {
+	switch status {
+	case models.OrderStatusConfirmed, models.OrderStatusCancelled, models.OrderStatusRefunded:
+		return true
+	}
+	return false
+}
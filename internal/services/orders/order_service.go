@@ -2,50 +2,210 @@ package orders
 
 import (
 	"context"
+	"log"
+	"sort"
 
 	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
 	"github.com/cecvl/art-print-backend/internal/services/config"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
 	"github.com/cecvl/art-print-backend/internal/services/matching"
+	"github.com/google/uuid"
 )
 
 type OrderService struct {
 	configService config.ConfigService
-
-	autoMatcher   *matching.AutoMatcher
-	manualMatcher *matching.ManualMatcher
-	smartMatcher  *matching.SmartMatcher
+	pipeline      *matching.Pipeline
 }
 
-func NewOrderService(cfg config.ConfigService) *OrderService {
+func NewOrderService(cfg config.ConfigService, store *repositories.Store, geocoder geo.Geocoder) *OrderService {
 	return &OrderService{
 		configService: cfg,
-		autoMatcher:   matching.NewAutoMatcher(),
-		manualMatcher: matching.NewManualMatcher(),
-		smartMatcher:  matching.NewSmartMatcher(),
+		pipeline:      matching.NewPipeline(store, cfg, geocoder),
 	}
 }
 
-// AssignShopForOrder assigns a print shop to an order based on fulfillment mode
+// AssignShopForOrder assigns a print shop to an order based on fulfillment mode. The mode is
+// resolved for every candidate shop, honouring shop and print material overrides: shops in
+// manual mode are never picked, and the others are ranked by their mode's strategy. An order
+// with several items goes to one shop that can print all of them. Shops that already declined
+// the order are skipped. An order no shop can take automatically stays unassigned.
 func (s *OrderService) AssignShopForOrder(ctx context.Context, order *models.Order) error {
-	mode := s.configService.GetFulfillmentMode()
+	category := order.PrintOptions.Material
+	best, strategy, err := s.matchAll(ctx, orderOptions(order), s.strategyFor(ctx, category), order.DeclinedShopIDs)
+	if err != nil {
+		log.Printf("❌ Failed to find matching shops: %v", err)
+		return err
+	}
+	if best == nil {
+		order.PrintShopID = ""
+		if s.configService.FulfillmentModeFor(ctx, "", category) == models.FulfillmentManual {
+			log.Printf("📋 Manual mode: Order %s requires manual shop assignment", order.OrderID)
+		} else {
+			log.Printf("⚠️ No matching shops found for order %s", order.OrderID)
+		}
+		return nil
+	}
+	order.PrintShopID = best.ShopID
+
+	log.Printf("✅ Assigned order %s to shop %s via %s matching (score: %.2f, price: %s)",
+		order.OrderID, best.ShopName, strategy, best.MatchScore, best.TotalPrice)
 
-	// Extract print options from order
-	options := order.PrintOptions
+	return nil
+}
 
-	switch mode {
-	case models.FulfillmentAuto:
-		return s.autoMatcher.Assign(ctx, order, options)
-	case models.FulfillmentManual:
-		return s.manualMatcher.Assign(ctx, order, options)
-	case models.FulfillmentSmart:
-		return s.smartMatcher.Assign(ctx, order, options)
-	default:
-		return s.autoMatcher.Assign(ctx, order, options)
+// strategyFor returns the strategy each shop is matched with for a print material, or "" for
+// shops in manual mode
+func (s *OrderService) strategyFor(ctx context.Context, category string) matching.StrategyFunc {
+	return func(shopID string) string {
+		mode := s.configService.FulfillmentModeFor(ctx, shopID, category)
+		if mode == models.FulfillmentManual {
+			return ""
+		}
+		return StrategyForMode(mode)
 	}
 }
 
-// GetMatchesForOrder returns all matching shops for an order (useful for manual mode)
+// orderOptions returns the print options of each item of an order, with the item's quantity.
+// Orders without items are matched on the order's own options.
+func orderOptions(order *models.Order) []models.PrintOrderOptions {
+	if len(order.Items) == 0 {
+		return []models.PrintOrderOptions{order.PrintOptions}
+	}
+	options := make([]models.PrintOrderOptions, len(order.Items))
+	for i, item := range order.Items {
+		options[i] = item.PrintOptions
+		if item.Quantity > 0 {
+			options[i].Quantity = item.Quantity
+		}
+	}
+	return options
+}
+
+// matchAll matches every options set on its own and returns the best shop that matched all of
+// them, or nil if none did, with the strategy it was ranked by. A shop's matches are added up:
+// cheapest matching picks the lowest total price, the other strategies the highest total score.
+// Ties keep the first set's order.
+func (s *OrderService) matchAll(ctx context.Context, options []models.PrintOrderOptions, strategyFor matching.StrategyFunc, excludeShopIDs []string) (*models.ShopMatch, string, error) {
+	var combined []models.ShopMatch
+	strategy := ""
+	for i, opts := range options {
+		result, err := s.pipeline.MatchPerShop(ctx, opts, strategyFor, excludeShopIDs...)
+		if err != nil {
+			return nil, "", err
+		}
+		if i == 0 {
+			combined, strategy = result.Matches, result.Strategy
+			continue
+		}
+		if result.Strategy != strategy {
+			strategy = "mixed"
+		}
+
+		byShop := make(map[string]models.ShopMatch, len(result.Matches))
+		for _, m := range result.Matches {
+			byShop[m.ShopID] = m
+		}
+		kept := combined[:0]
+		for _, c := range combined {
+			m, ok := byShop[c.ShopID]
+			if !ok {
+				continue
+			}
+			c.TotalPrice = c.TotalPrice.Add(m.TotalPrice)
+			c.MatchScore += m.MatchScore
+			c.DeliveryDays = max(c.DeliveryDays, m.DeliveryDays)
+			if m.ReadyBy.After(c.ReadyBy) {
+				c.ReadyBy = m.ReadyBy
+			}
+			kept = append(kept, c)
+		}
+		combined = kept
+	}
+	if len(combined) == 0 {
+		return nil, strategy, nil
+	}
+
+	if len(options) > 1 {
+		sort.SliceStable(combined, func(i, j int) bool {
+			if strategy == matching.StrategyCheapest {
+				return combined[i].TotalPrice.Cmp(combined[j].TotalPrice) < 0
+			}
+			return combined[i].MatchScore > combined[j].MatchScore
+		})
+	}
+	return &combined[0], strategy, nil
+}
+
+// GetMatchesForOrder returns all matching shops for an order, cheapest first (useful for manual mode)
 func (s *OrderService) GetMatchesForOrder(ctx context.Context, order *models.Order) ([]models.ShopMatch, error) {
-	options := order.PrintOptions
-	return s.manualMatcher.GetMatches(ctx, order, options)
+	result, err := s.pipeline.Match(ctx, order.PrintOptions, matching.StrategyCheapest)
+	if err != nil {
+		log.Printf("❌ Failed to find matching shops: %v", err)
+		return nil, err
+	}
+	return result.Matches, nil
+}
+
+// StrategyForMode returns the matching strategy an automatic fulfillment mode assigns with
+func StrategyForMode(mode models.FulfillmentMode) string {
+	if mode == models.FulfillmentSmart {
+		return matching.StrategySmart
+	}
+	return matching.StrategyCheapest
+}
+
+// MatchItemShops matches each item on its own print options and returns the chosen shop ID
+// per item ("" when no shop matched, or for every item in manual mode)
+func (s *OrderService) MatchItemShops(ctx context.Context, items []models.CartItem) []string {
+	shopIDs := make([]string, len(items))
+	for i, item := range items {
+		options := item.PrintOptions
+		if options.Quantity == 0 {
+			options.Quantity = item.Quantity
+		}
+
+		probe := &models.Order{PrintOptions: options}
+		if err := s.AssignShopForOrder(ctx, probe); err != nil {
+			log.Printf("⚠️ Failed to match item %s: %v", item.ArtworkID, err)
+			continue
+		}
+		shopIDs[i] = probe.PrintShopID
+	}
+	return shopIDs
+}
+
+// GroupByShop builds one sub-order per distinct shop in shopIDs (shopIDs[i] belongs to
+// parent.Items[i]). Unmatched items share an unassigned sub-order.
+func GroupByShop(parent *models.Order, shopIDs []string) []*models.Order {
+	byShop := make(map[string]*models.Order)
+	var subOrders []*models.Order
+
+	for i, item := range parent.Items {
+		shopID := shopIDs[i]
+		sub, ok := byShop[shopID]
+		if !ok {
+			sub = &models.Order{
+				OrderID:        uuid.NewString(),
+				ParentOrderID:  parent.OrderID,
+				BuyerID:        parent.BuyerID,
+				PrintShopID:    shopID,
+				PrintOptions:   item.PrintOptions,
+				Status:         models.OrderStatusPending,
+				PaymentMethod:  parent.PaymentMethod,
+				PaymentStatus:  parent.PaymentStatus,
+				DeliveryStatus: parent.DeliveryStatus,
+				DeliveryMethod: parent.DeliveryMethod,
+				PickupLocation: parent.PickupLocation,
+				CreatedAt:      parent.CreatedAt,
+				UpdatedAt:      parent.UpdatedAt,
+			}
+			byShop[shopID] = sub
+			subOrders = append(subOrders, sub)
+		}
+		sub.Items = append(sub.Items, item)
+		sub.TotalAmount = sub.TotalAmount.Add(item.LineTotal())
+	}
+
+	return subOrders
 }
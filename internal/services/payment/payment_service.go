@@ -2,61 +2,107 @@ package payment
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/cecvl/art-print-backend/internal/models"
 	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/orders"
 	"github.com/cecvl/art-print-backend/internal/services/payment/providers"
 )
 
-// PaymentService handles payment operations
+// systemActor is recorded as the actor when payment processing confirms an order
+const systemActor = "system:payments"
+
+// PaymentService handles payment operations, routing each payment to the provider of its method
 type PaymentService struct {
-	repo     *repositories.PaymentRepository
-	provider providers.PaymentProvider
+	repo      repositories.PaymentRepository
+	events    repositories.PaymentEventRepository
+	orders    repositories.OrderRepository
+	lifecycle *orders.Lifecycle
+	providers *providers.Registry
 }
 
 // NewPaymentService creates a new payment service
-func NewPaymentService(repo *repositories.PaymentRepository, provider providers.PaymentProvider) *PaymentService {
+func NewPaymentService(store *repositories.Store, registry *providers.Registry) *PaymentService {
 	return &PaymentService{
-		repo:     repo,
-		provider: provider,
+		repo:      store.Payments,
+		events:    store.PaymentEvents,
+		orders:    store.Orders,
+		lifecycle: orders.NewLifecycle(store),
+		providers: registry,
+	}
+}
+
+// providerOf returns the provider that made a payment. Payments recorded before methods were
+// routed have no method and were all simulated.
+func (s *PaymentService) providerOf(payment *models.Payment) (providers.PaymentProvider, error) {
+	method := payment.PaymentMethod
+	if method == "" {
+		method = "simulated"
 	}
+	return s.providers.Registered(method)
 }
 
-// CreatePayment creates a new payment for an order
-func (s *PaymentService) CreatePayment(ctx context.Context, req models.PaymentRequest, orderAmount float64) (*models.Payment, error) {
+// CreatePayment creates a new payment for an order with the provider of the requested method.
+// An unknown or disabled method returns providers.ErrUnknownMethod or ErrMethodDisabled.
+func (s *PaymentService) CreatePayment(ctx context.Context, req models.PaymentRequest, orderAmount models.Money) (*models.Payment, error) {
+	req.PaymentMethod = strings.ToLower(strings.TrimSpace(req.PaymentMethod))
+	provider, err := s.providers.Provider(req.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the order into deposit and remaining halves; an odd minor unit goes to the deposit
+	// so the two always add up to the order total
+	halves := orderAmount.Allocate(1, 1)
+
 	// Calculate payment amount based on type
-	var amount float64
+	var amount models.Money
 	var paymentType models.PaymentType
 
 	switch req.PaymentType {
 	case "deposit":
-		amount = orderAmount * 0.5 // 50% deposit
+		amount = halves[0] // 50% deposit
 		paymentType = models.PaymentTypeDeposit
 	case "full":
 		amount = orderAmount // 100% full payment
 		paymentType = models.PaymentTypeFull
 	case "remaining":
-		amount = orderAmount * 0.5 // Remaining 50%
+		amount = halves[1] // Remaining 50%
 		paymentType = models.PaymentTypeRemaining
 	default:
 		// Default to deposit if not specified
-		amount = orderAmount * 0.5
+		amount = halves[0]
 		paymentType = models.PaymentTypeDeposit
 	}
 
 	// Override with explicit amount if provided
-	if req.Amount > 0 {
-		amount = req.Amount
+	if req.Amount != nil && req.Amount.IsPositive() {
+		if req.Amount.Currency != "" && req.Amount.Currency != orderAmount.Currency {
+			return nil, fmt.Errorf("%w: payment currency %s does not match order currency %s", providers.ErrInvalidPaymentDetails, req.Amount.Currency, orderAmount.Currency)
+		}
+		amount = models.NewMoney(req.Amount.Amount, orderAmount.Currency)
 	}
 
 	// Create payment with provider
-	transactionID, err := s.provider.CreatePayment(ctx, amount, req.OrderID, req.Metadata)
+	checkout, err := provider.CreatePayment(ctx, amount, req.OrderID, req.Metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create payment with provider: %w", err)
 	}
+	status := checkout.Status
+	if status == "" {
+		status = models.PaymentStatusPending
+	}
+	providerData := checkout.ProviderData
+	if providerData == nil {
+		providerData = make(map[string]interface{})
+	}
 
 	// Create payment record
 	payment := &models.Payment{
@@ -65,10 +111,11 @@ func (s *PaymentService) CreatePayment(ctx context.Context, req models.PaymentRe
 		BuyerID:       "", // Will be set from order
 		Amount:        amount,
 		PaymentMethod: req.PaymentMethod,
-		Status:        models.PaymentStatusPending,
-		TransactionID: transactionID,
+		Status:        status,
+		TransactionID: checkout.TransactionID,
+		PaymentURL:    checkout.PaymentURL,
 		PaymentType:   paymentType,
-		ProviderData:  make(map[string]interface{}),
+		ProviderData:  providerData,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
@@ -78,31 +125,304 @@ func (s *PaymentService) CreatePayment(ctx context.Context, req models.PaymentRe
 		return nil, fmt.Errorf("failed to save payment: %w", err)
 	}
 
-	log.Printf("✅ Created payment %s for order %s: %.2f", payment.ID, req.OrderID, amount)
+	log.Printf("✅ Created payment %s for order %s: %s", payment.ID, req.OrderID, amount)
 	return payment, nil
 }
 
-// ProcessPaymentWebhook processes a webhook from payment provider
-func (s *PaymentService) ProcessPaymentWebhook(ctx context.Context, webhook models.PaymentWebhook) error {
-	// Find payment by transaction ID
-	// Note: In real implementation, we'd need to store transactionID -> paymentID mapping
-	// For now, we'll search by transactionID in provider data or use a separate index
+// ProcessPaymentWebhook processes a webhook from the provider of a payment method. Providers
+// with their own webhook format parse the raw payload; others send models.PaymentWebhook JSON.
+// The raw webhook is stored in payment_events first, then the payment it names (looked up by
+// transaction ID) is moved to the reported status and its order's payment status refreshed.
+// Failures are confirmed with the provider before they apply. Captured payments never change,
+// and failed or cancelled ones only accept a verified capture, so providers may retry.
+func (s *PaymentService) ProcessPaymentWebhook(ctx context.Context, method string, req providers.WebhookRequest) (*models.Payment, error) {
+	event := &models.PaymentEvent{
+		PaymentMethod: method,
+		Payload:       string(req.Payload),
+		Header:        req.Header,
+		Query:         req.Query.Encode(),
+		Outcome:       models.PaymentEventReceived,
+		ReceivedAt:    time.Now(),
+	}
+	if err := s.events.CreatePaymentEvent(ctx, event); err != nil {
+		log.Printf("⚠️ Failed to record %s webhook: %v", method, err)
+		event.ID = ""
+	}
+
+	payment, err := s.handleWebhook(ctx, event, req, true)
+	s.recordOutcome(ctx, event, err)
+	return payment, err
+}
 
-	// Verify payment with provider
-	verified, err := s.provider.VerifyPayment(ctx, webhook.TransactionID)
+// ReplayPaymentEvent processes a stored webhook again, e.g. after a failure on our side. Events
+// whose signature was verified when they arrived are not re-verified, since signatures expire.
+func (s *PaymentService) ReplayPaymentEvent(ctx context.Context, eventID string) (*models.PaymentEvent, *models.Payment, error) {
+	event, err := s.events.GetPaymentEvent(ctx, eventID)
 	if err != nil {
-		return fmt.Errorf("failed to verify payment: %w", err)
+		return nil, nil, err
 	}
+	query, _ := url.ParseQuery(event.Query)
+	req := providers.WebhookRequest{Payload: []byte(event.Payload), Header: event.Header, Query: query}
+
+	event.Replays++
+	event.Outcome, event.Error = models.PaymentEventReceived, ""
+	payment, err := s.handleWebhook(ctx, event, req, !event.Verified)
+	s.recordOutcome(ctx, event, err)
+	return event, payment, err
+}
 
-	if !verified {
-		return fmt.Errorf("payment verification failed for transaction: %s", webhook.TransactionID)
+// handleWebhook parses, verifies and applies a webhook, noting what it found on the event
+func (s *PaymentService) handleWebhook(ctx context.Context, event *models.PaymentEvent, req providers.WebhookRequest, verifySignature bool) (*models.Payment, error) {
+	provider, err := s.providers.Registered(event.PaymentMethod)
+	if err != nil {
+		return nil, err
 	}
+	webhook, err := parseWebhook(ctx, provider, req)
+	if err != nil {
+		return nil, err
+	}
+	event.TransactionID, event.Status = webhook.TransactionID, webhook.Status
+	if verifier, ok := provider.(providers.WebhookVerifier); ok && verifySignature {
+		if err := verifier.VerifyWebhook(ctx, req.Payload, webhook); err != nil {
+			return nil, fmt.Errorf("%w: %v", providers.ErrInvalidWebhook, err)
+		}
+	}
+	event.Verified = true
 
-	// Update payment status
-	// In real implementation, we'd find the payment by transactionID
-	// For now, this is a placeholder - we'll need to add a method to find by transactionID
-	log.Printf("✅ Payment verified for transaction: %s", webhook.TransactionID)
+	payment, err := s.repo.GetPaymentByTransactionID(ctx, event.PaymentMethod, webhook.TransactionID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("%w: no %s payment %s", providers.ErrInvalidWebhook, event.PaymentMethod, webhook.TransactionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up payment %s: %w", webhook.TransactionID, err)
+	}
+	event.PaymentID, event.OrderID = payment.ID, payment.OrderID
 
+	if webhook.Refund != nil {
+		event.Status = "refund_" + string(webhook.Refund.Status)
+		return payment, s.applyRefundWebhook(ctx, event, provider, payment, webhook.Refund)
+	}
+	if !payment.Status.AcceptsWebhook(models.PaymentStatus(webhook.Status)) {
+		log.Printf("📋 Ignoring %s webhook for payment %s, already %s", webhook.Status, payment.ID, payment.Status)
+		event.Outcome = models.PaymentEventIgnored
+		return payment, nil
+	}
+	if err := checkWebhookAmount(payment, webhook); err != nil {
+		return nil, err
+	}
+	switch models.PaymentStatus(webhook.Status) {
+	case models.PaymentStatusCompleted:
+		// Verify payment with provider, so a forged webhook cannot mark a payment paid
+		verified, err := provider.VerifyPayment(ctx, webhook.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify payment: %w", err)
+		}
+		if !verified {
+			return nil, fmt.Errorf("%w: provider has not captured transaction %s", providers.ErrInvalidWebhook, webhook.TransactionID)
+		}
+		log.Printf("✅ Payment verified for transaction: %s", webhook.TransactionID)
+	case models.PaymentStatusFailed, models.PaymentStatusCancelled:
+		// Confirm with the provider too, so a forged failure cannot block the real payment
+		if err := confirmUnpaid(ctx, provider, webhook); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := s.applyWebhook(ctx, payment, webhook)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		event.Outcome = models.PaymentEventIgnored
+		return payment, nil
+	}
+	event.Outcome = models.PaymentEventApplied
+	if payment.Status.IsFinal() {
+		if err := s.SyncOrderPayment(ctx, payment.OrderID); err != nil {
+			log.Printf("⚠️ Failed to update order %s after payment %s: %v", payment.OrderID, payment.ID, err)
+		}
+	}
+	return payment, nil
+}
+
+// recordOutcome saves what processing did to a stored webhook
+func (s *PaymentService) recordOutcome(ctx context.Context, event *models.PaymentEvent, err error) {
+	if err != nil {
+		event.Error = err.Error()
+		event.Outcome = models.PaymentEventFailed
+		if errors.Is(err, providers.ErrInvalidWebhook) || errors.Is(err, providers.ErrUnknownMethod) {
+			event.Outcome = models.PaymentEventRejected
+		}
+	}
+	if event.ID == "" {
+		return
+	}
+	now := time.Now()
+	if err := s.events.UpdatePaymentEvent(ctx, event.ID, map[string]interface{}{
+		"transactionId": event.TransactionID,
+		"status":        event.Status,
+		"paymentId":     event.PaymentID,
+		"orderId":       event.OrderID,
+		"verified":      event.Verified,
+		"outcome":       event.Outcome,
+		"error":         event.Error,
+		"replays":       event.Replays,
+		"processedAt":   now,
+	}); err != nil {
+		log.Printf("⚠️ Failed to record outcome of payment event %s: %v", event.ID, err)
+	}
+}
+
+// parseWebhook decodes a webhook with the provider's parser, or as models.PaymentWebhook JSON
+func parseWebhook(ctx context.Context, provider providers.PaymentProvider, req providers.WebhookRequest) (*models.PaymentWebhook, error) {
+	if parser, ok := provider.(providers.WebhookParser); ok {
+		return parser.ParseWebhook(ctx, req)
+	}
+	var webhook models.PaymentWebhook
+	if err := json.Unmarshal(req.Payload, &webhook); err != nil {
+		return nil, fmt.Errorf("%w: %v", providers.ErrInvalidWebhook, err)
+	}
+	if webhook.TransactionID == "" {
+		return nil, fmt.Errorf("%w: missing transactionId", providers.ErrInvalidWebhook)
+	}
+	return &webhook, nil
+}
+
+// checkWebhookAmount refuses a webhook reporting a different currency than the payment, or less
+// than its amount. Providers may charge a little more, e.g. M-Pesa rounds up to whole shillings.
+func checkWebhookAmount(payment *models.Payment, webhook *models.PaymentWebhook) error {
+	if webhook.Amount.IsZero() {
+		return nil // failure callbacks often carry no amount
+	}
+	if webhook.Amount.Currency != payment.Amount.Currency || webhook.Amount.Cmp(payment.Amount) < 0 {
+		return fmt.Errorf("%w: webhook reports %s for payment %s of %s", providers.ErrInvalidWebhook, webhook.Amount, payment.ID, payment.Amount)
+	}
+	return nil
+}
+
+// confirmUnpaid asks the provider whether a transaction a webhook reports as failed or cancelled
+// really did not go through. A transaction the provider reports as paid turns the webhook into
+// a capture; one the buyer is still paying rejects it.
+func confirmUnpaid(ctx context.Context, provider providers.PaymentProvider, webhook *models.PaymentWebhook) error {
+	var status models.PaymentStatus
+	if checker, ok := provider.(providers.StatusChecker); ok {
+		var err error
+		if status, err = checker.PaymentStatus(ctx, webhook.TransactionID); err != nil {
+			return fmt.Errorf("failed to confirm payment status: %w", err)
+		}
+	} else {
+		paid, err := provider.VerifyPayment(ctx, webhook.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to confirm payment status: %w", err)
+		}
+		status = models.PaymentStatus(webhook.Status)
+		if paid {
+			status = models.PaymentStatusCompleted
+		}
+	}
+
+	switch status {
+	case models.PaymentStatusCompleted:
+		log.Printf("⚠️ Provider reports transaction %s paid, not %s", webhook.TransactionID, webhook.Status)
+		webhook.Status = string(models.PaymentStatusCompleted)
+		return nil
+	case models.PaymentStatusFailed, models.PaymentStatusCancelled:
+		return nil
+	}
+	return fmt.Errorf("%w: provider reports transaction %s %s, not %s", providers.ErrInvalidWebhook, webhook.TransactionID, status, webhook.Status)
+}
+
+// applyWebhook moves a payment to the status a webhook reports and merges the webhook's
+// metadata into its provider data. applied is false when the webhook reports nothing to
+// change, or the payment's status stopped accepting it since it was read.
+func (s *PaymentService) applyWebhook(ctx context.Context, payment *models.Payment, webhook *models.PaymentWebhook) (applied bool, err error) {
+	status := models.PaymentStatus(webhook.Status)
+	now := time.Now()
+	updates := map[string]interface{}{}
+
+	switch status {
+	case models.PaymentStatusCompleted:
+		updates["completedAt"] = now
+		payment.CompletedAt = &now
+	case models.PaymentStatusFailed, models.PaymentStatusCancelled:
+		reason, _ := webhook.Metadata["failureReason"].(string)
+		updates["failedAt"] = now
+		updates["failureReason"] = reason
+		payment.FailedAt, payment.FailureReason = &now, reason
+	case models.PaymentStatusRequiresAction, models.PaymentStatusProcessing:
+		// The buyer is still paying, e.g. completing 3-D Secure; the next webhook settles it
+		if status == payment.Status {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	providerData := make(map[string]interface{}, len(payment.ProviderData)+len(webhook.Metadata))
+	for k, v := range payment.ProviderData {
+		providerData[k] = v
+	}
+	for k, v := range webhook.Metadata {
+		providerData[k] = v
+	}
+	updates["providerData"] = providerData
+
+	if err := s.repo.TransitionPayment(ctx, payment.ID, status, updates); err != nil {
+		if errors.Is(err, repositories.ErrPaymentFinal) {
+			log.Printf("📋 Ignoring webhook for payment %s: %v", payment.ID, err)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update payment status: %w", err)
+	}
+	payment.Status = status
+	payment.ProviderData = providerData
+	payment.UpdatedAt = now
+	log.Printf("✅ Payment %s %s by %s webhook", payment.ID, payment.Status, payment.PaymentMethod)
+	return true, nil
+}
+
+// SyncOrderPayment recalculates an order's payment status from its payments and copies it to
+// its sub-orders. Once anything is paid a pending order is confirmed; an order whose payments
+// failed or were cancelled stays pending so the buyer can pay again. An order whose captured
+// funds were all refunded moves to refunded, along with its sub-orders.
+func (s *PaymentService) SyncOrderPayment(ctx context.Context, orderID string) error {
+	order, err := s.orders.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	paymentStatus, _, err := s.CalculatePaymentStatus(ctx, orderID, order.TotalAmount)
+	if err != nil {
+		return err
+	}
+	for _, id := range append([]string{orderID}, order.SubOrderIDs...) {
+		if err := s.orders.UpdateOrder(ctx, id, map[string]interface{}{
+			"paymentStatus": paymentStatus,
+		}); err != nil {
+			log.Printf("⚠️ Failed to update payment status of order %s: %v", id, err)
+		}
+	}
+
+	if paymentStatus == "refunded" && order.Status != models.OrderStatusRefunded {
+		if _, err := s.lifecycle.Transition(ctx, orderID, models.OrderStatusRefunded, systemActor, "payments refunded"); err != nil {
+			if errors.Is(err, repositories.ErrIllegalTransition) {
+				log.Printf("⚠️ Order %s was refunded in full but cannot move from %s to refunded", orderID, order.Status)
+				return nil
+			}
+			return fmt.Errorf("failed to mark order refunded: %w", err)
+		}
+		log.Printf("✅ Order %s refunded in full", orderID)
+		return nil
+	}
+	if (paymentStatus != "paid" && paymentStatus != "partial") || order.Status != models.OrderStatusPending {
+		return nil
+	}
+	if _, err := s.lifecycle.Transition(ctx, orderID, models.OrderStatusConfirmed, systemActor, "payment completed"); err != nil {
+		if errors.Is(err, repositories.ErrIllegalTransition) {
+			return nil // confirmed meanwhile
+		}
+		return fmt.Errorf("failed to confirm order: %w", err)
+	}
+	log.Printf("✅ Order %s confirmed after payment", orderID)
 	return nil
 }
 
@@ -113,13 +433,17 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, paymentID string) (*
 		return nil, fmt.Errorf("payment not found: %w", err)
 	}
 
-	// Verify with provider
-	verified, err := s.provider.VerifyPayment(ctx, payment.TransactionID)
+	// Verify with the provider that took the payment
+	provider, err := s.providerOf(payment)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := provider.VerifyPayment(ctx, payment.TransactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify payment: %w", err)
 	}
 
-	if verified && payment.Status != models.PaymentStatusCompleted {
+	if verified && !payment.Status.IsCaptured() {
 		// Update payment status to completed
 		now := time.Now()
 		updates := map[string]interface{}{
@@ -140,65 +464,302 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, paymentID string) (*
 	return payment, nil
 }
 
-// RefundPayment processes a refund
-func (s *PaymentService) RefundPayment(ctx context.Context, paymentID string, amount float64) error {
+// RefundPayment refunds part of a captured payment; a zero amount refunds all that is left of it.
+// A payment may be refunded several times until nothing is left. The refund is recorded before
+// the provider is asked, so concurrent refunds cannot exceed the payment, and marked failed only
+// if the provider refuses; when the provider cannot be reached it is returned still pending.
+// Returns repositories.ErrNotRefundable (wrapped) for refunds that do not fit.
+func (s *PaymentService) RefundPayment(ctx context.Context, paymentID string, amount models.Money, reason string) (*models.Refund, error) {
 	payment, err := s.repo.GetPaymentByID(ctx, paymentID)
 	if err != nil {
-		return fmt.Errorf("payment not found: %w", err)
+		return nil, fmt.Errorf("payment not found: %w", err)
 	}
 
-	if payment.Status != models.PaymentStatusCompleted {
-		return fmt.Errorf("cannot refund payment with status: %s", payment.Status)
+	if !payment.Status.IsCaptured() {
+		return nil, fmt.Errorf("%w: cannot refund payment with status: %s", repositories.ErrNotRefundable, payment.Status)
 	}
 
-	// Process refund with provider
 	refundAmount := amount
-	if refundAmount == 0 {
-		refundAmount = payment.Amount // Full refund if amount not specified
+	if refundAmount.IsZero() {
+		refundAmount = payment.NetCaptured() // Refund what is left if amount not specified
+		if !refundAmount.IsPositive() {
+			return nil, fmt.Errorf("%w: payment %s has nothing left to refund", repositories.ErrNotRefundable, paymentID)
+		}
+	} else if refundAmount.Currency != "" && refundAmount.Currency != payment.Amount.Currency {
+		return nil, fmt.Errorf("%w: refund currency %s does not match payment currency %s", repositories.ErrNotRefundable, refundAmount.Currency, payment.Amount.Currency)
+	} else {
+		refundAmount = models.NewMoney(refundAmount.Amount, payment.Amount.Currency)
+	}
+
+	provider, err := s.providerOf(payment)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.provider.RefundPayment(ctx, payment.TransactionID, refundAmount); err != nil {
-		return fmt.Errorf("failed to process refund: %w", err)
+	refund := &models.Refund{PaymentID: paymentID, Amount: refundAmount, Reason: reason}
+	if err := s.repo.CreateRefund(ctx, refund); err != nil {
+		return nil, err
 	}
 
-	// Update payment status
-	updates := map[string]interface{}{
-		"status":    models.PaymentStatusRefunded,
-		"updatedAt": time.Now(),
+	// Process refund with provider; the refund's ID keeps a retry from refunding twice
+	result, err := provider.RefundPayment(ctx, payment.TransactionID, refundAmount, refund.ID)
+	switch {
+	case err != nil && refundRefused(err):
+		if ferr := s.repo.FinishRefund(ctx, refund.ID, models.RefundStatusFailed, "", err.Error()); ferr != nil {
+			log.Printf("⚠️ Failed to record failure of refund %s: %v", refund.ID, ferr)
+		}
+		return nil, fmt.Errorf("failed to process refund: %w", err)
+	case err != nil:
+		// The provider may have refunded before the answer was lost, so the refund stays pending,
+		// holding its amount, until SettlePendingRefunds retries it with the same key
+		log.Printf("⚠️ Refund %s of payment %s left pending: %v", refund.ID, paymentID, err)
+	default:
+		if err := s.repo.FinishRefund(ctx, refund.ID, result.Status, result.RefundID, ""); err != nil {
+			log.Printf("⚠️ Failed to record provider answer for refund %s: %v", refund.ID, err)
+		}
+		refund.Status, refund.ProviderRefundID = result.Status, result.RefundID
 	}
 
-	if err := s.repo.UpdatePayment(ctx, paymentID, updates); err != nil {
-		return fmt.Errorf("failed to update payment status: %w", err)
+	if err := s.SyncOrderPayment(ctx, payment.OrderID); err != nil {
+		log.Printf("⚠️ Failed to update order %s after refund %s: %v", payment.OrderID, refund.ID, err)
 	}
 
-	log.Printf("✅ Refunded payment %s: %.2f", paymentID, refundAmount)
+	log.Printf("✅ Refunded payment %s: %s (%s)", paymentID, refundAmount, refund.Status)
+	return refund, nil
+}
+
+// refundPendingGrace is how old a pending refund must be before it is settled in the background,
+// so refunds whose provider call is still running are left alone
+const refundPendingGrace = time.Minute
+
+// refundRetryWindow bounds how long a refund whose answer was lost is retried. Gateways forget
+// idempotency keys after a day, after which a retry could refund twice.
+const refundRetryWindow = 23 * time.Hour
+
+// refundRefused reports whether a provider definitely did not refund
+func refundRefused(err error) bool {
+	return errors.Is(err, providers.ErrRefundDeclined) || errors.Is(err, providers.ErrRefundNotSupported)
+}
+
+// SettlePendingRefunds asks providers about refunds still pending: refunds whose first answer was
+// lost are sent again with the same idempotency key, and accepted ones are looked up until they
+// succeed or fail. It returns how many refunds settled.
+func (s *PaymentService) SettlePendingRefunds(ctx context.Context, now time.Time) (int, error) {
+	refunds, err := s.repo.GetRefundsByStatus(ctx, models.RefundStatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load pending refunds: %w", err)
+	}
+	settled := 0
+	for _, refund := range refunds {
+		if now.Sub(refund.CreatedAt) < refundPendingGrace {
+			continue
+		}
+		done, err := s.settleRefund(ctx, refund, now)
+		if err != nil {
+			log.Printf("⚠️ Failed to settle refund %s: %v", refund.ID, err)
+			continue
+		}
+		if done {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// RunRefundSettlementLoop calls SettlePendingRefunds every interval until ctx is cancelled
+func (s *PaymentService) RunRefundSettlementLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			settled, err := s.SettlePendingRefunds(ctx, now)
+			if err != nil {
+				log.Printf("⚠️ Failed to settle pending refunds: %v", err)
+			} else if settled > 0 {
+				log.Printf("📋 Settled %d pending refunds", settled)
+			}
+		}
+	}
+}
+
+// settleRefund learns the fate of one pending refund from its provider and records it
+func (s *PaymentService) settleRefund(ctx context.Context, refund *models.Refund, now time.Time) (bool, error) {
+	payment, err := s.repo.GetPaymentByID(ctx, refund.PaymentID)
+	if err != nil {
+		return false, err
+	}
+	provider, err := s.providerOf(payment)
+	if err != nil {
+		return false, err
+	}
+
+	var result *providers.ProviderRefund
+	if refund.ProviderRefundID == "" {
+		if now.Sub(refund.CreatedAt) > refundRetryWindow {
+			log.Printf("⚠️ Refund %s got no answer from %s within a day; check it with the provider", refund.ID, provider.GetProviderName())
+			return false, nil
+		}
+		result, err = provider.RefundPayment(ctx, payment.TransactionID, refund.Amount, refund.ID)
+		if err != nil && refundRefused(err) {
+			result = &providers.ProviderRefund{Status: models.RefundStatusFailed, FailureReason: err.Error()}
+		} else if err != nil {
+			return false, err
+		}
+	} else {
+		checker, ok := provider.(providers.RefundChecker)
+		if !ok {
+			return false, nil
+		}
+		if result, err = checker.RefundStatus(ctx, refund.ProviderRefundID); err != nil {
+			return false, err
+		}
+	}
+	return s.finishRefund(ctx, refund, payment.OrderID, result)
+}
+
+// finishRefund records a provider's answer to a refund, refreshing the order's payment status
+// once it has settled
+func (s *PaymentService) finishRefund(ctx context.Context, refund *models.Refund, orderID string, result *providers.ProviderRefund) (bool, error) {
+	if err := s.repo.FinishRefund(ctx, refund.ID, result.Status, result.RefundID, result.FailureReason); err != nil {
+		return false, err
+	}
+	if result.Status == models.RefundStatusPending {
+		return false, nil
+	}
+	log.Printf("✅ Refund %s %s", refund.ID, result.Status)
+	if err := s.SyncOrderPayment(ctx, orderID); err != nil {
+		log.Printf("⚠️ Failed to update order %s after refund %s: %v", orderID, refund.ID, err)
+	}
+	return true, nil
+}
+
+// applyRefundWebhook settles the refund a webhook reports on. The provider is asked for the
+// refund's state rather than trusting the payload.
+func (s *PaymentService) applyRefundWebhook(ctx context.Context, event *models.PaymentEvent, provider providers.PaymentProvider, payment *models.Payment, update *models.WebhookRefund) error {
+	checker, ok := provider.(providers.RefundChecker)
+	if !ok || update.ProviderRefundID == "" {
+		return fmt.Errorf("%w: %s refund updates cannot be confirmed", providers.ErrInvalidWebhook, provider.GetProviderName())
+	}
+	refunds, err := s.repo.GetRefundsByPaymentID(ctx, payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load refunds of payment %s: %w", payment.ID, err)
+	}
+	var refund *models.Refund
+	for _, r := range refunds {
+		if (update.RefundID != "" && r.ID == update.RefundID) || r.ProviderRefundID == update.ProviderRefundID {
+			refund = r
+			break
+		}
+	}
+	if refund == nil || refund.Status != models.RefundStatusPending {
+		event.Outcome = models.PaymentEventIgnored
+		return nil
+	}
+
+	result, err := checker.RefundStatus(ctx, update.ProviderRefundID)
+	if err != nil {
+		return fmt.Errorf("failed to confirm refund %s: %w", update.ProviderRefundID, err)
+	}
+	settled, err := s.finishRefund(ctx, refund, payment.OrderID, result)
+	if err != nil {
+		return err
+	}
+	event.Outcome = models.PaymentEventIgnored
+	if settled {
+		event.Outcome = models.PaymentEventApplied
+	}
 	return nil
 }
 
+// RefundOrder refunds an amount across an order's captured payments, newest payment first, so
+// each payment is refunded at most what is left of it; a zero amount refunds everything left.
+// Refunds made before a failure are returned along with the error.
+func (s *PaymentService) RefundOrder(ctx context.Context, orderID string, amount models.Money, reason string) ([]*models.Refund, error) {
+	payments, err := s.repo.GetPaymentsByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payments of order %s: %w", orderID, err)
+	}
+
+	var refundable []*models.Payment
+	var left models.Money
+	for i := len(payments) - 1; i >= 0; i-- {
+		if net := payments[i].NetCaptured(); net.IsPositive() {
+			refundable = append(refundable, payments[i])
+			left = left.Add(net)
+		}
+	}
+	if len(refundable) == 0 {
+		return nil, fmt.Errorf("%w: order %s has no captured payments left to refund", repositories.ErrNotRefundable, orderID)
+	}
+
+	remaining := left
+	if !amount.IsZero() {
+		if amount.Currency != "" && amount.Currency != left.Currency {
+			return nil, fmt.Errorf("%w: refund currency %s does not match payment currency %s", repositories.ErrNotRefundable, amount.Currency, left.Currency)
+		}
+		remaining = models.NewMoney(amount.Amount, left.Currency)
+		if !remaining.IsPositive() || remaining.Cmp(left) > 0 {
+			return nil, fmt.Errorf("%w: refund of %s exceeds the %s left on order %s", repositories.ErrNotRefundable, remaining, left, orderID)
+		}
+	}
+
+	var refunds []*models.Refund
+	for _, p := range refundable {
+		if !remaining.IsPositive() {
+			break
+		}
+		part := remaining.Min(p.NetCaptured())
+		refund, err := s.RefundPayment(ctx, p.ID, part, reason)
+		if err != nil {
+			return refunds, err
+		}
+		refunds = append(refunds, refund)
+		remaining = remaining.Sub(part)
+	}
+	return refunds, nil
+}
+
+// GetRefunds retrieves the refunds of an order's payments
+func (s *PaymentService) GetRefunds(ctx context.Context, orderID string) ([]*models.Refund, error) {
+	return s.repo.GetRefundsByOrderID(ctx, orderID)
+}
+
 // GetPaymentHistory retrieves payment history for an order
 func (s *PaymentService) GetPaymentHistory(ctx context.Context, orderID string) ([]*models.Payment, error) {
 	return s.repo.GetPaymentsByOrderID(ctx, orderID)
 }
 
-// CalculatePaymentStatus calculates the overall payment status for an order
-func (s *PaymentService) CalculatePaymentStatus(ctx context.Context, orderID string, orderAmount float64) (string, float64, error) {
+// CalculatePaymentStatus calculates the overall payment status for an order from the funds its
+// payments captured net of refunds, and returns that net amount
+func (s *PaymentService) CalculatePaymentStatus(ctx context.Context, orderID string, orderAmount models.Money) (string, models.Money, error) {
+	captured := models.NewMoney(0, orderAmount.Currency)
+	refunded := models.NewMoney(0, orderAmount.Currency)
 	payments, err := s.repo.GetPaymentsByOrderID(ctx, orderID)
 	if err != nil {
-		return "unpaid", 0, err
+		return "unpaid", captured, err
 	}
 
-	var totalPaid float64
 	for _, payment := range payments {
-		if payment.Status == models.PaymentStatusCompleted {
-			totalPaid += payment.Amount
+		if payment.Status.IsCaptured() {
+			captured = captured.Add(payment.Amount)
+			refunded = refunded.Add(payment.Refunded())
 		}
 	}
-
-	if totalPaid == 0 {
-		return "unpaid", 0, nil
-	} else if totalPaid >= orderAmount {
-		return "paid", totalPaid, nil
-	} else {
-		return "partial", totalPaid, nil
+	net := captured.Sub(refunded)
+
+	switch {
+	case captured.IsZero():
+		return "unpaid", net, nil
+	case refunded.IsPositive() && !net.IsPositive():
+		return "refunded", net, nil
+	case refunded.IsPositive():
+		return "partially_refunded", net, nil
+	case net.Cmp(orderAmount) >= 0:
+		return "paid", net, nil
+	default:
+		return "partial", net, nil
 	}
 }
@@ -0,0 +1,475 @@
+package providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// CardSignatureHeader carries the signature of a card webhook: "t=<unix time>,v1=<hex HMAC>"
+const CardSignatureHeader = "Stripe-Signature"
+
+// defaultSignatureTolerance is how old a signed webhook may be before it is refused as a replay
+const defaultSignatureTolerance = 5 * time.Minute
+
+// CardConfig holds the card gateway's endpoint, keys and checkout return pages
+type CardConfig struct {
+	BaseURL       string // API root, e.g. https://api.stripe.com; point it at a local mock to test
+	SecretKey     string // API secret key
+	WebhookSecret string // signing secret shared with the webhook endpoint
+	SuccessURL    string // where the hosted page sends the buyer after paying
+	CancelURL     string // where the hosted page sends the buyer if they give up
+	// SignatureTolerance bounds the age of a webhook's signed timestamp; 0 means five minutes
+	SignatureTolerance time.Duration
+}
+
+// CardConfigFromEnv reads the card gateway settings from CARD_* variables. ok is false when no
+// secret key is set, i.e. card payments are not configured in this environment.
+func CardConfigFromEnv() (cfg CardConfig, ok bool) {
+	cfg = CardConfig{
+		BaseURL:       os.Getenv("CARD_API_URL"),
+		SecretKey:     os.Getenv("CARD_SECRET_KEY"),
+		WebhookSecret: os.Getenv("CARD_WEBHOOK_SECRET"),
+		SuccessURL:    os.Getenv("CARD_SUCCESS_URL"),
+		CancelURL:     os.Getenv("CARD_CANCEL_URL"),
+	}
+	return cfg, cfg.SecretKey != ""
+}
+
+// CardProvider takes card payments through a gateway modelled on Stripe's checkout sessions and
+// payment intents. The buyer pays on the gateway's hosted page; banks may ask for 3-D Secure,
+// which the gateway reports as "requires action" until the buyer completes it.
+type CardProvider struct {
+	cfg    CardConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewCardProvider creates a card provider
+func NewCardProvider(cfg CardConfig) *CardProvider {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.stripe.com"
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.SignatureTolerance == 0 {
+		cfg.SignatureTolerance = defaultSignatureTolerance
+	}
+	return &CardProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// GetProviderName returns the provider name
+func (p *CardProvider) GetProviderName() string {
+	return "card"
+}
+
+// cardSession is a hosted checkout session
+type cardSession struct {
+	ID            string            `json:"id"`
+	URL           string            `json:"url"`
+	PaymentIntent string            `json:"payment_intent"`
+	PaymentStatus string            `json:"payment_status"` // "unpaid", "paid"
+	Status        string            `json:"status"`         // "open", "complete", "expired"
+	AmountTotal   int64             `json:"amount_total"`
+	Currency      string            `json:"currency"`
+	Metadata      map[string]string `json:"metadata"`
+}
+
+// cardIntent is a payment intent: one attempt to collect an amount from a card
+type cardIntent struct {
+	ID               string            `json:"id"`
+	Status           string            `json:"status"` // "requires_payment_method", "requires_action", "processing", "succeeded", "canceled"
+	Amount           int64             `json:"amount"`
+	Currency         string            `json:"currency"`
+	Metadata         map[string]string `json:"metadata"`
+	LastPaymentError *struct {
+		Message string `json:"message"`
+	} `json:"last_payment_error"`
+	NextAction *struct {
+		Type          string `json:"type"`
+		RedirectToURL *struct {
+			URL string `json:"url"`
+		} `json:"redirect_to_url"`
+	} `json:"next_action"`
+}
+
+// CreatePayment opens a hosted checkout session for the amount and returns its URL. The
+// payment intent behind the session is the transaction ID, since webhooks report on it.
+func (p *CardProvider) CreatePayment(ctx context.Context, amount models.Money, orderID string, metadata map[string]string) (*Checkout, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidPaymentDetails)
+	}
+
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("client_reference_id", orderID)
+	form.Set("success_url", p.cfg.SuccessURL)
+	form.Set("cancel_url", p.cfg.CancelURL)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(amount.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(amount.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", "Art print order "+accountReference(orderID))
+	form.Set("metadata[orderId]", orderID)
+	form.Set("payment_intent_data[metadata][orderId]", orderID)
+	for k, v := range metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+
+	var session cardSession
+	if err := p.call(ctx, http.MethodPost, "/v1/checkout/sessions", form, &session); err != nil {
+		return nil, err
+	}
+	if session.ID == "" || session.URL == "" {
+		return nil, errors.New("card gateway returned a checkout session without an id or url")
+	}
+
+	transactionID := session.PaymentIntent
+	if transactionID == "" {
+		transactionID = session.ID
+	}
+	return &Checkout{
+		TransactionID: transactionID,
+		Status:        models.PaymentStatusPending,
+		PaymentURL:    session.URL,
+		ProviderData:  map[string]interface{}{"checkoutSessionId": session.ID},
+	}, nil
+}
+
+// VerifyPayment fetches the payment intent (or the session, before it has one) and reports
+// whether the money was captured
+func (p *CardProvider) VerifyPayment(ctx context.Context, transactionID string) (bool, error) {
+	if strings.HasPrefix(transactionID, "cs_") {
+		var session cardSession
+		if err := p.call(ctx, http.MethodGet, "/v1/checkout/sessions/"+url.PathEscape(transactionID), nil, &session); err != nil {
+			return false, err
+		}
+		return session.PaymentStatus == "paid", nil
+	}
+	var intent cardIntent
+	if err := p.call(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(transactionID), nil, &intent); err != nil {
+		return false, err
+	}
+	return intent.Status == "succeeded", nil
+}
+
+// PaymentStatus fetches the payment intent (or the session, before it has one). A declined card
+// returns the intent to requires_payment_method, where the buyer may try another card; it counts
+// as failed until they do.
+func (p *CardProvider) PaymentStatus(ctx context.Context, transactionID string) (models.PaymentStatus, error) {
+	if strings.HasPrefix(transactionID, "cs_") {
+		var session cardSession
+		if err := p.call(ctx, http.MethodGet, "/v1/checkout/sessions/"+url.PathEscape(transactionID), nil, &session); err != nil {
+			return "", err
+		}
+		switch {
+		case session.PaymentStatus == "paid":
+			return models.PaymentStatusCompleted, nil
+		case session.Status == "expired":
+			return models.PaymentStatusCancelled, nil
+		}
+		return models.PaymentStatusPending, nil
+	}
+	var intent cardIntent
+	if err := p.call(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(transactionID), nil, &intent); err != nil {
+		return "", err
+	}
+	switch intent.Status {
+	case "succeeded":
+		return models.PaymentStatusCompleted, nil
+	case "canceled":
+		return models.PaymentStatusCancelled, nil
+	case "processing":
+		return models.PaymentStatusProcessing, nil
+	case "requires_action":
+		return models.PaymentStatusRequiresAction, nil
+	case "requires_payment_method":
+		if intent.LastPaymentError != nil {
+			return models.PaymentStatusFailed, nil
+		}
+	}
+	return models.PaymentStatusPending, nil
+}
+
+// cardRefund is a refund of a payment intent
+type cardRefund struct {
+	ID            string            `json:"id"`
+	Status        string            `json:"status"` // "pending", "requires_action", "succeeded", "failed", "canceled"
+	PaymentIntent string            `json:"payment_intent"`
+	FailureReason string            `json:"failure_reason"`
+	Metadata      map[string]string `json:"metadata"`
+}
+
+// providerRefund converts a gateway refund to the provider-neutral form
+func (r *cardRefund) providerRefund() *ProviderRefund {
+	result := &ProviderRefund{RefundID: r.ID, Status: models.RefundStatusPending}
+	switch r.Status {
+	case "succeeded":
+		result.Status = models.RefundStatusSucceeded
+	case "failed", "canceled":
+		result.Status = models.RefundStatusFailed
+		result.FailureReason = r.FailureReason
+		if result.FailureReason == "" {
+			result.FailureReason = "refund " + r.Status
+		}
+	}
+	return result
+}
+
+// RefundPayment refunds all or part of a captured payment intent. The idempotency key is sent
+// as the Idempotency-Key header and kept in the refund's metadata, so its webhooks can be
+// matched to our refund. Errors the gateway answered with a client error are refusals; network
+// failures and server errors leave the refund's fate open.
+func (p *CardProvider) RefundPayment(ctx context.Context, transactionID string, amount models.Money, idempotencyKey string) (*ProviderRefund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", transactionID)
+	if amount.IsPositive() {
+		form.Set("amount", strconv.FormatInt(amount.Amount, 10))
+	}
+	if idempotencyKey != "" {
+		form.Set("metadata[refundId]", idempotencyKey)
+	}
+	var refund cardRefund
+	if err := p.do(ctx, http.MethodPost, "/v1/refunds", form, idempotencyKey, &refund); err != nil {
+		var gatewayErr *cardError
+		if errors.As(err, &gatewayErr) && gatewayErr.refused() {
+			return nil, fmt.Errorf("%w: %v", ErrRefundDeclined, err)
+		}
+		return nil, err
+	}
+	result := refund.providerRefund()
+	if result.Status == models.RefundStatusFailed {
+		return nil, fmt.Errorf("%w: card refund %s %s", ErrRefundDeclined, refund.ID, refund.Status)
+	}
+	return result, nil
+}
+
+// RefundStatus fetches a refund to see whether it has settled
+func (p *CardProvider) RefundStatus(ctx context.Context, providerRefundID string) (*ProviderRefund, error) {
+	var refund cardRefund
+	if err := p.call(ctx, http.MethodGet, "/v1/refunds/"+url.PathEscape(providerRefundID), nil, &refund); err != nil {
+		return nil, err
+	}
+	return refund.providerRefund(), nil
+}
+
+// cardEvent is a webhook event; Data.Object is a payment intent or checkout session
+type cardEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook turns a card event into a payment webhook, taking the signature from its header.
+// Events about other objects parse with no status and change nothing.
+func (p *CardProvider) ParseWebhook(ctx context.Context, req WebhookRequest) (*models.PaymentWebhook, error) {
+	var event cardEvent
+	if err := json.Unmarshal(req.Payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	webhook := &models.PaymentWebhook{
+		Signature: req.Header.Get(CardSignatureHeader),
+		Metadata:  map[string]interface{}{"eventId": event.ID, "eventType": event.Type},
+	}
+
+	switch {
+	case strings.HasPrefix(event.Type, "payment_intent."):
+		var intent cardIntent
+		if err := json.Unmarshal(event.Data.Object, &intent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		webhook.TransactionID = intent.ID
+		webhook.Amount = models.NewMoney(intent.Amount, intent.Currency)
+		webhook.Metadata["orderId"] = intent.Metadata["orderId"]
+		switch event.Type {
+		case "payment_intent.succeeded":
+			webhook.Status = string(models.PaymentStatusCompleted)
+		case "payment_intent.processing":
+			webhook.Status = string(models.PaymentStatusProcessing)
+		case "payment_intent.requires_action":
+			webhook.Status = string(models.PaymentStatusRequiresAction)
+			if next := intent.NextAction; next != nil && next.RedirectToURL != nil {
+				webhook.Metadata["nextActionUrl"] = next.RedirectToURL.URL
+			}
+		case "payment_intent.payment_failed":
+			webhook.Status = string(models.PaymentStatusFailed)
+			webhook.Metadata["failureReason"] = "payment failed"
+			if intent.LastPaymentError != nil {
+				webhook.Metadata["failureReason"] = intent.LastPaymentError.Message
+			}
+		case "payment_intent.canceled":
+			webhook.Status = string(models.PaymentStatusCancelled)
+			webhook.Metadata["failureReason"] = "payment cancelled"
+		}
+
+	case strings.HasPrefix(event.Type, "refund.") || strings.HasPrefix(event.Type, "charge.refund."):
+		// Refund updates, e.g. refund.updated or charge.refund.updated, settle pending refunds
+		var refund cardRefund
+		if err := json.Unmarshal(event.Data.Object, &refund); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		webhook.TransactionID = refund.PaymentIntent
+		update := refund.providerRefund()
+		webhook.Refund = &models.WebhookRefund{
+			RefundID:         refund.Metadata["refundId"],
+			ProviderRefundID: refund.ID,
+			Status:           update.Status,
+			FailureReason:    update.FailureReason,
+		}
+
+	case strings.HasPrefix(event.Type, "checkout.session."):
+		var session cardSession
+		if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		webhook.TransactionID = session.PaymentIntent
+		if webhook.TransactionID == "" {
+			webhook.TransactionID = session.ID
+		}
+		webhook.Amount = models.NewMoney(session.AmountTotal, session.Currency)
+		webhook.Metadata["orderId"] = session.Metadata["orderId"]
+		webhook.Metadata["checkoutSessionId"] = session.ID
+		switch {
+		case event.Type == "checkout.session.completed" && session.PaymentStatus == "paid":
+			webhook.Status = string(models.PaymentStatusCompleted)
+		case event.Type == "checkout.session.expired":
+			webhook.Status = string(models.PaymentStatusCancelled)
+			webhook.Metadata["failureReason"] = "checkout session expired"
+		}
+	}
+
+	if webhook.TransactionID == "" {
+		return nil, fmt.Errorf("%w: event %s has no payment", ErrInvalidWebhook, event.ID)
+	}
+	return webhook, nil
+}
+
+// VerifyWebhook checks the webhook's signature: an HMAC-SHA256, keyed with the webhook secret,
+// of "<timestamp>.<payload>", signed within the tolerance
+func (p *CardProvider) VerifyWebhook(ctx context.Context, payload []byte, webhook *models.PaymentWebhook) error {
+	if p.cfg.WebhookSecret == "" {
+		return errors.New("card webhook secret is not configured")
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(webhook.Signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("missing or malformed signature")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := p.now().Sub(time.Unix(unix, 0)); age > p.cfg.SignatureTolerance || age < -p.cfg.SignatureTolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	expected := SignCardPayload(p.cfg.WebhookSecret, timestamp, payload)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return errors.New("signature does not match")
+}
+
+// SignCardPayload returns the hex HMAC-SHA256 of "<timestamp>.<payload>", as sent in the v1
+// part of the signature header. Mock gateways use it to sign the webhooks they send.
+func SignCardPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cardError is an error response from the gateway
+type cardError struct {
+	Status  int
+	Type    string
+	Message string
+}
+
+func (e *cardError) Error() string {
+	return fmt.Sprintf("card gateway returned %d: %s %s", e.Status, e.Type, e.Message)
+}
+
+// refused reports whether the gateway turned the request down without acting on it. Conflicts
+// (a request with the same idempotency key still running) and rate limits may be retried.
+func (e *cardError) refused() bool {
+	return e.Status >= 400 && e.Status < 500 && e.Status != http.StatusConflict && e.Status != http.StatusTooManyRequests
+}
+
+// call sends a form-encoded request with the secret key and decodes the JSON answer.
+// Error statuses are returned as *cardError.
+func (p *CardProvider) call(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	return p.do(ctx, method, path, form, "", out)
+}
+
+// do is call with an optional Idempotency-Key, which makes the gateway answer a repeated
+// request with the result of the first instead of acting again
+func (p *CardProvider) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.SecretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("card gateway request to %s failed: %w", path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&e)
+		return &cardError{Status: res.StatusCode, Type: e.Error.Type, Message: e.Error.Message}
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("card gateway %s returned an invalid body: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+func TestCardProviderVerifyWebhook(t *testing.T) {
+	const secret = "whsec_test"
+	now := time.Unix(1_700_000_000, 0)
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	ts := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+	sign := func(timestamp string) string { return SignCardPayload(secret, timestamp, payload) }
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		wantErr   bool
+	}{
+		{"valid", secret, "t=" + ts(0) + ",v1=" + sign(ts(0)), false},
+		{"spaces after commas", secret, "t=" + ts(0) + ", v1=" + sign(ts(0)), false},
+		{"one of several v1 signatures matches", secret, "t=" + ts(0) + ",v1=" + SignCardPayload("whsec_old", ts(0), payload) + ",v1=" + sign(ts(0)), false},
+		{"unknown schemes are ignored", secret, "t=" + ts(0) + ",v0=deadbeef,v1=" + sign(ts(0)), false},
+		{"just inside the tolerance", secret, "t=" + ts(-5*time.Minute) + ",v1=" + sign(ts(-5*time.Minute)), false},
+		{"older than the tolerance", secret, "t=" + ts(-5*time.Minute-time.Second) + ",v1=" + sign(ts(-5*time.Minute-time.Second)), true},
+		{"too far in the future", secret, "t=" + ts(6*time.Minute) + ",v1=" + sign(ts(6*time.Minute)), true},
+		{"no v1 signature matches", secret, "t=" + ts(0) + ",v1=" + SignCardPayload("whsec_old", ts(0), payload), true},
+		{"signature for another timestamp", secret, "t=" + ts(0) + ",v1=" + sign(ts(-time.Second)), true},
+		{"missing timestamp", secret, "v1=" + sign(ts(0)), true},
+		{"missing signature", secret, "t=" + ts(0), true},
+		{"malformed timestamp", secret, "t=soon,v1=" + sign("soon"), true},
+		{"empty header", secret, "", true},
+		{"no webhook secret configured", "", "t=" + ts(0) + ",v1=" + sign(ts(0)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewCardProvider(CardConfig{SecretKey: "sk_test", WebhookSecret: tt.secret})
+			p.now = func() time.Time { return now }
+
+			err := p.VerifyWebhook(context.Background(), payload, &models.PaymentWebhook{Signature: tt.signature})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCardProviderRefundPayment(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantStatus   models.RefundStatus
+		wantDeclined bool
+		wantErr      bool
+	}{
+		{"succeeded", http.StatusOK, `{"id":"re_1","status":"succeeded"}`, models.RefundStatusSucceeded, false, false},
+		{"pending", http.StatusOK, `{"id":"re_1","status":"pending"}`, models.RefundStatusPending, false, false},
+		{"failed at once", http.StatusOK, `{"id":"re_1","status":"failed","failure_reason":"expired_or_canceled_card"}`, "", true, true},
+		{"refused by the gateway", http.StatusBadRequest, `{"error":{"type":"invalid_request_error","message":"charge already refunded"}}`, "", true, true},
+		{"idempotent request still running", http.StatusConflict, `{"error":{"type":"idempotency_error"}}`, "", false, true},
+		{"gateway error", http.StatusInternalServerError, `{"error":{"type":"api_error"}}`, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/refunds" {
+					t.Errorf("request = %s %s, want POST /v1/refunds", r.Method, r.URL.Path)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer sk_test" {
+					t.Errorf("Authorization = %q", got)
+				}
+				if got := r.Header.Get("Idempotency-Key"); got != "refund-1" {
+					t.Errorf("Idempotency-Key = %q, want refund-1", got)
+				}
+				r.ParseForm()
+				if r.Form.Get("payment_intent") != "pi_1" || r.Form.Get("amount") != "2500" || r.Form.Get("metadata[refundId]") != "refund-1" {
+					t.Errorf("form = %v", r.Form)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer gateway.Close()
+
+			p := NewCardProvider(CardConfig{BaseURL: gateway.URL + "/", SecretKey: "sk_test"})
+			refund, err := p.RefundPayment(context.Background(), "pi_1", models.NewMoney(2500, "KES"), "refund-1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RefundPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if declined := errors.Is(err, ErrRefundDeclined); declined != tt.wantDeclined {
+				t.Errorf("declined = %v, want %v (err %v)", declined, tt.wantDeclined, err)
+			}
+			if err == nil && (refund.RefundID != "re_1" || refund.Status != tt.wantStatus) {
+				t.Errorf("refund = %+v, want re_1 %s", refund, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// MpesaSandboxURL is the Daraja sandbox; production uses https://api.safaricom.co.ke
+const MpesaSandboxURL = "https://sandbox.safaricom.co.ke"
+
+// MpesaConfig holds the Daraja app credentials and the till or paybill that receives payments
+type MpesaConfig struct {
+	BaseURL        string // Daraja API root; point it at a local stand-in server to test end to end
+	ConsumerKey    string
+	ConsumerSecret string
+	ShortCode      string // paybill or till number
+	Passkey        string // Lipa na M-Pesa Online passkey
+	CallbackURL    string // public URL of /payments/webhook/mpesa
+	// TransactionType is "CustomerPayBillOnline" (default) or "CustomerBuyGoodsOnline" for tills
+	TransactionType string
+}
+
+// MpesaConfigFromEnv reads the M-Pesa settings from MPESA_* variables. ok is false when no
+// consumer key is set, i.e. M-Pesa is not configured in this environment.
+func MpesaConfigFromEnv() (cfg MpesaConfig, ok bool) {
+	cfg = MpesaConfig{
+		BaseURL:         os.Getenv("MPESA_BASE_URL"),
+		ConsumerKey:     os.Getenv("MPESA_CONSUMER_KEY"),
+		ConsumerSecret:  os.Getenv("MPESA_CONSUMER_SECRET"),
+		ShortCode:       os.Getenv("MPESA_SHORTCODE"),
+		Passkey:         os.Getenv("MPESA_PASSKEY"),
+		CallbackURL:     os.Getenv("MPESA_CALLBACK_URL"),
+		TransactionType: os.Getenv("MPESA_TRANSACTION_TYPE"),
+	}
+	return cfg, cfg.ConsumerKey != ""
+}
+
+// MpesaProvider takes mobile money payments with Lipa na M-Pesa Online (STK push): the buyer
+// gets a PIN prompt on their phone and Safaricom calls back with the result
+type MpesaProvider struct {
+	cfg    MpesaConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewMpesaProvider creates an M-Pesa provider
+func NewMpesaProvider(cfg MpesaConfig) *MpesaProvider {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = MpesaSandboxURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.TransactionType == "" {
+		cfg.TransactionType = "CustomerPayBillOnline"
+	}
+	return &MpesaProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// GetProviderName returns the provider name
+func (p *MpesaProvider) GetProviderName() string {
+	return "mpesa"
+}
+
+// stkPushRequest is the body of a Daraja STK push or STK query
+type stkPushRequest struct {
+	BusinessShortCode string `json:"BusinessShortCode"`
+	Password          string `json:"Password"`
+	Timestamp         string `json:"Timestamp"`
+	TransactionType   string `json:"TransactionType,omitempty"`
+	Amount            int64  `json:"Amount,omitempty"`
+	PartyA            string `json:"PartyA,omitempty"`
+	PartyB            string `json:"PartyB,omitempty"`
+	PhoneNumber       string `json:"PhoneNumber,omitempty"`
+	CallBackURL       string `json:"CallBackURL,omitempty"`
+	AccountReference  string `json:"AccountReference,omitempty"`
+	TransactionDesc   string `json:"TransactionDesc,omitempty"`
+	CheckoutRequestID string `json:"CheckoutRequestID,omitempty"`
+}
+
+// stkResponse covers the fields of Daraja's STK push, STK query and error responses
+type stkResponse struct {
+	MerchantRequestID   string `json:"MerchantRequestID"`
+	CheckoutRequestID   string `json:"CheckoutRequestID"`
+	ResponseCode        string `json:"ResponseCode"`
+	ResponseDescription string `json:"ResponseDescription"`
+	ResultCode          string `json:"ResultCode"`
+	ResultDesc          string `json:"ResultDesc"`
+	ErrorCode           string `json:"errorCode"`    // error responses only
+	ErrorMessage        string `json:"errorMessage"` // error responses only
+}
+
+// CreatePayment sends an STK push to the phone in metadata["phoneNumber"] and returns the
+// CheckoutRequestID, which the callback and status queries refer to. M-Pesa charges whole
+// shillings, so an amount with cents is rounded up.
+func (p *MpesaProvider) CreatePayment(ctx context.Context, amount models.Money, orderID string, metadata map[string]string) (*Checkout, error) {
+	if amount.Currency != "KES" {
+		return nil, fmt.Errorf("%w: M-Pesa only takes KES, not %s", ErrInvalidPaymentDetails, amount.Currency)
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidPaymentDetails)
+	}
+	phone, err := NormalizeMpesaPhone(metadata["phoneNumber"])
+	if err != nil {
+		return nil, err
+	}
+	callback, err := url.Parse(p.cfg.CallbackURL)
+	if err != nil || p.cfg.CallbackURL == "" {
+		return nil, fmt.Errorf("invalid M-Pesa callback URL %q", p.cfg.CallbackURL)
+	}
+	// Daraja echoes nothing of ours in the callback, so the order rides along in its URL
+	q := callback.Query()
+	q.Set("orderId", orderID)
+	callback.RawQuery = q.Encode()
+
+	password, timestamp := p.password()
+	body := stkPushRequest{
+		BusinessShortCode: p.cfg.ShortCode,
+		Password:          password,
+		Timestamp:         timestamp,
+		TransactionType:   p.cfg.TransactionType,
+		Amount:            (amount.Amount + 99) / 100,
+		PartyA:            phone,
+		PartyB:            p.cfg.ShortCode,
+		PhoneNumber:       phone,
+		CallBackURL:       callback.String(),
+		AccountReference:  accountReference(orderID),
+		TransactionDesc:   "Art print order",
+	}
+
+	var resp stkResponse
+	if err := p.call(ctx, "/mpesa/stkpush/v1/processrequest", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.ResponseCode != "0" || resp.CheckoutRequestID == "" {
+		return nil, fmt.Errorf("M-Pesa rejected STK push: %s %s", resp.ResponseCode, resp.ResponseDescription)
+	}
+	return &Checkout{
+		TransactionID: resp.CheckoutRequestID,
+		Status:        models.PaymentStatusPending,
+		ProviderData:  map[string]interface{}{"merchantRequestId": resp.MerchantRequestID, "phoneNumber": phone},
+	}, nil
+}
+
+// VerifyPayment queries the STK push's status; it is verified once the buyer has paid
+func (p *MpesaProvider) VerifyPayment(ctx context.Context, transactionID string) (bool, error) {
+	status, err := p.PaymentStatus(ctx, transactionID)
+	return status == models.PaymentStatusCompleted, err
+}
+
+// PaymentStatus queries the STK push's result: pending while the buyer has not answered the
+// prompt, then completed, cancelled (result code 1032) or failed
+func (p *MpesaProvider) PaymentStatus(ctx context.Context, transactionID string) (models.PaymentStatus, error) {
+	password, timestamp := p.password()
+	body := stkPushRequest{
+		BusinessShortCode: p.cfg.ShortCode,
+		Password:          password,
+		Timestamp:         timestamp,
+		CheckoutRequestID: transactionID,
+	}
+
+	var resp stkResponse
+	err := p.call(ctx, "/mpesa/stkpushquery/v1/query", body, &resp)
+	// Daraja answers a push still awaiting the buyer's PIN with an error rather than a result
+	var apiErr *mpesaError
+	if errors.As(err, &apiErr) && apiErr.Code == mpesaStillProcessing {
+		return models.PaymentStatusPending, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case resp.ResponseCode != "0" || resp.ResultCode == "":
+		return models.PaymentStatusPending, nil
+	case resp.ResultCode == "0":
+		return models.PaymentStatusCompleted, nil
+	case resp.ResultCode == strconv.Itoa(mpesaResultCancelled):
+		return models.PaymentStatusCancelled, nil
+	}
+	return models.PaymentStatusFailed, nil
+}
+
+// RefundPayment is not available over STK push: M-Pesa reverses payments through the
+// organisation portal or the reversal API, which needs initiator credentials we do not hold
+func (p *MpesaProvider) RefundPayment(ctx context.Context, transactionID string, amount models.Money, idempotencyKey string) (*ProviderRefund, error) {
+	return nil, fmt.Errorf("%w: M-Pesa payments are reversed from the M-Pesa organisation portal", ErrRefundNotSupported)
+}
+
+// mpesaCallback is the body Safaricom posts to the STK callback URL
+type mpesaCallback struct {
+	Body struct {
+		StkCallback struct {
+			MerchantRequestID string `json:"MerchantRequestID"`
+			CheckoutRequestID string `json:"CheckoutRequestID"`
+			ResultCode        int    `json:"ResultCode"`
+			ResultDesc        string `json:"ResultDesc"`
+			CallbackMetadata  struct {
+				Item []struct {
+					Name  string      `json:"Name"`
+					Value interface{} `json:"Value"`
+				} `json:"Item"`
+			} `json:"CallbackMetadata"`
+		} `json:"stkCallback"`
+	} `json:"Body"`
+}
+
+// mpesaResultCancelled is the STK result code for a prompt the buyer dismissed
+const mpesaResultCancelled = 1032
+
+// ParseWebhook turns an STK callback into a payment webhook. ResultCode 0 means paid and 1032
+// that the buyer cancelled the prompt; any other code (1037 phone unreachable, ...) means it failed.
+func (p *MpesaProvider) ParseWebhook(ctx context.Context, req WebhookRequest) (*models.PaymentWebhook, error) {
+	var cb mpesaCallback
+	if err := json.Unmarshal(req.Payload, &cb); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	stk := cb.Body.StkCallback
+	if stk.CheckoutRequestID == "" {
+		return nil, fmt.Errorf("%w: missing CheckoutRequestID", ErrInvalidWebhook)
+	}
+
+	webhook := &models.PaymentWebhook{
+		TransactionID: stk.CheckoutRequestID,
+		Status:        string(models.PaymentStatusFailed),
+		Metadata: map[string]interface{}{
+			"orderId":           req.Query.Get("orderId"),
+			"merchantRequestId": stk.MerchantRequestID,
+			"resultCode":        stk.ResultCode,
+			"resultDesc":        stk.ResultDesc,
+		},
+	}
+	switch stk.ResultCode {
+	case 0:
+		webhook.Status = string(models.PaymentStatusCompleted)
+	case mpesaResultCancelled:
+		webhook.Status = string(models.PaymentStatusCancelled)
+		webhook.Metadata["failureReason"] = stk.ResultDesc
+	default:
+		webhook.Metadata["failureReason"] = stk.ResultDesc
+	}
+	for _, item := range stk.CallbackMetadata.Item {
+		switch item.Name {
+		case "Amount":
+			if v, ok := item.Value.(float64); ok {
+				webhook.Amount = models.FromMajor(v, "KES")
+			}
+		case "MpesaReceiptNumber":
+			webhook.Metadata["mpesaReceiptNumber"] = item.Value
+		case "PhoneNumber":
+			if v, ok := item.Value.(float64); ok {
+				webhook.Metadata["phoneNumber"] = fmt.Sprintf("%.0f", v)
+			}
+		case "TransactionDate":
+			if v, ok := item.Value.(float64); ok {
+				webhook.Metadata["transactionDate"] = fmt.Sprintf("%.0f", v)
+			}
+		}
+	}
+	return webhook, nil
+}
+
+// password returns the STK password (base64 of shortcode, passkey and timestamp) and its
+// timestamp, in Nairobi time as Daraja expects
+func (p *MpesaProvider) password() (string, string) {
+	timestamp := p.now().In(nairobi).Format("20060102150405")
+	raw := p.cfg.ShortCode + p.cfg.Passkey + timestamp
+	return base64.StdEncoding.EncodeToString([]byte(raw)), timestamp
+}
+
+var nairobi = time.FixedZone("EAT", 3*60*60)
+
+// mpesaStillProcessing is the error code of an STK query made before the buyer has answered
+const mpesaStillProcessing = "500.001.1001"
+
+// mpesaError is an error response from Daraja
+type mpesaError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *mpesaError) Error() string {
+	return fmt.Sprintf("M-Pesa returned %d: %s %s", e.Status, e.Code, e.Message)
+}
+
+// call posts a JSON body to a Daraja endpoint with a bearer token and decodes the answer.
+// Error statuses are returned as *mpesaError.
+func (p *MpesaProvider) call(ctx context.Context, path string, body interface{}, out *stkResponse) error {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("M-Pesa request to %s failed: %w", path, err)
+	}
+	defer res.Body.Close()
+
+	decodeErr := json.NewDecoder(res.Body).Decode(out)
+	if res.StatusCode >= 300 {
+		return &mpesaError{Status: res.StatusCode, Code: out.ErrorCode, Message: out.ErrorMessage}
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("M-Pesa %s returned an invalid body: %w", path, decodeErr)
+	}
+	return nil
+}
+
+// accessToken returns a cached OAuth token, fetching a new one shortly before it expires
+func (p *MpesaProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && p.now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.BaseURL+"/oauth/v1/generate?grant_type=client_credentials", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(p.cfg.ConsumerKey, p.cfg.ConsumerSecret)
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("M-Pesa auth failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("M-Pesa auth returned %d", res.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"` // Daraja sends seconds as a string
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("M-Pesa auth returned an invalid body: %w", err)
+	}
+	seconds, _ := body.ExpiresIn.Int64()
+	if seconds <= 0 {
+		seconds = 3599
+	}
+	p.token = body.AccessToken
+	p.tokenExpiry = p.now().Add(time.Duration(seconds)*time.Second - time.Minute)
+	return p.token, nil
+}
+
+// NormalizeMpesaPhone converts a Kenyan mobile number ("0712 345678", "+254712345678",
+// "712345678") to the 2547XXXXXXXX / 2541XXXXXXXX form M-Pesa expects
+func NormalizeMpesaPhone(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	switch {
+	case len(digits) == 10 && digits[0] == '0':
+		digits = "254" + digits[1:]
+	case len(digits) == 9:
+		digits = "254" + digits
+	}
+	if len(digits) != 12 || !strings.HasPrefix(digits, "254") || (digits[3] != '7' && digits[3] != '1') {
+		return "", fmt.Errorf("%w: %q is not a Kenyan mobile number", ErrInvalidPaymentDetails, phone)
+	}
+	return digits, nil
+}
+
+// accountReference is the short reference shown on the buyer's M-Pesa prompt (12 characters max)
+func accountReference(orderID string) string {
+	ref := strings.ToUpper(strings.ReplaceAll(orderID, "-", ""))
+	if len(ref) > 12 {
+		ref = ref[:12]
+	}
+	return ref
+}
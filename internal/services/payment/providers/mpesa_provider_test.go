@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// darajaStub stands in for the Daraja API: it issues tokens and answers STK requests with
+// the stk handler
+type darajaStub struct {
+	t         *testing.T
+	tokens    int // OAuth tokens issued
+	expiresIn string
+	stk       func(w http.ResponseWriter, path string, body stkPushRequest)
+}
+
+func (d *darajaStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/oauth/v1/generate" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "key" || pass != "secret" || r.URL.Query().Get("grant_type") != "client_credentials" {
+			d.t.Errorf("token request with credentials %q:%q, query %q", user, pass, r.URL.RawQuery)
+		}
+		d.tokens++
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%q}`, d.tokens, d.expiresIn)
+		return
+	}
+	if got, want := r.Header.Get("Authorization"), fmt.Sprintf("Bearer token-%d", d.tokens); got != want {
+		d.t.Errorf("%s Authorization = %q, want %q", r.URL.Path, got, want)
+	}
+	var body stkPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		d.t.Errorf("%s body: %v", r.URL.Path, err)
+	}
+	d.stk(w, r.URL.Path, body)
+}
+
+func newMpesaStub(t *testing.T, stk func(w http.ResponseWriter, path string, body stkPushRequest)) (*MpesaProvider, *darajaStub) {
+	stub := &darajaStub{t: t, expiresIn: "3599", stk: stk}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	p := NewMpesaProvider(MpesaConfig{
+		BaseURL:        server.URL + "/",
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		ShortCode:      "174379",
+		Passkey:        "passkey",
+		CallbackURL:    "https://api.example.com/payments/webhook/mpesa?src=daraja",
+	})
+	return p, stub
+}
+
+func TestMpesaProviderCreatePayment(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	p, stub := newMpesaStub(t, func(w http.ResponseWriter, path string, body stkPushRequest) {
+		if path != "/mpesa/stkpush/v1/processrequest" {
+			t.Errorf("path = %s, want the STK push endpoint", path)
+		}
+		// Daraja wants the timestamp in Nairobi time, three hours ahead of UTC
+		timestamp := now.Add(3 * time.Hour).Format("20060102150405")
+		wantPassword := base64.StdEncoding.EncodeToString([]byte("174379passkey" + timestamp))
+		if body.BusinessShortCode != "174379" || body.PartyB != "174379" || body.Timestamp != timestamp || body.Password != wantPassword {
+			t.Errorf("STK credentials = %+v", body)
+		}
+		if body.TransactionType != "CustomerPayBillOnline" || body.Amount != 1501 || body.PartyA != "254712345678" || body.PhoneNumber != "254712345678" {
+			t.Errorf("STK payment = %+v", body)
+		}
+		if body.AccountReference != "ORDER1234567" {
+			t.Errorf("AccountReference = %q, want ORDER1234567", body.AccountReference)
+		}
+		callback, err := url.Parse(body.CallBackURL)
+		if err != nil || callback.Query().Get("orderId") != "order-1234567890" || callback.Query().Get("src") != "daraja" {
+			t.Errorf("CallBackURL = %q, want the configured URL with the orderId added", body.CallBackURL)
+		}
+		fmt.Fprint(w, `{"MerchantRequestID":"mr-1","CheckoutRequestID":"ws_CO_1","ResponseCode":"0","ResponseDescription":"Success. Request accepted for processing"}`)
+	})
+	p.now = func() time.Time { return now }
+
+	pay := func() {
+		t.Helper()
+		checkout, err := p.CreatePayment(context.Background(), models.NewMoney(150050, "KES"), "order-1234567890", map[string]string{"phoneNumber": "0712 345678"})
+		if err != nil {
+			t.Fatalf("CreatePayment() error = %v", err)
+		}
+		if checkout.TransactionID != "ws_CO_1" || checkout.Status != models.PaymentStatusPending || checkout.ProviderData["merchantRequestId"] != "mr-1" {
+			t.Errorf("checkout = %+v", checkout)
+		}
+	}
+
+	pay()
+	pay()
+	if stub.tokens != 1 {
+		t.Errorf("fetched %d tokens for two pushes, want the first one cached", stub.tokens)
+	}
+	// The token is renewed a minute before Daraja expires it
+	now = now.Add(3599*time.Second - time.Minute)
+	pay()
+	if stub.tokens != 2 {
+		t.Errorf("fetched %d tokens, want a new one once the cached token expires", stub.tokens)
+	}
+}
+
+func TestMpesaProviderCreatePaymentRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   models.Money
+		phone    string
+		response string
+	}{
+		{"not KES", models.NewMoney(1000, "USD"), "0712345678", ""},
+		{"zero amount", models.NewMoney(0, "KES"), "0712345678", ""},
+		{"not a Kenyan mobile", models.NewMoney(1000, "KES"), "0201234567", ""},
+		{"push refused", models.NewMoney(1000, "KES"), "0712345678", `{"ResponseCode":"1","ResponseDescription":"Invalid PhoneNumber"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pushes := 0
+			p, _ := newMpesaStub(t, func(w http.ResponseWriter, path string, body stkPushRequest) {
+				pushes++
+				fmt.Fprint(w, tt.response)
+			})
+
+			if _, err := p.CreatePayment(context.Background(), tt.amount, "order-1", map[string]string{"phoneNumber": tt.phone}); err == nil {
+				t.Fatal("CreatePayment() succeeded, want an error")
+			}
+			if tt.response == "" && pushes > 0 {
+				t.Errorf("invalid payment was sent to Daraja")
+			}
+		})
+	}
+}
+
+func TestMpesaProviderPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus models.PaymentStatus
+		wantErr    bool
+	}{
+		{"paid", http.StatusOK, `{"ResponseCode":"0","ResultCode":"0","ResultDesc":"The service request is processed successfully."}`, models.PaymentStatusCompleted, false},
+		{"cancelled by the buyer", http.StatusOK, `{"ResponseCode":"0","ResultCode":"1032","ResultDesc":"Request cancelled by user"}`, models.PaymentStatusCancelled, false},
+		{"failed", http.StatusOK, `{"ResponseCode":"0","ResultCode":"1037","ResultDesc":"DS timeout user cannot be reached"}`, models.PaymentStatusFailed, false},
+		{"no result yet", http.StatusOK, `{"ResponseCode":"0"}`, models.PaymentStatusPending, false},
+		{"awaiting the PIN", http.StatusInternalServerError, `{"errorCode":"500.001.1001","errorMessage":"The transaction is being processed"}`, models.PaymentStatusPending, false},
+		{"gateway error", http.StatusBadRequest, `{"errorCode":"400.002.02","errorMessage":"Bad Request - Invalid CheckoutRequestID"}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newMpesaStub(t, func(w http.ResponseWriter, path string, body stkPushRequest) {
+				if path != "/mpesa/stkpushquery/v1/query" || body.CheckoutRequestID != "ws_CO_1" || body.BusinessShortCode != "174379" || body.Password == "" {
+					t.Errorf("query %s %+v", path, body)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			status, err := p.PaymentStatus(context.Background(), "ws_CO_1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PaymentStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			verified, _ := p.VerifyPayment(context.Background(), "ws_CO_1")
+			if verified != (tt.wantStatus == models.PaymentStatusCompleted) {
+				t.Errorf("VerifyPayment() = %v for status %q", verified, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMpesaProviderParseWebhook(t *testing.T) {
+	const paid = `{"Body":{"stkCallback":{"MerchantRequestID":"mr-1","CheckoutRequestID":"ws_CO_1","ResultCode":0,"ResultDesc":"The service request is processed successfully.",
+		"CallbackMetadata":{"Item":[{"Name":"Amount","Value":1501},{"Name":"MpesaReceiptNumber","Value":"NLJ7RT61SV"},{"Name":"TransactionDate","Value":20260301123512},{"Name":"PhoneNumber","Value":254712345678}]}}}}`
+
+	tests := []struct {
+		name        string
+		payload     string
+		wantStatus  models.PaymentStatus
+		wantAmount  models.Money
+		wantReason  string
+		wantErr     bool
+		wantReceipt bool
+	}{
+		{"paid", paid, models.PaymentStatusCompleted, models.NewMoney(150100, "KES"), "", false, true},
+		{"cancelled by the buyer", `{"Body":{"stkCallback":{"CheckoutRequestID":"ws_CO_1","ResultCode":1032,"ResultDesc":"Request cancelled by user"}}}`, models.PaymentStatusCancelled, models.Money{}, "Request cancelled by user", false, false},
+		{"phone unreachable", `{"Body":{"stkCallback":{"CheckoutRequestID":"ws_CO_1","ResultCode":1037,"ResultDesc":"DS timeout user cannot be reached"}}}`, models.PaymentStatusFailed, models.Money{}, "DS timeout user cannot be reached", false, false},
+		{"missing CheckoutRequestID", `{"Body":{"stkCallback":{"ResultCode":0}}}`, "", models.Money{}, "", true, false},
+		{"not JSON", `<xml/>`, "", models.Money{}, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewMpesaProvider(MpesaConfig{ConsumerKey: "key"})
+
+			webhook, err := p.ParseWebhook(context.Background(), WebhookRequest{Payload: []byte(tt.payload), Query: url.Values{"orderId": {"order-1"}}})
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidWebhook) {
+					t.Fatalf("err = %v, want ErrInvalidWebhook", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if webhook.TransactionID != "ws_CO_1" || webhook.Status != string(tt.wantStatus) || webhook.Amount != tt.wantAmount {
+				t.Errorf("webhook = %s %s %s, want ws_CO_1 %s %s", webhook.TransactionID, webhook.Status, webhook.Amount, tt.wantStatus, tt.wantAmount)
+			}
+			if webhook.Metadata["orderId"] != "order-1" {
+				t.Errorf("orderId = %v, want the one from the callback URL", webhook.Metadata["orderId"])
+			}
+			if reason, _ := webhook.Metadata["failureReason"].(string); reason != tt.wantReason {
+				t.Errorf("failureReason = %q, want %q", reason, tt.wantReason)
+			}
+			if tt.wantReceipt && (webhook.Metadata["mpesaReceiptNumber"] != "NLJ7RT61SV" || webhook.Metadata["phoneNumber"] != "254712345678" || webhook.Metadata["transactionDate"] != "20260301123512") {
+				t.Errorf("metadata = %v", webhook.Metadata)
+			}
+		})
+	}
+}
+
+func TestMpesaProviderRefundPayment(t *testing.T) {
+	p, stub := newMpesaStub(t, func(w http.ResponseWriter, path string, body stkPushRequest) {
+		t.Errorf("refund called Daraja at %s", path)
+	})
+
+	refund, err := p.RefundPayment(context.Background(), "ws_CO_1", models.NewMoney(1000, "KES"), "refund-1")
+	if !errors.Is(err, ErrRefundNotSupported) || refund != nil {
+		t.Errorf("RefundPayment() = %v, %v, want ErrRefundNotSupported", refund, err)
+	}
+	if stub.tokens != 0 {
+		t.Errorf("refund fetched an OAuth token")
+	}
+}
@@ -2,19 +2,89 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+var (
+	// ErrInvalidPaymentDetails is returned when a provider cannot take a payment as requested,
+	// e.g. a missing phone number or an unsupported currency
+	ErrInvalidPaymentDetails = errors.New("invalid payment details")
+	// ErrRefundNotSupported is returned by providers that cannot refund through their API
+	ErrRefundNotSupported = errors.New("refunds are not supported by this payment method")
+	// ErrRefundDeclined is returned when a provider definitely refused a refund, so no money
+	// moved. Any other refund error leaves open whether the provider refunded.
+	ErrRefundDeclined = errors.New("refund declined by provider")
+	// ErrInvalidWebhook is returned for a webhook that cannot be parsed or verified
+	ErrInvalidWebhook = errors.New("invalid payment webhook")
 )
 
+// Checkout is a payment a provider has started
+type Checkout struct {
+	TransactionID string                 // provider's ID for the payment; webhooks refer to it
+	Status        models.PaymentStatus   // pending unless the provider already knows better
+	PaymentURL    string                 // hosted page to send the buyer to, for redirect providers
+	ProviderData  map[string]interface{} // provider-specific details kept on the payment
+}
+
+// ProviderRefund is a refund a provider has accepted
+type ProviderRefund struct {
+	RefundID      string              // provider's reference for the refund
+	Status        models.RefundStatus // succeeded, or pending while the provider settles it
+	FailureReason string              // why a refund the provider accepted failed later
+}
+
 // PaymentProvider defines the interface for payment providers
 type PaymentProvider interface {
-	// CreatePayment initiates a payment and returns a transaction ID
-	CreatePayment(ctx context.Context, amount float64, orderID string, metadata map[string]string) (string, error)
+	// CreatePayment initiates a payment
+	CreatePayment(ctx context.Context, amount models.Money, orderID string, metadata map[string]string) (*Checkout, error)
 
 	// VerifyPayment verifies the status of a payment
 	VerifyPayment(ctx context.Context, transactionID string) (bool, error)
 
-	// RefundPayment processes a refund
-	RefundPayment(ctx context.Context, transactionID string, amount float64) error
+	// RefundPayment refunds all or part of a captured payment. Providers pass idempotencyKey to
+	// the gateway, so retrying a refund whose answer was lost cannot refund twice. Definite
+	// refusals are returned wrapping ErrRefundDeclined or ErrRefundNotSupported.
+	RefundPayment(ctx context.Context, transactionID string, amount models.Money, idempotencyKey string) (*ProviderRefund, error)
 
 	// GetProviderName returns the name of the provider
 	GetProviderName() string
 }
+
+// WebhookRequest is a webhook as it arrived from a provider
+type WebhookRequest struct {
+	Payload []byte
+	Header  http.Header
+	Query   url.Values
+}
+
+// WebhookParser is implemented by providers whose webhooks have their own format. Webhooks of
+// other providers are decoded as models.PaymentWebhook.
+type WebhookParser interface {
+	// ParseWebhook turns a raw webhook into a payment webhook
+	ParseWebhook(ctx context.Context, req WebhookRequest) (*models.PaymentWebhook, error)
+}
+
+// WebhookVerifier is implemented by providers that sign their webhooks
+type WebhookVerifier interface {
+	// VerifyWebhook checks webhook.Signature against the raw payload the webhook was parsed from
+	VerifyWebhook(ctx context.Context, payload []byte, webhook *models.PaymentWebhook) error
+}
+
+// StatusChecker is implemented by providers that can report where a payment stands, so a
+// webhook saying it failed or was cancelled can be confirmed before it is applied
+type StatusChecker interface {
+	// PaymentStatus asks the provider for a transaction's status: pending, requires_action or
+	// processing while the buyer is still paying, then completed, failed or cancelled
+	PaymentStatus(ctx context.Context, transactionID string) (models.PaymentStatus, error)
+}
+
+// RefundChecker is implemented by providers whose refunds may settle after they are accepted,
+// so pending refunds can be looked up until they succeed or fail
+type RefundChecker interface {
+	// RefundStatus returns the current state of a refund the provider accepted
+	RefundStatus(ctx context.Context, providerRefundID string) (*ProviderRefund, error)
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnknownMethod is returned for a payment method no provider handles
+	ErrUnknownMethod = errors.New("unknown payment method")
+	// ErrMethodDisabled is returned for a method whose provider is not enabled in this environment
+	ErrMethodDisabled = errors.New("payment method is not enabled")
+)
+
+// Registry routes payments to providers by payment method ("simulated", "mpesa", ...)
+type Registry struct {
+	providers map[string]PaymentProvider
+	enabled   map[string]bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]PaymentProvider),
+		enabled:   make(map[string]bool),
+	}
+}
+
+// Register adds a provider under its name. Providers that are registered but not enabled take
+// no new payments, but can still verify and refund the payments they already made.
+func (r *Registry) Register(provider PaymentProvider, enabled bool) {
+	name := provider.GetProviderName()
+	r.providers[name] = provider
+	r.enabled[name] = enabled
+}
+
+// Provider returns the provider that takes new payments for a method
+func (r *Registry) Provider(method string) (PaymentProvider, error) {
+	provider, err := r.Registered(method)
+	if err != nil {
+		return nil, err
+	}
+	if !r.enabled[method] {
+		return nil, fmt.Errorf("%w: %s", ErrMethodDisabled, method)
+	}
+	return provider, nil
+}
+
+// Registered returns the provider for a method whether or not it is enabled, for working with
+// payments it has already made
+func (r *Registry) Registered(method string) (PaymentProvider, error) {
+	provider, ok := r.providers[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMethod, method)
+	}
+	return provider, nil
+}
+
+// EnabledMethods lists the methods buyers can pay with, sorted by name
+func (r *Registry) EnabledMethods() []string {
+	var methods []string
+	for name, enabled := range r.enabled {
+		if enabled {
+			methods = append(methods, name)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// NewRegistryFromEnv registers the built-in providers and enables those listed in
+// PAYMENT_METHODS (comma-separated, e.g. "mpesa,simulated"). When it is empty only the
+// simulated provider is enabled, and only outside production. M-Pesa is registered when its
+// MPESA_* credentials are set, and cards when CARD_SECRET_KEY is.
+func NewRegistryFromEnv() *Registry {
+	r := NewRegistry()
+	enabled := enabledMethodsFromEnv()
+	r.Register(NewSimulatedProvider(), enabled["simulated"])
+	if cfg, ok := MpesaConfigFromEnv(); ok {
+		r.Register(NewMpesaProvider(cfg), enabled["mpesa"])
+	}
+	if cfg, ok := CardConfigFromEnv(); ok {
+		r.Register(NewCardProvider(cfg), enabled["card"])
+	}
+
+	for method := range enabled {
+		if _, ok := r.providers[method]; !ok {
+			log.Printf("⚠️ PAYMENT_METHODS lists %q, which has no provider", method)
+		}
+	}
+	log.Printf("✅ Payment methods enabled: %v", r.EnabledMethods())
+	return r
+}
+
+func enabledMethodsFromEnv() map[string]bool {
+	enabled := make(map[string]bool)
+	list := os.Getenv("PAYMENT_METHODS")
+	if strings.TrimSpace(list) == "" {
+		env := os.Getenv("APP_ENV")
+		enabled["simulated"] = env != "" && env != "production" && env != "prod"
+		return enabled
+	}
+	for _, method := range strings.Split(list, ",") {
+		if method = strings.ToLower(strings.TrimSpace(method)); method != "" {
+			enabled[method] = true
+		}
+	}
+	return enabled
+}
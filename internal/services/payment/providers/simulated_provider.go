@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
 )
 
 // SimulatedProvider simulates payment processing for testing
@@ -15,9 +17,11 @@ type SimulatedProvider struct {
 // SimulatedTransaction represents a simulated payment transaction
 type SimulatedTransaction struct {
 	TransactionID string
-	Amount        float64
+	Amount        models.Money
 	OrderID       string
-	Status        string // "pending", "completed", "failed"
+	Status        string            // "pending", "completed", "failed", "refunded"
+	Refunded      models.Money      // total refunded so far
+	RefundKeys    map[string]string // refund ID by idempotency key
 	CreatedAt     time.Time
 }
 
@@ -35,8 +39,8 @@ func (p *SimulatedProvider) GetProviderName() string {
 
 // CreatePayment simulates creating a payment
 // In simulation, payments are automatically completed after a short delay
-func (p *SimulatedProvider) CreatePayment(ctx context.Context, amount float64, orderID string, metadata map[string]string) (string, error) {
-	transactionID := fmt.Sprintf("sim_%d_%s", time.Now().Unix(), orderID)
+func (p *SimulatedProvider) CreatePayment(ctx context.Context, amount models.Money, orderID string, metadata map[string]string) (*Checkout, error) {
+	transactionID := fmt.Sprintf("sim_%d_%s", time.Now().UnixNano(), orderID)
 
 	transaction := &SimulatedTransaction{
 		TransactionID: transactionID,
@@ -57,7 +61,7 @@ func (p *SimulatedProvider) CreatePayment(ctx context.Context, amount float64, o
 		}
 	}()
 
-	return transactionID, nil
+	return &Checkout{TransactionID: transactionID, Status: models.PaymentStatusPending}, nil
 }
 
 // VerifyPayment verifies the status of a simulated payment
@@ -70,20 +74,53 @@ func (p *SimulatedProvider) VerifyPayment(ctx context.Context, transactionID str
 	return transaction.Status == "completed", nil
 }
 
-// RefundPayment simulates a refund
-func (p *SimulatedProvider) RefundPayment(ctx context.Context, transactionID string, amount float64) error {
+// PaymentStatus reports the status of a simulated payment
+func (p *SimulatedProvider) PaymentStatus(ctx context.Context, transactionID string) (models.PaymentStatus, error) {
+	transaction, exists := p.transactions[transactionID]
+	if !exists {
+		return "", fmt.Errorf("transaction not found: %s", transactionID)
+	}
+	switch transaction.Status {
+	case "completed", "refunded":
+		return models.PaymentStatusCompleted, nil
+	case "failed":
+		return models.PaymentStatusFailed, nil
+	}
+	return models.PaymentStatusPending, nil
+}
+
+// RefundPayment simulates a refund; a transaction can be refunded in parts up to its amount.
+// A repeated idempotency key returns the first refund again.
+func (p *SimulatedProvider) RefundPayment(ctx context.Context, transactionID string, amount models.Money, idempotencyKey string) (*ProviderRefund, error) {
 	transaction, exists := p.transactions[transactionID]
 	if !exists {
-		return fmt.Errorf("transaction not found: %s", transactionID)
+		return nil, fmt.Errorf("%w: transaction not found: %s", ErrRefundDeclined, transactionID)
+	}
+	if refundID, ok := transaction.RefundKeys[idempotencyKey]; ok && idempotencyKey != "" {
+		return &ProviderRefund{RefundID: refundID, Status: models.RefundStatusSucceeded}, nil
 	}
 
 	if transaction.Status != "completed" {
-		return fmt.Errorf("cannot refund transaction with status: %s", transaction.Status)
+		return nil, fmt.Errorf("%w: cannot refund transaction with status: %s", ErrRefundDeclined, transaction.Status)
+	}
+	refunded := transaction.Refunded.Add(amount)
+	if refunded.Cmp(transaction.Amount) > 0 {
+		return nil, fmt.Errorf("%w: refund of %s exceeds the %s left on transaction %s", ErrRefundDeclined, amount, transaction.Amount.Sub(transaction.Refunded), transactionID)
 	}
 
-	// In simulation, mark as refunded
-	transaction.Status = "refunded"
-	return nil
+	// In simulation, mark as refunded once nothing is left
+	transaction.Refunded = refunded
+	if refunded.Cmp(transaction.Amount) == 0 {
+		transaction.Status = "refunded"
+	}
+	refundID := fmt.Sprintf("simref_%d", time.Now().UnixNano())
+	if idempotencyKey != "" {
+		if transaction.RefundKeys == nil {
+			transaction.RefundKeys = make(map[string]string)
+		}
+		transaction.RefundKeys[idempotencyKey] = refundID
+	}
+	return &ProviderRefund{RefundID: refundID, Status: models.RefundStatusSucceeded}, nil
 }
 
 // GetTransaction retrieves a simulated transaction (for testing)
@@ -1,7 +1,7 @@
 package pricing
 
 import (
-	"math"
+	"strings"
 
 	"github.com/cecvl/art-print-backend/internal/interfaces"
 	"github.com/cecvl/art-print-backend/internal/models"
@@ -23,7 +23,7 @@ type PriceRequest struct {
 }
 
 type PriceResponse struct {
-	Total int `json:"total"`
+	Total models.Money `json:"total"`
 }
 
 // CalculateFramePrice calculates the price for a frame based on size
@@ -83,90 +83,87 @@ func (p *PricingService) Calculate(req PriceRequest, opts interfaces.PrintOption
 	}
 	base *= float64(quantity)
 
-	return PriceResponse{Total: int(math.Round(base))}
+	return PriceResponse{Total: models.FromMajor(base, models.DefaultCurrency)}
 }
 
-// CalculateShopPrice calculates price using a shop's service size-based pricing
-// This is the new shop-specific pricing method
-func (p *PricingService) CalculateShopPrice(service *models.PrintService, options models.PrintOrderOptions) float64 {
-	// Get the total price for the requested size
-	price, ok := service.SizePricing[options.Size]
-	if !ok {
-		return 0 // Size not available for this service
-	}
-
-	// Note: Frame pricing is handled separately outside this function
-	// Each frame can have its own size-specific pricing
-
-	// Apply quantity discount if configured
-	for _, tier := range service.QuantityTiers {
-		if options.Quantity >= tier.MinQuantity && (tier.MaxQuantity == 0 || options.Quantity <= tier.MaxQuantity) {
-			price *= (1.0 - tier.Discount) // Apply discount
-			break
-		}
-	}
-
-	// Add rush order fee if applicable
-	if options.RushOrder {
-		price += service.RushOrderFee
-	}
-
-	// Multiply by quantity
-	price *= float64(options.Quantity)
-
-	return price
-}
+// PriceBreakdown is kept as an alias so existing callers keep compiling
+type PriceBreakdown = models.PriceBreakdown
 
-// CalculateShopPriceWithBreakdown returns price with detailed breakdown
-type PriceBreakdown struct {
-	BasePrice        float64 `json:"basePrice"`
-	SizeModifier     float64 `json:"sizeModifier"`
-	MaterialMarkup   float64 `json:"materialMarkup"`
-	MediumMarkup     float64 `json:"mediumMarkup"`
-	FramePrice       float64 `json:"framePrice"`
-	Quantity         int     `json:"quantity"`
-	QuantityDiscount float64 `json:"quantityDiscount"`
-	RushOrderFee     float64 `json:"rushOrderFee"`
-	Subtotal         float64 `json:"subtotal"`
-	Total            float64 `json:"total"`
-}
-
-func (p *PricingService) CalculateShopPriceWithBreakdown(service *models.PrintService, options models.PrintOrderOptions) PriceBreakdown {
-	// Get the size-specific price
+// PriceLine is the single shop pricing rule. Per unit it charges the service's price for the
+// size plus the frame's size price, applies the service's quantity tier discount to both, then
+// adds the rush fee (never discounted); the result is multiplied by quantity. The discounted
+// unit price is rounded half up to the minor unit first, so Total is always UnitPrice × Quantity.
+// frame may be nil. ok is false when the service does not offer the size.
+func (p *PricingService) PriceLine(service *models.PrintService, frame *models.Frame, options models.PrintOrderOptions) (breakdown models.PriceBreakdown, ok bool) {
 	sizePrice, ok := service.SizePricing[options.Size]
 	if !ok {
-		return PriceBreakdown{} // Size not available
+		return models.PriceBreakdown{}, false
 	}
-
-	breakdown := PriceBreakdown{
-		BasePrice: sizePrice,
-		Quantity:  options.Quantity,
+	if options.Quantity <= 0 {
+		options.Quantity = 1
 	}
 
-	price := sizePrice
+	currency := models.DefaultCurrency
+	breakdown = models.PriceBreakdown{
+		ShopID:     service.ShopID,
+		ServiceID:  service.ID,
+		BasePrice:  models.FromMajor(sizePrice, currency),
+		FramePrice: models.NewMoney(0, currency),
+		Quantity:   options.Quantity,
+	}
+	if frame != nil {
+		breakdown.FrameID = frame.ID
+		breakdown.FramePrice = models.FromMajor(p.CalculateFramePrice(frame, options.Size), currency)
+	}
 
-	// Note: Frame price is tracked but added separately
-	// Frame pricing is kept separate as frames have their own size-specific pricing
-	breakdown.Subtotal = price
+	unit := breakdown.BasePrice.Add(breakdown.FramePrice)
+	breakdown.Subtotal = unit.Mul(options.Quantity)
 
-	// Apply quantity discount if applicable
 	for _, tier := range service.QuantityTiers {
 		if options.Quantity >= tier.MinQuantity && (tier.MaxQuantity == 0 || options.Quantity <= tier.MaxQuantity) {
 			breakdown.QuantityDiscount = tier.Discount
-			price *= (1.0 - tier.Discount)
+			unit = unit.MulRate(1.0-tier.Discount, models.RoundHalfUp)
 			break
 		}
 	}
 
-	// Add rush order fee if applicable
+	breakdown.RushOrderFee = models.NewMoney(0, currency)
 	if options.RushOrder {
-		breakdown.RushOrderFee = service.RushOrderFee
-		price += service.RushOrderFee
+		breakdown.RushOrderFee = models.FromMajor(service.RushOrderFee, currency)
+		unit = unit.Add(breakdown.RushOrderFee)
 	}
 
-	// Multiply by quantity
-	price *= float64(options.Quantity)
-	breakdown.Total = price
+	breakdown.UnitPrice = unit
+	breakdown.Total = unit.Mul(options.Quantity)
+	return breakdown, true
+}
+
+// ResolveFrame picks the frame a buyer selected, by ID or (case-insensitive) type, from a
+// shop's frames. Active frames win over inactive ones with the same type. Returns nil when
+// no frame was requested or none matches.
+func ResolveFrame(frames []*models.Frame, selection string) *models.Frame {
+	if !WantsFrame(selection) {
+		return nil
+	}
+	var found *models.Frame
+	for _, f := range frames {
+		if f.ID == selection {
+			return f
+		}
+		if !strings.EqualFold(f.Type, selection) {
+			continue
+		}
+		if f.IsActive {
+			return f
+		}
+		if found == nil {
+			found = f
+		}
+	}
+	return found
+}
 
-	return breakdown
+// WantsFrame reports whether a frame selection asks for a frame at all
+func WantsFrame(selection string) bool {
+	return selection != "" && !strings.EqualFold(selection, "none")
 }
@@ -0,0 +1,225 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// ErrPromotionNotApplicable wraps every reason a coupon cannot be used on an order
+var ErrPromotionNotApplicable = errors.New("promotion does not apply")
+
+func notApplicable(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrPromotionNotApplicable, fmt.Sprintf(format, args...))
+}
+
+// PromotionEngine checks coupons against an order and takes their discount off it
+type PromotionEngine struct {
+	promotions repositories.PromotionRepository
+	orders     repositories.OrderRepository
+	artworks   repositories.ArtworkRepository
+}
+
+// NewPromotionEngine creates a new promotion engine
+func NewPromotionEngine(store *repositories.Store) *PromotionEngine {
+	return &PromotionEngine{
+		promotions: store.Promotions,
+		orders:     store.Orders,
+		artworks:   store.Artworks,
+	}
+}
+
+// eligibleLine is an order line a promotion's scope covers
+type eligibleLine struct {
+	sub   *models.Order
+	index int
+	total models.Money
+}
+
+// Apply checks that the buyer may use the coupon on a priced order group and takes the
+// discount off the parent and sub-orders, recording on each which lines and how much shipping
+// it covered. Shipping must already be applied. The promotion is returned so checkout can
+// redeem it once the order is saved.
+func (e *PromotionEngine) Apply(ctx context.Context, code string, parent *models.Order, subOrders []*models.Order) (*models.Promotion, error) {
+	promotion, err := e.promotions.GetPromotionByCode(ctx, code)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, notApplicable("unknown coupon code")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkEligible(ctx, promotion, parent.BuyerID, parent.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	lines := e.eligibleLines(ctx, promotion, subOrders)
+	subtotal := models.NewMoney(0, parent.TotalAmount.Currency)
+	for _, l := range lines {
+		subtotal = subtotal.Add(l.total)
+	}
+	if len(lines) == 0 || !subtotal.IsPositive() {
+		return nil, notApplicable("no items in this order qualify")
+	}
+	if minimum := models.FromMajor(promotion.MinSubtotal, subtotal.Currency); subtotal.Cmp(minimum) < 0 {
+		return nil, notApplicable("qualifying items must total at least %s", minimum)
+	}
+
+	discounts := make(map[*models.Order]*models.OrderDiscount)
+	discountOf := func(sub *models.Order) *models.OrderDiscount {
+		if d, ok := discounts[sub]; ok {
+			return d
+		}
+		d := &models.OrderDiscount{PromotionID: promotion.ID, Code: promotion.Code, Type: promotion.Type}
+		discounts[sub] = d
+		return d
+	}
+
+	switch promotion.Type {
+	case models.PromotionFreeShipping:
+		for _, l := range lines {
+			d := discountOf(l.sub)
+			if d.Shipping.IsZero() && l.sub.ShippingCost.IsPositive() {
+				d.Shipping = l.sub.ShippingCost
+				d.Amount = l.sub.ShippingCost
+			}
+		}
+	default:
+		var amount models.Money
+		if promotion.Type == models.PromotionPercentage {
+			amount = subtotal.MulRate(promotion.Value/100, models.RoundHalfUp)
+			if promotion.MaxDiscount > 0 {
+				amount = amount.Min(models.FromMajor(promotion.MaxDiscount, subtotal.Currency))
+			}
+		} else {
+			amount = models.FromMajor(promotion.Value, subtotal.Currency).Min(subtotal)
+		}
+		// Spread the discount over the lines in proportion to their totals
+		weights := make([]int64, len(lines))
+		for i, l := range lines {
+			weights[i] = l.total.Amount
+		}
+		for i, share := range amount.Allocate(weights...) {
+			l := lines[i]
+			d := discountOf(l.sub)
+			d.Lines = append(d.Lines, models.LineDiscount{LineID: l.sub.Items[l.index].LineID, Amount: share})
+			d.Amount = d.Amount.Add(share)
+		}
+	}
+
+	total := models.OrderDiscount{PromotionID: promotion.ID, Code: promotion.Code, Type: promotion.Type}
+	for _, sub := range subOrders {
+		d, ok := discounts[sub]
+		if !ok || !d.Amount.IsPositive() {
+			continue
+		}
+		sub.Discounts = append(sub.Discounts, *d)
+		sub.DiscountTotal = sub.DiscountTotal.Add(d.Amount)
+		sub.TotalAmount = sub.TotalAmount.Sub(d.Amount)
+		total.Lines = append(total.Lines, d.Lines...)
+		total.Shipping = total.Shipping.Add(d.Shipping)
+		total.Amount = total.Amount.Add(d.Amount)
+	}
+	if !total.Amount.IsPositive() {
+		return nil, notApplicable("this order has nothing the coupon can discount")
+	}
+	parent.Discounts = append(parent.Discounts, total)
+	parent.DiscountTotal = parent.DiscountTotal.Add(total.Amount)
+	parent.TotalAmount = parent.TotalAmount.Sub(total.Amount)
+	return promotion, nil
+}
+
+// Redeem records the promotion's use by a saved order, enforcing its limits
+func (e *PromotionEngine) Redeem(ctx context.Context, promotion *models.Promotion, parent *models.Order) error {
+	err := e.promotions.Redeem(ctx, &models.PromotionRedemption{
+		PromotionID: promotion.ID,
+		Code:        promotion.Code,
+		BuyerID:     parent.BuyerID,
+		OrderID:     parent.OrderID,
+		Amount:      parent.DiscountTotal,
+	})
+	if errors.Is(err, repositories.ErrRedemptionLimit) {
+		return notApplicable("this coupon has been used up")
+	}
+	return err
+}
+
+// Release gives back the uses an order redeemed, e.g. when checkout fails after redeeming
+func (e *PromotionEngine) Release(ctx context.Context, orderID string) {
+	if err := e.promotions.ReleaseRedemptions(ctx, orderID); err != nil {
+		log.Printf("⚠️ Failed to release promotion redemptions of order %s: %v", orderID, err)
+	}
+}
+
+// checkEligible applies the promotion's rules that do not depend on the order's contents
+func (e *PromotionEngine) checkEligible(ctx context.Context, promotion *models.Promotion, buyerID string, at time.Time) error {
+	switch {
+	case !promotion.IsActive:
+		return notApplicable("coupon is not active")
+	case !promotion.StartsAt.IsZero() && at.Before(promotion.StartsAt):
+		return notApplicable("coupon is not valid yet")
+	case !promotion.ExpiresAt.IsZero() && !at.Before(promotion.ExpiresAt):
+		return notApplicable("coupon has expired")
+	case promotion.UsageLimit > 0 && promotion.RedemptionCount >= promotion.UsageLimit:
+		return notApplicable("this coupon has been used up")
+	}
+
+	if promotion.PerBuyerLimit > 0 {
+		used, err := e.promotions.CountRedemptions(ctx, promotion.ID, buyerID)
+		if err != nil {
+			return err
+		}
+		if used >= promotion.PerBuyerLimit {
+			return notApplicable("you have already used this coupon")
+		}
+	}
+
+	if promotion.FirstOrderOnly {
+		previous, err := e.orders.ListOrders(ctx, repositories.OrderFilter{BuyerID: buyerID})
+		if err != nil {
+			return err
+		}
+		for _, o := range previous {
+			if !o.IsSubOrder() && o.Status != models.OrderStatusCancelled {
+				return notApplicable("coupon is only valid on your first order")
+			}
+		}
+	}
+	return nil
+}
+
+// eligibleLines returns the sub-order lines within the promotion's artist and shop scope
+func (e *PromotionEngine) eligibleLines(ctx context.Context, promotion *models.Promotion, subOrders []*models.Order) []eligibleLine {
+	artists := make(map[string]string)
+	artistOf := func(artworkID string) string {
+		if id, ok := artists[artworkID]; ok {
+			return id
+		}
+		artwork, err := e.artworks.GetArtworkByID(ctx, artworkID)
+		if err != nil {
+			log.Printf("⚠️ Failed to load artwork %s for promotion %s: %v", artworkID, promotion.Code, err)
+			artists[artworkID] = ""
+			return ""
+		}
+		artists[artworkID] = artwork.ArtistID
+		return artwork.ArtistID
+	}
+
+	var lines []eligibleLine
+	for _, sub := range subOrders {
+		if promotion.ShopID != "" && sub.PrintShopID != promotion.ShopID {
+			continue
+		}
+		for i, item := range sub.Items {
+			if promotion.ArtistID != "" && artistOf(item.ArtworkID) != promotion.ArtistID {
+				continue
+			}
+			lines = append(lines, eligibleLine{sub: sub, index: i, total: item.LineTotal()})
+		}
+	}
+	return lines
+}
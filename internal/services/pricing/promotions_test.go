@@ -0,0 +1,188 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+func TestPromotionEngineApply(t *testing.T) {
+	now := time.Now()
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+
+	tests := []struct {
+		name          string
+		promotion     models.Promotion
+		code          string // defaults to the promotion's code
+		inactive      bool
+		previousOrder bool // the buyer has checked out before
+		wantErr       bool
+		wantSubs      []int64 // discount taken off each sub-order, in minor units
+	}{
+		{
+			name:      "percentage spread over the lines",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Value: 10},
+			wantSubs:  []int64{600, 200},
+		},
+		{
+			name:      "percentage capped by the max discount",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Value: 50, MaxDiscount: 5},
+			wantSubs:  []int64{375, 125},
+		},
+		{
+			name:      "fixed amount",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 30},
+			wantSubs:  []int64{2250, 750},
+		},
+		{
+			name:      "fixed amount never exceeds the items",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 500},
+			wantSubs:  []int64{6000, 2000},
+		},
+		{
+			name:      "free shipping waives each parcel",
+			promotion: models.Promotion{Type: models.PromotionFreeShipping},
+			wantSubs:  []int64{500, 300},
+		},
+		{
+			name:      "shop scope",
+			promotion: models.Promotion{Type: models.PromotionPercentage, Value: 50, ShopID: "shop-b"},
+			wantSubs:  []int64{0, 1000},
+		},
+		{
+			name:      "artist scope",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, ArtistID: "artist-1"},
+			wantSubs:  []int64{1000, 0},
+		},
+		{
+			name:      "code is matched case-insensitively",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10},
+			code:      " save10 ",
+			wantSubs:  []int64{750, 250},
+		},
+		{
+			name:      "below the minimum subtotal",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, MinSubtotal: 80.01},
+			wantErr:   true,
+		},
+		{
+			name:      "no line in scope",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, ShopID: "shop-c"},
+			wantErr:   true,
+		},
+		{
+			name:      "inactive",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10},
+			inactive:  true,
+			wantErr:   true,
+		},
+		{
+			name:      "not started",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, StartsAt: now.Add(time.Hour)},
+			wantErr:   true,
+		},
+		{
+			name:      "expired",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, ExpiresAt: now.Add(-time.Hour)},
+			wantErr:   true,
+		},
+		{
+			name:      "used up",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10, UsageLimit: 5, RedemptionCount: 5},
+			wantErr:   true,
+		},
+		{
+			name:          "first order only",
+			promotion:     models.Promotion{Type: models.PromotionFixed, Value: 10, FirstOrderOnly: true},
+			previousOrder: true,
+			wantErr:       true,
+		},
+		{
+			name:      "unknown code",
+			promotion: models.Promotion{Type: models.PromotionFixed, Value: 10},
+			code:      "NOPE",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repositories.NewMemoryStore()
+			store.Artworks.CreateArtwork(ctx, &models.Artwork{ID: "art-1", ArtistID: "artist-1"})
+			store.Artworks.CreateArtwork(ctx, &models.Artwork{ID: "art-2", ArtistID: "artist-2"})
+			if tt.previousOrder {
+				store.Orders.CreateOrder(ctx, &models.Order{OrderID: "earlier", BuyerID: "buyer", Status: models.OrderStatusCompleted})
+			}
+
+			promotion := tt.promotion
+			promotion.Code, promotion.IsActive = "SAVE10", !tt.inactive
+			if err := store.Promotions.CreatePromotion(ctx, &promotion); err != nil {
+				t.Fatal(err)
+			}
+			code := tt.code
+			if code == "" {
+				code = promotion.Code
+			}
+
+			// 60.00 of prints plus 5.00 shipping from shop A, 20.00 plus 3.00 from shop B
+			subs := []*models.Order{
+				{
+					OrderID: "sub-a", ParentOrderID: "parent", BuyerID: "buyer", PrintShopID: "shop-a",
+					Items:        []models.CartItem{{LineID: "a1", ArtworkID: "art-1", Quantity: 2, Price: kes(3000)}},
+					ShippingCost: kes(500), TotalAmount: kes(6500),
+				},
+				{
+					OrderID: "sub-b", ParentOrderID: "parent", BuyerID: "buyer", PrintShopID: "shop-b",
+					Items:        []models.CartItem{{LineID: "b1", ArtworkID: "art-2", Quantity: 1, Price: kes(2000)}},
+					ShippingCost: kes(300), TotalAmount: kes(2300),
+				},
+			}
+			parent := &models.Order{OrderID: "parent", BuyerID: "buyer", SubOrderIDs: []string{"sub-a", "sub-b"}, TotalAmount: kes(8800), CreatedAt: now}
+
+			got, err := NewPromotionEngine(store).Apply(ctx, code, parent, subs)
+			if tt.wantErr {
+				if !errors.Is(err, ErrPromotionNotApplicable) {
+					t.Fatalf("err = %v, want ErrPromotionNotApplicable", err)
+				}
+				if parent.TotalAmount != kes(8800) || len(parent.Discounts) > 0 {
+					t.Errorf("refused coupon changed the order: total %s, discounts %v", parent.TotalAmount, parent.Discounts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.ID != promotion.ID {
+				t.Errorf("returned promotion %s, want %s", got.ID, promotion.ID)
+			}
+
+			var want int64
+			for i, sub := range subs {
+				want += tt.wantSubs[i]
+				if sub.DiscountTotal.Amount != tt.wantSubs[i] {
+					t.Errorf("%s discount = %d, want %d", sub.OrderID, sub.DiscountTotal.Amount, tt.wantSubs[i])
+				}
+				var lines int64
+				for _, d := range sub.Discounts {
+					for _, l := range d.Lines {
+						lines += l.Amount.Amount
+					}
+					lines += d.Shipping.Amount
+				}
+				if lines != sub.DiscountTotal.Amount {
+					t.Errorf("%s line and shipping discounts add up to %d, want %d", sub.OrderID, lines, sub.DiscountTotal.Amount)
+				}
+			}
+			if parent.DiscountTotal.Amount != want {
+				t.Errorf("parent discount = %d, want %d", parent.DiscountTotal.Amount, want)
+			}
+			if parent.TotalAmount != kes(8800-want) {
+				t.Errorf("parent total = %s, want %s", parent.TotalAmount, kes(8800-want))
+			}
+		})
+	}
+}
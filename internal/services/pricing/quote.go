@@ -0,0 +1,171 @@
+package pricing
+
+import (
+	"context"
+	"log"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+	"github.com/cecvl/art-print-backend/internal/services/catalog"
+)
+
+// Quoter computes authoritative line prices from a shop's configured services and frames.
+// Client-supplied prices are never consulted.
+type Quoter struct {
+	shops   repositories.PrintShopRepository
+	frames  repositories.FrameRepository
+	pricing *PricingService
+	catalog *catalog.CatalogService
+}
+
+// NewQuoter creates a new quoter
+func NewQuoter(shops repositories.PrintShopRepository, frames repositories.FrameRepository) *Quoter {
+	return &Quoter{
+		shops:   shops,
+		frames:  frames,
+		pricing: NewPricingService(),
+		catalog: catalog.NewCatalogService(),
+	}
+}
+
+// LineDrift reports a cart line whose checkout price differs from the price shown in the cart
+type LineDrift struct {
+	ArtworkID     string       `json:"artworkId"`
+	CartPrice     models.Money `json:"cartPrice"`     // unit price in the cart
+	CheckoutPrice models.Money `json:"checkoutPrice"` // unit price charged
+	Difference    models.Money `json:"difference"`    // line total difference (checkout - cart)
+}
+
+// QuoteLine prices one line for the given shop using the cheapest active service that
+// offers the size. Lines no shop can price (unassigned, or the shop lacks a suitable
+// service) fall back to the legacy catalog.
+func (q *Quoter) QuoteLine(ctx context.Context, shopID string, options models.PrintOrderOptions) models.PriceBreakdown {
+	if options.Quantity <= 0 {
+		options.Quantity = 1
+	}
+
+	if shopID != "" {
+		if breakdown, ok := q.quoteFromShop(ctx, shopID, options); ok {
+			return breakdown
+		}
+		log.Printf("⚠️ Shop %s cannot price size %q, falling back to catalog pricing", shopID, options.Size)
+	}
+	return q.quoteFromCatalog(options)
+}
+
+// QuoteService prices options against one service, resolving the selected frame among the
+// service's shop frames. This is the entry point matching, price calculators and checkout share.
+// ok is false when the service does not offer the size.
+func (q *Quoter) QuoteService(ctx context.Context, service *models.PrintService, options models.PrintOrderOptions) (models.PriceBreakdown, bool) {
+	breakdown, ok := q.pricing.PriceLine(service, q.frameFor(ctx, service.ShopID, options.Frame), options)
+	if !ok {
+		return breakdown, false
+	}
+	breakdown.Source = models.PriceSourceShop
+	return breakdown, true
+}
+
+// EstimateUnitPrice returns the catalog unit price shown in the cart before a shop is matched
+func (q *Quoter) EstimateUnitPrice(options models.PrintOrderOptions) models.Money {
+	options.Quantity = 1
+	return q.quoteFromCatalog(options).Total
+}
+
+// Reprice replaces the price of every item with a fresh quote from its shop and returns the
+// lines whose price moved. shopIDs[i] is the shop fulfilling items[i] ("" if unassigned).
+func (q *Quoter) Reprice(ctx context.Context, items []models.CartItem, shopIDs []string) []LineDrift {
+	var drift []LineDrift
+	for i := range items {
+		item := &items[i]
+		options := item.PrintOptions
+		options.Quantity = item.Quantity
+
+		breakdown := q.QuoteLine(ctx, shopIDs[i], options)
+		if breakdown.UnitPrice.Cmp(item.Price) != 0 {
+			drift = append(drift, LineDrift{
+				ArtworkID:     item.ArtworkID,
+				CartPrice:     item.Price,
+				CheckoutPrice: breakdown.UnitPrice,
+				Difference:    breakdown.Total.Sub(item.Price.Mul(item.Quantity)),
+			})
+		}
+		item.Price = breakdown.UnitPrice
+		item.PriceBreakdown = &breakdown
+	}
+	return drift
+}
+
+func (q *Quoter) quoteFromShop(ctx context.Context, shopID string, options models.PrintOrderOptions) (models.PriceBreakdown, bool) {
+	services, err := q.shops.GetServicesByShopID(ctx, shopID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get services for shop %s: %v", shopID, err)
+		return models.PriceBreakdown{}, false
+	}
+
+	var best models.PriceBreakdown
+	found := false
+	for _, service := range services {
+		if !service.IsActive {
+			continue
+		}
+		breakdown, ok := q.QuoteService(ctx, service, options)
+		if ok && breakdown.Total.IsPositive() && (!found || breakdown.Total.Cmp(best.Total) < 0) {
+			best, found = breakdown, true
+		}
+	}
+	return best, found
+}
+
+func (q *Quoter) quoteFromCatalog(options models.PrintOrderOptions) models.PriceBreakdown {
+	resp := q.pricing.Calculate(PriceRequest{
+		Size:      options.Size,
+		Frame:     options.Frame,
+		Material:  options.Material,
+		Medium:    options.Medium,
+		Quantity:  options.Quantity,
+		RushOrder: options.RushOrder,
+	}, q.catalog.GetPrintOptions())
+
+	return finalize(models.PriceBreakdown{
+		Source:     models.PriceSourceCatalog,
+		Quantity:   options.Quantity,
+		FramePrice: q.catalogFramePrice(options.Frame),
+		Total:      resp.Total,
+	})
+}
+
+// frameFor resolves a frame selection among a shop's frames. A frame the shop does not
+// configure is priced from the legacy catalog so the line is not silently under-charged.
+func (q *Quoter) frameFor(ctx context.Context, shopID, selection string) *models.Frame {
+	if !WantsFrame(selection) {
+		return nil
+	}
+	frames, err := q.frames.ListFrames(ctx, repositories.FrameFilter{ShopID: shopID})
+	if err != nil {
+		log.Printf("⚠️ Failed to get frames for shop %s: %v", shopID, err)
+	}
+	if frame := ResolveFrame(frames, selection); frame != nil {
+		return frame
+	}
+	return &models.Frame{Type: selection, BasePrice: q.catalogFramePrice(selection).Major()}
+}
+
+func (q *Quoter) catalogFramePrice(frame string) models.Money {
+	for _, f := range q.catalog.GetPrintOptions().Frames {
+		if f.Type == frame {
+			return models.FromMajor(float64(f.Price), models.DefaultCurrency)
+		}
+	}
+	return models.NewMoney(0, models.DefaultCurrency)
+}
+
+// finalize derives a catalog breakdown's unit price, rounded half up, and makes the total
+// UnitPrice × Quantity like shop quotes
+func finalize(b models.PriceBreakdown) models.PriceBreakdown {
+	if b.Quantity > 0 {
+		unit := models.Round(float64(b.Total.Amount)/float64(b.Quantity), models.RoundHalfUp)
+		b.UnitPrice = models.NewMoney(unit, b.Total.Currency)
+		b.Total = b.UnitPrice.Mul(b.Quantity)
+	}
+	return b
+}
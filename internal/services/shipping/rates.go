@@ -0,0 +1,60 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/services/pricing"
+)
+
+// ErrNoRate is returned when a provider cannot ship a parcel between two places
+var ErrNoRate = errors.New("no shipping rate for this route")
+
+// Parcel is one shipment from a print shop to a buyer
+type Parcel struct {
+	Origin      models.Location // the shop; empty when the order has no shop yet
+	Destination models.Location
+	WeightKg    float64
+}
+
+// RateProvider prices shipping a parcel
+type RateProvider interface {
+	// Name identifies the provider on stored quotes
+	Name() string
+	// Quote returns the price of shipping the parcel, or ErrNoRate
+	Quote(ctx context.Context, parcel Parcel) (models.ShippingQuote, error)
+}
+
+// Estimated packed weights used until services declare their own
+const (
+	packagingWeightKg    = 0.2
+	defaultPrintWeightKg = 0.5
+	frameWeightKg        = 1.5
+)
+
+// printWeightsKg is the packed weight of one unframed print by size
+var printWeightsKg = map[string]float64{
+	"A5": 0.15,
+	"A4": 0.3,
+	"A3": 0.5,
+	"A2": 0.9,
+	"A1": 1.6,
+}
+
+// ParcelWeightKg estimates the packed weight of a set of order lines
+func ParcelWeightKg(items []models.CartItem) float64 {
+	weight := packagingWeightKg
+	for _, item := range items {
+		unit, ok := printWeightsKg[strings.ToUpper(item.PrintOptions.Size)]
+		if !ok {
+			unit = defaultPrintWeightKg
+		}
+		if pricing.WantsFrame(item.PrintOptions.Frame) {
+			unit += frameWeightKg
+		}
+		weight += unit * float64(max(item.Quantity, 1))
+	}
+	return weight
+}
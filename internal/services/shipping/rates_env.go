@@ -0,0 +1,34 @@
+package shipping
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+)
+
+// NewRateProviderFromEnv builds the rate provider named by SHIPPING_RATE_PROVIDER. The table
+// provider is the default; SHIPPING_RATES may replace its zone rates with a JSON object keyed
+// by zone, e.g. {"local":{"base":300,"includedKg":2,"perKg":60,"estimatedDays":1}}. An unknown
+// provider or malformed rates are logged and the defaults used instead.
+func NewRateProviderFromEnv() RateProvider {
+	switch name := strings.ToLower(strings.TrimSpace(os.Getenv("SHIPPING_RATE_PROVIDER"))); name {
+	case "", "table":
+	default:
+		log.Printf("⚠️ Unknown shipping rate provider %q; using table rates", name)
+	}
+
+	provider := NewTableRateProvider()
+	if raw := os.Getenv("SHIPPING_RATES"); raw != "" {
+		var rates map[string]ZoneRate
+		if err := json.Unmarshal([]byte(raw), &rates); err != nil {
+			log.Printf("⚠️ Ignoring malformed SHIPPING_RATES: %v", err)
+		} else {
+			for zone, rate := range rates {
+				provider.SetRate(zone, rate)
+			}
+		}
+	}
+	log.Printf("✅ Shipping rates: %s", provider.Name())
+	return provider
+}
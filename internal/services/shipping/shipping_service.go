@@ -0,0 +1,72 @@
+package shipping
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// ShippingService applies a delivery choice to a checked-out order group
+type ShippingService struct {
+	provider RateProvider
+	shops    repositories.PrintShopRepository
+}
+
+// NewShippingService creates a shipping service quoting with the given rate provider
+func NewShippingService(provider RateProvider, shops repositories.PrintShopRepository) *ShippingService {
+	return &ShippingService{provider: provider, shops: shops}
+}
+
+// ApplyShipping quotes a parcel from each sub-order's shop to the address and adds the charge
+// to the sub-order's and the parent's totals
+func (s *ShippingService) ApplyShipping(ctx context.Context, parent *models.Order, subOrders []*models.Order, address models.Address) error {
+	for _, sub := range subOrders {
+		parcel := Parcel{Destination: address.Location, WeightKg: ParcelWeightKg(sub.Items)}
+		if shop := s.shopOf(ctx, sub); shop != nil {
+			parcel.Origin = shop.Location
+		}
+		quote, err := s.provider.Quote(ctx, parcel)
+		if err != nil {
+			return fmt.Errorf("failed to quote shipping for order %s: %w", sub.OrderID, err)
+		}
+
+		addr := address
+		sub.ShippingAddress = &addr
+		sub.ShippingQuote = &quote
+		sub.ShippingCost = quote.Amount
+		sub.TotalAmount = sub.TotalAmount.Add(quote.Amount)
+		parent.ShippingCost = parent.ShippingCost.Add(quote.Amount)
+		parent.TotalAmount = parent.TotalAmount.Add(quote.Amount)
+	}
+	parent.ShippingAddress = &address
+	return nil
+}
+
+// ApplyPickup sets each sub-order's pickup location to its shop's address. The parent
+// carries the location too when every item is collected from the same shop.
+func (s *ShippingService) ApplyPickup(ctx context.Context, parent *models.Order, subOrders []*models.Order) {
+	for _, sub := range subOrders {
+		if shop := s.shopOf(ctx, sub); shop != nil {
+			sub.PickupLocation = shop.Name + ", " + shop.Location.Format()
+		}
+	}
+	if len(subOrders) == 1 {
+		parent.PickupLocation = subOrders[0].PickupLocation
+	}
+}
+
+// shopOf loads the sub-order's shop, or returns nil when it is unassigned or cannot be loaded
+func (s *ShippingService) shopOf(ctx context.Context, sub *models.Order) *models.PrintShopProfile {
+	if sub.PrintShopID == "" {
+		return nil
+	}
+	shop, err := s.shops.GetShopByID(ctx, sub.PrintShopID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load shop %s for delivery of order %s: %v", sub.PrintShopID, sub.OrderID, err)
+		return nil
+	}
+	return shop
+}
@@ -0,0 +1,87 @@
+package shipping
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/services/geo"
+)
+
+// Shipping zones used by the table provider
+const (
+	ZoneLocal         = "local"         // same city, or within localRadiusKm
+	ZoneDomestic      = "domestic"      // same country
+	ZoneInternational = "international" // everything else
+)
+
+// localRadiusKm counts located parcels this close as local even across city lines
+const localRadiusKm = 30
+
+// ZoneRate prices one zone: Base covers the first IncludedKg, each further kg (or part) costs PerKg
+type ZoneRate struct {
+	Base          float64 `json:"base"`
+	IncludedKg    float64 `json:"includedKg"`
+	PerKg         float64 `json:"perKg"`
+	EstimatedDays int     `json:"estimatedDays"`
+}
+
+// TableRateProvider prices parcels from a zone/weight table; it is the default rate provider
+type TableRateProvider struct {
+	rates map[string]ZoneRate
+}
+
+// NewTableRateProvider creates a table provider with the default rates
+func NewTableRateProvider() *TableRateProvider {
+	return &TableRateProvider{rates: map[string]ZoneRate{
+		ZoneLocal:         {Base: 250, IncludedKg: 2, PerKg: 50, EstimatedDays: 1},
+		ZoneDomestic:      {Base: 450, IncludedKg: 1, PerKg: 100, EstimatedDays: 3},
+		ZoneInternational: {Base: 3500, IncludedKg: 0.5, PerKg: 900, EstimatedDays: 10},
+	}}
+}
+
+// SetRate adds or replaces the rate of a zone
+func (p *TableRateProvider) SetRate(zone string, rate ZoneRate) {
+	p.rates[zone] = rate
+}
+
+// Name returns the provider name
+func (p *TableRateProvider) Name() string {
+	return "table"
+}
+
+// Quote prices the parcel from its zone and weight
+func (p *TableRateProvider) Quote(ctx context.Context, parcel Parcel) (models.ShippingQuote, error) {
+	zone := zoneOf(parcel.Origin, parcel.Destination)
+	rate, ok := p.rates[zone]
+	if !ok {
+		return models.ShippingQuote{}, ErrNoRate
+	}
+
+	amount := rate.Base
+	if extra := parcel.WeightKg - rate.IncludedKg; extra > 0 {
+		amount += math.Ceil(extra) * rate.PerKg
+	}
+	return models.ShippingQuote{
+		Provider:      p.Name(),
+		Zone:          zone,
+		WeightKg:      math.Round(parcel.WeightKg*100) / 100,
+		Amount:        models.FromMajor(amount, models.DefaultCurrency),
+		EstimatedDays: rate.EstimatedDays,
+	}, nil
+}
+
+// zoneOf classifies a route. An unknown origin (no shop yet) is priced as domestic.
+func zoneOf(origin, dest models.Location) string {
+	if origin.HasCoordinates() && dest.HasCoordinates() && geo.DistanceKm(origin, dest) <= localRadiusKm {
+		return ZoneLocal
+	}
+	if origin.Country != "" && !strings.EqualFold(origin.Country, dest.Country) {
+		return ZoneInternational
+	}
+	if origin.City != "" && strings.EqualFold(origin.City, dest.City) {
+		return ZoneLocal
+	}
+	return ZoneDomestic
+}
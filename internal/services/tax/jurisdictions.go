@@ -0,0 +1,72 @@
+package tax
+
+import (
+	"strings"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+)
+
+// Jurisdiction is how one country, or one state of a country, taxes sales
+type Jurisdiction struct {
+	Code      string                      // "KE", or "US-CA" for a state
+	Name      string                      // tax name shown on tax lines, e.g. "VAT"
+	Rates     map[models.TaxClass]float64 // fraction per class; a missing class is not taxed
+	Inclusive bool                        // prices already include the tax
+}
+
+// Table holds the configured jurisdictions. A state entry overrides its country's entry.
+type Table struct {
+	jurisdictions map[string]Jurisdiction
+}
+
+// NewTable creates a table holding the jurisdictions in settings
+func NewTable(settings *models.TaxSettings) *Table {
+	t := &Table{jurisdictions: make(map[string]Jurisdiction)}
+	for _, j := range settings.Jurisdictions {
+		t.Set(j.Country, j.State, Jurisdiction{Name: j.Name, Rates: j.Rates, Inclusive: j.Inclusive})
+	}
+	return t
+}
+
+// Set adds or replaces the jurisdiction for a country (ISO code or name) and optional state
+func (t *Table) Set(country, state string, j Jurisdiction) {
+	j.Code = jurisdictionCode(country, state)
+	t.jurisdictions[j.Code] = j
+}
+
+// Lookup returns the jurisdiction covering a location, preferring a state entry
+func (t *Table) Lookup(loc models.Location) (Jurisdiction, bool) {
+	if loc.Country == "" {
+		return Jurisdiction{}, false
+	}
+	if loc.State != "" {
+		if j, ok := t.jurisdictions[jurisdictionCode(loc.Country, loc.State)]; ok {
+			return j, true
+		}
+	}
+	j, ok := t.jurisdictions[jurisdictionCode(loc.Country, "")]
+	return j, ok
+}
+
+// countryCodes maps the country names buyers type to the codes tax tables use
+var countryCodes = map[string]string{
+	"kenya":          "KE",
+	"uganda":         "UG",
+	"tanzania":       "TZ",
+	"united states":  "US",
+	"usa":            "US",
+	"united kingdom": "GB",
+	"uk":             "GB",
+}
+
+func jurisdictionCode(country, state string) string {
+	country = strings.TrimSpace(country)
+	if code, ok := countryCodes[strings.ToLower(country)]; ok {
+		country = code
+	}
+	code := strings.ToUpper(country)
+	if state = strings.TrimSpace(state); state != "" {
+		code += "-" + strings.ToUpper(state)
+	}
+	return code
+}
@@ -0,0 +1,153 @@
+package tax
+
+import (
+	"context"
+	"log"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// SettingsSource supplies the tax jurisdictions in effect
+type SettingsSource interface {
+	TaxSettings(ctx context.Context) *models.TaxSettings
+}
+
+// TaxService works out the tax on checked-out orders
+type TaxService struct {
+	settings SettingsSource
+	shops    repositories.PrintShopRepository
+	users    repositories.UserRepository
+}
+
+// NewTaxService creates a tax service taxing with the jurisdictions settings currently lists
+func NewTaxService(settings SettingsSource, store *repositories.Store) *TaxService {
+	return &TaxService{settings: settings, shops: store.PrintShops, users: store.Users}
+}
+
+// taxClasses lists classes in the order their tax lines are written
+var taxClasses = []models.TaxClass{models.TaxClassPrint, models.TaxClassFrame, models.TaxClassShipping}
+
+// ApplyTax adds tax lines to each sub-order and totals them on the parent. It runs after shipping
+// and promotions, since tax is charged on what the buyer pays. Each sub-order is taxed where it
+// is supplied: the shipping address, or the shop for pickup. Exclusive tax is added to the
+// totals; for exempt buyers inclusive tax is taken out of them instead.
+func (s *TaxService) ApplyTax(ctx context.Context, parent *models.Order, subOrders []*models.Order) {
+	exempt := false
+	if user, err := s.users.GetUserByID(ctx, parent.BuyerID); err == nil {
+		exempt = user.TaxExempt
+	}
+
+	table := NewTable(s.settings.TaxSettings(ctx))
+	parent.TaxExempt = exempt
+	for _, sub := range subOrders {
+		sub.TaxExempt = exempt
+		j, ok := table.Lookup(s.placeOfSupply(ctx, sub))
+		if !ok {
+			continue
+		}
+
+		amounts := classAmounts(sub)
+		for _, class := range taxClasses {
+			rate, gross := j.Rates[class], amounts[class]
+			if rate <= 0 || !gross.IsPositive() {
+				continue
+			}
+			line := models.TaxLine{Jurisdiction: j.Code, Name: j.Name, Class: class, Rate: rate, Inclusive: j.Inclusive}
+			if j.Inclusive {
+				line.Amount = gross.MulRate(rate/(1+rate), models.RoundHalfEven)
+				line.Taxable = gross.Sub(line.Amount)
+			} else {
+				line.Taxable = gross
+				line.Amount = gross.MulRate(rate, models.RoundHalfEven)
+			}
+
+			if exempt {
+				if line.Inclusive {
+					sub.TotalAmount = sub.TotalAmount.Sub(line.Amount)
+					parent.TotalAmount = parent.TotalAmount.Sub(line.Amount)
+				}
+				continue
+			}
+			if !line.Inclusive {
+				sub.TotalAmount = sub.TotalAmount.Add(line.Amount)
+				parent.TotalAmount = parent.TotalAmount.Add(line.Amount)
+			}
+			sub.TaxLines = append(sub.TaxLines, line)
+			sub.TaxTotal = sub.TaxTotal.Add(line.Amount)
+			parent.TaxLines = mergeTaxLine(parent.TaxLines, line)
+			parent.TaxTotal = parent.TaxTotal.Add(line.Amount)
+		}
+	}
+}
+
+// placeOfSupply returns where a sub-order is handed to the buyer
+func (s *TaxService) placeOfSupply(ctx context.Context, sub *models.Order) models.Location {
+	if sub.ShippingAddress != nil {
+		return sub.ShippingAddress.Location
+	}
+	if sub.PrintShopID != "" {
+		shop, err := s.shops.GetShopByID(ctx, sub.PrintShopID)
+		if err == nil {
+			return shop.Location
+		}
+		log.Printf("⚠️ Failed to load shop %s to tax order %s: %v", sub.PrintShopID, sub.OrderID, err)
+	}
+	if len(sub.Items) > 0 && sub.Items[0].PrintOptions.DeliveryLocation != nil {
+		return *sub.Items[0].PrintOptions.DeliveryLocation
+	}
+	return models.Location{}
+}
+
+// classAmounts splits what the buyer pays for a sub-order into tax classes, net of discounts.
+// A line's frames are its frame price times quantity; the rest of the line is the print.
+func classAmounts(sub *models.Order) map[models.TaxClass]models.Money {
+	amounts := make(map[models.TaxClass]models.Money)
+	for _, item := range sub.Items {
+		total := item.LineTotal()
+		if !total.IsPositive() {
+			continue
+		}
+		paid := total.Sub(sub.LineDiscount(item.LineID)).Max(models.NewMoney(0, total.Currency))
+		var frame models.Money
+		if b := item.PriceBreakdown; b != nil {
+			frame = b.FramePrice.Mul(b.Quantity).Min(total)
+		}
+		shares := paid.Allocate(frame.Amount, total.Amount-frame.Amount)
+		amounts[models.TaxClassFrame] = amounts[models.TaxClassFrame].Add(shares[0])
+		amounts[models.TaxClassPrint] = amounts[models.TaxClassPrint].Add(shares[1])
+	}
+
+	shipping := sub.ShippingCost
+	for _, d := range sub.Discounts {
+		shipping = shipping.Sub(d.Shipping)
+	}
+	if shipping.IsPositive() {
+		amounts[models.TaxClassShipping] = shipping
+	}
+	return amounts
+}
+
+// SumTaxLines totals the tax lines of sub-orders by jurisdiction and class, as ApplyTax does on their parent
+func SumTaxLines(subOrders []*models.Order) []models.TaxLine {
+	var lines []models.TaxLine
+	for _, sub := range subOrders {
+		for _, line := range sub.TaxLines {
+			lines = mergeTaxLine(lines, line)
+		}
+	}
+	return lines
+}
+
+// mergeTaxLine adds a line into the totals of the same jurisdiction and class
+func mergeTaxLine(lines []models.TaxLine, line models.TaxLine) []models.TaxLine {
+	for i := range lines {
+		l := &lines[i]
+		if l.Jurisdiction == line.Jurisdiction && l.Class == line.Class && l.Rate == line.Rate && l.Inclusive == line.Inclusive {
+			l.Taxable = l.Taxable.Add(line.Taxable)
+			l.Amount = l.Amount.Add(line.Amount)
+			return lines
+		}
+	}
+	return append(lines, line)
+}
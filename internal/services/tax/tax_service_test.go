@@ -0,0 +1,123 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cecvl/art-print-backend/internal/models"
+	"github.com/cecvl/art-print-backend/internal/repositories"
+)
+
+// staticSettings serves a fixed set of jurisdictions
+type staticSettings struct{ settings *models.TaxSettings }
+
+func (s staticSettings) TaxSettings(ctx context.Context) *models.TaxSettings { return s.settings }
+
+func TestApplyTax(t *testing.T) {
+	jurisdictions := &models.TaxSettings{Jurisdictions: []models.TaxJurisdiction{
+		{Country: "KE", Name: "VAT", Rates: map[models.TaxClass]float64{models.TaxClassPrint: 0.16, models.TaxClassShipping: 0.16}, Inclusive: true},
+		{Country: "US", State: "CA", Name: "Sales tax", Rates: map[models.TaxClass]float64{models.TaxClassPrint: 0.0725}},
+	}}
+	kes := func(minor int64) models.Money { return models.NewMoney(minor, "KES") }
+
+	tests := []struct {
+		name      string
+		location  models.Location
+		exempt    bool
+		price     models.Money
+		shipping  models.Money
+		wantTotal models.Money
+		wantTax   models.Money
+		wantLines int
+	}{
+		{
+			name:      "inclusive tax is carved out of the price",
+			location:  models.Location{Country: "Kenya"},
+			price:     kes(11600),
+			wantTotal: kes(11600),
+			wantTax:   kes(1600),
+			wantLines: 1,
+		},
+		{
+			name:      "inclusive tax on print and shipping",
+			location:  models.Location{Country: "KE"},
+			price:     kes(11600),
+			shipping:  kes(580),
+			wantTotal: kes(12180),
+			wantTax:   kes(1680),
+			wantLines: 2,
+		},
+		{
+			name:      "exclusive tax is added to the total",
+			location:  models.Location{Country: "US", State: "CA"},
+			price:     kes(10000),
+			wantTotal: kes(10725),
+			wantTax:   kes(725),
+			wantLines: 1,
+		},
+		{
+			name:      "exclusive tax rounds half to even",
+			location:  models.Location{Country: "US", State: "CA"},
+			price:     kes(200),
+			wantTotal: kes(214),
+			wantTax:   kes(14),
+			wantLines: 1,
+		},
+		{
+			name:      "exempt buyer pays the price less inclusive tax",
+			location:  models.Location{Country: "KE"},
+			exempt:    true,
+			price:     kes(11600),
+			wantTotal: kes(10000),
+		},
+		{
+			name:      "exempt buyer is not charged exclusive tax",
+			location:  models.Location{Country: "US", State: "CA"},
+			exempt:    true,
+			price:     kes(10000),
+			wantTotal: kes(10000),
+		},
+		{
+			name:      "no jurisdiction leaves the order untaxed",
+			location:  models.Location{Country: "UG"},
+			price:     kes(10000),
+			wantTotal: kes(10000),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repositories.NewMemoryStore()
+			if err := store.Users.CreateUser(ctx, &models.User{UID: "buyer", TaxExempt: tt.exempt}); err != nil {
+				t.Fatal(err)
+			}
+
+			total := tt.price.Add(tt.shipping)
+			sub := &models.Order{
+				OrderID:         "sub",
+				ParentOrderID:   "parent",
+				BuyerID:         "buyer",
+				Items:           []models.CartItem{{LineID: "line", Quantity: 1, Price: tt.price}},
+				ShippingAddress: &models.Address{Location: tt.location},
+				ShippingCost:    tt.shipping,
+				TotalAmount:     total,
+			}
+			parent := &models.Order{OrderID: "parent", BuyerID: "buyer", SubOrderIDs: []string{"sub"}, TotalAmount: total}
+
+			NewTaxService(staticSettings{jurisdictions}, store).ApplyTax(ctx, parent, []*models.Order{sub})
+
+			if sub.TotalAmount != tt.wantTotal || parent.TotalAmount != tt.wantTotal {
+				t.Errorf("totals = %s (sub), %s (parent), want %s", sub.TotalAmount, parent.TotalAmount, tt.wantTotal)
+			}
+			if sub.TaxTotal.Amount != tt.wantTax.Amount || parent.TaxTotal.Amount != tt.wantTax.Amount {
+				t.Errorf("tax = %s (sub), %s (parent), want %s", sub.TaxTotal, parent.TaxTotal, tt.wantTax)
+			}
+			if len(sub.TaxLines) != tt.wantLines || len(parent.TaxLines) != tt.wantLines {
+				t.Errorf("tax lines = %d (sub), %d (parent), want %d", len(sub.TaxLines), len(parent.TaxLines), tt.wantLines)
+			}
+			if sub.TaxExempt != tt.exempt || parent.TaxExempt != tt.exempt {
+				t.Errorf("tax exempt = %v (sub), %v (parent), want %v", sub.TaxExempt, parent.TaxExempt, tt.exempt)
+			}
+		})
+	}
+}